@@ -0,0 +1,131 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	accessAnalyzerResourceType = "AWS::IAM::Role"
+	accessAnalyzerStatus       = "ACTIVE"
+	accessAnalyzerFindingType  = "ExternalAccess"
+)
+
+// accessAnalyzerFinding is an external access finding shaped like those of IAM Access Analyzer, so veil output can be
+// cross-referenced with, or imported next to, the findings of an analyzer with the account as its zone of trust.
+type accessAnalyzerFinding struct {
+	ID                   string            `json:"id"`
+	Resource             string            `json:"resource"`
+	ResourceType         string            `json:"resourceType"`
+	ResourceOwnerAccount string            `json:"resourceOwnerAccount"`
+	Principal            map[string]string `json:"principal"`
+	Action               []string          `json:"action"`
+	Condition            map[string]string `json:"condition"`
+	IsPublic             bool              `json:"isPublic"`
+	Status               string            `json:"status"`
+	FindingType          string            `json:"findingType"`
+}
+
+// accessAnalyzerFindings wraps the findings as the ListFindings response of Access Analyzer does.
+type accessAnalyzerFindings struct {
+	Findings []accessAnalyzerFinding `json:"findings"`
+}
+
+// accessAnalyzerCondition flattens the conditions of a statement into the key to value form of Access Analyzer,
+// joining multiple values with commas.
+func accessAnalyzerCondition(statement Statement) map[string]string {
+	output := make(map[string]string)
+
+	for _, operator := range slices.Sorted(maps.Keys(statement.Condition)) {
+		for key, values := range statement.Condition[operator] {
+			output[key] = strings.Join(values, ",")
+		}
+	}
+
+	return output
+}
+
+// accessAnalyzerID derives a stable, UUID-shaped finding ID from the role, statement and principal.
+func accessAnalyzerID(role string, statement int, principal string) string {
+	sum := sha256Hex([]byte(role + "\n" + strconv.Itoa(statement) + "\n" + principal))
+
+	return sum[0:8] + "-" + sum[8:12] + "-" + sum[12:16] + "-" + sum[16:20] + "-" + sum[20:32]
+}
+
+// findExternalAccess returns the external access findings of the given trust policies: the AWS principals of other
+// accounts, wildcard principals and federated principals trusted by allowing statements. Wildcards are public unless
+// the statement narrows them, e.g. to an organisation.
+func findExternalAccess(trusts map[string]roleTrust) []accessAnalyzerFinding {
+	output := make([]accessAnalyzerFinding, 0)
+
+	for _, role := range slices.Sorted(maps.Keys(trusts)) {
+		roleAccount := accountFromARN(role)
+
+		for index, statement := range trusts[role].policy.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			external := make(map[string][]string)
+
+			for _, principal := range append(slices.Clone(statement.Principal.AWS), statement.Principal.Anonymous...) {
+				account := accountFromARN(normalisePrincipalIn(principal, rolePartition(role)))
+				if account == roleAccount {
+					continue
+				}
+
+				external["AWS"] = append(external["AWS"], principal)
+			}
+
+			external["Federated"] = statement.Principal.Federated
+
+			for _, kind := range slices.Sorted(maps.Keys(external)) {
+				for _, principal := range external[kind] {
+					output = append(output, accessAnalyzerFinding{
+						ID:                   accessAnalyzerID(role, index, principal),
+						Resource:             role,
+						ResourceType:         accessAnalyzerResourceType,
+						ResourceOwnerAccount: roleAccount,
+						Principal:            map[string]string{kind: principal},
+						Action:               statement.Action,
+						Condition:            accessAnalyzerCondition(statement),
+						IsPublic:             strings.ContainsAny(principal, "*?") && statement.wildcardPrincipal() != "",
+						Status:               accessAnalyzerStatus,
+						FindingType:          accessAnalyzerFindingType,
+					})
+				}
+			}
+		}
+	}
+
+	return output
+}
+
+func (a *App) runAccessAnalyzer(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := findExternalAccess(trusts)
+	a.logger.Debug(
+		"found external access to IAM roles",
+		slog.Int("roles", len(trusts)),
+		slog.Int("findings", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(accessAnalyzerFindings{Findings: output})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func Test_findExternalAccess(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		fixture string
+		want    []accessAnalyzerFinding
+	}{
+		{
+			name:    "cross-account",
+			fixture: fixtureCrossAccountTagSession,
+			want: []accessAnalyzerFinding{
+				{
+					Principal: map[string]string{"AWS": "arn:aws:iam::210987654321:root"},
+					Action:    []string{"sts:AssumeRole", "sts:TagSession"},
+					Condition: map[string]string{},
+					IsPublic:  false,
+				},
+			},
+		},
+		{
+			name:    "public",
+			fixture: fixtureWildcardPrincipal,
+			want: []accessAnalyzerFinding{
+				{
+					Principal: map[string]string{"AWS": "*"},
+					Action:    []string{"sts:AssumeRole"},
+					Condition: map[string]string{},
+					IsPublic:  true,
+				},
+			},
+		},
+		{
+			name:    "organisation-wide",
+			fixture: fixtureOrgWideTrust,
+			want: []accessAnalyzerFinding{
+				{
+					Principal: map[string]string{"AWS": "*"},
+					Action:    []string{"sts:AssumeRole"},
+					Condition: map[string]string{"aws:PrincipalOrgID": "o-a1b2c3d4e5"},
+					IsPublic:  false,
+				},
+			},
+		},
+		{
+			name:    "federated",
+			fixture: fixtureUnscopedSAMLTrust,
+			want: []accessAnalyzerFinding{
+				{
+					Principal: map[string]string{"Federated": "arn:aws:iam::123456789012:saml-provider/CorporateIdP"},
+					Action:    []string{"sts:AssumeRoleWithSAML", "sts:TagSession"},
+					Condition: map[string]string{},
+					IsPublic:  false,
+				},
+			},
+		},
+		{
+			name:    "service",
+			fixture: fixtureAWSServiceRoleForECS,
+			want:    []accessAnalyzerFinding{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			const role = "arn:aws:iam::123456789012:role/test"
+
+			got := findExternalAccess(map[string]roleTrust{role: mustDecodeTrust(t, role, tt.fixture)})
+			if len(got) != len(tt.want) {
+				t.Fatalf("findExternalAccess() = %+v, want %+v", got, tt.want)
+			}
+
+			for i, want := range tt.want {
+				want.ID = accessAnalyzerID(role, 0, slices.Collect(maps.Values(want.Principal))[0])
+				want.Resource = role
+				want.ResourceType = accessAnalyzerResourceType
+				want.ResourceOwnerAccount = "123456789012"
+				want.Status = accessAnalyzerStatus
+				want.FindingType = accessAnalyzerFindingType
+
+				if !reflect.DeepEqual(got[i], want) {
+					t.Errorf("findExternalAccess()[%d] = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func Test_accessAnalyzerFinding_structure(t *testing.T) {
+	t.Parallel()
+
+	const role = "arn:aws:iam::123456789012:role/test"
+
+	findings := findExternalAccess(map[string]roleTrust{role: mustDecodeTrust(t, role, fixtureOrgWideTrust)})
+
+	data, err := json.Marshal(accessAnalyzerFindings{Findings: findings})
+	if err != nil {
+		t.Fatalf("failed to marshal findings: %v", err)
+	}
+
+	var decoded struct {
+		Findings []map[string]any `json:"findings"`
+	}
+
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("failed to unmarshal findings: %v", err)
+	}
+
+	want := []string{
+		"action", "condition", "findingType", "id", "isPublic", "principal", "resource", "resourceOwnerAccount",
+		"resourceType", "status",
+	}
+	if got := slices.Sorted(maps.Keys(decoded.Findings[0])); !reflect.DeepEqual(got, want) {
+		t.Errorf("finding fields = %v, want %v", got, want)
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	if id, _ := decoded.Findings[0]["id"].(string); !uuid.MatchString(id) {
+		t.Errorf("finding id = %q, want a UUID", id)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	codeOverboardAction = "OVERBOARD_ACTION"
+
+	actionAssumeRole                = "sts:AssumeRole"
+	actionAssumeRoleWithSAML        = "sts:AssumeRoleWithSAML"
+	actionAssumeRoleWithWebIdentity = "sts:AssumeRoleWithWebIdentity"
+)
+
+// OverboardActionFinding describes a wildcard action in a trust policy, which grants every STS action including
+// sts:SetSourceIdentity and sts:TagSession.
+type OverboardActionFinding struct {
+	RoleARN    string `json:"roleArn"`
+	Action     string `json:"action"`
+	Suggestion string `json:"suggestion"`
+}
+
+// DetectOverboardActions returns the wildcard actions allowed by the trust policy of the given role, with the explicit
+// actions its principals need suggested instead.
+func DetectOverboardActions(role string, policy TrustPolicy) []OverboardActionFinding {
+	output := make([]OverboardActionFinding, 0)
+
+	for _, statement := range policy.Statement {
+		for _, action := range overboardActions(statement) {
+			output = append(output, OverboardActionFinding{
+				RoleARN:    role,
+				Action:     action,
+				Suggestion: "replace " + action + " with " + strings.Join(assumeActions(statement.Principal), ", "),
+			})
+		}
+	}
+
+	return output
+}
+
+// overboardActions returns the wildcard actions allowed by the statement.
+func overboardActions(statement Statement) []string {
+	if !strings.EqualFold(statement.Effect, "Allow") {
+		return nil
+	}
+
+	output := make([]string, 0)
+
+	for _, action := range statement.Action {
+		if action == "*" || strings.EqualFold(action, "sts:*") {
+			output = append(output, action)
+		}
+	}
+
+	return output
+}
+
+// assumeActions returns the explicit actions the given principals need to assume a role.
+func assumeActions(principal Principal) []string {
+	output := make([]string, 0)
+
+	if len(principal.AWS) > 0 || len(principal.Service) > 0 || len(principal.Anonymous) > 0 {
+		output = append(output, actionAssumeRole)
+	}
+
+	for _, provider := range principal.Federated {
+		if strings.Contains(provider, ":saml-provider/") {
+			output = append(output, actionAssumeRoleWithSAML)
+		} else {
+			output = append(output, actionAssumeRoleWithWebIdentity)
+		}
+	}
+
+	if len(output) == 0 {
+		output = append(output, actionAssumeRole)
+	}
+
+	slices.Sort(output)
+
+	return slices.Compact(output)
+}
+
+func checkOverboardAction(_ checkEnv, role string, statement Statement) []Finding {
+	actions := overboardActions(statement)
+	if len(actions) == 0 {
+		return nil
+	}
+
+	fixed := statement
+	fixed.Action = Items(assumeActions(statement.Principal))
+
+	return []Finding{
+		{
+			Code:      codeOverboardAction,
+			Severity:  severityMedium,
+			Role:      role,
+			Principal: "",
+			Message: "the statement allows " + strings.Join(actions, ", ") +
+				", including sts:SetSourceIdentity and sts:TagSession",
+			Remediation: newRemediation(
+				"Replace the wildcard with the explicit actions the principals need: "+
+					strings.Join(fixed.Action, ", ")+".",
+				fixed,
+			),
+		},
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectOverboardActions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []OverboardActionFinding
+	}{
+		{
+			name:     "sts wildcard",
+			document: fixtureWildcardAction,
+			want: []OverboardActionFinding{
+				{
+					RoleARN:    "arn:aws:iam::0123456789:role/test",
+					Action:     "sts:*",
+					Suggestion: "replace sts:* with sts:AssumeRole",
+				},
+			},
+		},
+		{
+			name:     "explicit actions",
+			document: fixtureCrossAccountTagSession,
+			want:     []OverboardActionFinding{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			role := "arn:aws:iam::0123456789:role/test"
+
+			trust := mustDecodeTrust(t, role, tt.document)
+			if got := DetectOverboardActions(role, trust.policy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectOverboardActions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_assumeActions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal Principal
+		want      []string
+	}{
+		{
+			name:      "service",
+			principal: Principal{Service: Items{"ecs.amazonaws.com"}},
+			want:      []string{actionAssumeRole},
+		},
+		{
+			name:      "SAML",
+			principal: Principal{Federated: Items{"arn:aws:iam::0123456789:saml-provider/idp"}},
+			want:      []string{actionAssumeRoleWithSAML},
+		},
+		{
+			name: "OIDC and AWS",
+			principal: Principal{
+				AWS:       Items{"arn:aws:iam::0123456789:root"},
+				Federated: Items{"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com"},
+			},
+			want: []string{actionAssumeRole, actionAssumeRoleWithWebIdentity},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := assumeActions(tt.principal); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assumeActions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+const codeUnparseablePolicy = "UNPARSEABLE_POLICY"
+
+var errNoPolicyFiles = errors.New("no trust policy files found")
+
+// policyFiles expands the given files, directories and glob patterns into the trust policy files they contain.
+// Directories are walked recursively for .json files and a trailing /... is accepted, as in ./policies/...
+func policyFiles(paths []string) ([]string, error) {
+	output := make([]string, 0)
+
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, string(filepath.Separator)+"...")
+
+		matches := []string{path}
+		if strings.ContainsAny(path, "*?[") {
+			globbed, err := filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand %s: %w", path, err)
+			}
+
+			matches = globbed
+		}
+
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(file string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				// Explicitly named files are analysed whatever their extension.
+				if !entry.IsDir() && (file == match || strings.EqualFold(filepath.Ext(file), ".json")) {
+					output = append(output, file)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to walk %s: %w", match, err)
+			}
+		}
+	}
+
+	if len(output) == 0 {
+		return nil, fmt.Errorf("%w in %s", errNoPolicyFiles, strings.Join(paths, ", "))
+	}
+
+	return uniqSlice(output), nil
+}
+
+// loadPolicyFiles decodes each file as a raw trust policy, keyed by its path in place of a role ARN. Files that
+// cannot be read or decoded are reported as findings, so they show up, and gate, like any other issue.
+func loadPolicyFiles(logger *slog.Logger, files []string, strict bool) (map[string]roleTrust, []Finding) {
+	trusts := make(map[string]roleTrust, len(files))
+	failures := make([]Finding, 0)
+
+	for _, file := range files {
+		policy, err := loadPolicyFile(file, strict)
+		if err != nil {
+			logger.Warn("failed to analyse trust policy", slog.String("file", file), slog.String("error", err.Error()))
+
+			failures = append(failures, Finding{
+				Code:        codeUnparseablePolicy,
+				Severity:    severityHigh,
+				Role:        file,
+				Principal:   "",
+				Message:     err.Error(),
+				Remediation: nil,
+			})
+
+			continue
+		}
+
+		trusts[file] = roleTrust{
+			role:   types.Role{Arn: aws.String(file)}, //nolint:exhaustruct
+			policy: policy,
+		}
+	}
+
+	return trusts, failures
+}
+
+// loadPolicyFile reads and decodes a single trust policy file.
+func loadPolicyFile(file string, strict bool) (TrustPolicy, error) {
+	data, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return TrustPolicy{}, fmt.Errorf("failed to read trust policy: %w", err)
+	}
+
+	return decodePolicy(data, strict)
+}
+
+// runAnalyze runs the built-in checks against trust policy files instead of the roles of an account, e.g. those of
+// a policy-as-code repository in CI.
+func (a *App) runAnalyze(paths []string) ([]byte, error) {
+	files, err := policyFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	trusts, output := loadPolicyFiles(a.logger, files, a.strict)
+
+	env := checkEnv{
+		orgID:                 a.orgID,
+		missingProviders:      nil,
+		expiringSAMLProviders: nil,
+		gitlabHosts:           a.gitlabHosts,
+		labels:                a.labels,
+	}
+	output = append(output, runChecks(env, trusts)...)
+	sortFindings(output)
+
+	a.logger.Debug(
+		"analysed trust policy files",
+		slog.Int("files", len(files)),
+		slog.Int("unparseable", len(files)-len(trusts)),
+		slog.Int("findings", len(output)),
+	)
+
+	a.gateFindings(output)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+// offlineConfigLoader returns an empty SDK config without reading the environment or shared config files, for
+// commands that never talk to AWS.
+type offlineConfigLoader struct{}
+
+// LoadDefaultConfig returns an empty SDK config.
+//
+//nolint:nonamedreturns
+func (offlineConfigLoader) LoadDefaultConfig(
+	context.Context,
+	...func(*config.LoadOptions) error,
+) (cfg aws.Config, err error) {
+	return aws.Config{}, nil //nolint:exhaustruct
+}
+
+var _ ConfigLoader = (*offlineConfigLoader)(nil)
+
+// runAnalyzeCommand analyses the trust policy files at paths and writes the findings like a scan would. It never
+// talks to AWS, so the App is built from an offline config and the scan's AWS side effects, such as DynamoDB writes
+// and SNS notifications, do not apply. It reports whether any finding failed the gate.
+func runAnalyzeCommand(
+	ctx context.Context,
+	paths []string,
+	flags *cliFlags,
+	opts []Option,
+	signingKey ed25519.PrivateKey,
+) (bool, error) {
+	app, err := NewApp(ctx, *flags.region, offlineConfigLoader{}, append(opts, WithLazyInit())...)
+	if err != nil {
+		return false, fmt.Errorf("failed to initialize app: %w", err)
+	}
+
+	marshal, err := app.runAnalyze(paths)
+	if err != nil {
+		return false, err
+	}
+
+	app.reportUnusedLabels()
+
+	err = app.requireJSONOutput(marshal)
+	if err != nil {
+		return false, fmt.Errorf("failed to rename output keys: %w", err)
+	}
+
+	marshal = app.redact(marshal)
+
+	err = writeOutput(marshal, os.Stdout, *flags.outputPath, *flags.tee)
+	if err != nil {
+		return false, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if *flags.digest || signingKey != nil {
+		err = writeIntegrity(*flags.outputPath, marshal, signingKey)
+		if err != nil {
+			return false, fmt.Errorf("failed to write output integrity files: %w", err)
+		}
+	}
+
+	return app.failed.Load(), nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writePolicyTree writes the given files, keyed by slash-separated path, under a temporary directory.
+func writePolicyTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func Test_policyFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := writePolicyTree(t, map[string]string{
+		"ecs.json":           fixtureAWSServiceRoleForECS,
+		"team/vendor.json":   fixtureCrossAccountTagSession,
+		"team/wildcard.JSON": fixtureWildcardPrincipal,
+		"team/notes.md":      "# trust policies",
+		"legacy.policy":      fixtureWildcardPrincipal,
+	})
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "directory walked recursively",
+			paths: []string{dir},
+			want: []string{
+				filepath.Join(dir, "ecs.json"),
+				filepath.Join(dir, "team", "vendor.json"),
+				filepath.Join(dir, "team", "wildcard.JSON"),
+			},
+			wantErr: false,
+		},
+		{
+			name:  "recursive suffix",
+			paths: []string{filepath.Join(dir, "team") + "/..."},
+			want: []string{
+				filepath.Join(dir, "team", "vendor.json"),
+				filepath.Join(dir, "team", "wildcard.JSON"),
+			},
+			wantErr: false,
+		},
+		{
+			name:    "glob",
+			paths:   []string{filepath.Join(dir, "*.json")},
+			want:    []string{filepath.Join(dir, "ecs.json")},
+			wantErr: false,
+		},
+		{
+			name:    "explicit file of any extension",
+			paths:   []string{filepath.Join(dir, "legacy.policy"), filepath.Join(dir, "legacy.policy")},
+			want:    []string{filepath.Join(dir, "legacy.policy")},
+			wantErr: false,
+		},
+		{
+			name:    "missing path",
+			paths:   []string{filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "no policy files",
+			paths:   []string{filepath.Join(dir, "*.yaml")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := policyFiles(tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("policyFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policyFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_runAnalyze(t *testing.T) {
+	t.Parallel()
+
+	dir := writePolicyTree(t, map[string]string{
+		"ecs.json":      fixtureAWSServiceRoleForECS,
+		"wildcard.json": fixtureWildcardPrincipal,
+		"broken.json":   `{"Statement": [`,
+	})
+
+	tests := []struct {
+		name       string
+		failOn     string
+		paths      []string
+		wantCodes  []string
+		wantFailed bool
+	}{
+		{
+			name:       "findings and unparseable files",
+			failOn:     "",
+			paths:      []string{dir},
+			wantCodes:  []string{codeUnparseablePolicy, codeWildcardPrincipal},
+			wantFailed: false,
+		},
+		{
+			name:       "gated on high findings",
+			failOn:     severityHigh,
+			paths:      []string{dir},
+			wantCodes:  []string{codeUnparseablePolicy, codeWildcardPrincipal},
+			wantFailed: true,
+		},
+		{
+			name:       "clean policy passes the gate",
+			failOn:     severityInfo,
+			paths:      []string{filepath.Join(dir, "ecs.json")},
+			wantCodes:  []string{},
+			wantFailed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{failOn: tt.failOn, logger: slog.New(slog.DiscardHandler)}
+
+			data, err := app.runAnalyze(tt.paths)
+			if err != nil {
+				t.Fatalf("runAnalyze() unexpected error: %v", err)
+			}
+
+			var findings []Finding
+
+			err = json.Unmarshal(data, &findings)
+			if err != nil {
+				t.Fatalf("runAnalyze() returned invalid JSON: %v", err)
+			}
+
+			codes := make([]string, 0, len(findings))
+			for _, finding := range findings {
+				codes = append(codes, finding.Code)
+			}
+
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("runAnalyze() codes = %v, want %v", codes, tt.wantCodes)
+			}
+
+			if app.failed.Load() != tt.wantFailed {
+				t.Errorf("runAnalyze() failed = %v, want %v", app.failed.Load(), tt.wantFailed)
+			}
+		})
+	}
+}
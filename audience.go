@@ -0,0 +1,163 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	codeOIDCNoAudience       = "OIDC_NO_AUDIENCE"
+	codeOIDCWildcardAudience = "OIDC_WILDCARD_AUDIENCE"
+
+	googleProvider  = "accounts.google.com"
+	cognitoProvider = "cognito-identity.amazonaws.com"
+)
+
+// webIdentityAudienceKeys maps the public identity providers to the condition key holding their audience, where it
+// is not the aud claim: Login with Amazon and Facebook identify the application by app_id.
+var webIdentityAudienceKeys = map[string]string{ //nolint:gochecknoglobals
+	"graph.facebook.com": "graph.facebook.com:app_id",
+	"www.amazon.com":     "www.amazon.com:app_id",
+}
+
+// audienceKey returns the condition key pinning the audience of the given web identity principal, e.g.
+// token.actions.githubusercontent.com:aud for the GitHub OIDC provider or cognito-identity.amazonaws.com:aud for
+// Cognito, or an empty string if the principal is neither an OIDC provider nor a public identity provider.
+func audienceKey(principal string) string {
+	host := oidcProviderHost(principal)
+	if host == "" && isWebIdentityProvider(principal) {
+		host = principal
+	}
+
+	if host == "" {
+		return ""
+	}
+
+	if key, ok := webIdentityAudienceKeys[host]; ok {
+		return key
+	}
+
+	return host + ":aud"
+}
+
+// conditionOperatorBase returns the operator without its ForAnyValue or ForAllValues qualifier and IfExists suffix,
+// e.g. StringLike for ForAnyValue:StringLikeIfExists.
+func conditionOperatorBase(operator string) string {
+	base := strings.TrimSuffix(operator, "IfExists")
+	if _, rest, found := strings.Cut(base, ":"); found {
+		base = rest
+	}
+
+	return base
+}
+
+// audienceValues returns the values the statement pins the given audience key to, exactly with StringEquals or
+// StringLike values free of wildcards, and the StringLike values containing wildcards.
+func audienceValues(statement Statement, key string) ([]string, []string) {
+	pinned, wildcards := make([]string, 0), make([]string, 0)
+
+	for _, operator := range slices.Sorted(maps.Keys(statement.Condition)) {
+		base := conditionOperatorBase(operator)
+		if base != "StringEquals" && base != "StringLike" {
+			continue
+		}
+
+		for name, values := range statement.Condition[operator] {
+			if !strings.EqualFold(name, key) {
+				continue
+			}
+
+			for _, value := range values {
+				if base == "StringLike" && strings.ContainsAny(value, "*?") {
+					wildcards = append(wildcards, value)
+				} else {
+					pinned = append(pinned, value)
+				}
+			}
+		}
+	}
+
+	return pinned, wildcards
+}
+
+// expectedAudience describes the audience the given web identity principal should be pinned to.
+func expectedAudience(principal string) string {
+	switch {
+	case oidcProviderHost(principal) == githubOIDCProvider:
+		return "sts.amazonaws.com"
+	case principal == googleProvider:
+		return "the OAuth client ID of the application"
+	case principal == cognitoProvider:
+		return "the ID of the identity pool"
+	case webIdentityAudienceKeys[principal] != "":
+		return "the ID of the application"
+	default:
+		return "the audience the provider issues the tokens for"
+	}
+}
+
+// checkOIDCAudience flags web identity trust that does not pin the audience: GitHub Actions to sts.amazonaws.com,
+// Google to the OAuth client ID, Cognito to the identity pool, and so on. Without it, tokens the provider issued to
+// any other application are accepted. A missing audience is left to the checks of unscoped federated trust and of
+// GitLab and Bitbucket subjects where they report it already. Remediations carry no statement, as those checks propose
+// their own for the same statements, which fix would refuse as conflicting.
+func checkOIDCAudience(env checkEnv, role string, statement Statement) []Finding {
+	if !statement.allowsAction(actionAssumeRoleWithWebIdentity) {
+		return nil
+	}
+
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		key := audienceKey(principal)
+		if key == "" {
+			continue
+		}
+
+		pinned, wildcards := audienceValues(statement, key)
+		remediation := &Remediation{
+			Summary:   "Require a StringEquals condition pinning " + key + " to " + expectedAudience(principal) + ".",
+			Fragment:  nil,
+			Statement: 0,
+		}
+
+		switch {
+		case len(wildcards) > 0:
+			output = append(output, Finding{
+				Code:      codeOIDCWildcardAudience,
+				Severity:  severityMedium,
+				Role:      role,
+				Principal: principal,
+				Message: "the StringLike condition on " + key + " matches " + strings.Join(wildcards, ", ") +
+					", accepting tokens issued to other audiences",
+				Remediation: remediation,
+			})
+		case len(pinned) == 0 && !audienceGapReported(env, statement, principal):
+			output = append(output, Finding{
+				Code:        codeOIDCNoAudience,
+				Severity:    severityMedium,
+				Role:        role,
+				Principal:   principal,
+				Message:     "no StringEquals condition on " + key + " pins the audience of the web identity tokens",
+				Remediation: remediation,
+			})
+		}
+	}
+
+	return output
+}
+
+// audienceGapReported reports whether a missing audience of the federated principal is reported by another check: that
+// of unscoped federated trust, when no condition scopes the principal at all, or the GitLab and Bitbucket ones.
+func audienceGapReported(env checkEnv, statement Statement, principal string) bool {
+	host := oidcProviderHost(principal)
+	if isGitLabHost(host, env.gitlabHosts) || isBitbucketHost(host) {
+		return true
+	}
+
+	return federatedTrustUnscoped(statement, principal) && !hasDedicatedFederatedCheck(env, statement, principal)
+}
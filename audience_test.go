@@ -0,0 +1,85 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_audienceKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		principal string
+		want      string
+	}{
+		{
+			principal: "arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com",
+			want:      "token.actions.githubusercontent.com:aud",
+		},
+		{
+			principal: "arn:aws:iam::123456789012:oidc-provider/oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLE",
+			want:      "oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLE:aud",
+		},
+		{principal: "accounts.google.com", want: "accounts.google.com:aud"},
+		{principal: "cognito-identity.amazonaws.com", want: "cognito-identity.amazonaws.com:aud"},
+		{principal: "graph.facebook.com", want: "graph.facebook.com:app_id"},
+		{principal: "arn:aws:iam::123456789012:saml-provider/okta", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.principal, func(t *testing.T) {
+			t.Parallel()
+
+			if got := audienceKey(tt.principal); got != tt.want {
+				t.Errorf("audienceKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkOIDCAudience(t *testing.T) {
+	t.Parallel()
+
+	role := "arn:aws:iam::123456789012:role/test"
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{name: "Cognito pinned to the identity pool", document: fixtureCognitoWebIdentityPinned, want: []string{}},
+		{name: "EKS pinned to STS", document: fixtureScopedOIDCTrust, want: []string{}},
+		{
+			name:     "GitHub wildcard audience",
+			document: fixtureGitHubOIDCWildcardAudience,
+			want:     []string{codeOIDCWildcardAudience},
+		},
+		{name: "Google without audience", document: fixtureGoogleWebIdentityNoAudience, want: []string{codeOIDCNoAudience}},
+		{name: "EKS without conditions reported as unscoped", document: fixtureUnscopedOIDCTrust, want: []string{}},
+		{
+			name:     "GitLab without audience reported by its own check",
+			document: fixtureGitLabOIDCGroupWildcard,
+			want:     []string{},
+		},
+		{name: "SAML provider", document: fixtureUnscopedSAMLTrust, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := make([]string, 0)
+
+			for _, statement := range mustDecodeTrust(t, role, tt.document).policy.Statement {
+				for _, finding := range checkOIDCAudience(checkEnv{}, role, statement) {
+					got = append(got, finding.Code)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkOIDCAudience() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+const actionTagSession = "sts:TagSession"
+
+// findSessionTaggingRoles returns a sorted list of roles whose trust policy allows sts:TagSession.
+// Session tags enable attribute-based access control, which reviewers should be aware of.
+func findSessionTaggingRoles(trusts map[string]roleTrust) []string {
+	output := make([]string, 0)
+
+	for role, trust := range trusts {
+		for _, statement := range trust.policy.Statement {
+			if statement.allowsAction(actionTagSession) {
+				output = append(output, role)
+
+				break
+			}
+		}
+	}
+
+	sort.Strings(output)
+
+	return output
+}
+
+// findAccountRootTrusts returns the account roots trusted by each role that trusts any, which lets every identity
+// of those accounts assume the role. Roots constrained to specific principals by aws:PrincipalArn are not counted.
+func findAccountRootTrusts(trusts map[string]roleTrust) map[string][]string {
+	output := make(map[string][]string)
+
+	for role, trust := range trusts {
+		for _, statement := range trust.policy.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			for _, principal := range statement.effectiveAWSPrincipals() {
+				if principalScope(principal) == principalScopeAccountRoot {
+					output[role] = append(output[role], principal)
+				}
+			}
+		}
+	}
+
+	for role, roots := range output {
+		output[role] = uniqSlice(roots)
+	}
+
+	return output
+}
+
+// policyGroup lists the roles sharing an identical trust policy.
+type policyGroup struct {
+	SHA256 string          `json:"sha256"`
+	Policy json.RawMessage `json:"policy"`
+	Roles  []string        `json:"roles"`
+}
+
+// groupIdenticalPolicies groups roles by the SHA-256 of their canonical trust policy.
+// Groups are ordered by the number of roles sharing them, largest first, so templated policies surface at the top.
+func groupIdenticalPolicies(trusts map[string]roleTrust) ([]policyGroup, error) {
+	groups := make(map[string]*policyGroup)
+
+	for arn, trust := range trusts {
+		if trust.role.AssumeRolePolicyDocument == nil {
+			continue
+		}
+
+		canonical, err := canonicalPolicy(*trust.role.AssumeRolePolicyDocument)
+		if err != nil {
+			return nil, fmt.Errorf("failed to canonicalise trust policy of %s: %w", arn, err)
+		}
+
+		hash := sha256Hex(canonical)
+		if groups[hash] == nil {
+			groups[hash] = &policyGroup{
+				SHA256: hash,
+				Policy: canonical,
+				Roles:  nil,
+			}
+		}
+
+		groups[hash].Roles = append(groups[hash].Roles, arn)
+	}
+
+	output := make([]policyGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.Roles)
+		output = append(output, *group)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if len(output[i].Roles) != len(output[j].Roles) {
+			return len(output[i].Roles) > len(output[j].Roles)
+		}
+
+		return output[i].SHA256 < output[j].SHA256
+	})
+
+	return output, nil
+}
+
+func (a *App) runIdenticalPoliciesAudit(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output, err := groupIdenticalPolicies(trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Debug(
+		"grouped IAM roles by identical trust policy",
+		slog.Int("roles", len(trusts)),
+		slog.Int("policies", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+func (a *App) runSessionTaggingAudit(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := findSessionTaggingRoles(trusts)
+	a.logger.Debug(
+		"found IAM roles allowing session tagging",
+		slog.Int("roles", len(trusts)),
+		slog.Int("flagged", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+func (a *App) runAccountRootAudit(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := findAccountRootTrusts(trusts)
+	a.logger.Debug(
+		"found IAM roles trusting account roots",
+		slog.Int("roles", len(trusts)),
+		slog.Int("flagged", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+func mustDecodeTrust(t *testing.T, arn string, document string) roleTrust {
+	t.Helper()
+
+	role := types.Role{
+		Arn:                      aws.String(arn),
+		AssumeRolePolicyDocument: aws.String(document),
+	}
+
+	policy, err := decodeRoleTrust(role, false)
+	if err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+
+	return roleTrust{
+		role:   role,
+		policy: policy,
+	}
+}
+
+func Test_findSessionTaggingRoles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		trusts map[string]roleTrust
+		want   []string
+	}{
+		{
+			name:   "no roles",
+			trusts: map[string]roleTrust{},
+			want:   []string{},
+		},
+		{
+			name: "with and without TagSession",
+			trusts: map[string]roleTrust{
+				"arn:aws:iam::0123456789:role/cross-account": mustDecodeTrust(
+					t,
+					"arn:aws:iam::0123456789:role/cross-account",
+					fixtureCrossAccountTagSession,
+				),
+				"arn:aws:iam::0123456789:role/ecs": mustDecodeTrust(
+					t,
+					"arn:aws:iam::0123456789:role/ecs",
+					fixtureAWSServiceRoleForECS,
+				),
+				"arn:aws:iam::0123456789:role/sso": mustDecodeTrust(
+					t,
+					"arn:aws:iam::0123456789:role/sso",
+					fixtureAWSReservedSSOFullAdmin,
+				),
+			},
+			want: []string{
+				"arn:aws:iam::0123456789:role/cross-account",
+				"arn:aws:iam::0123456789:role/sso",
+			},
+		},
+		{
+			name: "wildcard actions",
+			trusts: map[string]roleTrust{
+				"arn:aws:iam::0123456789:role/star": mustDecodeTrust(
+					t,
+					"arn:aws:iam::0123456789:role/star",
+					fixtureStarAction,
+				),
+				"arn:aws:iam::0123456789:role/sts": mustDecodeTrust(
+					t,
+					"arn:aws:iam::0123456789:role/sts",
+					fixtureWildcardAction,
+				),
+			},
+			want: []string{
+				"arn:aws:iam::0123456789:role/star",
+				"arn:aws:iam::0123456789:role/sts",
+			},
+		},
+		{
+			name: "deny statement is not flagged",
+			trusts: map[string]roleTrust{
+				"arn:aws:iam::0123456789:role/deny": {
+					role: types.Role{},
+					policy: TrustPolicy{
+						Version: "2012-10-17",
+						Statement: []Statement{
+							{
+								Effect: "Deny",
+								Principal: Principal{
+									AWS: Items{"*"},
+								},
+								Action: Items{"sts:TagSession"},
+							},
+						},
+					},
+				},
+			},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := findSessionTaggingRoles(tt.trusts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findSessionTaggingRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findAccountRootTrusts(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/root": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/root",
+			fixtureAccountRootPrincipal,
+		),
+		"arn:aws:iam::0123456789:role/specific": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/specific",
+			fixtureSpecificRolePrincipal,
+		),
+		"arn:aws:iam::0123456789:role/constrained": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/constrained",
+			fixturePrincipalArnEquals,
+		),
+		"arn:aws:iam::0123456789:role/wildcard": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/wildcard",
+			fixtureWildcardPrincipal,
+		),
+	}
+
+	want := map[string][]string{
+		"arn:aws:iam::0123456789:role/root": {"111122223333", "arn:aws:iam::210987654321:root"},
+	}
+	if got := findAccountRootTrusts(trusts); !reflect.DeepEqual(got, want) {
+		t.Errorf("findAccountRootTrusts() = %v, want %v", got, want)
+	}
+}
+
+func Test_groupIdenticalPolicies(t *testing.T) {
+	t.Parallel()
+
+	first := "arn:aws:iam::0123456789:role/lambda-first"
+	second := "arn:aws:iam::0123456789:role/lambda-second"
+	ecs := "arn:aws:iam::0123456789:role/ecs"
+	trusts := map[string]roleTrust{
+		first:  mustDecodeTrust(t, first, fixtureSharedLambdaTrust),
+		second: mustDecodeTrust(t, second, fixtureSharedLambdaTrustReordered),
+		ecs:    mustDecodeTrust(t, ecs, fixtureAWSServiceRoleForECS),
+	}
+
+	got, err := groupIdenticalPolicies(trusts)
+	if err != nil {
+		t.Fatalf("groupIdenticalPolicies() unexpected error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("groupIdenticalPolicies() got %d groups, want 2", len(got))
+	}
+
+	if !reflect.DeepEqual(got[0].Roles, []string{first, second}) {
+		t.Errorf("groupIdenticalPolicies() shared roles = %v", got[0].Roles)
+	}
+
+	if !reflect.DeepEqual(got[1].Roles, []string{ecs}) {
+		t.Errorf("groupIdenticalPolicies() single roles = %v", got[1].Roles)
+	}
+
+	if got[0].SHA256 != sha256Hex(got[0].Policy) {
+		t.Errorf("groupIdenticalPolicies() hash does not match representative policy")
+	}
+}
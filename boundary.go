@@ -0,0 +1,78 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// boundaryReport tells whether a role is constrained by a permissions boundary, and by which policy. ARN is null for
+// roles without one.
+type boundaryReport struct {
+	ARN         *string `json:"arn"`
+	HasBoundary bool    `json:"hasBoundary"`
+}
+
+// boundaryCount holds the number of roles with and without a permissions boundary.
+type boundaryCount struct {
+	Bounded   int `json:"bounded"`
+	Unbounded int `json:"unbounded"`
+}
+
+// newBoundaryReport describes the permissions boundary of the given role.
+func newBoundaryReport(role types.Role) *boundaryReport {
+	if role.PermissionsBoundary == nil || role.PermissionsBoundary.PermissionsBoundaryArn == nil {
+		return &boundaryReport{ARN: nil, HasBoundary: false}
+	}
+
+	return &boundaryReport{ARN: role.PermissionsBoundary.PermissionsBoundaryArn, HasBoundary: true}
+}
+
+// countBoundaries counts the given roles with and without a permissions boundary.
+func countBoundaries(trusts map[string]roleTrust, roles map[string][]string) boundaryCount {
+	var output boundaryCount
+
+	for role := range roles {
+		if newBoundaryReport(trusts[role].role).HasBoundary {
+			output.Bounded++
+		} else {
+			output.Unbounded++
+		}
+	}
+
+	return output
+}
+
+// roleBoundary returns the permissions boundary of the role, as already known, or fetched with GetRole otherwise, as
+// the boundary is not part of the role attributes returned by ListRoles. A nil boundary means the role has none.
+func (a *App) roleBoundary(ctx context.Context, role types.Role) (*types.AttachedPermissionsBoundary, error) {
+	if role.PermissionsBoundary != nil {
+		return role.PermissionsBoundary, nil
+	}
+
+	name := aws.ToString(role.RoleName)
+	if name == "" {
+		name = roleNameFromARN(aws.ToString(role.Arn))
+	}
+
+	output, err := retryWithBackoff(
+		ctx,
+		a.logger,
+		defaultRetryMaxAttempts,
+		defaultRetryInitialDelay,
+		func() (*iam.GetRoleOutput, error) {
+			return a.iamClient().GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(name)})
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions boundary of role %s: %w", name, err)
+	}
+
+	return output.Role.PermissionsBoundary, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+const boundaryPolicyARN = "arn:aws:iam::0123456789:policy/boundary"
+
+func boundaryRoles() []types.Role {
+	return []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/bounded"),
+			RoleName:                 aws.String("bounded"),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(fixtureAWSServiceRoleForECS)),
+			PermissionsBoundary: &types.AttachedPermissionsBoundary{
+				PermissionsBoundaryArn:  aws.String(boundaryPolicyARN),
+				PermissionsBoundaryType: types.PermissionsBoundaryAttachmentTypePolicy,
+			},
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/unbounded"),
+			RoleName:                 aws.String("unbounded"),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(fixtureCrossAccountTagSession)),
+		},
+	}
+}
+
+func TestApp_runScanRoles_boundary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		onlyUnbounded bool
+		getRoleErr    error
+		want          map[string]*boundaryReport
+		wantErr       bool
+	}{
+		{
+			name:          "every role",
+			onlyUnbounded: false,
+			getRoleErr:    nil,
+			want: map[string]*boundaryReport{
+				"arn:aws:iam::0123456789:role/bounded":   {ARN: aws.String(boundaryPolicyARN), HasBoundary: true},
+				"arn:aws:iam::0123456789:role/unbounded": {ARN: nil, HasBoundary: false},
+			},
+			wantErr: false,
+		},
+		{
+			name:          "only unbounded roles",
+			onlyUnbounded: true,
+			getRoleErr:    nil,
+			want: map[string]*boundaryReport{
+				"arn:aws:iam::0123456789:role/unbounded": {ARN: nil, HasBoundary: false},
+			},
+			wantErr: false,
+		},
+		{
+			name:          "boundary lookup fails",
+			onlyUnbounded: true,
+			getRoleErr:    errors.New("access denied"),
+			want:          nil,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &veiltest.FakeIAM{
+				Roles:  boundaryRoles(),
+				Errors: map[string]error{veiltest.OperationGetRole: tt.getRoleErr},
+			}
+			app := &App{logger: slog.New(slog.DiscardHandler), client: fake}
+			WithPermissionsBoundary(tt.onlyUnbounded)(app)
+
+			data, err := app.runScanRoles(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runScanRoles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			var reports map[string]roleReport
+
+			err = json.Unmarshal(data, &reports)
+			if err != nil {
+				t.Fatalf("runScanRoles() wrote invalid JSON: %v", err)
+			}
+
+			got := make(map[string]*boundaryReport, len(reports))
+			for arn, report := range reports {
+				got[arn] = report.Boundary
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runScanRoles() boundaries = %v, want %v", got, tt.want)
+			}
+
+			// ListRoles omits boundaries, so each role is looked up.
+			if calls := len(fake.Calls(veiltest.OperationGetRole)); calls != len(boundaryRoles()) {
+				t.Errorf("runScanRoles() looked up %d roles, want %d", calls, len(boundaryRoles()))
+			}
+		})
+	}
+}
+
+func TestApp_runScanRoles_boundaryNull(t *testing.T) {
+	t.Parallel()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{Roles: boundaryRoles()[1:]},
+	}
+	WithPermissionsBoundary(false)(app)
+
+	got, err := app.runScanRoles(t.Context())
+	if err != nil {
+		t.Fatalf("runScanRoles() unexpected error: %v", err)
+	}
+
+	want := `{
+  "arn:aws:iam::0123456789:role/unbounded": {
+    "principals": [
+      "arn:aws:iam::210987654321:root"
+    ],
+    "boundary": {
+      "arn": null,
+      "hasBoundary": false
+    }
+  }
+}`
+	if string(got) != want {
+		t.Errorf("runScanRoles() = %s, want %s", got, want)
+	}
+}
+
+func TestApp_runJSONCount_boundary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		withBoundary  bool
+		onlyUnbounded bool
+		want          string
+	}{
+		{
+			name:          "without boundaries",
+			withBoundary:  false,
+			onlyUnbounded: false,
+			want:          `{"roles":2,"principals":2}`,
+		},
+		{
+			name:          "with boundaries",
+			withBoundary:  true,
+			onlyUnbounded: false,
+			want:          `{"roles":2,"principals":2,"boundaries":{"bounded":1,"unbounded":1}}`,
+		},
+		{
+			name:          "only unbounded roles",
+			withBoundary:  true,
+			onlyUnbounded: true,
+			want:          `{"roles":1,"principals":1,"boundaries":{"bounded":0,"unbounded":1}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: &veiltest.FakeIAM{Roles: boundaryRoles()},
+			}
+			if tt.withBoundary {
+				WithPermissionsBoundary(tt.onlyUnbounded)(app)
+			}
+
+			got, err := app.runJSONCount(t.Context())
+			if err != nil {
+				t.Fatalf("runJSONCount() unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("runJSONCount() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	codeCrossAccountNoExternalID = "CROSS_ACCOUNT_NO_EXTERNAL_ID"
+	codeWildcardPrincipal        = "WILDCARD_PRINCIPAL"
+	codeGitHubOIDCUnpinned       = "GITHUB_OIDC_UNPINNED_SUBJECT"
+	codeCrossPartitionTrust      = "CROSS_PARTITION_TRUST"
+	codeOrgWideTrust             = "ORG_WIDE_TRUST"
+
+	conditionExternalID = "sts:ExternalId"
+	githubOIDCProvider  = "token.actions.githubusercontent.com"
+	githubOIDCSubject   = githubOIDCProvider + ":sub"
+	githubOIDCAudience  = githubOIDCProvider + ":aud"
+
+	placeholderExternalID = "EXTERNAL_ID"
+	placeholderPrincipal  = "arn:aws:iam::ACCOUNT_ID:role/ROLE_NAME"
+	placeholderSubject    = "repo:ORG/REPO:ref:refs/heads/BRANCH"
+)
+
+// placeholderPrincipalIn returns the placeholder principal in the given partition, so remediations of GovCloud or
+// China roles suggest a principal of their own partition.
+func placeholderPrincipalIn(partition string) string {
+	return strings.Replace(placeholderPrincipal, "arn:"+defaultPartition+":", "arn:"+partition+":", 1)
+}
+
+// checkEnv holds the facts about the scanned environment that checks compare trust policies against.
+type checkEnv struct {
+	// orgID is the ID of our own AWS organisation, if known.
+	orgID string
+	// missingProviders holds the federated providers found not to exist, if their existence was verified.
+	missingProviders map[string]bool
+	// expiringSAMLProviders holds the SAML providers whose certificates expire within the configured window.
+	expiringSAMLProviders map[string]samlProvider
+	// gitlabHosts lists the self-hosted GitLab instances trusted as OIDC providers, besides gitlab.com.
+	gitlabHosts []string
+	// labels holds the user-supplied labels of roles and principals, if any.
+	labels *labelSet
+}
+
+// trustCheck inspects a single statement of a role trust policy and returns its findings.
+type trustCheck func(env checkEnv, role string, statement Statement) []Finding
+
+// builtinChecks lists the checks run by the findings output.
+var builtinChecks = []trustCheck{ //nolint:gochecknoglobals
+	checkCrossAccountExternalID,
+	checkWildcardPrincipal,
+	checkGitHubOIDCSubject,
+	checkGitLabOIDCSubject,
+	checkBitbucketOIDCSubject,
+	checkFederatedTrustUnscoped,
+	checkOIDCAudience,
+	checkCrossPartition,
+	checkOrgWideTrust,
+	checkOverboardAction,
+	checkWildcardTagCondition,
+	checkMissingProvider,
+	checkMalformedPrincipal,
+	checkSAMLProviderCert,
+	checkSAMLTrustUnconditioned,
+}
+
+// runChecks runs the built-in checks against every allowing statement of the given trust policies, and the checks
+// depending on the policy version against every policy.
+func runChecks(env checkEnv, trusts map[string]roleTrust) []Finding {
+	output := make([]Finding, 0)
+
+	for role, trust := range trusts {
+		for index, statement := range trust.policy.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			for _, check := range builtinChecks {
+				for _, finding := range check(env, role, statement) {
+					if finding.Remediation != nil {
+						finding.Remediation.Statement = index
+					}
+
+					output = append(output, finding)
+				}
+			}
+		}
+
+		output = append(output, checkPolicyVariables(role, trust.policy)...)
+		output = append(output, checkPolicyVersion(role, trust.policy)...)
+	}
+
+	raiseLabelledFindings(env.labels, output)
+	sortFindings(output)
+
+	return output
+}
+
+func checkCrossAccountExternalID(_ checkEnv, role string, statement Statement) []Finding {
+	if statement.hasConditionKey(conditionExternalID) {
+		return nil
+	}
+
+	output := make([]Finding, 0)
+	roleAccount := accountFromARN(role)
+
+	for _, principal := range statement.effectiveAWSPrincipals() {
+		account := accountFromARN(normalisePrincipal(principal))
+		if account == "" || account == roleAccount || strings.ContainsAny(account, "*?") {
+			continue
+		}
+
+		output = append(output, Finding{
+			Code:      codeCrossAccountNoExternalID,
+			Severity:  severityMedium,
+			Role:      role,
+			Principal: principal,
+			Message:   "principal in another account can assume the role without an external ID",
+			Remediation: newRemediation(
+				"Require an sts:ExternalId condition agreed with the owner of account "+account+
+					", to prevent the confused deputy problem.",
+				withCondition(statement, "StringEquals", conditionExternalID, placeholderExternalID),
+			),
+		})
+	}
+
+	return output
+}
+
+func checkWildcardPrincipal(_ checkEnv, role string, statement Statement) []Finding {
+	wildcard := statement.wildcardPrincipal()
+	if wildcard == "" {
+		return nil
+	}
+
+	severity := severityHigh
+	if len(statement.Condition) > 0 {
+		severity = severityMedium
+	}
+
+	message := "any AWS principal can assume the role, subject only to its conditions"
+	if wildcard != "*" {
+		message = "any AWS principal matching " + wildcard + " can assume the role, subject only to its conditions"
+	}
+
+	// The specific principals trusted next to the wildcard keep their access, the placeholder standing in for the
+	// others only when none is left.
+	specific := slices.DeleteFunc(slices.Clone(statement.Principal.AWS), func(principal string) bool {
+		return strings.ContainsAny(principal, "*?")
+	})
+	if len(specific) == 0 {
+		specific = Items{placeholderPrincipalIn(rolePartition(role))}
+	}
+
+	fixed := statement
+	fixed.Principal = Principal{
+		Service:       statement.Principal.Service,
+		AWS:           specific,
+		Federated:     statement.Principal.Federated,
+		CanonicalUser: statement.Principal.CanonicalUser,
+		Anonymous:     nil,
+	}
+
+	return []Finding{
+		{
+			Code:      codeWildcardPrincipal,
+			Severity:  severity,
+			Role:      role,
+			Principal: wildcard,
+			Message:   message,
+			Remediation: newRemediation(
+				"Replace the wildcard with the specific principals that need to assume the role.",
+				fixed,
+			),
+		},
+	}
+}
+
+func checkGitHubOIDCSubject(_ checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		if !strings.HasSuffix(principal, "oidc-provider/"+githubOIDCProvider) {
+			continue
+		}
+
+		subjects := statement.stringConditionValues(githubOIDCSubject)
+		if len(subjects) > 0 && allSubjectsPinned(subjects) {
+			continue
+		}
+
+		fixed := withCondition(statement, "StringEquals", githubOIDCAudience, "sts.amazonaws.com")
+		fixed = withCondition(fixed, "StringLike", githubOIDCSubject, placeholderSubject)
+
+		output = append(output, Finding{
+			Code:      codeGitHubOIDCUnpinned,
+			Severity:  severityHigh,
+			Role:      role,
+			Principal: principal,
+			Message:   "GitHub Actions workflows outside a pinned repository can assume the role",
+			Remediation: newRemediation(
+				"Pin the "+githubOIDCSubject+" condition to a single repository, and ideally a branch or environment.",
+				fixed,
+			),
+		})
+	}
+
+	return output
+}
+
+func checkCrossPartition(_ checkEnv, role string, statement Statement) []Finding {
+	rolePartition := partitionFromARN(role)
+	if rolePartition == "" {
+		return nil
+	}
+
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.getAll() {
+		partition := partitionFromARN(principal)
+		if partition == "" || partition == rolePartition {
+			continue
+		}
+
+		output = append(output, Finding{
+			Code:      codeCrossPartitionTrust,
+			Severity:  severityHigh,
+			Role:      role,
+			Principal: principal,
+			Message: "principal in the " + partition + " partition is trusted by a role in the " +
+				rolePartition + " partition",
+			Remediation: &Remediation{
+				Summary: "Remove the principal, trust across partitions breaks the isolation of the " +
+					rolePartition + " partition.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		})
+	}
+
+	return output
+}
+
+func checkOrgWideTrust(env checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.organisationPrincipals() {
+		orgID := strings.TrimPrefix(principal, orgPrincipalPrefix)
+
+		finding := Finding{
+			Code:        codeOrgWideTrust,
+			Severity:    severityInfo,
+			Role:        role,
+			Principal:   principal,
+			Message:     "any principal in organisation " + orgID + " can assume the role",
+			Remediation: nil,
+		}
+
+		if env.orgID != "" && orgID != env.orgID {
+			finding.Severity = severityHigh
+			finding.Message = "any principal in organisation " + orgID + ", which is not ours, can assume the role"
+			finding.Remediation = &Remediation{
+				Summary: "Scope aws:PrincipalOrgID to organisation " + env.orgID +
+					", or trust specific principals instead.",
+				Fragment:  nil,
+				Statement: 0,
+			}
+		}
+
+		output = append(output, finding)
+	}
+
+	return output
+}
+
+// allSubjectsPinned reports whether every GitHub OIDC subject names a repository without wildcards.
+func allSubjectsPinned(subjects []string) bool {
+	for _, subject := range subjects {
+		repo, ok := strings.CutPrefix(subject, "repo:")
+		if !ok {
+			return false
+		}
+
+		repo, _, _ = strings.Cut(repo, ":")
+		if repo == "" || strings.ContainsAny(repo, "*?") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// withCondition returns a copy of the statement with the given condition added, leaving the original untouched.
+func withCondition(statement Statement, operator string, key string, values ...string) Statement {
+	condition := make(Condition, len(statement.Condition)+1)
+	for name, keys := range statement.Condition {
+		condition[name] = make(map[string]ConditionValues, len(keys))
+		for conditionKey, conditionValues := range keys {
+			condition[name][conditionKey] = conditionValues
+		}
+	}
+
+	if condition[operator] == nil {
+		condition[operator] = make(map[string]ConditionValues)
+	}
+
+	condition[operator][key] = values
+	statement.Condition = condition
+
+	return statement
+}
+
+// checkEnv returns the facts about the scanned environment used by the built-in checks, looking up the federated
+// providers trusted by the given trust policies, and the certificates of SAML ones, when they are to be verified.
+func (a *App) checkEnv(ctx context.Context, trusts map[string]roleTrust) (checkEnv, error) {
+	output := checkEnv{
+		orgID:                 a.orgID,
+		missingProviders:      nil,
+		expiringSAMLProviders: nil,
+		gitlabHosts:           a.gitlabHosts,
+		labels:                a.labels,
+	}
+
+	if a.verifyProviders {
+		missing, metadata, err := a.missingProviders(ctx, trusts)
+		if err != nil {
+			return checkEnv{}, fmt.Errorf("failed to verify federated providers: %w", err)
+		}
+
+		output.missingProviders = missing
+		output.expiringSAMLProviders = expiringSAMLProviders(metadata, time.Now(), a.samlCertWindow)
+	}
+
+	return output, nil
+}
+
+// newRemediation builds a remediation with the given statement as its policy fragment.
+func newRemediation(summary string, statement Statement) *Remediation {
+	fragment, err := json.Marshal(statement)
+	if err != nil {
+		slog.Debug("failed to marshal remediation fragment", slog.String("error", err.Error()))
+
+		fragment = nil
+	}
+
+	return &Remediation{
+		Summary:   summary,
+		Fragment:  fragment,
+		Statement: 0,
+	}
+}
+
+func (a *App) runFindings(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	env, err := a.checkEnv(ctx, trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	output := runChecks(env, trusts)
+	a.logger.Debug(
+		"checked IAM roles trust policies",
+		slog.Int("roles", len(trusts)),
+		slog.Int("findings", len(output)),
+	)
+
+	a.gateFindings(output)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
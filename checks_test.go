@@ -0,0 +1,320 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files") //nolint:gochecknoglobals
+
+func Test_runChecks_golden(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      checkEnv
+		role     string
+		document string
+		code     string
+		golden   string
+	}{
+		{
+			name:     "cross account without external ID",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureCrossAccountTagSession,
+			code:     codeCrossAccountNoExternalID,
+			golden:   "fixtures/golden/" + codeCrossAccountNoExternalID + ".json",
+		},
+		{
+			name:     "GitLab OIDC group wildcard",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureGitLabOIDCGroupWildcard,
+			code:     codeGitLabOIDCUnpinned,
+			golden:   "fixtures/golden/" + codeGitLabOIDCUnpinned + ".json",
+		},
+		{
+			name:     "self-hosted GitLab OIDC unpinned ref",
+			env:      checkEnv{gitlabHosts: []string{"gitlab.example.com"}},
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureGitLabOIDCSelfHosted,
+			code:     codeGitLabOIDCUnpinned,
+			golden:   "fixtures/golden/" + codeGitLabOIDCUnpinned + "SelfHosted.json",
+		},
+		{
+			name:     "Bitbucket OIDC workspace wildcard",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureBitbucketOIDCWorkspaceWildcard,
+			code:     codeBitbucketOIDCUnpinned,
+			golden:   "fixtures/golden/" + codeBitbucketOIDCUnpinned + ".json",
+		},
+		{
+			name:     "GitHub OIDC wildcard audience",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureGitHubOIDCWildcardAudience,
+			code:     codeOIDCWildcardAudience,
+			golden:   "fixtures/golden/" + codeOIDCWildcardAudience + ".json",
+		},
+		{
+			name:     "GitHub OIDC without subject",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureGitHubOIDCNoSubject,
+			code:     codeGitHubOIDCUnpinned,
+			golden:   "fixtures/golden/" + codeGitHubOIDCUnpinned + "NoSubject.json",
+		},
+		{
+			name:     "Google web identity without audience",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureGoogleWebIdentityNoAudience,
+			code:     codeOIDCNoAudience,
+			golden:   "fixtures/golden/" + codeOIDCNoAudience + ".json",
+		},
+		{
+			name:     "unscoped SAML trust",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureSAMLTrustNoAudience,
+			code:     codeFederatedTrustUnscoped,
+			golden:   "fixtures/golden/" + codeFederatedTrustUnscoped + ".json",
+		},
+		{
+			name:     "GovCloud cross account without external ID",
+			role:     "arn:aws-us-gov:iam::123456789012:role/test",
+			document: fixtureGovCloudTrust,
+			code:     codeCrossAccountNoExternalID,
+			golden:   "fixtures/golden/" + codeCrossAccountNoExternalID + "GovCloud.json",
+		},
+		{
+			name:     "wildcard principal",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureWildcardPrincipal,
+			code:     codeWildcardPrincipal,
+			golden:   "fixtures/golden/" + codeWildcardPrincipal + ".json",
+		},
+		{
+			name:     "China wildcard principal",
+			role:     "arn:aws-cn:iam::123456789012:role/test",
+			document: fixtureChinaTrust,
+			code:     codeWildcardPrincipal,
+			golden:   "fixtures/golden/" + codeWildcardPrincipal + "China.json",
+		},
+		{
+			name:     "wildcard next to a specific principal",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureMixedWildcardPrincipal,
+			code:     codeWildcardPrincipal,
+			golden:   "fixtures/golden/" + codeWildcardPrincipal + "Mixed.json",
+		},
+		{
+			name:     "GitHub OIDC unpinned subject",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureGitHubOIDCUnpinned,
+			code:     codeGitHubOIDCUnpinned,
+			golden:   "fixtures/golden/" + codeGitHubOIDCUnpinned + ".json",
+		},
+		{
+			name:     "GitHub OIDC negated subject",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureGitHubOIDCNegatedSubject,
+			code:     codeGitHubOIDCUnpinned,
+			golden:   "fixtures/golden/" + codeGitHubOIDCUnpinned + "NegatedSubject.json",
+		},
+		{
+			name:     "service principal",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureAWSServiceRoleForECS,
+			golden:   "fixtures/golden/NoFindings.json",
+		},
+		{
+			name:     "cross partition",
+			role:     "arn:aws-us-gov:iam::111111111111:role/test",
+			document: fixtureMixedPartitions,
+			code:     codeCrossPartitionTrust,
+			golden:   "fixtures/golden/" + codeCrossPartitionTrust + ".json",
+		},
+		{
+			name:     "malformed principals",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureMalformedPrincipal,
+			code:     codeMalformedPrincipal,
+			golden:   "fixtures/golden/" + codeMalformedPrincipal + ".json",
+		},
+		{
+			name:     "misplaced principals",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureMisplacedPrincipal,
+			code:     codeMalformedPrincipal,
+			golden:   "fixtures/golden/" + codeMalformedPrincipal + "Misplaced.json",
+		},
+		{
+			name:     "account root constrained to a role via condition",
+			role:     "arn:aws:iam::012345678901:role/test",
+			document: fixturePrincipalArnEquals,
+			golden:   "fixtures/golden/NoFindings.json",
+		},
+		{
+			name:     "wildcard constrained to a wildcard pattern via condition",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixturePrincipalArnLike,
+			code:     codeWildcardPrincipal,
+			golden:   "fixtures/golden/PrincipalArnLike.json",
+		},
+		{
+			name:     "foreign account root constrained to roles via condition",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixturePrincipalArnMultiValue,
+			code:     codeCrossAccountNoExternalID,
+			golden:   "fixtures/golden/PrincipalArnMultiValue.json",
+		},
+		{
+			name:     "wildcard scoped to our organisation",
+			env:      checkEnv{orgID: "o-a1b2c3d4e5"},
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureOrgWideTrust,
+			code:     codeOrgWideTrust,
+			golden:   "fixtures/golden/" + codeOrgWideTrust + ".json",
+		},
+		{
+			name:     "wildcard scoped to another organisation",
+			env:      checkEnv{orgID: "o-zzzzzzzzzz"},
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureOrgWideTrust,
+			code:     codeOrgWideTrust,
+			golden:   "fixtures/golden/" + codeOrgWideTrust + "Foreign.json",
+		},
+		{
+			name:     "wildcard action",
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureWildcardAction,
+			code:     codeOverboardAction,
+			golden:   "fixtures/golden/" + codeOverboardAction + ".json",
+		},
+		{
+			name:     "wildcard tag condition",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixtureWildcardTagCondition,
+			code:     codeWildcardTagCondition,
+			golden:   "fixtures/golden/" + codeWildcardTagCondition + ".json",
+		},
+		{
+			name:     "tag policy variable",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixturePolicyVariableTag,
+			code:     codePolicyVariableInTrust,
+			golden:   "fixtures/golden/" + codePolicyVariableInTrust + ".json",
+		},
+		{
+			name:     "username policy variable under the legacy version",
+			role:     "arn:aws:iam::123456789012:role/test",
+			document: fixturePolicyVariableUsername,
+			code:     codePolicyVariableInTrust,
+			golden:   "fixtures/golden/" + codePolicyVariableInTrust + "Legacy.json",
+		},
+		{
+			name: "missing SAML provider",
+			env: checkEnv{
+				missingProviders: map[string]bool{"arn:aws:iam::0123456789:saml-provider/AWSSSO_42_DO_NOT_DELETE": true},
+			},
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureAWSReservedSSOFullAdmin,
+			code:     codeMissingFederatedProvider,
+			golden:   "fixtures/golden/" + codeMissingFederatedProvider + ".json",
+		},
+		{
+			name: "expired SAML provider certificate",
+			env: checkEnv{
+				expiringSAMLProviders: map[string]samlProvider{
+					"arn:aws:iam::0123456789:saml-provider/AWSSSO_42_DO_NOT_DELETE": {
+						entityID: "https://idp.example.com/saml",
+						notAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+						expired:  true,
+					},
+				},
+			},
+			role:     "arn:aws:iam::0123456789:role/test",
+			document: fixtureAWSReservedSSOFullAdmin,
+			code:     codeSAMLProviderCertExpiring,
+			golden:   "fixtures/golden/" + codeSAMLProviderCertExpiring + ".json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			findings := runChecks(tt.env, map[string]roleTrust{
+				tt.role: mustDecodeTrust(t, tt.role, tt.document),
+			})
+			if tt.code != "" {
+				// scope the golden to the finding under test, so adding a check does not churn unrelated goldens
+				findings = slices.DeleteFunc(findings, func(finding Finding) bool { return finding.Code != tt.code })
+			}
+
+			got, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to marshal findings: %v", err)
+			}
+
+			if *updateGolden {
+				errWrite := os.WriteFile(tt.golden, got, 0o600)
+				if errWrite != nil {
+					t.Fatalf("failed to update golden file: %v", errWrite)
+				}
+			}
+
+			want, err := os.ReadFile(tt.golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("runChecks() got = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func Test_allSubjectsPinned(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		subjects []string
+		want     bool
+	}{
+		{
+			name:     "pinned repository and branch",
+			subjects: []string{"repo:wakeful/veil:ref:refs/heads/main"},
+			want:     true,
+		},
+		{
+			name:     "pinned repository with any ref",
+			subjects: []string{"repo:wakeful/veil:*"},
+			want:     true,
+		},
+		{
+			name:     "wildcard repository",
+			subjects: []string{"repo:wakeful/veil:*", "repo:wakeful/*"},
+			want:     false,
+		},
+		{
+			name:     "wildcard subject",
+			subjects: []string{"*"},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := allSubjectsPinned(tt.subjects); got != tt.want {
+				t.Errorf("allSubjectsPinned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
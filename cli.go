@@ -0,0 +1,917 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// cliFlags holds the flags configuring a scan, shared by the command line and the Lambda handler.
+type cliFlags struct {
+	format              *string
+	orgID               *string
+	orgStructurePath    *string
+	region              *string
+	showVersion         *bool
+	outputSchema        *bool
+	verbose             *bool
+	outputPath          *string
+	tee                 *bool
+	sessionTagging      *bool
+	identicalPolicies   *bool
+	expectedPath        *string
+	labelsFile          *string
+	effective           *bool
+	derivedPrincipals   *bool
+	findings            *bool
+	simulateActions     *string
+	focus               *string
+	focusDepth          *int
+	minPrincipals       *int
+	minRoles            *int
+	roleAccounts        *string
+	excludeRoleAccounts *string
+	temporal            *bool
+	redactAccounts      *bool
+	redactConsistent    *bool
+	renameKeys          *string
+	explain             *bool
+	policyVariables     *bool
+	policyVersion       *bool
+	checkSAML           *bool
+	snsTopicARN         *string
+	dynamoDBTable       *string
+	configSnapshot      *string
+	strict              *bool
+	continueOnError     *bool
+	quietErrors         *bool
+	includeMetadata     *bool
+	scanPathOnly        *bool
+	includeRawPolicy    *bool
+	prettyPolicies      *bool
+	includeTags         *bool
+	withBoundary        *bool
+	onlyUnbounded       *bool
+	rawPolicyLimit      *int
+	assumeRole          *string
+	roleSessionName     *string
+	sourceIdentity      *string
+	assumeRoleDuration  *time.Duration
+	fips                *bool
+	dualStack           *bool
+	endpointURL         *string
+	irsa                *bool
+	lambda              *bool
+	verifyProviders     *bool
+	samlCertWindow      *time.Duration
+	gitlabHosts         *string
+	digest              *bool
+	signKey             *string
+	outputPerAccount    *bool
+	splitByPrincipal    *bool
+	exportRawPolicies   *string
+	dedupeAccounts      *bool
+	accounts            *string
+	accountRole         *string
+	accountConcurrency  *int
+	roleConcurrency     *int
+	newSince            *string
+	roleMinAge          *string
+	invertMatch         *bool
+	roleMaxAge          *string
+	roleNames           *string
+	pathPrefixes        *string
+	conditionStats      *bool
+	failOn              *string
+	accountRootTrust    *bool
+	denyServices        *string
+	groupByTag          *string
+	diffScan            *string
+	historyDB           *string
+	historyRetention    *time.Duration
+	diffPolicyBefore    *string
+	diffPolicyAfter     *string
+	shortNames          *bool
+	countByRegion       *bool
+}
+
+// registerFlags defines the scan flags on the given flag set.
+func registerFlags(fs *flag.FlagSet) *cliFlags {
+	f := &cliFlags{} //nolint:exhaustruct
+
+	f.format = fs.String(
+		"format",
+		formatJSON,
+		"output format: json, org, markdown, count, json-count, gexf, distribution, tables, public, access-analyzer, "+
+			"inventory, proto, protojson, shell or d2",
+	)
+	f.shortNames = fs.Bool(
+		"short-names",
+		false,
+		"with -format markdown, gexf or d2, label roles by path and name, e.g. /service-role/MyAppRole, keeping ARNs aside",
+	)
+	f.countByRegion = fs.Bool(
+		"count-by-region",
+		false,
+		"with -format count or json-count, also count roles and principals per region encoded in IAM Identity Center "+
+			"role paths, other roles being global",
+	)
+	f.orgID = fs.String(
+		"org-id",
+		"",
+		"ID of our AWS organisation, e.g. o-a1b2c3d4e5; trust granted to other organisations is flagged",
+	)
+	f.orgStructurePath = fs.String("org-structure", "", "path to a JSON file mapping OUs to account IDs")
+	f.region = fs.String("region", "eu-west-1", "AWS region used for IAM communication")
+	f.showVersion = fs.Bool("version", false, "show version")
+	f.outputSchema = fs.Bool("output-schema", false, "print the JSON Schema of the -format output and exit")
+	f.verbose = fs.Bool("verbose", false, "verbose log output")
+	f.outputPath = fs.String("output", "", "write output to the given file instead of stdout")
+	f.tee = fs.Bool("tee", false, "with -output, write output to stdout as well as the file")
+	f.digest = fs.Bool("digest", false, "with -output, write the SHA-256 of the output to a .sha256 sidecar file")
+	f.signKey = fs.String(
+		"sign-key",
+		"",
+		"with -output, path to a PEM Ed25519 private key signing the output into a detached .sig file",
+	)
+	f.outputPerAccount = fs.Bool(
+		"output-per-account",
+		false,
+		"write one json file per account owning the scanned roles to the -output directory",
+	)
+	f.splitByPrincipal = fs.Bool(
+		"split-output-by-principal-type",
+		false,
+		"write one json file per principal type, e.g. service-principals.json, to the -output directory",
+	)
+	f.exportRawPolicies = fs.String(
+		"export-raw-policies",
+		"",
+		"write the URL-decoded trust policy of each role to <role-name>.json in the given directory",
+	)
+	f.dedupeAccounts = fs.Bool(
+		"dedupe-across-accounts",
+		false,
+		"with the json format, group roles by name so a role deployed to several accounts is listed once",
+	)
+	f.accounts = fs.String(
+		"accounts",
+		"",
+		"comma-separated account IDs to scan concurrently, assuming -account-role in each",
+	)
+	f.accountRole = fs.String(
+		"account-role",
+		defaultAccountRoleName,
+		"with -accounts, name of the role assumed in each account",
+	)
+	f.accountConcurrency = fs.Int(
+		"max-account-concurrency",
+		defaultAccountConcurrency,
+		"with -accounts, number of accounts scanned at once",
+	)
+	fs.IntVar(
+		f.accountConcurrency,
+		"account-concurrency",
+		defaultAccountConcurrency,
+		"deprecated alias of -max-account-concurrency",
+	)
+	f.roleConcurrency = fs.Int(
+		"max-concurrency-per-account",
+		defaultRoleConcurrency,
+		"with -accounts, number of roles of each account processed at once",
+	)
+	f.sessionTagging = fs.Bool("session-tagging", false, "output roles whose trust policy allows sts:TagSession")
+	f.accountRootTrust = fs.Bool(
+		"account-root-trust",
+		false,
+		"output roles trusting account roots, i.e. any identity of the account, with the roots they trust",
+	)
+	f.denyServices = fs.String(
+		"deny-services",
+		"",
+		"comma-separated service principal globs forbidden from assuming roles, e.g. ec2.amazonaws.com; output the "+
+			"roles trusting any of them",
+	)
+	f.identicalPolicies = fs.Bool(
+		"identical-policies",
+		false,
+		"output distinct trust policies with the roles sharing each of them",
+	)
+	f.conditionStats = fs.Bool(
+		"condition-stats",
+		false,
+		"output how often each condition operator and key is used in trust policies, with example roles",
+	)
+	f.diffScan = fs.String(
+		"diff",
+		"",
+		"path to the json output of an earlier scan; output the trust edges added and removed since as JSON",
+	)
+	f.historyDB = fs.String(
+		"history-db",
+		"",
+		"path to a JSON lines store recording when each trust edge is first and last seen, updated on every scan",
+	)
+	f.historyRetention = fs.Duration(
+		"history-retention",
+		0,
+		"with -history-db, forget trust edges removed and last seen longer ago than this, e.g. 2160h (default keep all)",
+	)
+	f.diffPolicyBefore = fs.String(
+		"diff-policy-before",
+		"",
+		"with -diff-policy-after, path to a trust policy JSON file to compare, e.g. from an earlier scan",
+	)
+	f.diffPolicyAfter = fs.String(
+		"diff-policy-after",
+		"",
+		"with -diff-policy-before, path to a trust policy JSON file; output the statements and principals changed",
+	)
+	f.expectedPath = fs.String(
+		"expected",
+		"",
+		"path to a YAML expected-trust spec; output findings for roles deviating from it",
+	)
+	f.labelsFile = fs.String(
+		"labels-file",
+		"",
+		"CSV or JSON file of pattern, key and value rows labelling the matching roles and principals, e.g. from a "+
+			"CMDB; criticality=high raises their findings to high severity",
+	)
+	f.effective = fs.Bool(
+		"effective",
+		false,
+		"list only the principals effectively trusted, leaving out those denied by a Deny statement",
+	)
+	f.derivedPrincipals = fs.Bool(
+		"derived-principals",
+		false,
+		"also list the aws:PrincipalArn patterns constraining an account root or wildcard principal, as via-condition",
+	)
+	f.findings = fs.Bool(
+		"findings",
+		false,
+		"output findings of the built-in trust checks with suggested remediations",
+	)
+	f.failOn = fs.String(
+		"fail-on",
+		"",
+		"with -findings or analyze, exit with status 1 if a finding is of the given severity or higher: info, medium or high",
+	)
+	f.verifyProviders = fs.Bool(
+		"verify-provider-existence",
+		false,
+		"output findings of the built-in trust checks, flagging trust in SAML and OIDC providers that do not exist",
+	)
+	f.samlCertWindow = fs.Duration(
+		"saml-cert-window",
+		defaultSAMLCertWindow,
+		"with -verify-provider-existence, flag SAML providers whose certificates expire within this window",
+	)
+	f.gitlabHosts = fs.String(
+		"gitlab-hosts",
+		"",
+		"comma-separated hosts of self-hosted GitLab instances whose OIDC trust is checked like gitlab.com",
+	)
+	f.simulateActions = fs.String(
+		"simulate-actions",
+		"",
+		"comma-separated IAM actions to simulate for every role, e.g. sts:AssumeRole,s3:GetObject",
+	)
+	f.focus = fs.String(
+		"focus",
+		"",
+		"restrict output to roles and principals around the matching ARN or glob, e.g. arn:aws:iam::210987654321:*",
+	)
+	f.focusDepth = fs.Int("depth", defaultFocusDepth, "with -focus, number of trust hops to follow from the focus")
+	f.minPrincipals = fs.Int(
+		"min-principals",
+		0,
+		"only output roles trusted by at least this many distinct principals",
+	)
+	f.minRoles = fs.Int(
+		"min-roles",
+		0,
+		"only output principals trusted by at least this many roles, counted after -min-principals",
+	)
+	f.roleAccounts = fs.String(
+		"role-accounts",
+		"",
+		"comma-separated account IDs; only scan roles owned by these accounts",
+	)
+	f.excludeRoleAccounts = fs.String(
+		"exclude-role-accounts",
+		"",
+		"comma-separated account IDs; skip roles owned by these accounts",
+	)
+	f.newSince = fs.String(
+		"new-since",
+		"",
+		"only scan roles created after the given duration ago, date or RFC 3339 time, e.g. 72h or 2025-01-02",
+	)
+	f.roleMaxAge = fs.String(
+		"role-max-age",
+		"",
+		"only scan roles created at most the given duration ago, e.g. 7d or 36h",
+	)
+	f.roleNames = fs.String(
+		"role-names",
+		"",
+		"comma-separated role names to scan, fetched one by one instead of listing every role",
+	)
+	f.pathPrefixes = fs.String(
+		"path-prefixes",
+		"",
+		"comma-separated role path prefixes to scan, e.g. /team-a/,/team-b/, each listed concurrently",
+	)
+	f.roleMinAge = fs.String(
+		"role-min-age",
+		"",
+		"only scan roles created at least the given duration ago, e.g. 30d; combines with -role-max-age",
+	)
+	f.invertMatch = fs.Bool(
+		"invert-match",
+		false,
+		"scan only the roles skipped by the role account, -new-since and role age filters, AND-ed together",
+	)
+	f.temporal = fs.Bool(
+		"temporal",
+		false,
+		"with -format gexf, stamp nodes and edges with the scan time for Gephi's timeline",
+	)
+	f.redactAccounts = fs.Bool(
+		"redact-accounts",
+		false,
+		"mask account IDs in the output, e.g. for sharing it externally; suppresses raw trust policies",
+	)
+	f.redactConsistent = fs.Bool(
+		"redact-consistent",
+		false,
+		"with -redact-accounts, mask each account ID with an HMAC keyed with $VEIL_REDACT_KEY, stable across runs",
+	)
+	f.explain = fs.Bool(
+		"explain",
+		false,
+		"describe each trust policy statement of every role in a plain-English sentence",
+	)
+	f.policyVariables = fs.Bool(
+		"policy-variables",
+		false,
+		"output the policy variables, e.g. ${aws:username}, referenced by the trust policy conditions of each role",
+	)
+	f.policyVersion = fs.Bool(
+		"check-policy-version",
+		false,
+		"output the roles whose trust policy uses the legacy policy version 2008-10-17, with a suggested upgrade",
+	)
+	f.checkSAML = fs.Bool(
+		"check-saml",
+		false,
+		"output the SAML providers trusted through sts:AssumeRoleWithSAML without any condition, e.g. on SAML:aud",
+	)
+	f.renameKeys = fs.String(
+		"rename-keys",
+		"",
+		"comma-separated from=to pairs renaming keys of the JSON output, e.g. principal=subject,role=target",
+	)
+	f.snsTopicARN = fs.String(
+		"sns-topic-arn",
+		"",
+		"publish a summary of the scan, with its highest finding severity as an attribute, to the given SNS topic",
+	)
+	f.dynamoDBTable = fs.String(
+		"dynamodb-table",
+		"",
+		"write the principals of each scanned role to the given DynamoDB table in -region, keyed by the role attribute",
+	)
+	f.configSnapshot = fs.String(
+		"config-snapshot",
+		"",
+		"S3 URI or path of an AWS Config snapshot whose recorded IAM roles are scanned instead of the live account",
+	)
+	f.strict = fs.Bool(
+		"strict",
+		false,
+		"reject trust policies containing keys outside the IAM policy grammar, e.g. misspelled ones",
+	)
+	f.continueOnError = fs.Bool(
+		"continue-on-error",
+		false,
+		"skip roles whose trust policy fails to decode, logging each of them, instead of failing the scan",
+	)
+	f.quietErrors = fs.Bool(
+		"quiet-errors",
+		false,
+		"with -continue-on-error, log a single summary line instead of one line per skipped role",
+	)
+	f.includeMetadata = fs.Bool(
+		"include-metadata",
+		false,
+		"with the json format, wrap the principals in an envelope listing the roles skipped under decode_errors",
+	)
+	f.scanPathOnly = fs.Bool(
+		"scan-path-only",
+		false,
+		"output only the path of each role, without analysing trust policies",
+	)
+	f.includeTags = fs.Bool(
+		"include-tags",
+		false,
+		"fetch role tags and include them in the raw policy, tables and markdown outputs",
+	)
+	f.groupByTag = fs.String(
+		"group-by-tag",
+		"",
+		"fetch role tags and output the roles, their principals and findings grouped by the value of the tag with "+
+			"this key, e.g. owner",
+	)
+	f.withBoundary = fs.Bool(
+		"with-boundary",
+		false,
+		"output roles with their permissions boundary, and count bounded and unbounded roles in the count formats",
+	)
+	f.onlyUnbounded = fs.Bool(
+		"only-unbounded",
+		false,
+		"scan only roles without a permissions boundary, implies -with-boundary",
+	)
+	f.includeRawPolicy = fs.Bool(
+		"include-raw-policy",
+		false,
+		"output roles with their URL-decoded trust policy document and its SHA-256",
+	)
+	f.prettyPolicies = fs.Bool(
+		"pretty-print-policies",
+		false,
+		"with -include-raw-policy, indent the trust policy documents with two spaces",
+	)
+	f.rawPolicyLimit = fs.Int(
+		"raw-policy-limit",
+		defaultRawPolicyLimit,
+		"maximum size in bytes of a raw trust policy document before it is truncated",
+	)
+	f.assumeRole = fs.String("assume-role", "", "IAM role ARN to assume before scanning, e.g. in another account")
+	f.roleSessionName = fs.String(
+		"role-session-name",
+		defaultRoleSessionName,
+		"session name used when assuming a role, visible in CloudTrail",
+	)
+	fs.StringVar(f.roleSessionName, "session-name", defaultRoleSessionName, "alias of -role-session-name")
+	f.sourceIdentity = fs.String(
+		"source-identity",
+		"",
+		"source identity set when assuming a role, kept through role chaining and visible in CloudTrail",
+	)
+	f.assumeRoleDuration = fs.Duration(
+		"assume-role-duration",
+		defaultAssumeRoleDuration,
+		"lifetime of the assumed role credentials, between 15m and 12h",
+	)
+	fs.DurationVar(
+		f.assumeRoleDuration,
+		"session-duration",
+		defaultAssumeRoleDuration,
+		"alias of -assume-role-duration",
+	)
+	f.fips = fs.Bool("fips", false, "use FIPS endpoints")
+	f.dualStack = fs.Bool("dualstack", false, "use IPv6 dual-stack endpoints")
+	f.endpointURL = fs.String("endpoint-url", "", "custom AWS endpoint URL, overrides -fips and -dualstack")
+	f.irsa = fs.Bool("irsa", false, "use IAM Roles for Service Accounts (web identity token) credentials")
+	f.lambda = fs.Bool(
+		"lambda",
+		false,
+		"run as an AWS Lambda handler taking these flags from the event, implied inside the Lambda runtime",
+	)
+
+	return f
+}
+
+// loader returns the config loader selected by the flags.
+func (f *cliFlags) loader() ConfigLoader {
+	if *f.irsa {
+		return &IRSAConfigLoader{}
+	}
+
+	return &DefaultConfigLoader{}
+}
+
+// options returns the App options selected by the flags, loading the files they point to.
+func (f *cliFlags) options() ([]Option, error) {
+	if *f.outputPerAccount && (*f.outputPath == "" || *f.format != formatJSON || *f.redactAccounts) {
+		return nil, errOutputPerAccount
+	}
+
+	if *f.splitByPrincipal && (*f.outputPath == "" || *f.format != formatJSON || *f.redactAccounts ||
+		*f.outputPerAccount) {
+		return nil, errSplitOutput
+	}
+
+	if (*f.digest || *f.signKey != "") && (*f.outputPath == "" || *f.outputPerAccount || *f.splitByPrincipal ||
+		*f.accounts != "") {
+		return nil, errIntegrityOutput
+	}
+
+	if *f.exportRawPolicies != "" && (*f.redactAccounts || *f.accounts != "" || *f.outputPerAccount ||
+		*f.splitByPrincipal) {
+		return nil, errExportRawPolicies
+	}
+
+	if *f.historyDB != "" && (*f.accounts != "" || *f.outputPerAccount || *f.splitByPrincipal) {
+		return nil, errHistoryDB
+	}
+
+	if *f.dedupeAccounts && (*f.outputPerAccount || *f.format != formatJSON) {
+		return nil, errDedupeAcrossAccounts
+	}
+
+	if *f.accounts != "" && (*f.assumeRole != "" || *f.format != formatJSON) {
+		return nil, errAccounts
+	}
+
+	if *f.accounts != "" && f.modeSelected() {
+		return nil, errAccountsMode
+	}
+
+	if *f.format == formatProto && (*f.redactAccounts || *f.renameKeys != "") {
+		return nil, errProtoOutput
+	}
+
+	if *f.format == formatProtoJSON && *f.renameKeys != "" {
+		return nil, errRenameProtoJSON
+	}
+
+	if (*f.diffPolicyBefore == "") != (*f.diffPolicyAfter == "") {
+		return nil, errDiffPolicy
+	}
+
+	if *f.configSnapshot != "" && (*f.accounts != "" || *f.assumeRole != "") {
+		return nil, errConfigSnapshot
+	}
+
+	if *f.invertMatch && *f.roleAccounts == "" && *f.excludeRoleAccounts == "" && *f.newSince == "" &&
+		*f.roleMinAge == "" && *f.roleMaxAge == "" {
+		return nil, errInvertMatch
+	}
+
+	if *f.quietErrors && !*f.continueOnError {
+		return nil, errQuietErrors
+	}
+
+	if *f.includeMetadata && (*f.format != formatJSON || f.modeSelected() || *f.accounts != "" ||
+		*f.dedupeAccounts) {
+		return nil, errIncludeMetadata
+	}
+
+	if *f.prettyPolicies && !*f.includeRawPolicy {
+		return nil, errPrettyPrintPolicies
+	}
+
+	if *f.snsTopicARN != "" {
+		_, err := parseSNSTopic(*f.snsTopicARN)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if *f.failOn != "" {
+		if _, ok := severityRank[*f.failOn]; !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidFailOn, *f.failOn)
+		}
+	}
+
+	opts := []Option{
+		WithRoleSessionName(*f.roleSessionName),
+		WithAssumeRoleDuration(*f.assumeRoleDuration),
+		WithSourceIdentity(*f.sourceIdentity),
+	}
+	if *f.assumeRole != "" {
+		opts = append(opts, WithAssumeRole(*f.assumeRole))
+	}
+
+	if *f.includeRawPolicy {
+		opts = append(opts, WithRawPolicy(*f.rawPolicyLimit))
+	}
+
+	if *f.prettyPolicies {
+		opts = append(opts, WithPrettyPolicies())
+	}
+
+	if *f.strict {
+		opts = append(opts, WithStrict())
+	}
+
+	if *f.continueOnError {
+		opts = append(opts, WithContinueOnError(*f.quietErrors))
+	}
+
+	if *f.includeMetadata {
+		opts = append(opts, WithMetadata())
+	}
+
+	if *f.includeTags {
+		opts = append(opts, WithRoleTags())
+	}
+
+	if *f.groupByTag != "" {
+		opts = append(opts, WithGroupByTag(*f.groupByTag))
+	}
+
+	if *f.withBoundary || *f.onlyUnbounded {
+		opts = append(opts, WithPermissionsBoundary(*f.onlyUnbounded))
+	}
+
+	if *f.roleAccounts != "" || *f.excludeRoleAccounts != "" {
+		opts = append(opts, WithRoleAccounts(splitList(*f.roleAccounts), splitList(*f.excludeRoleAccounts)))
+	}
+
+	if *f.newSince != "" {
+		marker, err := parseNewSince(*f.newSince, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse -new-since: %w", err)
+		}
+
+		opts = append(opts, WithNewSince(marker))
+	}
+
+	if *f.roleMinAge != "" || *f.roleMaxAge != "" {
+		var minAge, maxAge time.Duration
+
+		if *f.roleMinAge != "" {
+			age, err := parseRoleAge(*f.roleMinAge)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse -role-min-age: %w", err)
+			}
+
+			minAge = age
+		}
+
+		if *f.roleMaxAge != "" {
+			age, err := parseRoleAge(*f.roleMaxAge)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse -role-max-age: %w", err)
+			}
+
+			maxAge = age
+		}
+
+		opts = append(opts, WithRoleAge(minAge, maxAge))
+	}
+
+	if *f.invertMatch {
+		opts = append(opts, WithInvertMatch())
+	}
+
+	if *f.roleNames != "" {
+		opts = append(opts, WithRoleNames(splitList(*f.roleNames)))
+	}
+
+	if *f.pathPrefixes != "" {
+		opts = append(opts, WithPathPrefixes(splitList(*f.pathPrefixes)))
+	}
+
+	if *f.redactAccounts {
+		var key []byte
+
+		if *f.redactConsistent {
+			key = []byte(os.Getenv(redactKeyEnv))
+			if len(key) == 0 {
+				return nil, errRedactKey
+			}
+		}
+
+		opts = append(opts, WithRedactAccounts(key))
+
+		if *f.includeRawPolicy {
+			slog.Warn("raw trust policies are suppressed when redacting account IDs")
+		}
+	}
+
+	if *f.renameKeys != "" {
+		mapping, err := parseRenameKeys(*f.renameKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, WithRenameKeys(mapping))
+	}
+
+	if *f.temporal {
+		opts = append(opts, WithTemporal())
+	}
+
+	if *f.shortNames {
+		opts = append(opts, WithShortNames())
+	}
+
+	if *f.countByRegion {
+		opts = append(opts, WithRegionCounts())
+	}
+
+	if *f.focus != "" {
+		opts = append(opts, WithFocus(*f.focus, *f.focusDepth))
+	}
+
+	if *f.minPrincipals != 0 {
+		opts = append(opts, WithMinPrincipals(*f.minPrincipals))
+	}
+
+	if *f.minRoles != 0 {
+		opts = append(opts, WithMinRoles(*f.minRoles))
+	}
+
+	if *f.orgID != "" {
+		opts = append(opts, WithOrgID(*f.orgID))
+	}
+
+	if *f.failOn != "" {
+		opts = append(opts, WithFailOn(*f.failOn))
+	}
+
+	if *f.verifyProviders {
+		opts = append(opts, WithVerifyProviders(), WithSAMLCertWindow(*f.samlCertWindow))
+	}
+
+	if *f.gitlabHosts != "" {
+		opts = append(opts, WithGitLabHosts(splitList(*f.gitlabHosts)))
+	}
+
+	if *f.simulateActions != "" {
+		opts = append(opts, WithSimulateActions(splitList(*f.simulateActions)))
+	}
+
+	if *f.denyServices != "" {
+		opts = append(opts, WithDenyServices(splitList(*f.denyServices)))
+	}
+
+	if *f.fips {
+		opts = append(opts, WithFIPS())
+	}
+
+	if *f.dualStack {
+		opts = append(opts, WithDualStack())
+	}
+
+	if *f.endpointURL != "" {
+		opts = append(opts, WithEndpointURL(*f.endpointURL))
+	}
+
+	if *f.orgStructurePath != "" {
+		structure, err := loadOrgStructure(*f.orgStructurePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load org structure: %w", err)
+		}
+
+		opts = append(opts, WithOrgStructure(structure))
+	}
+
+	if *f.effective {
+		opts = append(opts, WithEffectivePrincipals())
+	}
+
+	if *f.derivedPrincipals {
+		opts = append(opts, WithDerivedPrincipals())
+	}
+
+	if *f.labelsFile != "" {
+		labels, err := loadLabels(*f.labelsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load labels: %w", err)
+		}
+
+		opts = append(opts, WithLabels(labels))
+	}
+
+	if *f.expectedPath != "" {
+		spec, err := loadExpectedTrust(*f.expectedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load expected-trust spec: %w", err)
+		}
+
+		opts = append(opts, WithExpectedTrust(spec))
+	}
+
+	return opts, nil
+}
+
+// modeSelected reports whether a mode flag replaces the trust graph output, e.g. with findings or the role-oriented
+// report.
+func (f *cliFlags) modeSelected() bool {
+	return *f.includeRawPolicy || *f.withBoundary || *f.onlyUnbounded || *f.labelsFile != "" || *f.sessionTagging ||
+		*f.accountRootTrust || *f.denyServices != "" || *f.groupByTag != "" || *f.identicalPolicies ||
+		*f.conditionStats || *f.explain || *f.policyVariables || *f.policyVersion || *f.checkSAML || *f.findings ||
+		*f.verifyProviders || *f.expectedPath != "" || *f.simulateActions != "" || *f.scanPathOnly ||
+		*f.diffScan != "" || *f.diffPolicyBefore != ""
+}
+
+// roleReport reports whether the output is the role-oriented report. The count formats show the boundaries themselves,
+// while the json format turns into the role-oriented output, which also carries the labels.
+func (f *cliFlags) roleReport() bool {
+	return *f.includeRawPolicy ||
+		(*f.withBoundary || *f.onlyUnbounded || *f.labelsFile != "") && *f.format == formatJSON
+}
+
+// schema returns the name of the JSON Schema describing the output, the role report's when the flags select it and
+// the format's otherwise.
+func (f *cliFlags) schema() string {
+	if f.roleReport() {
+		return schemaRoleReport
+	}
+
+	if *f.includeMetadata {
+		return schemaMetadata
+	}
+
+	return *f.format
+}
+
+// scan returns the scan function selected by the format and mode flags, the latter taking precedence.
+func (f *cliFlags) scan(client *App) (func(context.Context) ([]byte, error), error) {
+	scan, err := client.scanner(*f.format)
+	if err != nil {
+		return nil, err
+	}
+
+	if *f.dedupeAccounts {
+		scan = client.runDedupedScan
+	}
+
+	if f.roleReport() {
+		scan = client.runScanRoles
+	}
+
+	if *f.sessionTagging {
+		scan = client.runSessionTaggingAudit
+	}
+
+	if *f.accountRootTrust {
+		scan = client.runAccountRootAudit
+	}
+
+	if *f.denyServices != "" {
+		scan = client.runDeniedServicesAudit
+	}
+
+	if *f.groupByTag != "" {
+		scan = client.runGroupByTag
+	}
+
+	if *f.identicalPolicies {
+		scan = client.runIdenticalPoliciesAudit
+	}
+
+	if *f.conditionStats {
+		scan = client.runConditionStats
+	}
+
+	if *f.explain {
+		scan = client.runExplain
+	}
+
+	if *f.policyVariables {
+		scan = client.runPolicyVariables
+	}
+
+	if *f.policyVersion {
+		scan = client.runPolicyVersionCheck
+	}
+
+	if *f.checkSAML {
+		scan = client.runSAMLCheck
+	}
+
+	if *f.findings || *f.verifyProviders {
+		scan = client.runFindings
+	}
+
+	if *f.expectedPath != "" {
+		scan = client.runConformanceCheck
+	}
+
+	if *f.simulateActions != "" {
+		scan = client.runSimulation
+	}
+
+	if *f.scanPathOnly {
+		scan = client.runScanPaths
+	}
+
+	if *f.diffScan != "" {
+		scan = func(ctx context.Context) ([]byte, error) {
+			return client.runScanDiff(ctx, *f.diffScan)
+		}
+	}
+
+	if *f.diffPolicyBefore != "" {
+		scan = func(context.Context) ([]byte, error) {
+			return client.runPolicyDiff(*f.diffPolicyBefore, *f.diffPolicyAfter)
+		}
+	}
+
+	return scan, nil
+}
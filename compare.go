@@ -0,0 +1,231 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errCompareArgs        = errors.New("compare requires two scan outputs, or -profiles with two profiles")
+	errInvalidEquivalence = errors.New("expected a mapping of account IDs to equivalent account IDs")
+)
+
+// RoleDrift lists the principals trusted by a role in only one of the compared accounts.
+type RoleDrift struct {
+	Role         string   `json:"role"`
+	OnlyInFirst  []string `json:"onlyInFirst"`
+	OnlyInSecond []string `json:"onlyInSecond"`
+}
+
+// AccountDrift lists the roles found in only one of two compared accounts, by path and name, and the roles whose
+// trusted principals differ.
+type AccountDrift struct {
+	OnlyInFirst  []string    `json:"onlyInFirst"`
+	OnlyInSecond []string    `json:"onlyInSecond"`
+	Divergent    []RoleDrift `json:"divergent"`
+}
+
+// rolesByName keys the role to principals mapping of an account by role path and name, so roles can be matched
+// across accounts.
+func rolesByName(roles map[string][]string) map[string][]string {
+	output := make(map[string][]string, len(roles))
+
+	for role, principals := range roles {
+		name := shortRoleName(role)
+		output[name] = append(output[name], principals...)
+	}
+
+	return output
+}
+
+// equivalentPrincipals expands bare account IDs and replaces every account ID with its equivalent, if it has one, so
+// principals of paired accounts compare equal. The principals are returned sorted and deduplicated.
+func equivalentPrincipals(principals []string, equivalence map[string]string) []string {
+	output := make([]string, 0, len(principals))
+
+	for _, principal := range principals {
+		principal = accountIDInTextRegex.ReplaceAllStringFunc(normalisePrincipal(principal), func(account string) string {
+			if equivalent, ok := equivalence[account]; ok {
+				return equivalent
+			}
+
+			return account
+		})
+
+		output = append(output, principal)
+	}
+
+	return uniqSlice(output)
+}
+
+// CompareAccounts matches the roles of two accounts by path and name, reporting the roles present in only one of
+// them and the roles whose principals differ once account IDs are mapped through the equivalence.
+func CompareAccounts(
+	first map[string][]string,
+	second map[string][]string,
+	equivalence map[string]string,
+) AccountDrift {
+	firstRoles, secondRoles := rolesByName(first), rolesByName(second)
+	firstNames := slices.Sorted(maps.Keys(firstRoles))
+	secondNames := slices.Sorted(maps.Keys(secondRoles))
+
+	output := AccountDrift{
+		OnlyInFirst:  missingItems(firstNames, secondNames),
+		OnlyInSecond: missingItems(secondNames, firstNames),
+		Divergent:    make([]RoleDrift, 0),
+	}
+
+	for _, name := range firstNames {
+		other, ok := secondRoles[name]
+		if !ok {
+			continue
+		}
+
+		firstPrincipals := equivalentPrincipals(firstRoles[name], equivalence)
+		secondPrincipals := equivalentPrincipals(other, equivalence)
+
+		drift := RoleDrift{
+			Role:         name,
+			OnlyInFirst:  missingItems(firstPrincipals, secondPrincipals),
+			OnlyInSecond: missingItems(secondPrincipals, firstPrincipals),
+		}
+		if len(drift.OnlyInFirst) > 0 || len(drift.OnlyInSecond) > 0 {
+			output.Divergent = append(output.Divergent, drift)
+		}
+	}
+
+	return output
+}
+
+// loadScanOutput reads the json output of an earlier scan, keyed by principal, as a role to principals mapping.
+func loadScanOutput(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan output: %w", err)
+	}
+
+	var principals map[string][]string
+
+	err = json.Unmarshal(data, &principals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scan output %s: %w", path, err)
+	}
+
+	return mapFlip(principals), nil
+}
+
+// loadEquivalence reads a YAML mapping of account IDs to the account IDs they stand for in the other account, e.g.
+// the shared services account of production to that of staging.
+func loadEquivalence(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read equivalence file: %w", err)
+	}
+
+	var equivalence map[string]string
+
+	err = yaml.Unmarshal(data, &equivalence)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errInvalidEquivalence, path, err)
+	}
+
+	for account, equivalent := range equivalence {
+		if !accountIDRegex.MatchString(account) || !accountIDRegex.MatchString(equivalent) {
+			return nil, fmt.Errorf("%w: %s: %s to %s", errInvalidEquivalence, path, account, equivalent)
+		}
+	}
+
+	return equivalence, nil
+}
+
+// runCompareCommand compares the trust configurations of two accounts, read from scan outputs or, with -profiles,
+// scanned live with the given profiles, logging to logger.
+func runCompareCommand(
+	ctx context.Context,
+	logger *slog.Logger,
+	args []string,
+	out io.Writer,
+	scanProfile func(context.Context, string) (map[string][]string, error),
+) error {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	equivalencePath := flags.String(
+		"equivalence",
+		"",
+		"path to a YAML mapping of account IDs in the first account to their equivalents in the second",
+	)
+	profiles := flags.String("profiles", "", "comma-separated pair of AWS profiles to scan and compare live")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse compare flags: %w", err)
+	}
+
+	sources := flags.Args()
+	load := func(_ context.Context, path string) (map[string][]string, error) {
+		return loadScanOutput(path)
+	}
+
+	if *profiles != "" {
+		sources = splitList(*profiles)
+		load = scanProfile
+
+		if flags.NArg() > 0 {
+			return errCompareArgs
+		}
+	}
+
+	if len(sources) != 2 { //nolint:mnd
+		return errCompareArgs
+	}
+
+	equivalence := make(map[string]string)
+	if *equivalencePath != "" {
+		equivalence, err = loadEquivalence(*equivalencePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	first, err := load(ctx, sources[0])
+	if err != nil {
+		return err
+	}
+
+	second, err := load(ctx, sources[1])
+	if err != nil {
+		return err
+	}
+
+	drift := CompareAccounts(first, second, equivalence)
+	logger.Debug(
+		"compared accounts",
+		slog.Int("onlyInFirst", len(drift.OnlyInFirst)),
+		slog.Int("onlyInSecond", len(drift.OnlyInSecond)),
+		slog.Int("divergent", len(drift.Divergent)),
+	)
+
+	marshal, err := json.MarshalIndent(drift, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	_, err = fmt.Fprintln(out, string(marshal))
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
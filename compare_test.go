@@ -0,0 +1,211 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func mustScanOutput(t *testing.T, document string) map[string][]string {
+	t.Helper()
+
+	var principals map[string][]string
+
+	err := json.Unmarshal([]byte(document), &principals)
+	if err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+
+	return mapFlip(principals)
+}
+
+func TestCompareAccounts(t *testing.T) {
+	t.Parallel()
+
+	prod := mustScanOutput(t, fixtureCompareProd)
+	staging := mustScanOutput(t, fixtureCompareStaging)
+	equivalence := map[string]string{"111111111111": "222222222222", "333333333333": "444444444444"}
+
+	tests := []struct {
+		name        string
+		first       map[string][]string
+		second      map[string][]string
+		equivalence map[string]string
+		want        AccountDrift
+	}{
+		{
+			name:        "renamed, missing and divergent roles",
+			first:       prod,
+			second:      staging,
+			equivalence: equivalence,
+			want: AccountDrift{
+				OnlyInFirst:  []string{"/app/worker", "/ops/breakglass"},
+				OnlyInSecond: []string{"/ops/break-glass"},
+				Divergent: []RoleDrift{
+					{
+						Role:         "/vendor/audit",
+						OnlyInFirst:  []string{"arn:aws:iam::555555555555:root"},
+						OnlyInSecond: []string{"arn:aws:iam::666666666666:root"},
+					},
+				},
+			},
+		},
+		{
+			name:        "without equivalence",
+			first:       prod,
+			second:      staging,
+			equivalence: nil,
+			want: AccountDrift{
+				OnlyInFirst:  []string{"/app/worker", "/ops/breakglass"},
+				OnlyInSecond: []string{"/ops/break-glass"},
+				Divergent: []RoleDrift{
+					{
+						Role:         "/ci/deploy",
+						OnlyInFirst:  []string{"arn:aws:iam::333333333333:role/deployer"},
+						OnlyInSecond: []string{"arn:aws:iam::444444444444:role/deployer"},
+					},
+					{
+						Role:         "/vendor/audit",
+						OnlyInFirst:  []string{"arn:aws:iam::555555555555:root"},
+						OnlyInSecond: []string{"arn:aws:iam::666666666666:root"},
+					},
+				},
+			},
+		},
+		{
+			name:        "identical",
+			first:       prod,
+			second:      prod,
+			equivalence: nil,
+			want: AccountDrift{
+				OnlyInFirst:  []string{},
+				OnlyInSecond: []string{},
+				Divergent:    []RoleDrift{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := CompareAccounts(tt.first, tt.second, tt.equivalence)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareAccounts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_equivalentPrincipals(t *testing.T) {
+	t.Parallel()
+
+	got := equivalentPrincipals(
+		[]string{"111111111111", "arn:aws:iam::111111111111:root", "ecs-tasks.amazonaws.com"},
+		map[string]string{"111111111111": "222222222222"},
+	)
+	want := []string{"arn:aws:iam::222222222222:root", "ecs-tasks.amazonaws.com"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("equivalentPrincipals() = %v, want %v", got, want)
+	}
+}
+
+func Test_runCompareCommand(t *testing.T) {
+	t.Parallel()
+
+	dir := writePolicyTree(t, map[string]string{
+		"prod.json":        fixtureCompareProd,
+		"staging.json":     fixtureCompareStaging,
+		"equivalence.yaml": fixtureCompareEquivalence,
+		"invalid.yaml":     "111111111111: shared-tools\n",
+	})
+	scanProfile := func(_ context.Context, profile string) (map[string][]string, error) {
+		switch profile {
+		case "prod":
+			return mustScanOutput(t, fixtureCompareProd), nil
+		case "staging":
+			return mustScanOutput(t, fixtureCompareStaging), nil
+		default:
+			return nil, errors.New("test error")
+		}
+	}
+	equivalence := filepath.Join(dir, "equivalence.yaml")
+
+	tests := []struct {
+		name          string
+		args          []string
+		wantDivergent int
+		wantErr       error
+	}{
+		{
+			name: "scan outputs",
+			args: []string{
+				"-equivalence", equivalence, filepath.Join(dir, "prod.json"), filepath.Join(dir, "staging.json"),
+			},
+			wantDivergent: 1,
+			wantErr:       nil,
+		},
+		{
+			name:          "profiles",
+			args:          []string{"-equivalence", equivalence, "-profiles", "prod,staging"},
+			wantDivergent: 1,
+			wantErr:       nil,
+		},
+		{
+			name:          "one scan output",
+			args:          []string{filepath.Join(dir, "prod.json")},
+			wantDivergent: 0,
+			wantErr:       errCompareArgs,
+		},
+		{
+			name:          "profiles and scan outputs",
+			args:          []string{"-profiles", "prod,staging", filepath.Join(dir, "prod.json")},
+			wantDivergent: 0,
+			wantErr:       errCompareArgs,
+		},
+		{
+			name: "invalid equivalence",
+			args: []string{
+				"-equivalence", filepath.Join(dir, "invalid.yaml"),
+				filepath.Join(dir, "prod.json"), filepath.Join(dir, "staging.json"),
+			},
+			wantDivergent: 0,
+			wantErr:       errInvalidEquivalence,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+
+			err := runCompareCommand(t.Context(), slog.New(slog.DiscardHandler), tt.args, &out, scanProfile)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runCompareCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			var drift AccountDrift
+
+			err = json.Unmarshal(out.Bytes(), &drift)
+			if err != nil {
+				t.Fatalf("failed to decode output: %v", err)
+			}
+
+			if len(drift.Divergent) != tt.wantDivergent {
+				t.Errorf("runCompareCommand() divergent = %+v, want %d roles", drift.Divergent, tt.wantDivergent)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// maxConditionExamples caps the example roles listed for each operator and key combination.
+const maxConditionExamples = 3
+
+// conditionUsage counts the statements using a condition key with a given operator.
+type conditionUsage struct {
+	Operator string   `json:"operator"`
+	Key      string   `json:"key"`
+	Count    int      `json:"count"`
+	Examples []string `json:"examples"`
+}
+
+// conditionStats tallies the condition operators and keys used across trust policies.
+type conditionStats struct {
+	Operators    map[string]int   `json:"operators"`
+	Keys         map[string]int   `json:"keys"`
+	Combinations []conditionUsage `json:"combinations"`
+}
+
+// tallyConditions counts how many statements use each condition operator, each condition key and each combination
+// of the two, listing up to maxConditionExamples roles per combination. Combinations are ordered by count, most used
+// first.
+func tallyConditions(trusts map[string]roleTrust) conditionStats {
+	output := conditionStats{
+		Operators:    make(map[string]int),
+		Keys:         make(map[string]int),
+		Combinations: make([]conditionUsage, 0),
+	}
+
+	type combination struct{ operator, key string }
+
+	usage := make(map[combination]*conditionUsage)
+
+	roles := make([]string, 0, len(trusts))
+	for role := range trusts {
+		roles = append(roles, role)
+	}
+
+	// Roles are visited in order so the examples are stable between runs.
+	sort.Strings(roles)
+
+	for _, role := range roles {
+		for _, statement := range trusts[role].policy.Statement {
+			for operator, keys := range statement.Condition {
+				output.Operators[operator]++
+
+				for key := range keys {
+					output.Keys[key]++
+
+					current := combination{operator: operator, key: key}
+					if usage[current] == nil {
+						usage[current] = &conditionUsage{
+							Operator: operator,
+							Key:      key,
+							Count:    0,
+							Examples: make([]string, 0, maxConditionExamples),
+						}
+					}
+
+					usage[current].Count++
+
+					examples := usage[current].Examples
+					if len(examples) < maxConditionExamples && (len(examples) == 0 || examples[len(examples)-1] != role) {
+						usage[current].Examples = append(examples, role)
+					}
+				}
+			}
+		}
+	}
+
+	for _, item := range usage {
+		output.Combinations = append(output.Combinations, *item)
+	}
+
+	sort.Slice(output.Combinations, func(i, j int) bool {
+		if output.Combinations[i].Count != output.Combinations[j].Count {
+			return output.Combinations[i].Count > output.Combinations[j].Count
+		}
+
+		if output.Combinations[i].Operator != output.Combinations[j].Operator {
+			return output.Combinations[i].Operator < output.Combinations[j].Operator
+		}
+
+		return output.Combinations[i].Key < output.Combinations[j].Key
+	})
+
+	return output
+}
+
+func (a *App) runConditionStats(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := tallyConditions(trusts)
+	a.logger.Debug(
+		"tallied trust policy conditions",
+		slog.Int("roles", len(trusts)),
+		slog.Int("combinations", len(output.Combinations)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_tallyConditions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		documents map[string]string
+		want      conditionStats
+	}{
+		{
+			name:      "no roles",
+			documents: map[string]string{},
+			want: conditionStats{
+				Operators:    map[string]int{},
+				Keys:         map[string]int{},
+				Combinations: []conditionUsage{},
+			},
+		},
+		{
+			name: "no conditions",
+			documents: map[string]string{
+				"arn:aws:iam::0123456789:role/ecs": fixtureAWSServiceRoleForECS,
+			},
+			want: conditionStats{
+				Operators:    map[string]int{},
+				Keys:         map[string]int{},
+				Combinations: []conditionUsage{},
+			},
+		},
+		{
+			name: "operators and keys across roles",
+			documents: map[string]string{
+				"arn:aws:iam::0123456789:role/sso":      fixtureAWSReservedSSOFullAdmin,
+				"arn:aws:iam::0123456789:role/deployer": fixturePrincipalArnEquals,
+				"arn:aws:iam::0123456789:role/org":      fixtureOrgWideTrust,
+				"arn:aws:iam::0123456789:role/github":   fixtureGitHubOIDCUnpinned,
+				"arn:aws:iam::0123456789:role/ecs":      fixtureAWSServiceRoleForECS,
+			},
+			want: conditionStats{
+				Operators: map[string]int{"StringEquals": 3, "StringLike": 1},
+				Keys: map[string]int{
+					"SAML:aud":           1,
+					"aws:PrincipalArn":   1,
+					"aws:PrincipalOrgID": 1,
+					"token.actions.githubusercontent.com:sub": 1,
+				},
+				Combinations: []conditionUsage{
+					{
+						Operator: "StringEquals",
+						Key:      "SAML:aud",
+						Count:    1,
+						Examples: []string{"arn:aws:iam::0123456789:role/sso"},
+					},
+					{
+						Operator: "StringEquals",
+						Key:      "aws:PrincipalArn",
+						Count:    1,
+						Examples: []string{"arn:aws:iam::0123456789:role/deployer"},
+					},
+					{
+						Operator: "StringEquals",
+						Key:      "aws:PrincipalOrgID",
+						Count:    1,
+						Examples: []string{"arn:aws:iam::0123456789:role/org"},
+					},
+					{
+						Operator: "StringLike",
+						Key:      "token.actions.githubusercontent.com:sub",
+						Count:    1,
+						Examples: []string{"arn:aws:iam::0123456789:role/github"},
+					},
+				},
+			},
+		},
+		{
+			name: "examples capped and ordered",
+			documents: map[string]string{
+				"arn:aws:iam::0123456789:role/sso-d": fixtureAWSReservedSSOFullAdmin,
+				"arn:aws:iam::0123456789:role/sso-b": fixtureAWSReservedSSOFullAdmin,
+				"arn:aws:iam::0123456789:role/sso-a": fixtureAWSReservedSSOFullAdmin,
+				"arn:aws:iam::0123456789:role/sso-c": fixtureAWSReservedSSOFullAdmin,
+				"arn:aws:iam::0123456789:role/org":   fixtureOrgWideTrust,
+			},
+			want: conditionStats{
+				Operators: map[string]int{"StringEquals": 5},
+				Keys:      map[string]int{"SAML:aud": 4, "aws:PrincipalOrgID": 1},
+				Combinations: []conditionUsage{
+					{
+						Operator: "StringEquals",
+						Key:      "SAML:aud",
+						Count:    4,
+						Examples: []string{
+							"arn:aws:iam::0123456789:role/sso-a",
+							"arn:aws:iam::0123456789:role/sso-b",
+							"arn:aws:iam::0123456789:role/sso-c",
+						},
+					},
+					{
+						Operator: "StringEquals",
+						Key:      "aws:PrincipalOrgID",
+						Count:    1,
+						Examples: []string{"arn:aws:iam::0123456789:role/org"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trusts := make(map[string]roleTrust, len(tt.documents))
+			for role, document := range tt.documents {
+				trusts[role] = mustDecodeTrust(t, role, document)
+			}
+
+			if got := tallyConditions(trusts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tallyConditions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
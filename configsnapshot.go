@@ -0,0 +1,328 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const configRoleResourceType = "AWS::IAM::Role"
+
+var (
+	errInvalidConfigSnapshot = errors.New("expected an AWS Config snapshot with configurationItems")
+	errSnapshotOffline       = errors.New("not available when scanning an AWS Config snapshot")
+	errConfigSnapshot        = errors.New("-config-snapshot cannot be combined with -accounts or -assume-role")
+)
+
+// ServiceS3Reader downloads objects via AWS SDK clients.
+type ServiceS3Reader interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// configSnapshot is the configuration snapshot AWS Config delivers to S3, holding an item per recorded resource.
+type configSnapshot struct {
+	ConfigurationItems []configItem `json:"configurationItems"`
+}
+
+// configItem is a recorded resource of a snapshot. The configuration is an object in snapshots, but a JSON encoded
+// string in some other deliveries, e.g. the results of advanced queries.
+type configItem struct {
+	ResourceType         string          `json:"resourceType"`
+	ARN                  string          `json:"ARN"`
+	ResourceID           string          `json:"resourceId"`
+	ResourceName         string          `json:"resourceName"`
+	ResourceCreationTime string          `json:"resourceCreationTime"`
+	Configuration        json.RawMessage `json:"configuration"`
+}
+
+// configRole is the configuration AWS Config records for an IAM role.
+type configRole struct {
+	Path                     string `json:"path"`
+	RoleName                 string `json:"roleName"`
+	RoleID                   string `json:"roleId"`
+	Arn                      string `json:"arn"`
+	CreateDate               string `json:"createDate"`
+	AssumeRolePolicyDocument string `json:"assumeRolePolicyDocument"`
+	Description              string `json:"description"`
+	MaxSessionDuration       int32  `json:"maxSessionDuration"`
+	Tags                     []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"tags"`
+}
+
+// configTime parses the times of Config items, returning nil if the time is missing or malformed.
+func configTime(value string) *time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+
+	return &parsed
+}
+
+// role converts the recorded configuration of a role, falling back to the item for the fields it lacks.
+func (c configItem) role() (types.Role, error) {
+	configuration := c.Configuration
+
+	var encoded string
+	if json.Unmarshal(configuration, &encoded) == nil {
+		configuration = json.RawMessage(encoded)
+	}
+
+	var recorded configRole
+
+	err := json.Unmarshal(configuration, &recorded)
+	if err != nil {
+		return types.Role{}, fmt.Errorf("failed to parse configuration of %s: %w", c.ARN, err)
+	}
+
+	// Roles carry their trust policy URL-encoded, as returned by the IAM API and recorded by Config.
+	document := recorded.AssumeRolePolicyDocument
+	if strings.HasPrefix(strings.TrimSpace(document), "{") {
+		document = url.QueryEscape(document)
+	}
+
+	output := types.Role{ //nolint:exhaustruct
+		Arn:                      aws.String(cmp.Or(recorded.Arn, c.ARN)),
+		RoleName:                 aws.String(cmp.Or(recorded.RoleName, c.ResourceName)),
+		RoleId:                   aws.String(cmp.Or(recorded.RoleID, c.ResourceID)),
+		Path:                     aws.String(cmp.Or(recorded.Path, "/")),
+		AssumeRolePolicyDocument: aws.String(document),
+		CreateDate:               configTime(cmp.Or(recorded.CreateDate, c.ResourceCreationTime)),
+	}
+
+	if recorded.Description != "" {
+		output.Description = aws.String(recorded.Description)
+	}
+
+	if recorded.MaxSessionDuration > 0 {
+		output.MaxSessionDuration = aws.Int32(recorded.MaxSessionDuration)
+	}
+
+	for _, tag := range recorded.Tags {
+		output.Tags = append(output.Tags, types.Tag{Key: aws.String(tag.Key), Value: aws.String(tag.Value)})
+	}
+
+	return output, nil
+}
+
+// ParseConfigSnapshot reads an AWS Config snapshot, gzipped as delivered to S3 or not, returning the IAM roles it
+// records as the IAM API would list them.
+func ParseConfigSnapshot(r io.Reader) ([]types.Role, error) {
+	buffered := bufio.NewReader(r)
+
+	magic, _ := buffered.Peek(2) //nolint:mnd
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+		}
+
+		defer func() {
+			_ = gz.Close()
+		}()
+
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	var snapshot configSnapshot
+
+	err := json.NewDecoder(r).Decode(&snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfigSnapshot, err)
+	}
+
+	if snapshot.ConfigurationItems == nil {
+		return nil, errInvalidConfigSnapshot
+	}
+
+	output := make([]types.Role, 0)
+
+	for _, item := range snapshot.ConfigurationItems {
+		if item.ResourceType != configRoleResourceType {
+			continue
+		}
+
+		role, err := item.role()
+		if err != nil {
+			return nil, err
+		}
+
+		output = append(output, role)
+	}
+
+	return output, nil
+}
+
+// ConfigSnapshotLoader reads the IAM roles recorded by an AWS Config snapshot, from an S3 URI such as
+// s3://config-bucket/AWSLogs/123456789012/Config/eu-west-1/.../ConfigSnapshot/snapshot.json.gz or a local path.
+type ConfigSnapshotLoader struct {
+	Client   ServiceS3Reader
+	Location string
+}
+
+// LoadRoles downloads, or reads, and parses the snapshot.
+func (c ConfigSnapshotLoader) LoadRoles(ctx context.Context) ([]types.Role, error) {
+	if !strings.HasPrefix(c.Location, "s3://") {
+		file, err := os.Open(c.Location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Config snapshot: %w", err)
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		return ParseConfigSnapshot(file)
+	}
+
+	bucket, key, err := parseS3URI(c.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := c.Client.GetObject(ctx, &s3.GetObjectInput{ //nolint:exhaustruct
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download Config snapshot: %w", err)
+	}
+
+	defer func() {
+		_ = object.Body.Close()
+	}()
+
+	return ParseConfigSnapshot(object.Body)
+}
+
+// configSnapshotRoles returns the option scanning the roles of the Config snapshot at the given location instead of
+// the live account, downloading it with the default credentials.
+func configSnapshotRoles(ctx context.Context, loader ConfigLoader, location string) (Option, error) {
+	snapshot := ConfigSnapshotLoader{Client: nil, Location: location}
+
+	if strings.HasPrefix(location, "s3://") {
+		cfg, err := loader.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config, %w", err)
+		}
+
+		snapshot.Client = s3.NewFromConfig(cfg)
+	}
+
+	roles, err := snapshot.LoadRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return WithSnapshotRoles(roles), nil
+}
+
+// snapshotIAM serves the roles of a Config snapshot in place of the IAM API. Only listing and reading roles and their
+// tags is supported, as the snapshot records nothing else.
+type snapshotIAM struct {
+	roles []types.Role
+}
+
+var _ ServiceIAM = (*snapshotIAM)(nil)
+
+// ListRoles returns every role of the snapshot under the path prefix of the input in a single page.
+func (s snapshotIAM) ListRoles(
+	_ context.Context,
+	input *iam.ListRolesInput,
+	_ ...func(*iam.Options),
+) (*iam.ListRolesOutput, error) {
+	roles := make([]types.Role, 0, len(s.roles))
+
+	for _, role := range s.roles {
+		if strings.HasPrefix(aws.ToString(role.Path), aws.ToString(input.PathPrefix)) {
+			roles = append(roles, role)
+		}
+	}
+
+	return &iam.ListRolesOutput{Roles: roles, IsTruncated: false, Marker: nil}, nil //nolint:exhaustruct
+}
+
+// GetRole returns the role of the snapshot with the given name.
+func (s snapshotIAM) GetRole(
+	_ context.Context,
+	input *iam.GetRoleInput,
+	_ ...func(*iam.Options),
+) (*iam.GetRoleOutput, error) {
+	for _, role := range s.roles {
+		if aws.ToString(role.RoleName) == aws.ToString(input.RoleName) {
+			return &iam.GetRoleOutput{Role: &role}, nil //nolint:exhaustruct
+		}
+	}
+
+	return nil, &types.NoSuchEntityException{ //nolint:exhaustruct
+		Message: aws.String("role not found in the Config snapshot"),
+	}
+}
+
+// ListRoleTags returns the tags the snapshot recorded for the role with the given name.
+func (s snapshotIAM) ListRoleTags(
+	ctx context.Context,
+	input *iam.ListRoleTagsInput,
+	_ ...func(*iam.Options),
+) (*iam.ListRoleTagsOutput, error) {
+	role, err := s.GetRole(ctx, &iam.GetRoleInput{RoleName: input.RoleName})
+	if err != nil {
+		return nil, err
+	}
+
+	return &iam.ListRoleTagsOutput{Tags: role.Role.Tags, IsTruncated: false, Marker: nil}, nil //nolint:exhaustruct
+}
+
+func (s snapshotIAM) SimulatePrincipalPolicy(
+	_ context.Context,
+	_ *iam.SimulatePrincipalPolicyInput,
+	_ ...func(*iam.Options),
+) (*iam.SimulatePrincipalPolicyOutput, error) {
+	return nil, fmt.Errorf("simulating policies is %w", errSnapshotOffline)
+}
+
+func (s snapshotIAM) UpdateAssumeRolePolicy(
+	_ context.Context,
+	_ *iam.UpdateAssumeRolePolicyInput,
+	_ ...func(*iam.Options),
+) (*iam.UpdateAssumeRolePolicyOutput, error) {
+	return nil, fmt.Errorf("updating trust policies is %w", errSnapshotOffline)
+}
+
+func (s snapshotIAM) GetSAMLProvider(
+	_ context.Context,
+	_ *iam.GetSAMLProviderInput,
+	_ ...func(*iam.Options),
+) (*iam.GetSAMLProviderOutput, error) {
+	return nil, fmt.Errorf("verifying providers is %w", errSnapshotOffline)
+}
+
+func (s snapshotIAM) GetOpenIDConnectProvider(
+	_ context.Context,
+	_ *iam.GetOpenIDConnectProviderInput,
+	_ ...func(*iam.Options),
+) (*iam.GetOpenIDConnectProviderOutput, error) {
+	return nil, fmt.Errorf("verifying providers is %w", errSnapshotOffline)
+}
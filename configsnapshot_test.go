@@ -0,0 +1,267 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type MockServiceS3Reader struct {
+	objects map[string][]byte
+}
+
+func (m MockServiceS3Reader) GetObject(
+	_ context.Context,
+	input *s3.GetObjectInput,
+	_ ...func(*s3.Options),
+) (*s3.GetObjectOutput, error) {
+	object, ok := m.objects[aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key)]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object))}, nil
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+
+	_, err := gz.Write([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	err = gz.Close()
+	if err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParseConfigSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		snapshot  []byte
+		wantRoles []string
+		wantErr   error
+	}{
+		{
+			name:     "snapshot",
+			snapshot: []byte(fixtureConfigSnapshot),
+			wantRoles: []string{
+				"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS",
+				"arn:aws:iam::0123456789:role/vendor",
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "gzipped snapshot",
+			snapshot: gzipped(t, fixtureConfigSnapshot),
+			wantRoles: []string{
+				"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS",
+				"arn:aws:iam::0123456789:role/vendor",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "no roles",
+			snapshot:  []byte(`{"fileVersion":"1.0","configurationItems":[]}`),
+			wantRoles: []string{},
+			wantErr:   nil,
+		},
+		{
+			name:      "not a snapshot",
+			snapshot:  []byte(fixtureAWSServiceRoleForECS),
+			wantRoles: nil,
+			wantErr:   errInvalidConfigSnapshot,
+		},
+		{
+			name:      "malformed",
+			snapshot:  []byte(`{"configurationItems":[`),
+			wantRoles: nil,
+			wantErr:   errInvalidConfigSnapshot,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			roles, err := ParseConfigSnapshot(bytes.NewReader(tt.snapshot))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseConfigSnapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			got := make([]string, 0, len(roles))
+			for _, role := range roles {
+				got = append(got, aws.ToString(role.Arn))
+			}
+
+			if !reflect.DeepEqual(got, tt.wantRoles) {
+				t.Errorf("ParseConfigSnapshot() roles = %v, want %v", got, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestParseConfigSnapshot_role(t *testing.T) {
+	t.Parallel()
+
+	roles, err := ParseConfigSnapshot(strings.NewReader(fixtureConfigSnapshot))
+	if err != nil {
+		t.Fatalf("ParseConfigSnapshot() unexpected error: %v", err)
+	}
+
+	for _, role := range roles {
+		if role.CreateDate == nil || aws.ToString(role.RoleName) == "" || aws.ToString(role.Path) == "" {
+			t.Errorf("ParseConfigSnapshot() role %s lacks its name, path or creation date", aws.ToString(role.Arn))
+		}
+
+		_, err = decodeRoleTrust(role, true)
+		if err != nil {
+			t.Errorf("decodeRoleTrust(%s) unexpected error: %v", aws.ToString(role.Arn), err)
+		}
+	}
+}
+
+func TestConfigSnapshotLoader_LoadRoles(t *testing.T) {
+	t.Parallel()
+
+	client := MockServiceS3Reader{objects: map[string][]byte{
+		"config-bucket/AWSLogs/0123456789/Config/snapshot.json.gz": gzipped(t, fixtureConfigSnapshot),
+	}}
+
+	tests := []struct {
+		name      string
+		location  string
+		wantRoles int
+		wantErr   bool
+	}{
+		{
+			name:      "S3 object",
+			location:  "s3://config-bucket/AWSLogs/0123456789/Config/snapshot.json.gz",
+			wantRoles: 2,
+			wantErr:   false,
+		},
+		{
+			name:      "local file",
+			location:  "fixtures/ConfigSnapshot.json",
+			wantRoles: 2,
+			wantErr:   false,
+		},
+		{
+			name:      "missing S3 object",
+			location:  "s3://config-bucket/missing.json.gz",
+			wantRoles: 0,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid S3 URI",
+			location:  "s3://config-bucket/",
+			wantRoles: 0,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			loader := ConfigSnapshotLoader{Client: client, Location: tt.location}
+
+			roles, err := loader.LoadRoles(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRoles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(roles) != tt.wantRoles {
+				t.Errorf("LoadRoles() got %d roles, want %d", len(roles), tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestWithSnapshotRoles(t *testing.T) {
+	t.Parallel()
+
+	roles, err := ParseConfigSnapshot(strings.NewReader(fixtureConfigSnapshot))
+	if err != nil {
+		t.Fatalf("ParseConfigSnapshot() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		prefixes []string
+		want     map[string][]string
+	}{
+		{
+			name:     "every role",
+			prefixes: nil,
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS": {
+					"ecs.amazonaws.com",
+				},
+				"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+			},
+		},
+		{
+			name:     "path prefix",
+			prefixes: []string{"/aws-service-role/"},
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS": {
+					"ecs.amazonaws.com",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithSnapshotRoles(roles),
+				WithPathPrefixes(tt.prefixes),
+			)
+			if err != nil {
+				t.Fatalf("NewApp() unexpected error: %v", err)
+			}
+
+			got, err := app.getRolesWithTrust(t.Context())
+			if err != nil {
+				t.Fatalf("getRolesWithTrust() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getRolesWithTrust() got = %v, want %v", got, tt.want)
+			}
+
+			_, err = app.iamClient().SimulatePrincipalPolicy(t.Context(), nil)
+			if !errors.Is(err, errSnapshotOffline) {
+				t.Errorf("SimulatePrincipalPolicy() error = %v, want %v", err, errSnapshotOffline)
+			}
+		})
+	}
+}
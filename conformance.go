@@ -0,0 +1,263 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	codeUnexpectedTrust = "UNEXPECTED_TRUST"
+	codeMissingTrust    = "MISSING_TRUST"
+)
+
+var (
+	errInvalidSpec = errors.New("invalid expected-trust spec")
+	accountIDRegex = regexp.MustCompile(`^\d{12}$`)
+)
+
+// trustRule allows the principals matching any of its patterns to be trusted by roles matching its role pattern.
+type trustRule struct {
+	rolePattern string
+	role        *regexp.Regexp
+	principals  []principalPattern
+}
+
+// principalPattern is a single allowed principal glob.
+type principalPattern struct {
+	pattern string
+	match   *regexp.Regexp
+}
+
+// expectedTrust is the desired-state spec mapping role ARN globs to allowed principal globs.
+type expectedTrust []trustRule
+
+// loadExpectedTrust reads and validates an expected-trust spec from a YAML file.
+func loadExpectedTrust(path string) (expectedTrust, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expected-trust spec: %w", err)
+	}
+
+	return parseExpectedTrust(path, data)
+}
+
+// parseExpectedTrust validates the shape of the spec, reporting the position of the first offending node.
+// The spec must be a mapping from role ARN globs to lists of allowed principal globs.
+func parseExpectedTrust(name string, data []byte) (expectedTrust, error) {
+	var document yaml.Node
+
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errInvalidSpec, name, err)
+	}
+
+	if len(document.Content) == 0 {
+		return expectedTrust{}, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, specError(name, root, "expected a mapping of role ARN patterns to allowed principals")
+	}
+
+	output := make(expectedTrust, 0, len(root.Content)/2) //nolint:mnd
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Kind != yaml.ScalarNode || key.Value == "" {
+			return nil, specError(name, key, "role ARN pattern must be a non-empty string")
+		}
+
+		if value.Kind != yaml.SequenceNode {
+			return nil, specError(name, value, "allowed principals of "+key.Value+" must be a list")
+		}
+
+		rule := trustRule{
+			rolePattern: key.Value,
+			role:        globRegexp(key.Value),
+			principals:  make([]principalPattern, 0, len(value.Content)),
+		}
+
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode || item.Value == "" {
+				return nil, specError(name, item, "allowed principal must be a non-empty string")
+			}
+
+			rule.principals = append(rule.principals, principalPattern{
+				pattern: normalisePrincipal(item.Value),
+				match:   globRegexp(principalGlob(item.Value)),
+			})
+		}
+
+		output = append(output, rule)
+	}
+
+	return output, nil
+}
+
+func specError(name string, node *yaml.Node, message string) error {
+	return fmt.Errorf("%w: %s:%d:%d: %s", errInvalidSpec, name, node.Line, node.Column, message)
+}
+
+// globRegexp compiles a glob where * matches any sequence of characters, including /, and ? matches one character.
+func globRegexp(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// normalisePrincipal expands a bare account ID into its account-root ARN, as AWS does when storing trust policies.
+func normalisePrincipal(principal string) string {
+	return normalisePrincipalIn(principal, defaultPartition)
+}
+
+// normalisePrincipalIn expands a bare account ID into its account-root ARN in the given partition, as AWS does when
+// storing the trust policy of a role in that partition, e.g. arn:aws-us-gov:iam::111111111111:root in GovCloud.
+func normalisePrincipalIn(principal string, partition string) string {
+	if accountIDRegex.MatchString(principal) {
+		return "arn:" + partition + ":iam::" + principal + ":root"
+	}
+
+	return principal
+}
+
+// principalGlob turns a bare account ID into a glob matching its account root in any partition, leaving other
+// principals and patterns unchanged.
+func principalGlob(principal string) string {
+	if accountIDRegex.MatchString(principal) {
+		return "arn:*:iam::" + principal + ":root"
+	}
+
+	return principal
+}
+
+// check compares the principals trusted by each role against the spec. Roles not matching any rule are skipped.
+func (e expectedTrust) check(roles map[string][]string) []Finding {
+	output := make([]Finding, 0)
+
+	for role, principals := range roles {
+		allowed := make([]principalPattern, 0)
+
+		for _, rule := range e {
+			if rule.role.MatchString(role) {
+				allowed = append(allowed, rule.principals...)
+			}
+		}
+
+		if len(allowed) == 0 {
+			continue
+		}
+
+		output = append(output, unexpectedTrust(role, principals, allowed)...)
+		output = append(output, missingTrust(role, principals, allowed)...)
+	}
+
+	sortFindings(output)
+
+	return output
+}
+
+func unexpectedTrust(role string, principals []string, allowed []principalPattern) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range principals {
+		if matchesAny(normalisePrincipalIn(principal, rolePartition(role)), allowed) {
+			continue
+		}
+
+		output = append(output, Finding{
+			Code:      codeUnexpectedTrust,
+			Severity:  severityHigh,
+			Role:      role,
+			Principal: principal,
+			Message:   "principal is trusted but not allowed by the expected-trust spec",
+			Remediation: &Remediation{
+				Summary:   "Remove the principal from the trust policy, or allow it in the expected-trust spec.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		})
+	}
+
+	return output
+}
+
+func missingTrust(role string, principals []string, allowed []principalPattern) []Finding {
+	output := make([]Finding, 0)
+	seen := make(map[string]struct{}, len(allowed))
+	partition := rolePartition(role)
+
+	for _, pattern := range allowed {
+		if _, ok := seen[pattern.pattern]; ok {
+			continue
+		}
+
+		seen[pattern.pattern] = struct{}{}
+
+		found := false
+
+		for _, principal := range principals {
+			if pattern.match.MatchString(normalisePrincipalIn(principal, partition)) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			output = append(output, Finding{
+				Code:        codeMissingTrust,
+				Severity:    severityInfo,
+				Role:        role,
+				Principal:   pattern.pattern,
+				Message:     "principal is allowed by the expected-trust spec but not trusted",
+				Remediation: nil,
+			})
+		}
+	}
+
+	return output
+}
+
+func matchesAny(principal string, allowed []principalPattern) bool {
+	for _, pattern := range allowed {
+		if pattern.match.MatchString(principal) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *App) runConformanceCheck(ctx context.Context) ([]byte, error) {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := a.expectedTrust.check(roles)
+	a.logger.Debug(
+		"checked IAM roles against expected trust",
+		slog.Int("roles", len(roles)),
+		slog.Int("findings", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_loadExpectedTrust(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		path      string
+		wantRules int
+		wantErr   string
+	}{
+		{
+			name:      "missing file",
+			path:      "fixtures/missing.yaml",
+			wantRules: 0,
+			wantErr:   "failed to read",
+		},
+		{
+			name:      "valid spec",
+			path:      "fixtures/Expected.yaml",
+			wantRules: 2,
+			wantErr:   "",
+		},
+		{
+			name:      "nested principal",
+			path:      "fixtures/ExpectedInvalid.yaml",
+			wantRules: 0,
+			wantErr:   "fixtures/ExpectedInvalid.yaml:3:5: allowed principal must be a non-empty string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := loadExpectedTrust(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadExpectedTrust() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("loadExpectedTrust() unexpected error: %v", err)
+			}
+
+			if len(got) != tt.wantRules {
+				t.Errorf("loadExpectedTrust() got %d rules, want %d", len(got), tt.wantRules)
+			}
+		})
+	}
+}
+
+func Test_parseExpectedTrust(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: "",
+		},
+		{
+			name:    "not a mapping",
+			data:    "- arn:aws:iam::0123456789:role/test",
+			wantErr: "spec.yaml:1:1: expected a mapping",
+		},
+		{
+			name:    "principals not a list",
+			data:    "arn:aws:iam::0123456789:role/test: ecs.amazonaws.com",
+			wantErr: "spec.yaml:1:36: allowed principals of arn:aws:iam::0123456789:role/test must be a list",
+		},
+		{
+			name:    "malformed YAML",
+			data:    "arn: [",
+			wantErr: "spec.yaml: yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseExpectedTrust("spec.yaml", []byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseExpectedTrust() unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, errInvalidSpec) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseExpectedTrust() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_expectedTrust_check(t *testing.T) {
+	t.Parallel()
+
+	spec, err := loadExpectedTrust("fixtures/Expected.yaml")
+	if err != nil {
+		t.Fatalf("loadExpectedTrust() unexpected error: %v", err)
+	}
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/deploy-app": {
+			"arn:aws:iam::111111111111:root",
+			"arn:aws:iam::999999999999:root",
+		},
+		"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS": {
+			"ecs.amazonaws.com",
+		},
+		"arn:aws:iam::0123456789:role/unmanaged": {
+			"*",
+		},
+	}
+
+	want := []Finding{
+		{
+			Code:      codeMissingTrust,
+			Severity:  severityInfo,
+			Role:      "arn:aws:iam::0123456789:role/deploy-app",
+			Principal: "arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+			Message:   "principal is allowed by the expected-trust spec but not trusted",
+		},
+		{
+			Code:      codeUnexpectedTrust,
+			Severity:  severityHigh,
+			Role:      "arn:aws:iam::0123456789:role/deploy-app",
+			Principal: "arn:aws:iam::999999999999:root",
+			Message:   "principal is trusted but not allowed by the expected-trust spec",
+			Remediation: &Remediation{
+				Summary:   "Remove the principal from the trust policy, or allow it in the expected-trust spec.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		},
+	}
+
+	if got := spec.check(roles); !reflect.DeepEqual(got, want) {
+		t.Errorf("check() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_normalisePrincipal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+	}{
+		{
+			name:      "bare account ID",
+			principal: "111111111111",
+			want:      "arn:aws:iam::111111111111:root",
+		},
+		{
+			name:      "account root",
+			principal: "arn:aws:iam::111111111111:root",
+			want:      "arn:aws:iam::111111111111:root",
+		},
+		{
+			name:      "service",
+			principal: "ecs.amazonaws.com",
+			want:      "ecs.amazonaws.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := normalisePrincipal(tt.principal); got != tt.want {
+				t.Errorf("normalisePrincipal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expectedTrust_check_partitions(t *testing.T) {
+	t.Parallel()
+
+	spec, err := parseExpectedTrust("partitions.yaml", []byte(`
+"arn:aws-*:iam::*:role/deploy":
+  - "111111111111"
+`))
+	if err != nil {
+		t.Fatalf("parseExpectedTrust() unexpected error: %v", err)
+	}
+
+	roles := map[string][]string{
+		"arn:aws-us-gov:iam::123456789012:role/deploy": {"arn:aws-us-gov:iam::111111111111:root"},
+		"arn:aws-cn:iam::123456789012:role/deploy":     {"111111111111"},
+	}
+
+	if got := spec.check(roles); len(got) != 0 {
+		t.Errorf("check() = %+v, want no findings", got)
+	}
+}
+
+func Test_normalisePrincipalIn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		partition string
+		want      string
+	}{
+		{
+			name:      "GovCloud account ID",
+			principal: "111111111111",
+			partition: "aws-us-gov",
+			want:      "arn:aws-us-gov:iam::111111111111:root",
+		},
+		{
+			name:      "China account ID",
+			principal: "111111111111",
+			partition: "aws-cn",
+			want:      "arn:aws-cn:iam::111111111111:root",
+		},
+		{
+			name:      "ARN of another partition kept",
+			principal: "arn:aws:iam::111111111111:root",
+			partition: "aws-cn",
+			want:      "arn:aws:iam::111111111111:root",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := normalisePrincipalIn(tt.principal, tt.partition); got != tt.want {
+				t.Errorf("normalisePrincipalIn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
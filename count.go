@@ -0,0 +1,210 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// regionGlobal is the region roles are counted in unless their path encodes one.
+const regionGlobal = "global"
+
+// ssoRegionRegex matches the region in the path of roles created by IAM Identity Center for a permission set, e.g.
+// /aws-reserved/sso.amazonaws.com/eu-west-1/.
+var ssoRegionRegex = regexp.MustCompile(`/sso\.amazonaws\.com/([a-z]{2}(?:-[a-z]+)+-\d+)/`)
+
+// trustCount holds the number of distinct roles and principals in the trust graph.
+// Boundaries is only set when permissions boundaries were fetched, and Regions when counting by region.
+type trustCount struct {
+	Roles      int                   `json:"roles"`
+	Principals int                   `json:"principals"`
+	Boundaries *boundaryCount        `json:"boundaries,omitempty"`
+	Regions    map[string]trustCount `json:"regions,omitempty"`
+}
+
+// countTrust counts the distinct roles and principals of the given role to principals mapping.
+func countTrust(data map[string][]string) trustCount {
+	return trustCount{
+		Roles:      len(data),
+		Principals: len(mapFlip(data)),
+		Boundaries: nil,
+		Regions:    nil,
+	}
+}
+
+// roleRegion returns the region encoded in the path of the given role, or global. IAM roles are global, but those
+// created by IAM Identity Center carry the region of its instance in their path.
+func roleRegion(arn string) string {
+	match := ssoRegionRegex.FindStringSubmatch(arn)
+	if match == nil {
+		return regionGlobal
+	}
+
+	return match[1]
+}
+
+// countByRegion counts the distinct roles and principals of the given role to principals mapping per region, see
+// roleRegion.
+func countByRegion(data map[string][]string) map[string]trustCount {
+	regions := make(map[string]map[string][]string)
+
+	for role, principals := range data {
+		region := roleRegion(role)
+		if regions[region] == nil {
+			regions[region] = make(map[string][]string)
+		}
+
+		regions[region][role] = principals
+	}
+
+	output := make(map[string]trustCount, len(regions))
+	for region, roles := range regions {
+		output[region] = countTrust(roles)
+	}
+
+	return output
+}
+
+// WriteCount writes the number of roles and principals of the given role to principals mapping, one per line.
+func WriteCount(w io.Writer, data map[string][]string) error {
+	count := countTrust(data)
+
+	_, err := fmt.Fprintf(w, "roles: %d\nprincipals: %d\n", count.Roles, count.Principals)
+	if err != nil {
+		return fmt.Errorf("failed to write count: %w", err)
+	}
+
+	return nil
+}
+
+// writeRegionCount writes the number of roles and principals of each region, one per line in region order.
+func writeRegionCount(w io.Writer, counts map[string]trustCount) error {
+	for _, region := range slices.Sorted(maps.Keys(counts)) {
+		_, err := fmt.Fprintf(
+			w,
+			"%s roles: %d\n%s principals: %d\n",
+			region,
+			counts[region].Roles,
+			region,
+			counts[region].Principals,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write count: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeBoundaryCount writes the number of roles with and without a permissions boundary, one per line.
+func writeBoundaryCount(w io.Writer, count boundaryCount) error {
+	_, err := fmt.Fprintf(w, "bounded: %d\nunbounded: %d\n", count.Bounded, count.Unbounded)
+	if err != nil {
+		return fmt.Errorf("failed to write count: %w", err)
+	}
+
+	return nil
+}
+
+func (a *App) runCount(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	roles := a.rolePrincipals(trusts)
+
+	var buf bytes.Buffer
+
+	err = WriteCount(&buf, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.withBoundary {
+		err = writeBoundaryCount(&buf, countBoundaries(trusts, roles))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.countByRegion {
+		err = writeRegionCount(&buf, countByRegion(roles))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (a *App) runJSONCount(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	roles := a.rolePrincipals(trusts)
+	count := countTrust(roles)
+
+	if a.withBoundary {
+		boundaries := countBoundaries(trusts, roles)
+		count.Boundaries = &boundaries
+	}
+
+	if a.countByRegion {
+		count.Regions = countByRegion(roles)
+	}
+
+	output, err := a.renameFields(count)
+	if err != nil {
+		return nil, err
+	}
+
+	marshal, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+// WritePublic writes a role_arn<TAB>public line per role, telling whether its trust policy trusts a wildcard
+// principal, e.g. for grep true.
+func WritePublic(w io.Writer, trusts map[string]roleTrust) error {
+	for _, role := range slices.Sorted(maps.Keys(trusts)) {
+		trust := trusts[role]
+
+		_, err := fmt.Fprintf(w, "%s\t%s\n", role, strconv.FormatBool(trust.policy.isPublic()))
+		if err != nil {
+			return fmt.Errorf("failed to write public roles: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (a *App) runPublic(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	err = WritePublic(&buf, trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func TestWriteCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data map[string][]string
+		want string
+	}{
+		{
+			name: "shared principal",
+			data: map[string][]string{
+				"arn:aws:iam::0123456789:role/a": {"ecs.amazonaws.com", "arn:aws:iam::210987654321:root"},
+				"arn:aws:iam::0123456789:role/b": {"ecs.amazonaws.com"},
+			},
+			want: "roles: 2\nprincipals: 2\n",
+		},
+		{
+			name: "empty",
+			data: map[string][]string{},
+			want: "roles: 0\nprincipals: 0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := WriteCount(&buf, tt.data)
+			if err != nil {
+				t.Fatalf("WriteCount() error = %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteCount() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_runJSONCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		client  ServiceIAM
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "success",
+			client: &veiltest.FakeIAM{
+				Roles: []types.Role{
+					{
+						Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+						AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+					},
+				},
+			},
+			want:    `{"roles":1,"principals":1}`,
+			wantErr: false,
+		},
+		{
+			name: "failed to list roles",
+			client: &veiltest.FakeIAM{
+				Errors: map[string]error{veiltest.OperationListRoles: errors.New("test error")},
+			},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: tt.client,
+			}
+
+			got, err := a.runJSONCount(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runJSONCount() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("runJSONCount() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_runCount_byRegion(t *testing.T) {
+	t.Parallel()
+
+	fake := &veiltest.FakeIAM{
+		Roles: []types.Role{
+			{
+				Arn: aws.String(
+					"arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/eu-west-1/AWSReservedSSO_Admin_1a2b3c",
+				),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSReservedSSOFullAdmin),
+			},
+			{
+				Arn: aws.String(
+					"arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/eu-west-1/AWSReservedSSO_View_4d5e6f",
+				),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSReservedSSOFullAdmin),
+			},
+			{
+				Arn: aws.String(
+					"arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/us-east-1/AWSReservedSSO_Admin_7a8b9c",
+				),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSReservedSSOFullAdmin),
+			},
+			{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/Legacy"),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			},
+			{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+				AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+			},
+		},
+	}
+
+	a := &App{logger: slog.New(slog.DiscardHandler), client: fake}
+	WithRegionCounts()(a)
+
+	got, err := a.runJSONCount(t.Context())
+	if err != nil {
+		t.Fatalf("runJSONCount() unexpected error: %v", err)
+	}
+
+	want := `{"roles":5,"principals":4,"regions":{"eu-west-1":{"roles":2,"principals":2},` +
+		`"global":{"roles":2,"principals":2},"us-east-1":{"roles":1,"principals":2}}}`
+	if string(got) != want {
+		t.Errorf("runJSONCount() got = %s, want %s", got, want)
+	}
+
+	var schema, value map[string]any
+
+	data, _ := outputSchema(formatJSONCount)
+	if json.Unmarshal(data, &schema) != nil || json.Unmarshal(got, &value) != nil {
+		t.Fatalf("failed to parse the json-count schema or output")
+	}
+
+	err = validateSchema(schema, value, "$")
+	if err != nil {
+		t.Errorf("runJSONCount() output does not match its schema: %v", err)
+	}
+
+	got, err = a.runCount(t.Context())
+	if err != nil {
+		t.Fatalf("runCount() unexpected error: %v", err)
+	}
+
+	want = "roles: 5\nprincipals: 4\n" +
+		"eu-west-1 roles: 2\neu-west-1 principals: 2\n" +
+		"global roles: 2\nglobal principals: 2\n" +
+		"us-east-1 roles: 1\nus-east-1 principals: 2\n"
+	if string(got) != want {
+		t.Errorf("runCount() got = %q, want %q", got, want)
+	}
+}
+
+func TestWritePublic(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/public": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/public",
+			fixtureWildcardPrincipal,
+		),
+		"arn:aws:iam::0123456789:role/ecs": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/ecs",
+			fixtureAWSServiceRoleForECS,
+		),
+		"arn:aws:iam::0123456789:role/org": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/org",
+			fixtureOrgWideTrust,
+		),
+		"arn:aws:iam::0123456789:role/ci": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/ci",
+			fixturePrincipalArnLike,
+		),
+	}
+
+	var buf bytes.Buffer
+
+	err := WritePublic(&buf, trusts)
+	if err != nil {
+		t.Fatalf("WritePublic() error = %v", err)
+	}
+
+	want := "arn:aws:iam::0123456789:role/ci\ttrue\n" +
+		"arn:aws:iam::0123456789:role/ecs\tfalse\n" +
+		"arn:aws:iam::0123456789:role/org\tfalse\n" +
+		"arn:aws:iam::0123456789:role/public\ttrue\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WritePublic() got = %q, want %q", got, want)
+	}
+}
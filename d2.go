@@ -0,0 +1,195 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	d2ClassRole       = "role"
+	d2ClassService    = "service"
+	d2ClassAWSAccount = "aws-account"
+	d2ClassFederated  = "federated"
+)
+
+// d2Classes styles each class of node, in the order they are declared.
+var d2Classes = []struct { //nolint:gochecknoglobals
+	name  string
+	shape string
+}{
+	{name: d2ClassRole, shape: "rectangle"},
+	{name: d2ClassService, shape: "hexagon"},
+	{name: d2ClassAWSAccount, shape: "person"},
+	{name: d2ClassFederated, shape: "cloud"},
+}
+
+// d2Quote quotes an identifier or label as a D2 double-quoted string, escaping backslashes, quotes, newlines and the
+// dollar sign, which would otherwise start a substitution.
+func d2Quote(input string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+
+	return `"` + replacer.Replace(input) + `"`
+}
+
+// d2Class returns the class of the given principal, or an empty string for principals without one, such as
+// anonymous or organization principals.
+func d2Class(principal string) string {
+	switch ClassifyPrincipal(principal) {
+	case principalTypeService:
+		return d2ClassService
+	case principalTypeAWS:
+		return d2ClassAWSAccount
+	case principalTypeFederated:
+		return d2ClassFederated
+	default:
+		return ""
+	}
+}
+
+// d2Account returns the account the given node belongs to, or an empty string if it is not tied to one, as with
+// service principals and public identity providers.
+func d2Account(node string) string {
+	node = strings.TrimSuffix(node, viaConditionSuffix)
+	if accountIDRegex.MatchString(node) {
+		return node
+	}
+
+	return accountFromARN(node)
+}
+
+// d2Path returns the D2 key of the given node, nested in its account container if it has one.
+func d2Path(node string) string {
+	if account := d2Account(node); account != "" {
+		return d2Quote(account) + "." + d2Quote(node)
+	}
+
+	return d2Quote(node)
+}
+
+// edgeActions returns the sts actions each principal of the trust policy is allowed, keyed by principal. Principals
+// not named in a statement, such as those derived from conditions, are listed under the empty key with the actions
+// of every statement.
+func edgeActions(policy TrustPolicy) map[string][]string {
+	output := make(map[string][]string)
+
+	for _, statement := range policy.Statement {
+		if !strings.EqualFold(statement.Effect, "Allow") {
+			continue
+		}
+
+		actions := make([]string, 0, len(statement.Action))
+		for _, action := range statement.Action {
+			if strings.HasPrefix(strings.ToLower(action), "sts:") || action == "*" {
+				actions = append(actions, action)
+			}
+		}
+
+		for _, principal := range slices.Concat(statement.Principal.getAll(), []string{""}) {
+			output[principal] = append(output[principal], actions...)
+		}
+	}
+
+	for principal, actions := range output {
+		output[principal] = uniqSlice(actions)
+	}
+
+	return output
+}
+
+// buildD2 renders the trust graph as D2 source, with an edge labelled with the sts actions from each principal to
+// every role trusting it. Roles and principals are grouped in a container per account, nodes are styled by class,
+// and everything is sorted so the same scan always produces the same source.
+func buildD2(trusts map[string]roleTrust, roles map[string][]string, labels map[string]string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("direction: right\n\nclasses: {\n")
+
+	for _, class := range d2Classes {
+		fmt.Fprintf(&buf, "  %s: {shape: %s}\n", d2Quote(class.name), class.shape)
+	}
+
+	buf.WriteString("}\n")
+
+	classes := make(map[string]string)
+	for principal := range mapFlip(roles) {
+		classes[principal] = d2Class(principal)
+	}
+
+	for role := range roles {
+		classes[role] = d2ClassRole
+	}
+
+	containers := make(map[string][]string)
+	for node := range classes {
+		account := d2Account(node)
+		containers[account] = append(containers[account], node)
+	}
+
+	for _, account := range slices.Sorted(maps.Keys(containers)) {
+		indent := ""
+		if account != "" {
+			indent = "  "
+			fmt.Fprintf(&buf, "\n%s: {\n", d2Quote(account))
+		} else {
+			buf.WriteString("\n")
+		}
+
+		for _, node := range slices.Sorted(slices.Values(containers[account])) {
+			attributes := make([]string, 0, 2) //nolint:mnd
+			if classes[node] != "" {
+				attributes = append(attributes, "class: "+d2Quote(classes[node]))
+			}
+
+			if label := roleLabel(labels, node); label != node {
+				attributes = append(attributes, "label: "+d2Quote(label))
+			}
+
+			fmt.Fprintf(&buf, "%s%s: {%s}\n", indent, d2Quote(node), strings.Join(attributes, "; "))
+		}
+
+		if account != "" {
+			buf.WriteString("}\n")
+		}
+	}
+
+	buf.WriteString("\n")
+
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		actions := edgeActions(trusts[role].policy)
+
+		for _, principal := range uniqSlice(roles[role]) {
+			label, ok := actions[principal]
+			if !ok {
+				label = actions[""]
+			}
+
+			if len(label) == 0 {
+				label = []string{actionAssumeRole}
+			}
+
+			fmt.Fprintf(&buf, "%s -> %s: %s\n", d2Path(principal), d2Path(role), d2Quote(strings.Join(label, ", ")))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func (a *App) runD2(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	roles := a.rolePrincipals(trusts)
+	a.logger.Debug("rendering D2 diagram", slog.Int("roles", len(roles)))
+
+	return buildD2(trusts, roles, a.roleLabels(slices.Collect(maps.Keys(roles)))), nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"oss.terrastruct.com/d2/d2parser"
+)
+
+func Test_d2Quote(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "arn", input: "arn:aws:iam::0123456789:role/admin", want: `"arn:aws:iam::0123456789:role/admin"`},
+		{name: "quote and backslash", input: `a"b\c`, want: `"a\"b\\c"`},
+		{name: "substitution", input: "${vars.x}", want: `"\${vars.x}"`},
+		{name: "newline", input: "a\nb", want: `"a\nb"`},
+		{name: "glob", input: "*", want: `"*"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := d2Quote(tt.input); got != tt.want {
+				t.Errorf("d2Quote() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildD2(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/vendor": mustDecodeTrust(
+			t, "arn:aws:iam::0123456789:role/vendor", fixtureCrossAccountTagSession,
+		),
+		"arn:aws:iam::0123456789:role/ecs": mustDecodeTrust(
+			t, "arn:aws:iam::0123456789:role/ecs", fixtureAWSServiceRoleForECS,
+		),
+		"arn:aws:iam::0123456789:role/sso": mustDecodeTrust(
+			t, "arn:aws:iam::0123456789:role/sso", fixtureUnscopedSAMLTrust,
+		),
+	}
+	roles := (&App{}).rolePrincipals(trusts)
+
+	got := string(buildD2(trusts, roles, nil))
+
+	for _, want := range []string{
+		`"aws-account": {shape: person}`,
+		"\n\"ecs.amazonaws.com\": {class: \"service\"}\n",
+		`"0123456789": {`,
+		`  "arn:aws:iam::0123456789:role/vendor": {class: "role"}`,
+		`"123456789012": {`,
+		`  "arn:aws:iam::123456789012:saml-provider/CorporateIdP": {class: "federated"}`,
+		`  "arn:aws:iam::210987654321:root": {class: "aws-account"}`,
+		`"ecs.amazonaws.com" -> "0123456789"."arn:aws:iam::0123456789:role/ecs": "sts:AssumeRole"`,
+		`"210987654321"."arn:aws:iam::210987654321:root" -> "0123456789"."arn:aws:iam::0123456789:role/vendor": ` +
+			`"sts:AssumeRole, sts:TagSession"`,
+		`-> "0123456789"."arn:aws:iam::0123456789:role/sso": "sts:AssumeRoleWithSAML, sts:TagSession"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("buildD2() missing %q in:\n%s", want, got)
+		}
+	}
+
+	if strings.Count(got, "{") != strings.Count(got, "}") {
+		t.Errorf("buildD2() has unbalanced braces:\n%s", got)
+	}
+
+	for range 10 {
+		if again := string(buildD2(trusts, roles, nil)); again != got {
+			t.Fatalf("buildD2() is not deterministic:\n%s\nvs\n%s", got, again)
+		}
+	}
+
+	labelled := string(buildD2(trusts, roles, shortRoleNames([]string{"arn:aws:iam::0123456789:role/vendor"})))
+	if !strings.Contains(labelled, `"arn:aws:iam::0123456789:role/vendor": {class: "role"; label: "/vendor"}`) {
+		t.Errorf("buildD2() with labels did not label the role:\n%s", labelled)
+	}
+
+	for _, diagram := range []string{got, labelled} {
+		_, err := d2parser.Parse("veil.d2", strings.NewReader(diagram), nil)
+		if err != nil {
+			t.Errorf("d2parser rejected the diagram: %v\n%s", err, diagram)
+		}
+	}
+}
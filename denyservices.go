@@ -0,0 +1,68 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// findDeniedServiceTrusts returns the service principals matching any of the given globs, e.g. ec2.amazonaws.com or
+// *.sagemaker.amazonaws.com, that each role trusting any of them allows to assume it.
+func findDeniedServiceTrusts(trusts map[string]roleTrust, patterns []string) map[string][]string {
+	denied := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		denied = append(denied, globRegexp(pattern))
+	}
+
+	output := make(map[string][]string)
+
+	for role, trust := range trusts {
+		for _, statement := range trust.policy.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			for _, service := range statement.Principal.Service {
+				for _, match := range denied {
+					if match.MatchString(service) {
+						output[role] = append(output[role], service)
+
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for role, services := range output {
+		output[role] = uniqSlice(services)
+	}
+
+	return output
+}
+
+func (a *App) runDeniedServicesAudit(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := findDeniedServiceTrusts(trusts, a.denyServices)
+	a.logger.Debug(
+		"found IAM roles trusting denied services",
+		slog.Int("roles", len(trusts)),
+		slog.Int("flagged", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_findDeniedServiceTrusts(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/ec2": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/ec2",
+			`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":`+
+				`["ec2.amazonaws.com","lambda.amazonaws.com"]},"Action":"sts:AssumeRole"}]}`,
+		),
+		"arn:aws:iam::0123456789:role/sagemaker": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/sagemaker",
+			`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":`+
+				`"notebook.sagemaker.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+		),
+		"arn:aws:iam::0123456789:role/denied-ec2": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/denied-ec2",
+			`{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":{"Service":`+
+				`"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+		),
+		"arn:aws:iam::0123456789:role/ecs": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/ecs",
+			fixtureAWSServiceRoleForECS,
+		),
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     map[string][]string
+	}{
+		{
+			name:     "exact service",
+			patterns: []string{"ec2.amazonaws.com"},
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/ec2": {"ec2.amazonaws.com"},
+			},
+		},
+		{
+			name:     "glob",
+			patterns: []string{"*.sagemaker.amazonaws.com", "lambda.*"},
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/ec2":       {"lambda.amazonaws.com"},
+				"arn:aws:iam::0123456789:role/sagemaker": {"notebook.sagemaker.amazonaws.com"},
+			},
+		},
+		{
+			name:     "only allowed services trusted",
+			patterns: []string{"ssm.amazonaws.com"},
+			want:     map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := findDeniedServiceTrusts(trusts, tt.patterns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findDeniedServiceTrusts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+var errDiffPolicy = errors.New("-diff-policy-before and -diff-policy-after must be used together")
+
+// TrustPolicyDiff lists the statements and principals added to and removed from a trust policy.
+type TrustPolicyDiff struct {
+	AddedStatements   []Statement `json:"addedStatements"`
+	RemovedStatements []Statement `json:"removedStatements"`
+	AddedPrincipals   []string    `json:"addedPrincipals"`
+	RemovedPrincipals []string    `json:"removedPrincipals"`
+}
+
+// DiffTrustPolicies compares two versions of a trust policy. Statements are matched with Statement.Equal, so
+// reordering statements or their values is not reported as a change.
+func DiffTrustPolicies(before TrustPolicy, after TrustPolicy) TrustPolicyDiff {
+	return TrustPolicyDiff{
+		AddedStatements:   missingStatements(after.Statement, before.Statement),
+		RemovedStatements: missingStatements(before.Statement, after.Statement),
+		AddedPrincipals:   missingItems(after.getAllPrincipals(), before.getAllPrincipals()),
+		RemovedPrincipals: missingItems(before.getAllPrincipals(), after.getAllPrincipals()),
+	}
+}
+
+// TrustEdge is a principal trusted by a role, an edge of the trust graph.
+type TrustEdge struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+}
+
+// TrustGraphDiff lists the edges of the trust graph added and removed between two scans, for alerting systems to act
+// on precise changes.
+type TrustGraphDiff struct {
+	Added   []TrustEdge `json:"added"`
+	Removed []TrustEdge `json:"removed"`
+}
+
+// compareTrustEdges orders edges by principal and role.
+func compareTrustEdges(a, b TrustEdge) int {
+	return cmp.Or(cmp.Compare(a.Principal, b.Principal), cmp.Compare(a.Role, b.Role))
+}
+
+// trustEdges returns the distinct edges of the given role to principals mapping, sorted by principal and role.
+func trustEdges(roles map[string][]string) []TrustEdge {
+	output := make([]TrustEdge, 0)
+
+	for role, principals := range roles {
+		for _, principal := range principals {
+			output = append(output, TrustEdge{Principal: principal, Role: role})
+		}
+	}
+
+	slices.SortFunc(output, compareTrustEdges)
+
+	return slices.Compact(output)
+}
+
+// DiffTrustGraphs compares the role to principals mappings of two scans, returning the edges only found after as
+// added and those only found before as removed, both sorted by principal and role. The sorted edges of both scans are
+// merged in a single pass, so large accounts compare in linear time after sorting.
+func DiffTrustGraphs(before map[string][]string, after map[string][]string) TrustGraphDiff {
+	beforeEdges, afterEdges := trustEdges(before), trustEdges(after)
+
+	output := TrustGraphDiff{Added: make([]TrustEdge, 0), Removed: make([]TrustEdge, 0)}
+
+	for len(beforeEdges) > 0 && len(afterEdges) > 0 {
+		switch order := compareTrustEdges(beforeEdges[0], afterEdges[0]); {
+		case order < 0:
+			output.Removed = append(output.Removed, beforeEdges[0])
+			beforeEdges = beforeEdges[1:]
+		case order > 0:
+			output.Added = append(output.Added, afterEdges[0])
+			afterEdges = afterEdges[1:]
+		default:
+			beforeEdges, afterEdges = beforeEdges[1:], afterEdges[1:]
+		}
+	}
+
+	output.Removed = append(output.Removed, beforeEdges...)
+	output.Added = append(output.Added, afterEdges...)
+
+	return output
+}
+
+// runScanDiff compares the trust graph of the scan with the json output of an earlier scan.
+func (a *App) runScanDiff(ctx context.Context, beforePath string) ([]byte, error) {
+	before, err := loadScanOutput(beforePath)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := DiffTrustGraphs(before, after)
+	a.logger.Debug(
+		"compared trust graphs",
+		slog.Int("added", len(output.Added)),
+		slog.Int("removed", len(output.Removed)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+// missingStatements returns the statements of from without an equal statement in other.
+func missingStatements(from []Statement, other []Statement) []Statement {
+	output := make([]Statement, 0)
+
+	for _, statement := range from {
+		if !slices.ContainsFunc(other, statement.Equal) {
+			output = append(output, statement)
+		}
+	}
+
+	return output
+}
+
+// missingItems returns the values of from not found in other.
+func missingItems(from []string, other []string) []string {
+	output := make([]string, 0)
+
+	for _, item := range from {
+		if !slices.Contains(other, item) {
+			output = append(output, item)
+		}
+	}
+
+	return output
+}
+
+// runPolicyDiff compares the trust policies in the given JSON files.
+func (a *App) runPolicyDiff(beforePath string, afterPath string) ([]byte, error) {
+	before, err := loadPolicyFile(beforePath, a.strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", beforePath, err)
+	}
+
+	after, err := loadPolicyFile(afterPath, a.strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", afterPath, err)
+	}
+
+	output := DiffTrustPolicies(before, after)
+	a.logger.Debug(
+		"compared trust policies",
+		slog.Int("addedStatements", len(output.AddedStatements)),
+		slog.Int("removedStatements", len(output.RemovedStatements)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+// diffLines returns a line-based diff turning before into after. Every line is prefixed with a space when
+// unchanged, with - when removed, or with + when added.
+func diffLines(before []string, after []string) []string {
+	// lengths[i][j] holds the length of the longest common subsequence of before[i:] and after[j:].
+	lengths := make([][]int, len(before)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(after)+1)
+	}
+
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	output := make([]string, 0, len(before)+len(after))
+
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			output = append(output, " "+before[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			output = append(output, "-"+before[i])
+			i++
+		default:
+			output = append(output, "+"+after[j])
+			j++
+		}
+	}
+
+	for ; i < len(before); i++ {
+		output = append(output, "-"+before[i])
+	}
+
+	for ; j < len(after); j++ {
+		output = append(output, "+"+after[j])
+	}
+
+	return output
+}
@@ -0,0 +1,314 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func Test_diffLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		before []string
+		after  []string
+		want   []string
+	}{
+		{
+			name:   "empty",
+			before: nil,
+			after:  nil,
+			want:   []string{},
+		},
+		{
+			name:   "unchanged",
+			before: []string{"a", "b"},
+			after:  []string{"a", "b"},
+			want:   []string{" a", " b"},
+		},
+		{
+			name:   "replaced line",
+			before: []string{"a", "b", "c"},
+			after:  []string{"a", "x", "c"},
+			want:   []string{" a", "-b", "+x", " c"},
+		},
+		{
+			name:   "added and removed at the edges",
+			before: []string{"a", "b"},
+			after:  []string{"b", "c"},
+			want:   []string{"-a", " b", "+c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := diffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTrustPolicies(t *testing.T) {
+	t.Parallel()
+
+	vendor := Statement{
+		Effect:    "Allow",
+		Principal: Principal{AWS: Items{"arn:aws:iam::210987654321:root"}},
+		Action:    Items{"sts:AssumeRole"},
+	}
+	ecs := Statement{
+		Effect:    "Allow",
+		Principal: Principal{Service: Items{"ecs-tasks.amazonaws.com"}},
+		Action:    Items{"sts:AssumeRole"},
+	}
+	vendorWithExternalID := Statement{
+		Effect:    "Allow",
+		Principal: Principal{AWS: Items{"arn:aws:iam::210987654321:root"}},
+		Action:    Items{"sts:AssumeRole"},
+		Condition: Condition{"StringEquals": {"sts:ExternalId": {"vendor"}}},
+	}
+	lambda := Statement{
+		Effect:    "Allow",
+		Principal: Principal{Service: Items{"lambda.amazonaws.com"}},
+		Action:    Items{"sts:AssumeRole"},
+	}
+
+	tests := []struct {
+		name   string
+		before []Statement
+		after  []Statement
+		want   TrustPolicyDiff
+	}{
+		{
+			name:   "reordered statements",
+			before: []Statement{vendor, ecs},
+			after:  []Statement{ecs, vendor},
+			want: TrustPolicyDiff{
+				AddedStatements:   []Statement{},
+				RemovedStatements: []Statement{},
+				AddedPrincipals:   []string{},
+				RemovedPrincipals: []string{},
+			},
+		},
+		{
+			name:   "condition added to a statement",
+			before: []Statement{vendor, ecs},
+			after:  []Statement{vendorWithExternalID, ecs},
+			want: TrustPolicyDiff{
+				AddedStatements:   []Statement{vendorWithExternalID},
+				RemovedStatements: []Statement{vendor},
+				AddedPrincipals:   []string{},
+				RemovedPrincipals: []string{},
+			},
+		},
+		{
+			name:   "principals replaced",
+			before: []Statement{vendor, ecs},
+			after:  []Statement{lambda},
+			want: TrustPolicyDiff{
+				AddedStatements:   []Statement{lambda},
+				RemovedStatements: []Statement{vendor, ecs},
+				AddedPrincipals:   []string{"lambda.amazonaws.com"},
+				RemovedPrincipals: []string{"arn:aws:iam::210987654321:root", "ecs-tasks.amazonaws.com"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := DiffTrustPolicies(
+				TrustPolicy{Version: "2012-10-17", Statement: tt.before},
+				TrustPolicy{Version: "2012-10-17", Statement: tt.after},
+			)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffTrustPolicies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_runPolicyDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := writePolicyTree(t, map[string]string{
+		"before.json": fixtureCrossAccountTagSession,
+		"after.json":  fixtureSpecificRolePrincipal,
+	})
+
+	app := &App{logger: slog.New(slog.DiscardHandler)}
+
+	data, err := app.runPolicyDiff(filepath.Join(dir, "before.json"), filepath.Join(dir, "after.json"))
+	if err != nil {
+		t.Fatalf("runPolicyDiff() unexpected error: %v", err)
+	}
+
+	var got TrustPolicyDiff
+
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("runPolicyDiff() returned invalid JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.AddedPrincipals, []string{"arn:aws:iam::210987654321:role/deployer"}) ||
+		!reflect.DeepEqual(got.RemovedPrincipals, []string{"arn:aws:iam::210987654321:root"}) ||
+		len(got.AddedStatements) != 1 || len(got.RemovedStatements) != 1 {
+		t.Errorf("runPolicyDiff() = %+v", got)
+	}
+
+	_, err = app.runPolicyDiff(filepath.Join(dir, "missing.json"), filepath.Join(dir, "after.json"))
+	if err == nil {
+		t.Errorf("runPolicyDiff() with a missing file, want error")
+	}
+}
+
+func TestDiffTrustGraphs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		before map[string][]string
+		after  map[string][]string
+		want   TrustGraphDiff
+	}{
+		{
+			name: "added and removed edges",
+			before: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+				"arn:aws:iam::0123456789:role/ecs":    {"ecs.amazonaws.com"},
+			},
+			after: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {
+					"arn:aws:iam::210987654321:role/deployer",
+					"arn:aws:iam::210987654321:root",
+				},
+				"arn:aws:iam::0123456789:role/ci": {
+					"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+				},
+			},
+			want: TrustGraphDiff{
+				Added: []TrustEdge{
+					{
+						Principal: "arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+						Role:      "arn:aws:iam::0123456789:role/ci",
+					},
+					{
+						Principal: "arn:aws:iam::210987654321:role/deployer",
+						Role:      "arn:aws:iam::0123456789:role/vendor",
+					},
+				},
+				Removed: []TrustEdge{
+					{Principal: "ecs.amazonaws.com", Role: "arn:aws:iam::0123456789:role/ecs"},
+				},
+			},
+		},
+		{
+			name: "reordered and repeated principals",
+			before: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"210987654321", "ecs.amazonaws.com"},
+			},
+			after: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"ecs.amazonaws.com", "210987654321", "210987654321"},
+			},
+			want: TrustGraphDiff{Added: []TrustEdge{}, Removed: []TrustEdge{}},
+		},
+		{
+			name: "every edge removed",
+			before: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root", "ecs.amazonaws.com"},
+			},
+			after: map[string][]string{},
+			want: TrustGraphDiff{
+				Added: []TrustEdge{},
+				Removed: []TrustEdge{
+					{Principal: "arn:aws:iam::210987654321:root", Role: "arn:aws:iam::0123456789:role/vendor"},
+					{Principal: "ecs.amazonaws.com", Role: "arn:aws:iam::0123456789:role/vendor"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := DiffTrustGraphs(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffTrustGraphs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_runScanDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := writePolicyTree(t, map[string]string{"before.json": fixtureCompareProd})
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::111111111111:role/app/task"),
+					AssumeRolePolicyDocument: aws.String(strings.ReplaceAll(fixtureAWSServiceRoleForECS, "ecs.", "ecs-tasks.")),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::111111111111:role/vendor/audit"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+			},
+		},
+	}
+
+	data, err := app.runScanDiff(t.Context(), filepath.Join(dir, "before.json"))
+	if err != nil {
+		t.Fatalf("runScanDiff() unexpected error: %v", err)
+	}
+
+	want := `{
+  "added": [
+    {
+      "principal": "arn:aws:iam::210987654321:root",
+      "role": "arn:aws:iam::111111111111:role/vendor/audit"
+    }
+  ],
+  "removed": [
+    {
+      "principal": "arn:aws:iam::111111111111:root",
+      "role": "arn:aws:iam::111111111111:role/ops/breakglass"
+    },
+    {
+      "principal": "arn:aws:iam::333333333333:role/deployer",
+      "role": "arn:aws:iam::111111111111:role/ci/deploy"
+    },
+    {
+      "principal": "arn:aws:iam::555555555555:root",
+      "role": "arn:aws:iam::111111111111:role/vendor/audit"
+    },
+    {
+      "principal": "ecs-tasks.amazonaws.com",
+      "role": "arn:aws:iam::111111111111:role/app/worker"
+    }
+  ]
+}`
+	if string(data) != want {
+		t.Errorf("runScanDiff() = %s, want %s", data, want)
+	}
+
+	_, err = app.runScanDiff(t.Context(), filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Errorf("runScanDiff() with a missing file, want error")
+	}
+}
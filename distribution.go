@@ -0,0 +1,104 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const (
+	principalTypeService       = "service"
+	principalTypeAWS           = "aws"
+	principalTypeFederated     = "federated"
+	principalTypeCanonicalUser = "canonical_user"
+	principalTypeAnonymous     = "anonymous"
+	principalTypeOrganization  = "organization"
+	principalTypeUnknown       = "unknown"
+)
+
+var canonicalUserRegex = regexp.MustCompile(`^[0-9a-f]{64}$`) //nolint:gochecknoglobals
+
+// webIdentityProviders lists the public identity providers that can be trusted as federated principals by name.
+var webIdentityProviders = []string{ //nolint:gochecknoglobals
+	"accounts.google.com",
+	"cognito-identity.amazonaws.com",
+	"graph.facebook.com",
+	"www.amazon.com",
+}
+
+// ClassifyPrincipal returns the type of the given trust policy principal, based on its format: service, aws,
+// federated, canonical_user, anonymous or organization, or unknown if the format is not recognised.
+func ClassifyPrincipal(principal string) string {
+	principal = strings.TrimSuffix(principal, viaConditionSuffix)
+
+	switch {
+	case principal == "*":
+		return principalTypeAnonymous
+	case strings.HasPrefix(principal, orgPrincipalPrefix):
+		return principalTypeOrganization
+	case isWebIdentityProvider(principal),
+		strings.Contains(principal, ":saml-provider/"),
+		strings.Contains(principal, ":oidc-provider/"):
+		return principalTypeFederated
+	case strings.HasSuffix(principal, ".amazonaws.com"), strings.HasSuffix(principal, ".amazonaws.com.cn"):
+		return principalTypeService
+	case accountIDRegex.MatchString(principal),
+		strings.HasPrefix(principal, "arn:") && strings.Contains(principal, ":iam::"),
+		strings.HasPrefix(principal, "arn:") && strings.Contains(principal, ":sts::"):
+		return principalTypeAWS
+	case canonicalUserRegex.MatchString(principal):
+		return principalTypeCanonicalUser
+	default:
+		return principalTypeUnknown
+	}
+}
+
+// isWebIdentityProvider reports whether the principal is one of the well-known public identity providers.
+func isWebIdentityProvider(principal string) bool {
+	for _, provider := range webIdentityProviders {
+		if principal == provider {
+			return true
+		}
+	}
+
+	return false
+}
+
+// principalDistribution counts the distinct principals of each type in the given role to principals mapping.
+func principalDistribution(roles map[string][]string) map[string]int {
+	output := map[string]int{
+		principalTypeService:       0,
+		principalTypeAWS:           0,
+		principalTypeFederated:     0,
+		principalTypeCanonicalUser: 0,
+		principalTypeAnonymous:     0,
+	}
+
+	for principal := range mapFlip(roles) {
+		output[ClassifyPrincipal(principal)]++
+	}
+
+	return output
+}
+
+func (a *App) runDistribution(ctx context.Context) ([]byte, error) {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := principalDistribution(a.redactRoles(roles))
+	a.logger.Debug("classified principals", slog.Int("roles", len(roles)), slog.Int("types", len(output)))
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyPrincipal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+	}{
+		{name: "service", principal: "ecs.amazonaws.com", want: principalTypeService},
+		{name: "China service", principal: "ec2.amazonaws.com.cn", want: principalTypeService},
+		{name: "account root", principal: "arn:aws:iam::0123456789:root", want: principalTypeAWS},
+		{name: "account ID", principal: "210987654321", want: principalTypeAWS},
+		{name: "GovCloud role", principal: "arn:aws-us-gov:iam::0123456789:role/test", want: principalTypeAWS},
+		{name: "assumed role", principal: "arn:aws:sts::0123456789:assumed-role/test/session", want: principalTypeAWS},
+		{
+			name:      "derived principal",
+			principal: "arn:aws:iam::0123456789:role/deployer (via-condition)",
+			want:      principalTypeAWS,
+		},
+		{
+			name:      "SAML provider",
+			principal: "arn:aws:iam::0123456789:saml-provider/AWSSSO_24_DO_NOT_DELETE",
+			want:      principalTypeFederated,
+		},
+		{
+			name:      "OIDC provider",
+			principal: "arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+			want:      principalTypeFederated,
+		},
+		{name: "Cognito", principal: "cognito-identity.amazonaws.com", want: principalTypeFederated},
+		{name: "Google", principal: "accounts.google.com", want: principalTypeFederated},
+		{
+			name:      "canonical user",
+			principal: "79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be",
+			want:      principalTypeCanonicalUser,
+		},
+		{name: "anonymous", principal: "*", want: principalTypeAnonymous},
+		{name: "organization", principal: "org:o-a1b2c3d4e5", want: principalTypeOrganization},
+		{name: "unknown", principal: "example.com", want: principalTypeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ClassifyPrincipal(tt.principal); got != tt.want {
+				t.Errorf("ClassifyPrincipal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_principalDistribution(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/a": {"ecs.amazonaws.com", "arn:aws:iam::210987654321:root"},
+		"arn:aws:iam::0123456789:role/b": {"ecs.amazonaws.com", "lambda.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/c": {"arn:aws:iam::0123456789:saml-provider/idp"},
+	}
+
+	want := map[string]int{
+		principalTypeService:       2,
+		principalTypeAWS:           1,
+		principalTypeFederated:     1,
+		principalTypeCanonicalUser: 0,
+		principalTypeAnonymous:     0,
+	}
+
+	if got := principalDistribution(roles); !reflect.DeepEqual(got, want) {
+		t.Errorf("principalDistribution() = %v, want %v", got, want)
+	}
+}
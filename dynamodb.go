@@ -0,0 +1,222 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// dynamoDBBatchSize is the maximum number of items BatchWriteItem accepts in a single call.
+	dynamoDBBatchSize = 25
+
+	dynamoDBRoleAttribute       = "role"
+	dynamoDBPrincipalsAttribute = "principals"
+)
+
+var errUnprocessedItems = errors.New("items left unprocessed by DynamoDB")
+
+// DynamoDBItem is a trust graph entry as stored in DynamoDB: a role keyed by its ARN with the principals it trusts,
+// stored as the role string and principals list attributes.
+type DynamoDBItem struct {
+	Role       string
+	Principals []string
+}
+
+// DynamoDBBatchAPI writes a batch of at most 25 items to a DynamoDB table, returning those left unprocessed, as
+// BatchWriteItem does when the table is throttled.
+type DynamoDBBatchAPI interface {
+	BatchWriteItem(ctx context.Context, table string, items []DynamoDBItem) ([]DynamoDBItem, error)
+}
+
+// DynamoDBWriter writes the trust graph to a DynamoDB table, one item per role.
+type DynamoDBWriter struct {
+	Client DynamoDBBatchAPI
+	Table  string
+	// MaxAttempts bounds the calls made for a batch while items are left unprocessed.
+	MaxAttempts int
+	// InitialDelay is the delay before resubmitting unprocessed items, doubled after every attempt.
+	InitialDelay time.Duration
+}
+
+// NewDynamoDBWriter returns a DynamoDBWriter for the given table, resubmitting unprocessed items with the default
+// retry settings.
+func NewDynamoDBWriter(client DynamoDBBatchAPI, table string) *DynamoDBWriter {
+	return &DynamoDBWriter{
+		Client:       client,
+		Table:        table,
+		MaxAttempts:  defaultRetryMaxAttempts,
+		InitialDelay: defaultRetryInitialDelay,
+	}
+}
+
+// Write writes the given role to principals mapping in batches of 25 items, sorted by role.
+func (w *DynamoDBWriter) Write(ctx context.Context, data map[string][]string) error {
+	items := make([]DynamoDBItem, 0, len(data))
+	for _, role := range slices.Sorted(maps.Keys(data)) {
+		items = append(items, DynamoDBItem{Role: role, Principals: data[role]})
+	}
+
+	for batch := range slices.Chunk(items, dynamoDBBatchSize) {
+		err := w.writeBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Debug("wrote trust graph to DynamoDB", slog.String("table", w.Table), slog.Int("items", len(items)))
+
+	return nil
+}
+
+// writeBatch submits a batch, resubmitting the items DynamoDB leaves unprocessed with an exponential backoff.
+func (w *DynamoDBWriter) writeBatch(ctx context.Context, batch []DynamoDBItem) error {
+	delay := w.InitialDelay
+
+	for attempt := 1; ; attempt++ {
+		unprocessed, err := w.Client.BatchWriteItem(ctx, w.Table, batch)
+		if err != nil {
+			return fmt.Errorf("failed to write to %s: %w", w.Table, err)
+		}
+
+		if len(unprocessed) == 0 {
+			return nil
+		}
+
+		if attempt >= w.MaxAttempts {
+			return fmt.Errorf("%w: %d items of %s", errUnprocessedItems, len(unprocessed), w.Table)
+		}
+
+		slog.Debug(
+			"resubmitting unprocessed items",
+			slog.Int("attempt", attempt),
+			slog.Int("items", len(unprocessed)),
+			slog.Duration("delay", delay),
+		)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("retry interrupted: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		batch = unprocessed
+		delay *= 2
+	}
+}
+
+// ServiceDynamoDB writes batches of items to DynamoDB tables via AWS SDK clients.
+type ServiceDynamoDB interface {
+	BatchWriteItem(
+		ctx context.Context,
+		params *dynamodb.BatchWriteItemInput,
+		optFns ...func(*dynamodb.Options),
+	) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// dynamoDBBatchClient adapts a DynamoDB SDK client to DynamoDBBatchAPI, putting every item of a batch.
+type dynamoDBBatchClient struct {
+	client ServiceDynamoDB
+}
+
+var _ DynamoDBBatchAPI = (*dynamoDBBatchClient)(nil)
+
+// BatchWriteItem puts the items to the table, returning those DynamoDB left unprocessed.
+func (c dynamoDBBatchClient) BatchWriteItem(
+	ctx context.Context,
+	table string,
+	items []DynamoDBItem,
+) ([]DynamoDBItem, error) {
+	requests := make([]types.WriteRequest, 0, len(items))
+
+	for _, item := range items {
+		principals := make([]types.AttributeValue, 0, len(item.Principals))
+		for _, principal := range item.Principals {
+			principals = append(principals, &types.AttributeValueMemberS{Value: principal})
+		}
+
+		requests = append(requests, types.WriteRequest{ //nolint:exhaustruct
+			PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+				dynamoDBRoleAttribute:       &types.AttributeValueMemberS{Value: item.Role},
+				dynamoDBPrincipalsAttribute: &types.AttributeValueMemberL{Value: principals},
+			}},
+		})
+	}
+
+	output, err := c.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{ //nolint:exhaustruct
+		RequestItems: map[string][]types.WriteRequest{table: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write batch: %w", err)
+	}
+
+	unprocessed := make([]DynamoDBItem, 0, len(output.UnprocessedItems[table]))
+
+	for _, request := range output.UnprocessedItems[table] {
+		if request.PutRequest == nil {
+			continue
+		}
+
+		unprocessed = append(unprocessed, dynamoDBItemFrom(request.PutRequest.Item))
+	}
+
+	return unprocessed, nil
+}
+
+// dynamoDBItemFrom decodes a trust graph entry from the attributes of a DynamoDB item.
+func dynamoDBItemFrom(attributes map[string]types.AttributeValue) DynamoDBItem {
+	item := DynamoDBItem{Role: "", Principals: make([]string, 0)}
+
+	if role, ok := attributes[dynamoDBRoleAttribute].(*types.AttributeValueMemberS); ok {
+		item.Role = role.Value
+	}
+
+	if principals, ok := attributes[dynamoDBPrincipalsAttribute].(*types.AttributeValueMemberL); ok {
+		for _, principal := range principals.Value {
+			if value, isString := principal.(*types.AttributeValueMemberS); isString {
+				item.Principals = append(item.Principals, value.Value)
+			}
+		}
+	}
+
+	return item
+}
+
+// dynamoDBOutput returns the option writing the trust graph to the table in the given region, with the default
+// credentials rather than those of a role assumed for scanning.
+func dynamoDBOutput(ctx context.Context, loader ConfigLoader, region string, table string) (Option, error) {
+	cfg, err := loader.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config, %w", err)
+	}
+
+	return WithDynamoDBTable(dynamoDBBatchClient{client: dynamodb.NewFromConfig(cfg)}, table), nil
+}
+
+// writeDynamoDB writes the principals of each role of the last scan, regardless of the focus and minimum counts
+// restricting the output, to the configured DynamoDB table.
+func (a *App) writeDynamoDB(ctx context.Context) error {
+	if a.dynamoDB == nil {
+		return nil
+	}
+
+	roles := a.scannedRoles()
+	if roles == nil {
+		a.logger.Warn("the selected output does not scan trust policies, not writing them to DynamoDB")
+
+		return nil
+	}
+
+	return a.dynamoDB.Write(ctx, roles)
+}
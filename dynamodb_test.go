@@ -0,0 +1,180 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+// mockDynamoDB records the items written to it, leaving the first unprocessed items of every call unprocessed until
+// their budget is spent.
+type mockDynamoDB struct {
+	mutex       sync.Mutex
+	calls       int
+	written     map[string][]string
+	unprocessed int
+}
+
+func (m *mockDynamoDB) BatchWriteItem(
+	_ context.Context,
+	_ string,
+	items []DynamoDBItem,
+) ([]DynamoDBItem, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.calls++
+
+	if len(items) > dynamoDBBatchSize {
+		return nil, fmt.Errorf("batch of %d items", len(items))
+	}
+
+	skip := min(m.unprocessed, len(items))
+	m.unprocessed -= skip
+
+	for _, item := range items[skip:] {
+		m.written[item.Role] = item.Principals
+	}
+
+	return items[:skip], nil
+}
+
+func TestDynamoDBWriter_Write(t *testing.T) {
+	t.Parallel()
+
+	data := make(map[string][]string, 60)
+	for i := range 60 {
+		data[fmt.Sprintf("arn:aws:iam::123456789012:role/role-%02d", i)] = []string{"ecs.amazonaws.com"}
+	}
+
+	tests := []struct {
+		name        string
+		unprocessed int
+		maxAttempts int
+		wantCalls   int
+		wantErr     error
+	}{
+		{name: "batches of 25", unprocessed: 0, maxAttempts: 3, wantCalls: 3, wantErr: nil},
+		{name: "unprocessed items resubmitted", unprocessed: 5, maxAttempts: 3, wantCalls: 4, wantErr: nil},
+		{name: "unprocessed items left", unprocessed: 5, maxAttempts: 1, wantCalls: 1, wantErr: errUnprocessedItems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock := &mockDynamoDB{written: make(map[string][]string), unprocessed: tt.unprocessed}
+			writer := NewDynamoDBWriter(mock, "trust-graph")
+			writer.MaxAttempts = tt.maxAttempts
+			writer.InitialDelay = 0
+
+			err := writer.Write(t.Context(), data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Write() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if mock.calls != tt.wantCalls {
+				t.Errorf("Write() made %d BatchWriteItem calls, want %d", mock.calls, tt.wantCalls)
+			}
+
+			if tt.wantErr == nil && len(mock.written) != len(data) {
+				t.Errorf("Write() wrote %d items, want %d", len(mock.written), len(data))
+			}
+		})
+	}
+}
+
+// mockDynamoDBClient records the requests of the last call, leaving its last request unprocessed.
+type mockDynamoDBClient struct {
+	input *dynamodb.BatchWriteItemInput
+}
+
+func (m *mockDynamoDBClient) BatchWriteItem(
+	_ context.Context,
+	params *dynamodb.BatchWriteItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.BatchWriteItemOutput, error) {
+	m.input = params
+
+	unprocessed := make(map[string][]dynamodbtypes.WriteRequest)
+	for table, requests := range params.RequestItems {
+		unprocessed[table] = requests[len(requests)-1:]
+	}
+
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: unprocessed}, nil
+}
+
+func Test_dynamoDBBatchClient_BatchWriteItem(t *testing.T) {
+	t.Parallel()
+
+	mock := &mockDynamoDBClient{}
+	items := []DynamoDBItem{
+		{Role: "arn:aws:iam::123456789012:role/ecs", Principals: []string{"ecs.amazonaws.com"}},
+		{Role: "arn:aws:iam::123456789012:role/vendor", Principals: []string{"arn:aws:iam::210987654321:root", "*"}},
+	}
+
+	got, err := dynamoDBBatchClient{client: mock}.BatchWriteItem(t.Context(), "trust-graph", items)
+	if err != nil {
+		t.Fatalf("BatchWriteItem() unexpected error: %v", err)
+	}
+
+	if requests := mock.input.RequestItems["trust-graph"]; len(requests) != len(items) {
+		t.Fatalf("BatchWriteItem() sent %d requests, want %d", len(requests), len(items))
+	}
+
+	if !reflect.DeepEqual(got, items[1:]) {
+		t.Errorf("BatchWriteItem() unprocessed = %v, want %v", got, items[1:])
+	}
+}
+
+func TestApp_writeDynamoDB(t *testing.T) {
+	t.Parallel()
+
+	mock := &mockDynamoDB{written: make(map[string][]string)}
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{Roles: []types.Role{
+			{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+				AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+			},
+		}},
+	}
+	WithDynamoDBTable(mock, "trust-graph")(app)
+
+	err := app.writeDynamoDB(t.Context())
+	if err != nil {
+		t.Fatalf("writeDynamoDB() before a scan unexpected error: %v", err)
+	}
+
+	if mock.calls != 0 {
+		t.Errorf("writeDynamoDB() before a scan made %d calls", mock.calls)
+	}
+
+	_, err = app.getRolesWithTrust(t.Context())
+	if err != nil {
+		t.Fatalf("getRolesWithTrust() unexpected error: %v", err)
+	}
+
+	err = app.writeDynamoDB(t.Context())
+	if err != nil {
+		t.Fatalf("writeDynamoDB() unexpected error: %v", err)
+	}
+
+	want := map[string][]string{"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"}}
+	if !reflect.DeepEqual(mock.written, want) {
+		t.Errorf("writeDynamoDB() wrote %v, want %v", mock.written, want)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// principalAssumeAction returns the action the given principal assumes a role with: sts:AssumeRoleWithSAML for SAML
+// providers, sts:AssumeRoleWithWebIdentity for other federated providers and sts:AssumeRole for everything else.
+func principalAssumeAction(principal string) string {
+	principal = strings.TrimSuffix(principal, viaConditionSuffix)
+
+	switch {
+	case ClassifyPrincipal(principal) != principalTypeFederated:
+		return actionAssumeRole
+	case strings.Contains(principal, ":saml-provider/"):
+		return actionAssumeRoleWithSAML
+	default:
+		return actionAssumeRoleWithWebIdentity
+	}
+}
+
+// deniesAction reports whether the statement is a Deny of the given action, matching wildcards such as sts:*
+// case-insensitively. Only aws:PrincipalArn conditions can be evaluated against a principal; any other condition may
+// not apply, so such a Deny never overrides an Allow here.
+func (s *Statement) deniesAction(action string) bool {
+	if !strings.EqualFold(s.Effect, "Deny") || !s.onlyPrincipalArnConditions() {
+		return false
+	}
+
+	return slices.ContainsFunc(s.Action, func(item string) bool {
+		return globRegexp(strings.ToLower(item)).MatchString(strings.ToLower(action))
+	})
+}
+
+// onlyPrincipalArnConditions reports whether every condition of the statement constrains aws:PrincipalArn to a list of
+// allowed ARNs, see principalArnConstraints.
+func (s *Statement) onlyPrincipalArnConditions() bool {
+	for operator, keys := range s.Condition {
+		if _, ok := newPrincipalArnConstraint(operator); !ok {
+			return false
+		}
+
+		for name := range keys {
+			if !strings.EqualFold(name, conditionPrincipalArn) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// deniesPrincipal reports whether a principal of the statement covers the given one, which must also meet its
+// aws:PrincipalArn conditions: the wildcard principal, the principal itself, or the root of its account, which stands
+// for every principal of that account.
+func (s *Statement) deniesPrincipal(principal string) bool {
+	principal = normalisePrincipal(strings.TrimSuffix(principal, viaConditionSuffix))
+	if !meetsPrincipalArnConstraints(s.principalArnConstraints(), principal) {
+		return false
+	}
+
+	for _, denied := range s.Principal.getAll() {
+		denied = normalisePrincipal(denied)
+		if denied == "*" || denied == principal {
+			return true
+		}
+
+		parsed, err := arn.Parse(denied)
+		if err == nil && parsed.Service == "iam" && parsed.Resource == "root" &&
+			accountFromARN(principal) == parsed.AccountID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// denies reports whether a Deny statement of the trust policy overrides any Allow of the given
+// principal, denying it the action it assumes the role with.
+func (p *TrustPolicy) denies(principal string) bool {
+	action := principalAssumeAction(principal)
+
+	return slices.ContainsFunc(p.Statement, func(statement Statement) bool {
+		return statement.deniesAction(action) && statement.deniesPrincipal(principal)
+	})
+}
+
+// getEffectivePrincipals returns the principals effectively trusted by the policy, applying IAM's deny-overrides-allow
+// at the principal granularity: the principals of allowing statements, including organisation principals and, with
+// derived set, those derived from conditions, less those denied by a Deny statement.
+func (p *TrustPolicy) getEffectivePrincipals(derived bool) []string {
+	output := make([]string, 0)
+
+	for _, statement := range p.Statement {
+		if strings.EqualFold(statement.Effect, "Allow") {
+			output = append(output, statement.Principal.getAll()...)
+		}
+	}
+
+	output = uniqSlice(output)
+	if derived {
+		output = append(output, p.getDerivedPrincipals()...)
+	}
+
+	output = append(output, p.getOrgPrincipals()...)
+
+	return slices.DeleteFunc(output, p.denies)
+}
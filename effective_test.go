@@ -0,0 +1,86 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrustPolicy_getEffectivePrincipals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		derived  bool
+		want     []string
+	}{
+		{
+			name:     "account root deny overrides allow, conditional deny does not",
+			document: fixtureAllowDenyAccount,
+			want:     []string{"arn:aws:iam::111122223333:role/audit", "arn:aws:iam::111122223333:role/deploy"},
+		},
+		{
+			name:     "wildcard principal and action denies",
+			document: fixtureDenyWildcard,
+			want:     []string{"arn:aws:iam::111122223333:role/deploy", "ec2.amazonaws.com"},
+		},
+		{
+			name:     "no deny statements",
+			document: fixtureCrossAccountTagSession,
+			want:     []string{"arn:aws:iam::210987654321:root"},
+		},
+		{
+			name:     "derived principals left out",
+			document: fixturePrincipalArnLike,
+			derived:  false,
+			want:     []string{"*"},
+		},
+		{
+			name:     "derived principals",
+			document: fixturePrincipalArnLike,
+			derived:  true,
+			want:     []string{"*", "arn:aws:iam::*:role/ci-*" + viaConditionSuffix},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trust := mustDecodeTrust(t, "arn:aws:iam::0123456789:role/effective", tt.document)
+
+			if got := trust.policy.getEffectivePrincipals(tt.derived); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEffectivePrincipals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustPolicy_denies(t *testing.T) {
+	t.Parallel()
+
+	trust := mustDecodeTrust(t, "arn:aws:iam::0123456789:role/effective", fixtureDenyWildcard)
+
+	tests := []struct {
+		principal string
+		want      bool
+	}{
+		{principal: "arn:aws:iam::111122223333:role/sandbox-ci", want: true},
+		{principal: "arn:aws:iam::111122223333:role/sandbox-ci" + viaConditionSuffix, want: true},
+		{principal: "arn:aws:iam::111122223333:role/deploy", want: false},
+		{principal: "arn:aws:iam::444455556666:role/sandbox-ci", want: false},
+		{principal: "arn:aws:iam::111122223333:saml-provider/okta", want: true},
+		{principal: "ec2.amazonaws.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.principal, func(t *testing.T) {
+			t.Parallel()
+
+			if got := trust.policy.denies(tt.principal); got != tt.want {
+				t.Errorf("denies(%s) = %v, want %v", tt.principal, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// explainSentence renders a statement as a sentence from its principals, actions and conditions, already phrased.
+var explainSentence = template.Must(template.New("statement").Parse( //nolint:gochecknoglobals
+	`{{.Principals}} {{if .Deny}}may not{{else}}may{{end}} assume this role using {{.Actions}}` +
+		`{{with .Conditions}}{{if $.Deny}} when{{else}}, but only when{{end}} {{.}}{{end}}.`,
+))
+
+// conditionPhrases phrases every condition operator IAM supports, given the phrased condition key and values.
+var conditionPhrases = map[string]string{ //nolint:gochecknoglobals
+	"StringEquals":              "{{.Key}} is {{.Values}}",
+	"StringNotEquals":           "{{.Key}} is not {{.Values}}",
+	"StringEqualsIgnoreCase":    "{{.Key}} is {{.Values}}, ignoring case",
+	"StringNotEqualsIgnoreCase": "{{.Key}} is not {{.Values}}, ignoring case",
+	"StringLike":                "{{.Key}} matches {{.Values}}",
+	"StringNotLike":             "{{.Key}} does not match {{.Values}}",
+	"NumericEquals":             "{{.Key}} equals {{.Values}}",
+	"NumericNotEquals":          "{{.Key}} does not equal {{.Values}}",
+	"NumericLessThan":           "{{.Key}} is less than {{.Values}}",
+	"NumericLessThanEquals":     "{{.Key}} is at most {{.Values}}",
+	"NumericGreaterThan":        "{{.Key}} is greater than {{.Values}}",
+	"NumericGreaterThanEquals":  "{{.Key}} is at least {{.Values}}",
+	"DateEquals":                "{{.Key}} is {{.Values}}",
+	"DateNotEquals":             "{{.Key}} is not {{.Values}}",
+	"DateLessThan":              "{{.Key}} is before {{.Values}}",
+	"DateLessThanEquals":        "{{.Key}} is at or before {{.Values}}",
+	"DateGreaterThan":           "{{.Key}} is after {{.Values}}",
+	"DateGreaterThanEquals":     "{{.Key}} is at or after {{.Values}}",
+	"Bool":                      "{{.Key}} is {{.Values}}",
+	"BinaryEquals":              "{{.Key}} is the base64-encoded value {{.Values}}",
+	"IpAddress":                 "{{.Key}} is in {{.Values}}",
+	"NotIpAddress":              "{{.Key}} is not in {{.Values}}",
+	"ArnEquals":                 "{{.Key}} is {{.Values}}",
+	"ArnNotEquals":              "{{.Key}} is not {{.Values}}",
+	"ArnLike":                   "{{.Key}} matches {{.Values}}",
+	"ArnNotLike":                "{{.Key}} does not match {{.Values}}",
+	"Null":                      "{{if eq .Values \"'true'\"}}{{.Key}} is absent{{else}}{{.Key}} is present{{end}}",
+}
+
+// conditionTemplates holds the parsed condition phrases, keyed by operator.
+var conditionTemplates = parseConditionPhrases() //nolint:gochecknoglobals
+
+// conditionKeyPhrases names the condition keys commonly found in trust policies.
+var conditionKeyPhrases = map[string]string{ //nolint:gochecknoglobals
+	conditionExternalID:          "the external ID",
+	"aws:PrincipalOrgID":         "the organisation of the principal",
+	"aws:PrincipalOrgPaths":      "the organisational unit path of the principal",
+	"aws:PrincipalArn":           "the ARN of the principal",
+	"aws:PrincipalAccount":       "the account of the principal",
+	"aws:PrincipalType":          "the type of the principal",
+	"aws:SourceAccount":          "the source account",
+	"aws:SourceArn":              "the source ARN",
+	"aws:SourceIp":               "the source IP address",
+	"aws:SourceVpce":             "the source VPC endpoint",
+	"aws:MultiFactorAuthPresent": "multi-factor authentication being present",
+	"aws:CurrentTime":            "the current time",
+	"sts:RoleSessionName":        "the role session name",
+	"sts:SourceIdentity":         "the source identity",
+	samlAudience:                 "the SAML audience",
+}
+
+func parseConditionPhrases() map[string]*template.Template {
+	output := make(map[string]*template.Template, len(conditionPhrases))
+
+	for operator, phrase := range conditionPhrases {
+		output[operator] = template.Must(template.New(operator).Parse(phrase))
+	}
+
+	return output
+}
+
+// capitalise upper-cases the first letter of a sentence.
+func capitalise(sentence string) string {
+	first, size := utf8.DecodeRuneInString(sentence)
+
+	return string(unicode.ToUpper(first)) + sentence[size:]
+}
+
+// joinPhrases joins phrases as in prose: a, b and c, or a, b or c.
+func joinPhrases(phrases []string, conjunction string) string {
+	if len(phrases) < 2 { //nolint:mnd
+		return strings.Join(phrases, "")
+	}
+
+	return strings.Join(phrases[:len(phrases)-1], ", ") + " " + conjunction + " " + phrases[len(phrases)-1]
+}
+
+// explainAWSPrincipal phrases an AWS principal: an account, a role, user or session of an account, or anyone.
+func explainAWSPrincipal(principal string) string {
+	if principal == "*" {
+		return "anyone"
+	}
+
+	if accountIDRegex.MatchString(principal) {
+		return "account " + principal
+	}
+
+	parsed, err := arn.Parse(principal)
+	if err != nil || parsed.Service != "iam" && parsed.Service != "sts" {
+		return "principal " + principal
+	}
+
+	kind, name, _ := strings.Cut(parsed.Resource, "/")
+
+	switch kind {
+	case "root":
+		return "account " + parsed.AccountID
+	case "role":
+		return "role " + name + " in account " + parsed.AccountID
+	case "user":
+		return "user " + name + " in account " + parsed.AccountID
+	case "assumed-role":
+		role, session, _ := strings.Cut(name, "/")
+
+		return "session " + session + " of role " + role + " in account " + parsed.AccountID
+	default:
+		return "principal " + principal
+	}
+}
+
+// explainFederatedPrincipal phrases a federated principal: a SAML or OIDC provider, or a web identity provider.
+func explainFederatedPrincipal(principal string) string {
+	if _, name, found := strings.Cut(principal, samlProviderResource); found {
+		return "users federated through SAML provider " + name + " in account " + accountFromARN(principal)
+	}
+
+	if host := oidcProviderHost(principal); host != "" {
+		return "identities of OIDC provider " + host + " in account " + accountFromARN(principal)
+	}
+
+	return "identities of " + principal
+}
+
+// explainPrincipals phrases every principal of a statement.
+func explainPrincipals(principal Principal) string {
+	phrases := make([]string, 0)
+
+	for _, item := range append(slices.Clone(principal.AWS), principal.Anonymous...) {
+		phrases = append(phrases, explainAWSPrincipal(item))
+	}
+
+	for _, item := range principal.Service {
+		phrases = append(phrases, "the "+item+" service")
+	}
+
+	for _, item := range principal.Federated {
+		phrases = append(phrases, explainFederatedPrincipal(item))
+	}
+
+	for _, item := range principal.CanonicalUser {
+		phrases = append(phrases, "canonical user "+item)
+	}
+
+	if len(phrases) == 0 {
+		return "no principal"
+	}
+
+	return joinPhrases(phrases, "and")
+}
+
+// explainActions phrases the actions of a statement.
+func explainActions(actions Items) string {
+	if len(actions) == 0 {
+		return "no action"
+	}
+
+	phrases := make([]string, 0, len(actions))
+	for _, action := range actions {
+		if action == "*" {
+			action = "any action"
+		}
+
+		phrases = append(phrases, action)
+	}
+
+	return joinPhrases(phrases, "and")
+}
+
+// explainConditionKey phrases a condition key, naming the claims of OIDC providers and the tags of principals.
+func explainConditionKey(key string) string {
+	if phrase, ok := conditionKeyPhrases[key]; ok {
+		return phrase
+	}
+
+	if tag, found := strings.CutPrefix(key, "aws:PrincipalTag/"); found {
+		return "the principal tag " + tag
+	}
+
+	if tag, found := strings.CutPrefix(key, "aws:RequestTag/"); found {
+		return "the session tag " + tag
+	}
+
+	if host, claim, found := strings.Cut(key, ":"); found && strings.Contains(host, ".") {
+		return "the " + claim + " claim of the " + host + " token"
+	}
+
+	return key
+}
+
+// explainCondition phrases one condition key of an operator. The ForAnyValue and ForAllValues set operators and the
+// IfExists suffix qualify the phrase, and unknown operators are rendered literally.
+func explainCondition(operator string, key string, values ConditionValues) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, "'"+value+"'")
+	}
+
+	data := struct{ Key, Values string }{Key: explainConditionKey(key), Values: joinPhrases(quoted, "or")}
+
+	if set, _, found := strings.Cut(operator, ":"); found {
+		switch set {
+		case "ForAnyValue":
+			data.Key = "any of " + data.Key
+		case "ForAllValues":
+			data.Key = "all of " + data.Key
+		default:
+			return key + " " + operator + " " + data.Values
+		}
+	}
+
+	tmpl, ok := conditionTemplates[conditionOperatorBase(operator)]
+	if !ok {
+		return key + " " + operator + " " + data.Values
+	}
+
+	qualifier := ""
+	if strings.HasSuffix(operator, "IfExists") {
+		qualifier = ", if present"
+	}
+
+	var buf bytes.Buffer
+
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return key + " " + operator + " " + data.Values
+	}
+
+	return buf.String() + qualifier
+}
+
+// explainConditions phrases every condition of a statement, sorted by operator and key.
+func explainConditions(condition Condition) string {
+	phrases := make([]string, 0)
+
+	for _, operator := range slices.Sorted(maps.Keys(condition)) {
+		for _, key := range slices.Sorted(maps.Keys(condition[operator])) {
+			phrases = append(phrases, explainCondition(operator, key, condition[operator][key]))
+		}
+	}
+
+	return joinPhrases(phrases, "and")
+}
+
+// explainStatement renders a trust policy statement as a plain-English sentence.
+func explainStatement(statement Statement) (string, error) {
+	var buf bytes.Buffer
+
+	err := explainSentence.Execute(&buf, struct {
+		Principals string
+		Deny       bool
+		Actions    string
+		Conditions string
+	}{
+		Principals: explainPrincipals(statement.Principal),
+		Deny:       strings.EqualFold(statement.Effect, "Deny"),
+		Actions:    explainActions(statement.Action),
+		Conditions: explainConditions(statement.Condition),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to explain statement: %w", err)
+	}
+
+	return capitalise(buf.String()), nil
+}
+
+// explainTrusts returns a sentence per statement of the trust policy of each role.
+func explainTrusts(trusts map[string]roleTrust) (map[string][]string, error) {
+	output := make(map[string][]string, len(trusts))
+
+	for role, trust := range trusts {
+		sentences := make([]string, 0, len(trust.policy.Statement))
+
+		for _, statement := range trust.policy.Statement {
+			sentence, err := explainStatement(statement)
+			if err != nil {
+				return nil, err
+			}
+
+			sentences = append(sentences, sentence)
+		}
+
+		output[role] = sentences
+	}
+
+	return output, nil
+}
+
+func (a *App) runExplain(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output, err := explainTrusts(trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Debug("explained IAM roles trust policies", slog.Int("roles", len(output)))
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
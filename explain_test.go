@@ -0,0 +1,166 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func Test_explainTrusts_golden(t *testing.T) {
+	t.Parallel()
+
+	fixtures := map[string]string{
+		"AWSReservedSSOFullAdmin": fixtureAWSReservedSSOFullAdmin,
+		"AWSServiceRoleForECS":    fixtureAWSServiceRoleForECS,
+		"BitbucketOIDCPinned":     fixtureBitbucketOIDCPinned,
+		"CrossAccountTagSession":  fixtureCrossAccountTagSession,
+		"GitHubOIDCUnpinned":      fixtureGitHubOIDCUnpinned,
+		"GitLabOIDCPinned":        fixtureGitLabOIDCPinned,
+		"MixedPartitions":         fixtureMixedPartitions,
+		"OrgWideTrust":            fixtureOrgWideTrust,
+		"PrincipalArnLike":        fixturePrincipalArnLike,
+		"PrincipalArnMultiValue":  fixturePrincipalArnMultiValue,
+		"ScopedOIDCTrust":         fixtureScopedOIDCTrust,
+		"SpecificRolePrincipal":   fixtureSpecificRolePrincipal,
+		"UnscopedSAMLTrust":       fixtureUnscopedSAMLTrust,
+		"WildcardAction":          fixtureWildcardAction,
+		"WildcardPrincipal":       fixtureWildcardPrincipal,
+	}
+	golden := "fixtures/golden/Explain.json"
+
+	trusts := make(map[string]roleTrust, len(fixtures))
+	for name, document := range fixtures {
+		role := "arn:aws:iam::0123456789:role/" + name
+		trusts[role] = mustDecodeTrust(t, role, document)
+	}
+
+	explained, err := explainTrusts(trusts)
+	if err != nil {
+		t.Fatalf("explainTrusts() unexpected error: %v", err)
+	}
+
+	got, err := json.MarshalIndent(explained, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal sentences: %v", err)
+	}
+
+	if *updateGolden {
+		errWrite := os.WriteFile(golden, got, 0o600)
+		if errWrite != nil {
+			t.Fatalf("failed to update golden file: %v", errWrite)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("explainTrusts() got = %s, want %s", got, want)
+	}
+}
+
+func Test_explainCondition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		operator string
+		key      string
+		values   ConditionValues
+		want     string
+	}{
+		{
+			name:     "external ID",
+			operator: "StringLike",
+			key:      conditionExternalID,
+			values:   ConditionValues{"dd-*"},
+			want:     "the external ID matches 'dd-*'",
+		},
+		{
+			name:     "several values",
+			operator: "StringEquals",
+			key:      "aws:SourceAccount",
+			values:   ConditionValues{"111111111111", "222222222222", "333333333333"},
+			want:     "the source account is '111111111111', '222222222222' or '333333333333'",
+		},
+		{
+			name:     "set operator",
+			operator: "ForAnyValue:StringLike",
+			key:      "aws:PrincipalOrgPaths",
+			values:   ConditionValues{"o-a1b2c3d4e5/r-ab12/ou-ab12-11111111/*"},
+			want: "any of the organisational unit path of the principal matches " +
+				"'o-a1b2c3d4e5/r-ab12/ou-ab12-11111111/*'",
+		},
+		{
+			name:     "if exists",
+			operator: "BoolIfExists",
+			key:      "aws:MultiFactorAuthPresent",
+			values:   ConditionValues{"true"},
+			want:     "multi-factor authentication being present is 'true', if present",
+		},
+		{
+			name:     "absent key",
+			operator: "Null",
+			key:      "aws:PrincipalTag/team",
+			values:   ConditionValues{"true"},
+			want:     "the principal tag team is absent",
+		},
+		{
+			name:     "OIDC claim",
+			operator: "StringEquals",
+			key:      "token.actions.githubusercontent.com:aud",
+			values:   ConditionValues{"sts.amazonaws.com"},
+			want:     "the aud claim of the token.actions.githubusercontent.com token is 'sts.amazonaws.com'",
+		},
+		{
+			name:     "unknown operator",
+			operator: "StringStartsWith",
+			key:      "aws:PrincipalArn",
+			values:   ConditionValues{"arn:aws:iam::111111111111:role/ci-"},
+			want:     "aws:PrincipalArn StringStartsWith 'arn:aws:iam::111111111111:role/ci-'",
+		},
+		{
+			name:     "unknown set operator",
+			operator: "ForSomeValues:StringLike",
+			key:      "aws:PrincipalArn",
+			values:   ConditionValues{"*"},
+			want:     "aws:PrincipalArn ForSomeValues:StringLike '*'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := explainCondition(tt.operator, tt.key, tt.values); got != tt.want {
+				t.Errorf("explainCondition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_explainStatement(t *testing.T) {
+	t.Parallel()
+
+	statement := Statement{
+		Effect:    "Allow",
+		Principal: Principal{AWS: Items{"111122223333"}},
+		Action:    Items{"sts:AssumeRole"},
+		Condition: Condition{"StringLike": {conditionExternalID: ConditionValues{"dd-*"}}},
+	}
+
+	got, err := explainStatement(statement)
+	if err != nil {
+		t.Fatalf("explainStatement() unexpected error: %v", err)
+	}
+
+	want := "Account 111122223333 may assume this role using sts:AssumeRole, but only when the external ID matches 'dd-*'."
+	if got != want {
+		t.Errorf("explainStatement() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+var (
+	errExportRawPolicies = errors.New(
+		"-export-raw-policies cannot be combined with account redaction, -accounts, -output-per-account " +
+			"or -split-output-by-principal-type",
+	)
+
+	// unsafeFileNameRegex matches the characters of role paths not kept in file names.
+	unsafeFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9+=,.@_-]+`) //nolint:gochecknoglobals
+)
+
+// RoleEntry is a role with its URL-decoded trust policy document and creation date, as returned by IAM.
+type RoleEntry struct {
+	ARN        string
+	Name       string
+	Path       string
+	Policy     string
+	CreateDate time.Time
+}
+
+// sanitizePathSegment turns a role path into a file name segment, e.g. /team/app/ into team-app, and the root path
+// into root.
+func sanitizePathSegment(path string) string {
+	segments := strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/'
+	})
+	if len(segments) == 0 {
+		return "root"
+	}
+
+	for index, segment := range segments {
+		segments[index] = unsafeFileNameRegex.ReplaceAllString(segment, "_")
+	}
+
+	return strings.Join(segments, "-")
+}
+
+// rawPolicyFileNames names the file of each role, by ARN, after the role. Names shared by several roles, e.g. of
+// different accounts, are prefixed with the sanitized role path, and with the account ID if that is not enough.
+func rawPolicyFileNames(entries []RoleEntry) map[string]string {
+	count := func(name func(RoleEntry) string) map[string]int {
+		output := make(map[string]int)
+		for _, entry := range entries {
+			output[name(entry)]++
+		}
+
+		return output
+	}
+
+	byName := func(entry RoleEntry) string {
+		return entry.Name
+	}
+	byPath := func(entry RoleEntry) string {
+		return sanitizePathSegment(entry.Path) + "_" + entry.Name
+	}
+	names, paths := count(byName), count(byPath)
+
+	output := make(map[string]string, len(entries))
+
+	for _, entry := range entries {
+		switch {
+		case names[entry.Name] == 1:
+			output[entry.ARN] = entry.Name + ".json"
+		case paths[byPath(entry)] == 1:
+			output[entry.ARN] = byPath(entry) + ".json"
+		default:
+			output[entry.ARN] = accountFromARN(entry.ARN) + "_" + byPath(entry) + ".json"
+		}
+	}
+
+	return output
+}
+
+// ExportRawPolicies writes the trust policy of each role to its own <role-name>.json file in outputDir, e.g. to feed
+// external policy analysis tools, see rawPolicyFileNames for roles sharing a name.
+func ExportRawPolicies(ctx context.Context, outputDir string, entries []RoleEntry) error {
+	err := os.MkdirAll(outputDir, 0o750) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	names := rawPolicyFileNames(entries)
+
+	for _, entry := range entries {
+		err = ctx.Err()
+		if err != nil {
+			return fmt.Errorf("failed to export trust policies: %w", err)
+		}
+
+		err = os.WriteFile(filepath.Join(outputDir, names[entry.ARN]), []byte(entry.Policy), 0o600) //nolint:mnd
+		if err != nil {
+			return fmt.Errorf("failed to write trust policy of %s: %w", entry.ARN, err)
+		}
+	}
+
+	slog.Debug("exported trust policies", slog.String("dir", outputDir), slog.Int("roles", len(entries)))
+
+	return nil
+}
+
+// roleEntries returns the roles of the given trust policies, sorted by ARN, with their URL-decoded trust policies.
+func roleEntries(trusts map[string]roleTrust) ([]RoleEntry, error) {
+	output := make([]RoleEntry, 0, len(trusts))
+
+	for _, arn := range slices.Sorted(maps.Keys(trusts)) {
+		role := trusts[arn].role
+
+		policy, err := url.QueryUnescape(aws.ToString(role.AssumeRolePolicyDocument))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unescape trust policy of %s: %w", arn, err)
+		}
+
+		output = append(output, RoleEntry{
+			ARN:        arn,
+			Name:       roleNameFromARN(arn),
+			Path:       aws.ToString(role.Path),
+			Policy:     policy,
+			CreateDate: aws.ToTime(role.CreateDate),
+		})
+	}
+
+	return output, nil
+}
+
+// exportRawPolicies scans the roles and writes their decoded trust policies to dir.
+func (a *App) exportRawPolicies(ctx context.Context, dir string) error {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	entries, err := roleEntries(trusts)
+	if err != nil {
+		return err
+	}
+
+	return ExportRawPolicies(ctx, dir, entries)
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func Test_sanitizePathSegment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/", want: "root"},
+		{path: "", want: "root"},
+		{path: "/team/app/", want: "team-app"},
+		{path: "/team a/../app/", want: "team_a-..-app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			if got := sanitizePathSegment(tt.path); got != tt.want {
+				t.Errorf("sanitizePathSegment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rawPolicyFileNames(t *testing.T) {
+	t.Parallel()
+
+	entries := []RoleEntry{
+		{ARN: "arn:aws:iam::111111111111:role/admin", Name: "admin", Path: "/", Policy: ""},
+		{ARN: "arn:aws:iam::111111111111:role/team/deploy", Name: "deploy", Path: "/team/", Policy: ""},
+		{ARN: "arn:aws:iam::222222222222:role/deploy", Name: "deploy", Path: "/", Policy: ""},
+		{ARN: "arn:aws:iam::111111111111:role/ops/audit", Name: "audit", Path: "/ops/", Policy: ""},
+		{ARN: "arn:aws:iam::222222222222:role/ops/audit", Name: "audit", Path: "/ops/", Policy: ""},
+	}
+
+	want := map[string]string{
+		"arn:aws:iam::111111111111:role/admin":       "admin.json",
+		"arn:aws:iam::111111111111:role/team/deploy": "team_deploy.json",
+		"arn:aws:iam::222222222222:role/deploy":      "root_deploy.json",
+		"arn:aws:iam::111111111111:role/ops/audit":   "111111111111_ops_audit.json",
+		"arn:aws:iam::222222222222:role/ops/audit":   "222222222222_ops_audit.json",
+	}
+	if got := rawPolicyFileNames(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("rawPolicyFileNames() = %v, want %v", got, want)
+	}
+}
+
+func TestApp_exportRawPolicies(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "policies")
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/aws-service-role/ecs"),
+					Path:                     aws.String("/aws-service-role/"),
+					AssumeRolePolicyDocument: aws.String(url.QueryEscape(fixtureAWSServiceRoleForECS)),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					Path:                     aws.String("/"),
+					AssumeRolePolicyDocument: aws.String(url.QueryEscape(fixtureCrossAccountTagSession)),
+				},
+			},
+		},
+	}
+
+	err := app.exportRawPolicies(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("exportRawPolicies() unexpected error: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"ecs.json":    fixtureAWSServiceRoleForECS,
+		"vendor.json": fixtureCrossAccountTagSession,
+	} {
+		got, errRead := os.ReadFile(filepath.Join(dir, name))
+		if errRead != nil {
+			t.Fatalf("exportRawPolicies() did not write %s: %v", name, errRead)
+		}
+
+		if string(got) != want {
+			t.Errorf("exportRawPolicies() %s = %s, want %s", name, got, want)
+		}
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list exported policies: %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Errorf("exportRawPolicies() wrote %d files, want 2", len(files))
+	}
+}
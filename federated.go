@@ -0,0 +1,99 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	codeFederatedTrustUnscoped = "FEDERATED_TRUST_UNSCOPED"
+
+	samlAudience             = "SAML:aud"
+	placeholderAudienceValue = "AUDIENCE"
+)
+
+// federatedScopingKeys returns the condition keys that scope trust in the given federated principal to the intended
+// audience or subjects: SAML:aud for SAML providers, and the aud, sub or amr claims of OIDC and web identity
+// providers, prefixed by their host.
+func federatedScopingKeys(principal string) []string {
+	if strings.Contains(principal, samlProviderResource) {
+		return []string{samlAudience}
+	}
+
+	host := oidcProviderHost(principal)
+	if host == "" && isWebIdentityProvider(principal) {
+		host = principal
+	}
+
+	if host == "" {
+		return nil
+	}
+
+	return []string{host + ":aud", host + ":sub", host + ":amr"}
+}
+
+// federatedTrustUnscoped reports whether the statement trusts the federated principal without a condition on any of
+// its scoping keys, see federatedScopingKeys.
+func federatedTrustUnscoped(statement Statement, principal string) bool {
+	keys := federatedScopingKeys(principal)
+	if len(keys) == 0 {
+		return false
+	}
+
+	return !slices.ContainsFunc(keys, statement.hasConditionKey)
+}
+
+// hasDedicatedFederatedCheck reports whether unscoped trust in the federated principal is already reported by a check
+// dedicated to its provider: SAML providers of a statement without any condition, and the OIDC providers of CI
+// services, whose checks require a pinned subject.
+func hasDedicatedFederatedCheck(env checkEnv, statement Statement, principal string) bool {
+	if strings.Contains(principal, samlProviderResource) {
+		return len(unconditionedSAMLTrust(statement)) > 0
+	}
+
+	return isCIOIDCHost(oidcProviderHost(principal), env.gitlabHosts)
+}
+
+// checkFederatedTrustUnscoped flags federated principals trusted without any condition scoping them, unless a check
+// dedicated to their provider reports them already.
+func checkFederatedTrustUnscoped(env checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		if !federatedTrustUnscoped(statement, principal) || hasDedicatedFederatedCheck(env, statement, principal) {
+			continue
+		}
+
+		keys := federatedScopingKeys(principal)
+		output = append(output, Finding{
+			Code:      codeFederatedTrustUnscoped,
+			Severity:  severityHigh,
+			Role:      role,
+			Principal: principal,
+			Message: "any identity of the provider can assume the role, no condition on " +
+				strings.Join(keys, ", ") + " scopes the trust",
+			Remediation: newRemediation(
+				"Scope the trust with a condition on "+keys[0]+", and on the subject where the provider sets one.",
+				withCondition(statement, "StringEquals", keys[0], placeholderAudience(principal)),
+			),
+		})
+	}
+
+	return output
+}
+
+// placeholderAudience returns the audience to pin for the given federated principal: the AWS sign-in endpoint for
+// SAML providers, STS for GitHub Actions, or a placeholder to fill in.
+func placeholderAudience(principal string) string {
+	switch {
+	case strings.Contains(principal, samlProviderResource):
+		return "https://signin.aws.amazon.com/saml"
+	case oidcProviderHost(principal) == githubOIDCProvider:
+		return "sts.amazonaws.com"
+	default:
+		return placeholderAudienceValue
+	}
+}
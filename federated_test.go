@@ -0,0 +1,89 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_checkFederatedTrustUnscoped(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{name: "scoped SAML", document: fixtureAWSReservedSSOFullAdmin, want: []string{}},
+		{name: "scoped OIDC", document: fixtureScopedOIDCTrust, want: []string{}},
+		{name: "GitHub OIDC scoped by a wildcard subject", document: fixtureGitHubOIDCUnpinned, want: []string{}},
+		{name: "SAML without any condition reported by its own check", document: fixtureUnscopedSAMLTrust, want: []string{}},
+		{
+			name:     "SAML without audience",
+			document: fixtureSAMLTrustNoAudience,
+			want:     []string{"arn:aws:iam::123456789012:saml-provider/CorporateIdP"},
+		},
+		{
+			name:     "unscoped OIDC",
+			document: fixtureUnscopedOIDCTrust,
+			want: []string{
+				"arn:aws:iam::123456789012:oidc-provider/oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE",
+			},
+		},
+		{name: "no federated principal", document: fixtureWildcardPrincipal, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trust := mustDecodeTrust(t, "arn:aws:iam::123456789012:role/test", tt.document)
+
+			got := make([]string, 0)
+			for _, statement := range trust.policy.Statement {
+				for _, finding := range checkFederatedTrustUnscoped(checkEnv{}, "arn:aws:iam::123456789012:role/test", statement) {
+					got = append(got, finding.Principal)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkFederatedTrustUnscoped() flagged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runChecks_federatedOnce(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{name: "SAML without any condition", document: fixtureUnscopedSAMLTrust, want: []string{codeSAMLTrustUnconditioned}},
+		{name: "EKS without conditions", document: fixtureUnscopedOIDCTrust, want: []string{codeFederatedTrustUnscoped}},
+		{
+			name:     "GitLab without audience",
+			document: fixtureGitLabOIDCGroupWildcard,
+			want:     []string{codeGitLabOIDCUnpinned},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			role := "arn:aws:iam::123456789012:role/test"
+
+			got := make([]string, 0)
+			for _, finding := range runChecks(checkEnv{}, map[string]roleTrust{role: mustDecodeTrust(t, role, tt.document)}) {
+				got = append(got, finding.Code)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runChecks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+var errInvertMatch = errors.New(
+	"-invert-match requires -role-accounts, -exclude-role-accounts, -new-since, -role-min-age or -role-max-age",
+)
+
+// InvertFilter returns a filter matching exactly the roles f does not match.
+func InvertFilter(f func(RoleEntry) bool) func(RoleEntry) bool {
+	return func(entry RoleEntry) bool {
+		return !f(entry)
+	}
+}
+
+// allFilters returns a filter matching the roles matched by every one of filters, or every role if there are none.
+func allFilters(filters ...func(RoleEntry) bool) func(RoleEntry) bool {
+	return func(entry RoleEntry) bool {
+		for _, filter := range filters {
+			if !filter(entry) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// roleFilter returns the role account, new-since and role age filters AND-ed together, inverted with -invert-match.
+// Exclusions take precedence over inclusions.
+func (a *App) roleFilter() func(RoleEntry) bool {
+	filters := make([]func(RoleEntry) bool, 0)
+
+	if !a.newSince.IsZero() {
+		filters = append(filters, func(entry RoleEntry) bool {
+			return entry.CreateDate.After(a.newSince)
+		})
+	}
+
+	if a.roleMinAge > 0 || a.roleMaxAge > 0 {
+		filters = append(filters, func(entry RoleEntry) bool {
+			age := time.Since(entry.CreateDate)
+
+			return !entry.CreateDate.IsZero() && age >= a.roleMinAge && (a.roleMaxAge == 0 || age <= a.roleMaxAge)
+		})
+	}
+
+	if len(a.excludeAccounts) > 0 {
+		filters = append(filters, func(entry RoleEntry) bool {
+			return !slices.Contains(a.excludeAccounts, accountFromARN(entry.ARN))
+		})
+	}
+
+	if len(a.roleAccounts) > 0 {
+		filters = append(filters, func(entry RoleEntry) bool {
+			return slices.Contains(a.roleAccounts, accountFromARN(entry.ARN))
+		})
+	}
+
+	filter := allFilters(filters...)
+	if a.invertMatch {
+		return InvertFilter(filter)
+	}
+
+	return filter
+}
+
+// includesRole returns whether a role is matched by the role filters, which are built once for the whole listing.
+// Trust policies are not decoded yet, so the entries passed to the filters have no policy.
+func (a *App) includesRole() func(types.Role) bool {
+	filter := a.roleFilter()
+
+	return func(role types.Role) bool {
+		arn := aws.ToString(role.Arn)
+
+		return filter(RoleEntry{
+			ARN:        arn,
+			Name:       roleNameFromARN(arn),
+			Path:       aws.ToString(role.Path),
+			Policy:     "",
+			CreateDate: aws.ToTime(role.CreateDate),
+		})
+	}
+}
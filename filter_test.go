@@ -0,0 +1,96 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestInvertFilter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	entries := []RoleEntry{
+		{ARN: "arn:aws:iam::111111111111:role/old", CreateDate: now.Add(-90 * 24 * time.Hour)},
+		{ARN: "arn:aws:iam::111111111111:role/new", CreateDate: now.Add(-time.Hour)},
+		{ARN: "arn:aws:iam::222222222222:role/old", CreateDate: now.Add(-90 * 24 * time.Hour)},
+		{ARN: "arn:aws:iam::222222222222:role/new", CreateDate: now.Add(-time.Hour)},
+		{ARN: "arn:aws:iam::333333333333:role/undated"},
+	}
+
+	tests := []struct {
+		name string
+		app  *App
+		want []string
+	}{
+		{
+			name: "no filter",
+			app:  &App{},
+			want: []string{
+				"arn:aws:iam::111111111111:role/old",
+				"arn:aws:iam::111111111111:role/new",
+				"arn:aws:iam::222222222222:role/old",
+				"arn:aws:iam::222222222222:role/new",
+				"arn:aws:iam::333333333333:role/undated",
+			},
+		},
+		{
+			name: "accounts",
+			app:  &App{roleAccounts: []string{"111111111111", "222222222222"}, excludeAccounts: []string{"222222222222"}},
+			want: []string{"arn:aws:iam::111111111111:role/old", "arn:aws:iam::111111111111:role/new"},
+		},
+		{
+			name: "accounts and new since",
+			app:  &App{roleAccounts: []string{"222222222222"}, newSince: now.Add(-24 * time.Hour)},
+			want: []string{"arn:aws:iam::222222222222:role/new"},
+		},
+		{
+			name: "role age",
+			app:  &App{roleMinAge: 24 * time.Hour},
+			want: []string{"arn:aws:iam::111111111111:role/old", "arn:aws:iam::222222222222:role/old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			matched := make([]string, 0)
+			inverted := make([]string, 0)
+
+			filter := tt.app.roleFilter()
+			invert := InvertFilter(filter)
+
+			for _, entry := range entries {
+				if filter(entry) {
+					matched = append(matched, entry.ARN)
+				}
+
+				if invert(entry) {
+					inverted = append(inverted, entry.ARN)
+				}
+			}
+
+			if !slices.Equal(matched, tt.want) {
+				t.Errorf("roleFilter() matched %v, want %v", matched, tt.want)
+			}
+
+			for _, entry := range entries {
+				if slices.Contains(matched, entry.ARN) == slices.Contains(inverted, entry.ARN) {
+					t.Errorf("InvertFilter() is not the complement of the filter for %s", entry.ARN)
+				}
+			}
+
+			tt.app.invertMatch = true
+			inverseMatch := tt.app.roleFilter()
+
+			for _, entry := range entries {
+				if inverseMatch(entry) != invert(entry) {
+					t.Errorf("roleFilter() with -invert-match differs from InvertFilter() for %s", entry.ARN)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Severity levels attached to findings.
+const (
+	severityInfo   = "info"
+	severityMedium = "medium"
+	severityHigh   = "high"
+)
+
+// exitCodeFindings is the exit status of a scan whose findings reach the -fail-on severity.
+const exitCodeFindings = 1
+
+var errInvalidFailOn = errors.New("-fail-on must be info, medium or high")
+
+// severityRank orders severities from the least to the most severe.
+var severityRank = map[string]int{ //nolint:gochecknoglobals
+	severityInfo:   1,
+	severityMedium: 2,
+	severityHigh:   3,
+}
+
+// Finding describes a single issue, or informational note, about the trust configuration of a role.
+type Finding struct {
+	Code        string       `json:"code"`
+	Severity    string       `json:"severity"`
+	Role        string       `json:"role"`
+	Principal   string       `json:"principal,omitempty"`
+	Message     string       `json:"message"`
+	Remediation *Remediation `json:"remediation,omitempty"`
+}
+
+// Remediation suggests how to resolve a finding, in prose and, where one can be derived, as a trust policy
+// statement ready to replace the offending one at the given index.
+type Remediation struct {
+	Summary   string          `json:"summary"`
+	Fragment  json.RawMessage `json:"fragment,omitempty"`
+	Statement int             `json:"statement"`
+}
+
+// sortFindings orders findings by role, code and principal, then by message and remediation, so output is stable
+// between runs even for findings of the same role and code raised by several statements.
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return compareFindings(findings[i], findings[j]) < 0
+	})
+}
+
+func compareFindings(a Finding, b Finding) int {
+	return cmp.Or(
+		strings.Compare(a.Role, b.Role),
+		strings.Compare(a.Code, b.Code),
+		strings.Compare(a.Principal, b.Principal),
+		strings.Compare(a.Message, b.Message),
+		compareRemediations(a.Remediation, b.Remediation),
+	)
+}
+
+// compareRemediations orders findings without a remediation first, then by the statement remediated and its fragment.
+func compareRemediations(a *Remediation, b *Remediation) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	default:
+		return cmp.Or(
+			cmp.Compare(a.Statement, b.Statement),
+			strings.Compare(a.Summary, b.Summary),
+			bytes.Compare(a.Fragment, b.Fragment),
+		)
+	}
+}
+
+// gateFindings marks the scan as failed when any of the findings is at least as severe as the -fail-on severity.
+func (a *App) gateFindings(findings []Finding) {
+	a.recordFindings(findings)
+
+	if a.failOn == "" {
+		return
+	}
+
+	count := 0
+
+	for _, finding := range findings {
+		if severityRank[finding.Severity] >= severityRank[a.failOn] {
+			count++
+		}
+	}
+
+	if count > 0 {
+		a.failed.Store(true)
+		a.logger.Info("findings reached the fail-on severity", slog.String("severity", a.failOn), slog.Int("count", count))
+	}
+}
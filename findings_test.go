@@ -0,0 +1,44 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func Test_sortFindings(t *testing.T) {
+	t.Parallel()
+
+	finding := func(role string, message string, remediation *Remediation) Finding {
+		return Finding{
+			Code:        codeWildcardTagCondition,
+			Severity:    severityMedium,
+			Role:        role,
+			Principal:   "",
+			Message:     message,
+			Remediation: remediation,
+		}
+	}
+
+	want := []Finding{
+		finding("arn:aws:iam::0123456789:role/a", "tag condition matches any value", nil),
+		finding("arn:aws:iam::0123456789:role/a", "tag condition matches any value", &Remediation{Statement: 0}),
+		finding("arn:aws:iam::0123456789:role/a", "tag condition matches any value", &Remediation{Statement: 1}),
+		finding("arn:aws:iam::0123456789:role/a", "team tag condition matches any value", &Remediation{Statement: 0}),
+		finding("arn:aws:iam::0123456789:role/b", "tag condition matches any value", &Remediation{Statement: 0}),
+	}
+
+	reversed := slices.Clone(want)
+	slices.Reverse(reversed)
+
+	for _, input := range [][]Finding{slices.Clone(want), reversed} {
+		sortFindings(input)
+
+		if !reflect.DeepEqual(input, want) {
+			t.Errorf("sortFindings() = %+v, want %+v", input, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+var (
+	errMissingStatements = errors.New("trust policy has no statement list")
+	errStatementRange    = errors.New("remediation statement index out of range")
+)
+
+// fixOptions controls how trust policy remediations are applied.
+type fixOptions struct {
+	yes       bool
+	dryRun    bool
+	backupDir string
+	in        io.Reader
+	out       io.Writer
+	now       func() time.Time
+}
+
+// runFixCommand parses the fix subcommand flags, collects findings from a file or a fresh scan, and applies their
+// remediations.
+func runFixCommand(ctx context.Context, app *App, args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("fix", flag.ContinueOnError)
+	findingsPath := flags.String("findings-file", "", "path to a findings file produced by -findings; scan when empty")
+	yes := flags.Bool("yes", false, "apply changes without asking for confirmation")
+	dryRun := flags.Bool("dry-run", false, "only show the proposed changes")
+	backupDir := flags.String("backup-dir", ".", "directory original trust policies are backed up to")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse fix flags: %w", err)
+	}
+
+	findings, err := app.collectFindings(ctx, *findingsPath)
+	if err != nil {
+		return err
+	}
+
+	return app.applyFixes(ctx, findings, fixOptions{
+		yes:       *yes,
+		dryRun:    *dryRun,
+		backupDir: *backupDir,
+		in:        in,
+		out:       out,
+		now:       time.Now,
+	})
+}
+
+// collectFindings loads findings from the given file, or runs the built-in checks when path is empty.
+func (a *App) collectFindings(ctx context.Context, path string) ([]Finding, error) {
+	if path == "" {
+		trusts, err := a.getRoleTrusts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+		}
+
+		env, err := a.checkEnv(ctx, trusts)
+		if err != nil {
+			return nil, err
+		}
+
+		return runChecks(env, trusts), nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read findings: %w", err)
+	}
+
+	var output []Finding
+
+	errUnmarshal := json.Unmarshal(data, &output)
+	if errUnmarshal != nil {
+		return nil, fmt.Errorf("failed to unmarshal findings: %w", errUnmarshal)
+	}
+
+	return output, nil
+}
+
+// applyFixes proposes a corrected trust policy per role and, once confirmed, replaces the current one.
+// Service-linked roles and conflicting remediations are skipped. Fragments still holding placeholders, such as those of
+// a fresh scan, are only shown as in a dry run, until the placeholders are replaced in a findings file.
+func (a *App) applyFixes(ctx context.Context, findings []Finding, opts fixOptions) error {
+	fragments := make(map[string]map[int]json.RawMessage)
+	conflicts := make(map[string]struct{})
+
+	for _, finding := range findings {
+		if finding.Remediation == nil || len(finding.Remediation.Fragment) == 0 {
+			continue
+		}
+
+		if fragments[finding.Role] == nil {
+			fragments[finding.Role] = make(map[int]json.RawMessage)
+		}
+
+		index := finding.Remediation.Statement
+		if existing, ok := fragments[finding.Role][index]; ok && !bytes.Equal(existing, finding.Remediation.Fragment) {
+			conflicts[finding.Role] = struct{}{}
+		}
+
+		fragments[finding.Role][index] = finding.Remediation.Fragment
+	}
+
+	roles := make([]string, 0, len(fragments))
+	for role := range fragments {
+		roles = append(roles, role)
+	}
+
+	sort.Strings(roles)
+
+	reader := bufio.NewReader(opts.in)
+
+	for _, role := range roles {
+		if reason := skipFixReason(role, conflicts); reason != "" {
+			a.logger.Warn("skipping role", slog.String("role", role), slog.String("reason", reason))
+
+			continue
+		}
+
+		roleOpts := opts
+		if placeholder := fragmentPlaceholder(role, fragments[role]); placeholder != "" {
+			a.logger.Warn(
+				"not applying remediation, replace its placeholder in a findings file",
+				slog.String("role", role),
+				slog.String("placeholder", placeholder),
+			)
+
+			roleOpts.dryRun = true
+		}
+
+		err := a.fixRole(ctx, role, fragments[role], roleOpts, reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func skipFixReason(role string, conflicts map[string]struct{}) string {
+	if strings.Contains(role, ":role/aws-service-role/") {
+		return "service-linked roles are managed by AWS"
+	}
+
+	if _, ok := conflicts[role]; ok {
+		return "several remediations change the same statement, merge them in the findings file"
+	}
+
+	return ""
+}
+
+// fragmentPlaceholder returns a placeholder left in the remediation fragments of the role, or an empty string
+// when they can be applied as they are.
+func fragmentPlaceholder(role string, fragments map[int]json.RawMessage) string {
+	for _, fragment := range fragments {
+		placeholders := []string{
+			placeholderExternalID,
+			placeholderPrincipalIn(rolePartition(role)),
+			placeholderSubject,
+			placeholderGitLabSubject,
+			placeholderBitbucketRepo,
+			placeholderAudienceValue,
+		}
+		for _, placeholder := range placeholders {
+			if bytes.Contains(fragment, []byte(placeholder)) {
+				return placeholder
+			}
+		}
+	}
+
+	return ""
+}
+
+func (a *App) fixRole(
+	ctx context.Context,
+	role string,
+	fragments map[int]json.RawMessage,
+	opts fixOptions,
+	reader *bufio.Reader,
+) error {
+	roleName := role[strings.LastIndex(role, "/")+1:]
+
+	current, err := a.iamClient().GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		return fmt.Errorf("failed to get role %s: %w", role, err)
+	}
+
+	document, err := url.QueryUnescape(aws.ToString(current.Role.AssumeRolePolicyDocument))
+	if err != nil {
+		return fmt.Errorf("failed to unescape trust policy of %s: %w", role, err)
+	}
+
+	before, after, err := proposeTrustPolicy(document, fragments)
+	if err != nil {
+		return fmt.Errorf("failed to propose trust policy for %s: %w", role, err)
+	}
+
+	_, _ = fmt.Fprintf(opts.out, "--- %s\n+++ %s (proposed)\n", role, role)
+	for _, line := range diffLines(strings.Split(string(before), "\n"), strings.Split(string(after), "\n")) {
+		_, _ = fmt.Fprintln(opts.out, line)
+	}
+
+	if opts.dryRun || !confirm(opts, reader, role) {
+		return nil
+	}
+
+	backup := filepath.Join(
+		opts.backupDir,
+		fmt.Sprintf("%s-%s.json", roleName, opts.now().UTC().Format("20060102T150405Z")),
+	)
+
+	err = os.WriteFile(backup, []byte(document), 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to back up trust policy of %s: %w", role, err)
+	}
+
+	_, err = a.iamClient().UpdateAssumeRolePolicy(ctx, &iam.UpdateAssumeRolePolicyInput{
+		PolicyDocument: aws.String(string(after)),
+		RoleName:       aws.String(roleName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update trust policy of %s: %w", role, err)
+	}
+
+	a.logger.Info("updated trust policy", slog.String("role", role), slog.String("backup", backup))
+
+	return nil
+}
+
+func confirm(opts fixOptions, reader *bufio.Reader, role string) bool {
+	if opts.yes {
+		return true
+	}
+
+	_, _ = fmt.Fprintf(opts.out, "Apply proposed trust policy to %s? [y/N]: ", role)
+
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
+// proposeTrustPolicy replaces the statements of the document at the given indexes with the remediation fragments.
+// It returns the current and proposed documents, both indented the same way so they can be diffed.
+func proposeTrustPolicy(document string, fragments map[int]json.RawMessage) ([]byte, []byte, error) {
+	var policy map[string]any
+
+	err := json.Unmarshal([]byte(document), &policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	before, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	statements, ok := policy["Statement"].([]any)
+	if !ok {
+		return nil, nil, errMissingStatements
+	}
+
+	for index, fragment := range fragments {
+		if index < 0 || index >= len(statements) {
+			return nil, nil, fmt.Errorf("%w: %d", errStatementRange, index)
+		}
+
+		var statement any
+
+		errFragment := json.Unmarshal(fragment, &statement)
+		if errFragment != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal remediation: %w", errFragment)
+		}
+
+		statements[index] = statement
+	}
+
+	after, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return before, after, nil
+}
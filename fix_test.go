@@ -0,0 +1,315 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+const fixedCrossAccountStatement = `{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::210987654321:root"]},` +
+	`"Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"StringEquals":{"sts:ExternalId":["f3c0"]}}}`
+
+func fixFinding(role string, fragment string) Finding {
+	return Finding{
+		Code:      codeCrossAccountNoExternalID,
+		Severity:  severityMedium,
+		Role:      role,
+		Principal: "arn:aws:iam::210987654321:root",
+		Message:   "principal in another account can assume the role without an external ID",
+		Remediation: &Remediation{
+			Summary:   "Require an sts:ExternalId condition.",
+			Fragment:  json.RawMessage(fragment),
+			Statement: 0,
+		},
+	}
+}
+
+func TestApp_applyFixes(t *testing.T) {
+	t.Parallel()
+
+	vendor := "arn:aws:iam::0123456789:role/vendor"
+	roles := []types.Role{
+		{
+			Arn:                      aws.String(vendor),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+			RoleName:                 aws.String("vendor"),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		findings    []Finding
+		yes         bool
+		dryRun      bool
+		input       string
+		updateErr   error
+		wantUpdates int
+		wantDiff    bool
+		wantErr     bool
+	}{
+		{
+			name:        "dry run only shows the diff",
+			findings:    []Finding{fixFinding(vendor, fixedCrossAccountStatement)},
+			yes:         true,
+			dryRun:      true,
+			input:       "",
+			updateErr:   nil,
+			wantUpdates: 0,
+			wantDiff:    true,
+			wantErr:     false,
+		},
+		{
+			name:        "applied with yes",
+			findings:    []Finding{fixFinding(vendor, fixedCrossAccountStatement)},
+			yes:         true,
+			dryRun:      false,
+			input:       "",
+			updateErr:   nil,
+			wantUpdates: 1,
+			wantDiff:    true,
+			wantErr:     false,
+		},
+		{
+			name:        "applied after interactive confirmation",
+			findings:    []Finding{fixFinding(vendor, fixedCrossAccountStatement)},
+			yes:         false,
+			dryRun:      false,
+			input:       "y\n",
+			updateErr:   nil,
+			wantUpdates: 1,
+			wantDiff:    true,
+			wantErr:     false,
+		},
+		{
+			name:        "declined interactively",
+			findings:    []Finding{fixFinding(vendor, fixedCrossAccountStatement)},
+			yes:         false,
+			dryRun:      false,
+			input:       "n\n",
+			updateErr:   nil,
+			wantUpdates: 0,
+			wantDiff:    true,
+			wantErr:     false,
+		},
+		{
+			name: "service-linked role is refused",
+			findings: []Finding{
+				fixFinding(
+					"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS",
+					fixedCrossAccountStatement,
+				),
+			},
+			yes:         true,
+			dryRun:      false,
+			input:       "",
+			updateErr:   nil,
+			wantUpdates: 0,
+			wantDiff:    false,
+			wantErr:     false,
+		},
+		{
+			name: "placeholder is only shown",
+			findings: []Finding{
+				fixFinding(vendor, strings.Replace(fixedCrossAccountStatement, "f3c0", placeholderExternalID, 1)),
+			},
+			yes:         true,
+			dryRun:      false,
+			input:       "",
+			updateErr:   nil,
+			wantUpdates: 0,
+			wantDiff:    false,
+			wantErr:     false,
+		},
+		{
+			name: "conflicting remediations are refused",
+			findings: []Finding{
+				fixFinding(vendor, fixedCrossAccountStatement),
+				fixFinding(vendor, strings.Replace(fixedCrossAccountStatement, "f3c0", "a1b2", 1)),
+			},
+			yes:         true,
+			dryRun:      false,
+			input:       "",
+			updateErr:   nil,
+			wantUpdates: 0,
+			wantDiff:    false,
+			wantErr:     false,
+		},
+		{
+			name:        "update error",
+			findings:    []Finding{fixFinding(vendor, fixedCrossAccountStatement)},
+			yes:         true,
+			dryRun:      false,
+			input:       "",
+			updateErr:   errors.New("test error"),
+			wantUpdates: 1,
+			wantDiff:    true,
+			wantErr:     true,
+		},
+		{
+			name:        "unknown role",
+			findings:    []Finding{fixFinding("arn:aws:iam::0123456789:role/missing", fixedCrossAccountStatement)},
+			yes:         true,
+			dryRun:      false,
+			input:       "",
+			updateErr:   nil,
+			wantUpdates: 0,
+			wantDiff:    false,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+
+			backupDir := t.TempDir()
+			fake := &veiltest.FakeIAM{
+				Roles:  roles,
+				Errors: map[string]error{veiltest.OperationUpdateAssumeRolePolicy: tt.updateErr},
+			}
+			app := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: fake,
+			}
+
+			err := app.applyFixes(t.Context(), tt.findings, fixOptions{
+				yes:       tt.yes,
+				dryRun:    tt.dryRun,
+				backupDir: backupDir,
+				in:        strings.NewReader(tt.input),
+				out:       &out,
+				now: func() time.Time {
+					return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("applyFixes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			updates := fake.Calls(veiltest.OperationUpdateAssumeRolePolicy)
+			if len(updates) != tt.wantUpdates {
+				t.Fatalf("applyFixes() updates = %d, want %d", len(updates), tt.wantUpdates)
+			}
+
+			if got := strings.Contains(out.String(), `"f3c0"`); got != tt.wantDiff {
+				t.Errorf("applyFixes() diff shown = %v, want %v: %s", got, tt.wantDiff, out.String())
+			}
+
+			if tt.wantUpdates == 0 {
+				return
+			}
+
+			update, _ := updates[0].(*iam.UpdateAssumeRolePolicyInput)
+			if !strings.Contains(aws.ToString(update.PolicyDocument), "f3c0") {
+				t.Errorf("applyFixes() updated policy = %s", aws.ToString(update.PolicyDocument))
+			}
+
+			backup, err := os.ReadFile(filepath.Join(backupDir, "vendor-20250102T030405Z.json"))
+			if err != nil {
+				t.Fatalf("applyFixes() did not back up the trust policy: %v", err)
+			}
+
+			if string(backup) != fixtureCrossAccountTagSession {
+				t.Errorf("applyFixes() backup = %s", backup)
+			}
+		})
+	}
+}
+
+func Test_runFixCommand_freshScan(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	fake := &veiltest.FakeIAM{Roles: []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::123456789012:role/sso"),
+			AssumeRolePolicyDocument: aws.String(fixtureUnscopedSAMLTrust),
+			RoleName:                 aws.String("sso"),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+			RoleName:                 aws.String("vendor"),
+		},
+	}}
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: fake,
+	}
+
+	err := runFixCommand(t.Context(), app, []string{"-yes", "-backup-dir", t.TempDir()}, strings.NewReader(""), &out)
+	if err != nil {
+		t.Fatalf("runFixCommand() unexpected error: %v", err)
+	}
+
+	updates := fake.Calls(veiltest.OperationUpdateAssumeRolePolicy)
+	if len(updates) != 1 {
+		t.Fatalf("runFixCommand() updates = %d, want 1: %s", len(updates), out.String())
+	}
+
+	update, _ := updates[0].(*iam.UpdateAssumeRolePolicyInput)
+	if aws.ToString(update.RoleName) != "sso" ||
+		!strings.Contains(aws.ToString(update.PolicyDocument), "https://signin.aws.amazon.com/saml") {
+		t.Errorf("runFixCommand() updated %s with %s", aws.ToString(update.RoleName), aws.ToString(update.PolicyDocument))
+	}
+
+	if !strings.Contains(out.String(), placeholderExternalID) {
+		t.Errorf("runFixCommand() did not show the remediation holding a placeholder: %s", out.String())
+	}
+}
+
+func Test_proposeTrustPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		document  string
+		fragments map[int]json.RawMessage
+		wantErr   error
+	}{
+		{
+			name:      "no statement list",
+			document:  `{"Version": "2012-10-17"}`,
+			fragments: map[int]json.RawMessage{0: json.RawMessage(`{}`)},
+			wantErr:   errMissingStatements,
+		},
+		{
+			name:      "statement out of range",
+			document:  fixtureAWSServiceRoleForECS,
+			fragments: map[int]json.RawMessage{1: json.RawMessage(`{}`)},
+			wantErr:   errStatementRange,
+		},
+		{
+			name:      "replaced statement",
+			document:  fixtureCrossAccountTagSession,
+			fragments: map[int]json.RawMessage{0: json.RawMessage(fixedCrossAccountStatement)},
+			wantErr:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := proposeTrustPolicy(tt.document, tt.fragments)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("proposeTrustPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+const defaultFocusDepth = 1
+
+var errInvalidFocusDepth = errors.New("focus depth must be at least 1")
+
+// normaliseIdentity maps the different forms of the same identity onto one ARN: bare account IDs become the
+// account root in the given partition, and assumed-role sessions become the role they were assumed from.
+func normaliseIdentity(identity string, partition string) string {
+	identity = normalisePrincipalIn(strings.TrimSuffix(identity, viaConditionSuffix), partition)
+
+	if role, ok := sessionRole(identity); ok {
+		return role
+	}
+
+	return identity
+}
+
+// focusRoles restricts the trust graph to the roles and principals within depth hops of any identity matching the
+// focus pattern. A role trusted by another role is a single node, so depths above 1 follow trust transitively.
+func focusRoles(roles map[string][]string, focus string, depth int) map[string][]string {
+	match := globRegexp(normaliseIdentity(principalGlob(focus), defaultPartition))
+	edges := make(map[string][]string)
+	queue := make([]string, 0)
+	distance := make(map[string]int)
+
+	for role, principals := range roles {
+		roleNode := normaliseIdentity(role, defaultPartition)
+		partition := rolePartition(role)
+
+		for _, principal := range principals {
+			principalNode := normaliseIdentity(principal, partition)
+			edges[roleNode] = append(edges[roleNode], principalNode)
+			edges[principalNode] = append(edges[principalNode], roleNode)
+		}
+
+		if _, ok := edges[roleNode]; !ok {
+			edges[roleNode] = nil
+		}
+	}
+
+	for node := range edges {
+		if match.MatchString(node) {
+			distance[node] = 0
+			queue = append(queue, node)
+		}
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if distance[node] == depth {
+			continue
+		}
+
+		for _, next := range edges[node] {
+			if _, seen := distance[next]; !seen {
+				distance[next] = distance[node] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	output := make(map[string][]string)
+
+	for role, principals := range roles {
+		if _, ok := distance[normaliseIdentity(role, defaultPartition)]; !ok {
+			continue
+		}
+
+		partition := rolePartition(role)
+
+		kept := make([]string, 0, len(principals))
+		for _, principal := range principals {
+			if _, ok := distance[normaliseIdentity(principal, partition)]; ok {
+				kept = append(kept, principal)
+			}
+		}
+
+		if len(kept) > 0 || len(principals) == 0 {
+			output[role] = kept
+		}
+	}
+
+	slog.Debug(
+		"focused trust graph",
+		slog.String("focus", focus),
+		slog.Int("depth", depth),
+		slog.Int("roles", len(output)),
+	)
+
+	return output
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_normaliseIdentity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		identity  string
+		partition string
+		want      string
+	}{
+		{
+			name:      "account ID",
+			identity:  "210987654321",
+			partition: defaultPartition,
+			want:      "arn:aws:iam::210987654321:root",
+		},
+		{
+			name:      "account ID in GovCloud",
+			identity:  "210987654321",
+			partition: "aws-us-gov",
+			want:      "arn:aws-us-gov:iam::210987654321:root",
+		},
+		{
+			name:      "China assumed role session",
+			identity:  "arn:aws-cn:sts::210987654321:assumed-role/deployer/session",
+			partition: defaultPartition,
+			want:      "arn:aws-cn:iam::210987654321:role/deployer",
+		},
+		{
+			name:      "assumed role session",
+			identity:  "arn:aws:sts::210987654321:assumed-role/deployer/session",
+			partition: defaultPartition,
+			want:      "arn:aws:iam::210987654321:role/deployer",
+		},
+		{
+			name:      "derived principal",
+			identity:  "arn:aws:iam::210987654321:role/deployer (via-condition)",
+			partition: defaultPartition,
+			want:      "arn:aws:iam::210987654321:role/deployer",
+		},
+		{
+			name:      "service principal",
+			identity:  "ecs.amazonaws.com",
+			partition: defaultPartition,
+			want:      "ecs.amazonaws.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := normaliseIdentity(tt.identity, tt.partition); got != tt.want {
+				t.Errorf("normaliseIdentity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_focusRoles(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/vendor": {
+			"arn:aws:iam::210987654321:root",
+		},
+		"arn:aws:iam::0123456789:role/admin": {
+			"arn:aws:iam::0123456789:role/vendor",
+			"arn:aws:iam::0123456789:role/breakglass",
+		},
+		"arn:aws:iam::0123456789:role/ecs": {
+			"ecs.amazonaws.com",
+		},
+		"arn:aws-us-gov:iam::111122223333:role/vendor": {
+			"444455556666",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		focus string
+		depth int
+		want  map[string][]string
+	}{
+		{
+			name:  "vendor account ID",
+			focus: "210987654321",
+			depth: 1,
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+			},
+		},
+		{
+			name:  "vendor session, transitively",
+			focus: "arn:aws:sts::0123456789:assumed-role/vendor/session",
+			depth: 2,
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+				"arn:aws:iam::0123456789:role/admin": {
+					"arn:aws:iam::0123456789:role/vendor",
+					"arn:aws:iam::0123456789:role/breakglass",
+				},
+			},
+		},
+		{
+			name:  "glob",
+			focus: "*.amazonaws.com",
+			depth: 1,
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/ecs": {"ecs.amazonaws.com"},
+			},
+		},
+		{
+			name:  "GovCloud vendor account ID",
+			focus: "444455556666",
+			depth: 1,
+			want: map[string][]string{
+				"arn:aws-us-gov:iam::111122223333:role/vendor": {"444455556666"},
+			},
+		},
+		{
+			name:  "no match",
+			focus: "arn:aws:iam::111111111111:root",
+			depth: 3,
+			want:  map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := focusRoles(roles, tt.focus, tt.depth); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("focusRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
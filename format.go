@@ -0,0 +1,68 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	formatJSON           = "json"
+	formatOrg            = "org"
+	formatMarkdown       = "markdown"
+	formatCount          = "count"
+	formatJSONCount      = "json-count"
+	formatGEXF           = "gexf"
+	formatDistribution   = "distribution"
+	formatTables         = "tables"
+	formatPublic         = "public"
+	formatAccessAnalyzer = "access-analyzer"
+	formatInventory      = "inventory"
+	formatProto          = "proto"
+	formatProtoJSON      = "protojson"
+	formatShell          = "shell"
+	formatD2             = "d2"
+)
+
+var errUnknownFormat = errors.New("unknown output format")
+
+// scanner returns the scan function rendering its output in the given format.
+func (a *App) scanner(format string) (func(context.Context) ([]byte, error), error) {
+	switch format {
+	case formatJSON:
+		return a.runScanIAM, nil
+	case formatOrg:
+		return a.runScanOrg, nil
+	case formatMarkdown:
+		return a.runMarkdownReport, nil
+	case formatCount:
+		return a.runCount, nil
+	case formatJSONCount:
+		return a.runJSONCount, nil
+	case formatGEXF:
+		return a.runGEXF, nil
+	case formatDistribution:
+		return a.runDistribution, nil
+	case formatTables:
+		return a.runTables, nil
+	case formatPublic:
+		return a.runPublic, nil
+	case formatAccessAnalyzer:
+		return a.runAccessAnalyzer, nil
+	case formatInventory:
+		return a.runInventory, nil
+	case formatProto:
+		return a.runProto, nil
+	case formatProtoJSON:
+		return a.runProtoJSON, nil
+	case formatShell:
+		return a.runShell, nil
+	case formatD2:
+		return a.runD2, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownFormat, format)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestApp_scanner(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		format  string
+		wantErr error
+	}{
+		{
+			name:    "json",
+			format:  formatJSON,
+			wantErr: nil,
+		},
+		{
+			name:    "org",
+			format:  formatOrg,
+			wantErr: nil,
+		},
+		{
+			name:    "markdown",
+			format:  formatMarkdown,
+			wantErr: nil,
+		},
+		{
+			name:    "count",
+			format:  formatCount,
+			wantErr: nil,
+		},
+		{
+			name:    "json count",
+			format:  formatJSONCount,
+			wantErr: nil,
+		},
+		{
+			name:    "gexf",
+			format:  formatGEXF,
+			wantErr: nil,
+		},
+		{
+			name:    "distribution",
+			format:  formatDistribution,
+			wantErr: nil,
+		},
+		{
+			name:    "tables",
+			format:  formatTables,
+			wantErr: nil,
+		},
+		{
+			name:    "public",
+			format:  formatPublic,
+			wantErr: nil,
+		},
+		{
+			name:    "access analyzer",
+			format:  formatAccessAnalyzer,
+			wantErr: nil,
+		},
+		{
+			name:    "inventory",
+			format:  formatInventory,
+			wantErr: nil,
+		},
+		{
+			name:    "proto",
+			format:  formatProto,
+			wantErr: nil,
+		},
+		{
+			name:    "protojson",
+			format:  formatProtoJSON,
+			wantErr: nil,
+		},
+		{
+			name:    "shell",
+			format:  formatShell,
+			wantErr: nil,
+		},
+		{
+			name:    "d2",
+			format:  formatD2,
+			wantErr: nil,
+		},
+		{
+			name:    "unknown",
+			format:  "yaml",
+			wantErr: errUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := (&App{logger: slog.New(slog.DiscardHandler)}).scanner(tt.format)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("scanner() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if (got != nil) != (tt.wantErr == nil) {
+				t.Errorf("scanner() got nil = %v, want non-nil: %v", got == nil, tt.wantErr == nil)
+			}
+		})
+	}
+}
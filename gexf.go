@@ -0,0 +1,165 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	gexfNamespace = "http://gexf.net/1.3"
+	gexfVersion   = "1.3"
+
+	nodeTypeRole      = "role"
+	nodeTypePrincipal = "principal"
+)
+
+// gexfDocument is the root element of a GEXF file, as read by Gephi.
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string         `xml:"defaultedgetype,attr"`
+	Mode            string         `xml:"mode,attr"`
+	TimeFormat      string         `xml:"timeformat,attr,omitempty"`
+	Attributes      gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode     `xml:"nodes>node"`
+	Edges           []gexfEdge     `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	Start     string         `xml:"start,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Start  string `xml:"start,attr,omitempty"`
+}
+
+// buildGEXF renders the trust graph as GEXF, with an edge from each principal to every role trusting it. Nodes are
+// labelled by their ARN, or their label if they have one, in which case the ARN is kept in an arn attribute.
+// A non-zero timestamp switches the graph to dynamic mode, stamping every node and edge with it so successive scans
+// can be merged into a timeline.
+func buildGEXF(roles map[string][]string, timestamp time.Time, labels map[string]string) ([]byte, error) {
+	graph := gexfGraph{
+		DefaultEdgeType: "directed",
+		Mode:            "static",
+		TimeFormat:      "",
+		Attributes: gexfAttributes{
+			Class: "node",
+			Attributes: []gexfAttribute{
+				{ID: "type", Title: "type", Type: "string"},
+			},
+		},
+		Nodes: make([]gexfNode, 0),
+		Edges: make([]gexfEdge, 0),
+	}
+
+	if labels != nil {
+		graph.Attributes.Attributes = append(
+			graph.Attributes.Attributes,
+			gexfAttribute{ID: "arn", Title: "arn", Type: "string"},
+		)
+	}
+
+	start := ""
+	if !timestamp.IsZero() {
+		graph.Mode = "dynamic"
+		graph.TimeFormat = "dateTime"
+		start = timestamp.UTC().Format(time.RFC3339)
+	}
+
+	nodeTypes := make(map[string]string)
+	for _, principal := range slices.Sorted(maps.Keys(mapFlip(roles))) {
+		nodeTypes[principal] = nodeTypePrincipal
+	}
+
+	for role := range roles {
+		nodeTypes[role] = nodeTypeRole
+	}
+
+	ids := make(map[string]string, len(nodeTypes))
+	for index, node := range slices.Sorted(maps.Keys(nodeTypes)) {
+		attValues := []gexfAttValue{{For: "type", Value: nodeTypes[node]}}
+		if labels != nil {
+			attValues = append(attValues, gexfAttValue{For: "arn", Value: node})
+		}
+
+		ids[node] = "n" + strconv.Itoa(index)
+		graph.Nodes = append(graph.Nodes, gexfNode{
+			ID:        ids[node],
+			Label:     roleLabel(labels, node),
+			Start:     start,
+			AttValues: attValues,
+		})
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		for _, principal := range slices.Sorted(slices.Values(roles[role])) {
+			graph.Edges = append(graph.Edges, gexfEdge{
+				ID:     "e" + strconv.Itoa(len(graph.Edges)),
+				Source: ids[principal],
+				Target: ids[role],
+				Start:  start,
+			})
+		}
+	}
+
+	marshal, err := xml.MarshalIndent(gexfDocument{
+		XMLName: xml.Name{Space: "", Local: "gexf"},
+		Xmlns:   gexfNamespace,
+		Version: gexfVersion,
+		Graph:   graph,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return append([]byte(xml.Header), marshal...), nil
+}
+
+func (a *App) runGEXF(ctx context.Context) ([]byte, error) {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	timestamp := time.Time{}
+	if a.temporal {
+		timestamp = time.Now()
+	}
+
+	return buildGEXF(roles, timestamp, a.roleLabels(slices.Collect(maps.Keys(roles))))
+}
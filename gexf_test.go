@@ -0,0 +1,106 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_buildGEXF(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+		"arn:aws:iam::0123456789:role/admin": {
+			"arn:aws:iam::0123456789:role/vendor",
+			"arn:aws:iam::0123456789:saml-provider/<IdP>&Co",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		labels    map[string]string
+		want      []string
+		wantNot   []string
+	}{
+		{
+			name:      "static",
+			timestamp: time.Time{},
+			labels:    nil,
+			want: []string{
+				`<gexf xmlns="http://gexf.net/1.3" version="1.3">`,
+				`<graph defaultedgetype="directed" mode="static">`,
+				`<attribute id="type" title="type" type="string"></attribute>`,
+				`<node id="n0" label="arn:aws:iam::0123456789:role/admin">`,
+				`<attvalue for="type" value="role"></attvalue>`,
+				`<node id="n3" label="arn:aws:iam::210987654321:root">`,
+				`<attvalue for="type" value="principal"></attvalue>`,
+				`label="arn:aws:iam::0123456789:saml-provider/&lt;IdP&gt;&amp;Co"`,
+				`<edge id="e0" source="n1" target="n0"></edge>`,
+				`<edge id="e2" source="n3" target="n1"></edge>`,
+			},
+			wantNot: []string{"start=", "timeformat=", `attribute id="arn"`},
+		},
+		{
+			name:      "short names",
+			timestamp: time.Time{},
+			labels: shortRoleNames([]string{
+				"arn:aws:iam::0123456789:role/vendor",
+				"arn:aws:iam::0123456789:role/admin",
+			}),
+			want: []string{
+				`<attribute id="arn" title="arn" type="string"></attribute>`,
+				`<node id="n0" label="/admin">`,
+				`<attvalue for="arn" value="arn:aws:iam::0123456789:role/admin"></attvalue>`,
+				`<node id="n3" label="arn:aws:iam::210987654321:root">`,
+				`<attvalue for="arn" value="arn:aws:iam::210987654321:root"></attvalue>`,
+			},
+			wantNot: []string{`label="arn:aws:iam::0123456789:role/`},
+		},
+		{
+			name:      "temporal",
+			timestamp: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+			labels:    nil,
+			want: []string{
+				`<graph defaultedgetype="directed" mode="dynamic" timeformat="dateTime">`,
+				`<node id="n0" label="arn:aws:iam::0123456789:role/admin" start="2025-06-01T12:00:00Z">`,
+				`<edge id="e0" source="n1" target="n0" start="2025-06-01T12:00:00Z"></edge>`,
+			},
+			wantNot: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := buildGEXF(roles, tt.timestamp, tt.labels)
+			if err != nil {
+				t.Fatalf("buildGEXF() unexpected error: %v", err)
+			}
+
+			var document gexfDocument
+
+			err = xml.Unmarshal(got, &document)
+			if err != nil {
+				t.Fatalf("buildGEXF() produced invalid XML: %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("buildGEXF() missing %q in\n%s", want, got)
+				}
+			}
+
+			for _, wantNot := range tt.wantNot {
+				if strings.Contains(string(got), wantNot) {
+					t.Errorf("buildGEXF() unexpected %q in\n%s", wantNot, got)
+				}
+			}
+		})
+	}
+}
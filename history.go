@@ -0,0 +1,485 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	// historySchemaVersion is the version of the history store written by this release. Stores of older versions are
+	// migrated when read, see migrateHistory.
+	historySchemaVersion = 1
+
+	historyChangeScan    = "scan"
+	historyChangeAdded   = "added"
+	historyChangeRemoved = "removed"
+
+	// maxHistoryLineSize bounds the length of a line of the history store.
+	maxHistoryLineSize = 1 << 20
+)
+
+var (
+	errHistoryArgs    = errors.New("history requires -history-db and a principal or role")
+	errHistoryVersion = errors.New("unsupported history store version")
+	errInvalidHistory = errors.New("expected a history store of JSON lines")
+	errHistoryDB      = errors.New(
+		"-history-db cannot be combined with -accounts, -output-per-account or -split-output-by-principal-type",
+	)
+)
+
+// historyHeader is the first line of a history store, holding its schema version.
+type historyHeader struct {
+	Version int `json:"version"`
+}
+
+// historyEntry is a line of the history store: a scan, or a trust edge added or removed as of that scan.
+type historyEntry struct {
+	Time      time.Time `json:"time"`
+	Change    string    `json:"change"`
+	Principal string    `json:"principal,omitempty"`
+	Role      string    `json:"role,omitempty"`
+}
+
+// HistoryEdge is a span of scans during which a principal was trusted by a role. RemovedAt is the first scan no longer
+// finding the edge, unset while it is still trusted. An edge removed and added again has a span per addition.
+type HistoryEdge struct {
+	Principal string     `json:"principal"`
+	Role      string     `json:"role"`
+	FirstSeen time.Time  `json:"firstSeen"`
+	LastSeen  time.Time  `json:"lastSeen"`
+	RemovedAt *time.Time `json:"removedAt,omitempty"`
+}
+
+// readHistory reads a history store, migrating it to the current schema version. The returned flag reports whether
+// it was migrated, so the caller knows to rewrite it.
+func readHistory(r io.Reader) ([]historyEntry, bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxHistoryLineSize)
+
+	version := 0
+	entries := make([]historyEntry, 0)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		if line == 1 {
+			var header historyHeader
+
+			err := json.Unmarshal(data, &header)
+			if err != nil {
+				return nil, false, fmt.Errorf("%w: line %d: %w", errInvalidHistory, line, err)
+			}
+
+			version = header.Version
+			if version != 0 {
+				continue
+			}
+		}
+
+		var entry historyEntry
+
+		err := json.Unmarshal(data, &entry)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: line %d: %w", errInvalidHistory, line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read history store: %w", err)
+	}
+
+	migrated, err := migrateHistory(version, entries)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return migrated, version != historySchemaVersion, nil
+}
+
+// migrateHistory upgrades the entries of a store of the given version to the current schema version. Stores without
+// a version header, version 0, only hold added and removed edges, e.g. when assembled from -diff outputs; the scans
+// are taken to be the times of those changes.
+func migrateHistory(version int, entries []historyEntry) ([]historyEntry, error) {
+	if version > historySchemaVersion || version < 0 {
+		return nil, fmt.Errorf("%w: %d, expected at most %d", errHistoryVersion, version, historySchemaVersion)
+	}
+
+	if version == 0 {
+		output := make([]historyEntry, 0, len(entries))
+
+		slices.SortStableFunc(entries, func(a, b historyEntry) int {
+			return a.Time.Compare(b.Time)
+		})
+
+		for index, entry := range entries {
+			if index == 0 || !entry.Time.Equal(entries[index-1].Time) {
+				output = append(output, historyEntry{Time: entry.Time, Change: historyChangeScan, Principal: "", Role: ""})
+			}
+
+			output = append(output, entry)
+		}
+
+		entries = output
+	}
+
+	return entries, nil
+}
+
+// historyEdges folds the entries of a store, in order, into the spans of the edges, sorted by first seen, principal
+// and role.
+func historyEdges(entries []historyEntry) []HistoryEdge {
+	output := make([]HistoryEdge, 0)
+	open := make(map[TrustEdge]int)
+
+	var previousScan, lastScan time.Time
+
+	for _, entry := range entries {
+		edge := TrustEdge{Principal: entry.Principal, Role: entry.Role}
+
+		switch entry.Change {
+		case historyChangeScan:
+			previousScan, lastScan = lastScan, entry.Time
+
+			for _, index := range open {
+				output[index].LastSeen = entry.Time
+			}
+		case historyChangeAdded:
+			if _, ok := open[edge]; ok {
+				continue
+			}
+
+			open[edge] = len(output)
+			output = append(output, HistoryEdge{
+				Principal: entry.Principal,
+				Role:      entry.Role,
+				FirstSeen: entry.Time,
+				LastSeen:  entry.Time,
+				RemovedAt: nil,
+			})
+		case historyChangeRemoved:
+			index, ok := open[edge]
+			if !ok {
+				continue
+			}
+
+			removedAt := entry.Time
+			output[index].RemovedAt = &removedAt
+
+			if output[index].LastSeen.Equal(entry.Time) && !previousScan.IsZero() {
+				output[index].LastSeen = previousScan
+			}
+
+			delete(open, edge)
+		}
+	}
+
+	slices.SortStableFunc(output, func(a, b HistoryEdge) int {
+		return cmp.Or(
+			a.FirstSeen.Compare(b.FirstSeen),
+			cmp.Compare(a.Principal, b.Principal),
+			cmp.Compare(a.Role, b.Role),
+		)
+	})
+
+	return output
+}
+
+// currentHistoryRoles returns the role to principals mapping of the edges still trusted as of the last scan.
+func currentHistoryRoles(edges []HistoryEdge) map[string][]string {
+	output := make(map[string][]string)
+
+	for _, edge := range edges {
+		if edge.RemovedAt == nil {
+			output[edge.Role] = append(output[edge.Role], edge.Principal)
+		}
+	}
+
+	return output
+}
+
+// scanHistoryEntries returns the entries recording a scan at the given time finding the given roles, against the
+// edges trusted as of the previous scan.
+func scanHistoryEntries(edges []HistoryEdge, roles map[string][]string, now time.Time) []historyEntry {
+	diff := DiffTrustGraphs(currentHistoryRoles(edges), roles)
+	output := []historyEntry{{Time: now, Change: historyChangeScan, Principal: "", Role: ""}}
+
+	for _, edge := range diff.Added {
+		output = append(output, historyEntry{
+			Time:      now,
+			Change:    historyChangeAdded,
+			Principal: edge.Principal,
+			Role:      edge.Role,
+		})
+	}
+
+	for _, edge := range diff.Removed {
+		output = append(output, historyEntry{
+			Time:      now,
+			Change:    historyChangeRemoved,
+			Principal: edge.Principal,
+			Role:      edge.Role,
+		})
+	}
+
+	return output
+}
+
+// pruneHistory drops the spans of edges last seen before the cutoff, and the scans before it but the latest, which
+// the next scan is compared with. Edges still trusted are kept however old, so their first seen time is not lost.
+func pruneHistory(entries []historyEntry, cutoff time.Time) []historyEntry {
+	expired := make(map[historyEntry]bool)
+
+	for _, span := range historyEdges(entries) {
+		if span.RemovedAt == nil || !span.LastSeen.Before(cutoff) {
+			continue
+		}
+
+		added := historyEntry{Time: span.FirstSeen, Change: historyChangeAdded, Principal: span.Principal, Role: span.Role}
+		removed := added
+		removed.Time, removed.Change = *span.RemovedAt, historyChangeRemoved
+
+		expired[added], expired[removed] = true, true
+	}
+
+	latestScan := -1
+
+	for index, entry := range entries {
+		if entry.Change == historyChangeScan {
+			latestScan = index
+		}
+	}
+
+	output := make([]historyEntry, 0, len(entries))
+
+	for index, entry := range entries {
+		if expired[entry] {
+			continue
+		}
+
+		if entry.Change == historyChangeScan && entry.Time.Before(cutoff) && index != latestScan {
+			continue
+		}
+
+		output = append(output, entry)
+	}
+
+	return output
+}
+
+// marshalHistory renders entries as lines of the history store.
+func marshalHistory(entries []historyEntry) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// loadHistory reads the history store at path, which may not exist yet.
+func loadHistory(path string) ([]historyEntry, bool, error) {
+	file, err := os.Open(path) //nolint:gosec
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]historyEntry, 0), true, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return readHistory(file)
+}
+
+// writeHistory records a scan finding the given roles in the history store at path. The scan is appended, unless the
+// store is new, migrated or pruned by the retention, if any, in which case it is rewritten.
+func writeHistory(path string, roles map[string][]string, retention time.Duration, now time.Time) error {
+	entries, rewrite, err := loadHistory(path)
+	if err != nil {
+		return err
+	}
+
+	scan := scanHistoryEntries(historyEdges(entries), roles, now)
+	entries = append(entries, scan...)
+
+	if retention > 0 {
+		pruned := pruneHistory(entries, now.Add(-retention))
+		rewrite = rewrite || len(pruned) != len(entries)
+		entries = pruned
+	}
+
+	slog.Debug(
+		"recording scan history",
+		slog.String("path", path),
+		slog.Int("changes", len(scan)-1),
+		slog.Bool("rewrite", rewrite),
+	)
+
+	if !rewrite {
+		lines, errMarshal := marshalHistory(scan)
+		if errMarshal != nil {
+			return errMarshal
+		}
+
+		file, errOpen := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600) //nolint:gosec,mnd
+		if errOpen != nil {
+			return fmt.Errorf("failed to open history store: %w", errOpen)
+		}
+
+		_, err = file.Write(lines)
+		errClose := file.Close()
+
+		if err != nil || errClose != nil {
+			return fmt.Errorf("failed to append to history store: %w", errors.Join(err, errClose))
+		}
+
+		return nil
+	}
+
+	header, err := json.Marshal(historyHeader{Version: historySchemaVersion})
+	if err != nil {
+		return fmt.Errorf("failed to marshal history header: %w", err)
+	}
+
+	lines, err := marshalHistory(entries)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, slices.Concat(header, []byte("\n"), lines), 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to write history store: %w", err)
+	}
+
+	return nil
+}
+
+// recordHistory records the roles found by the scan in the history store at path. A scan which did not see every role
+// is not recorded, as every edge of the roles it missed would be recorded as removed.
+func (a *App) recordHistory(path string, retention time.Duration, now time.Time) error {
+	roles := a.scannedRoles()
+	if roles == nil {
+		a.logger.Warn("the selected output does not scan trust policies, not recording history")
+
+		return nil
+	}
+
+	if reason := a.partialScan(); reason != "" {
+		a.logger.Warn("the scan did not cover every role, not recording history", slog.String("reason", reason))
+
+		return nil
+	}
+
+	return writeHistory(path, roles, retention, now)
+}
+
+// partialScan returns why the last scan did not see every role of the account, or an empty string if it did.
+func (a *App) partialScan() string {
+	a.notificationMutex.Lock()
+	skipped := len(a.skippedRoles)
+	a.notificationMutex.Unlock()
+
+	switch {
+	case a.truncated.Load():
+		return "the scan was interrupted"
+	case skipped > 0:
+		return "roles failed to decode"
+	case len(a.roleNames) > 0 || len(a.pathPrefixes) > 0:
+		return "the scan was restricted to role names or path prefixes"
+	case len(a.roleAccounts) > 0 || len(a.excludeAccounts) > 0 || a.invertMatch:
+		return "the scan was restricted to role accounts"
+	case !a.newSince.IsZero() || a.roleMinAge > 0 || a.roleMaxAge > 0:
+		return "the scan was restricted by role creation date"
+	case a.onlyUnbounded:
+		return "the scan was restricted to roles without a permissions boundary"
+	default:
+		return ""
+	}
+}
+
+// HistoryTimeline returns the spans of the edges of the store whose principal or role is the given one.
+func HistoryTimeline(entries []historyEntry, query string) []HistoryEdge {
+	output := make([]HistoryEdge, 0)
+
+	for _, edge := range historyEdges(entries) {
+		if edge.Principal == query || edge.Role == query {
+			output = append(output, edge)
+		}
+	}
+
+	return output
+}
+
+// runHistoryCommand prints when each principal trusted by the given role, or each role trusting the given principal,
+// was first and last seen, and when it was removed, from the history store at path.
+func runHistoryCommand(args []string, out io.Writer, path string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	historyDB := flags.String("history-db", path, "path to the history store recorded by scans with -history-db")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse history flags: %w", err)
+	}
+
+	if *historyDB == "" || flags.NArg() != 1 {
+		return errHistoryArgs
+	}
+
+	file, err := os.Open(*historyDB)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	entries, _, err := readHistory(file)
+	if err != nil {
+		return err
+	}
+
+	timeline := HistoryTimeline(entries, flags.Arg(0))
+	slog.Debug("read scan history", slog.String("query", flags.Arg(0)), slog.Int("edges", len(timeline)))
+
+	marshal, err := json.MarshalIndent(timeline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	_, err = fmt.Fprintln(out, string(marshal))
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
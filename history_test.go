@@ -0,0 +1,485 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	historyRole     = "arn:aws:iam::0123456789:role/vendor"
+	historyVendor   = "arn:aws:iam::210987654321:root"
+	historyDeployer = "arn:aws:iam::210987654321:role/deployer"
+)
+
+// historyDay returns midnight UTC of the given day of January 2025.
+func historyDay(day int) time.Time {
+	return time.Date(2025, time.January, day, 0, 0, 0, 0, time.UTC)
+}
+
+// historyTime returns a pointer to midnight UTC of the given day of January 2025.
+func historyTime(day int) *time.Time {
+	value := historyDay(day)
+
+	return &value
+}
+
+// recordRuns records a scan per day in a new history store, finding the given principals of the vendor role.
+func recordRuns(t *testing.T, retention time.Duration, runs map[int][]string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	for day := range 32 {
+		principals, ok := runs[day]
+		if !ok {
+			continue
+		}
+
+		err := writeHistory(path, map[string][]string{historyRole: principals}, retention, historyDay(day))
+		if err != nil {
+			t.Fatalf("writeHistory() unexpected error: %v", err)
+		}
+	}
+
+	return path
+}
+
+func mustReadHistory(t *testing.T, path string) []historyEntry {
+	t.Helper()
+
+	entries, _, err := loadHistory(path)
+	if err != nil {
+		t.Fatalf("loadHistory() unexpected error: %v", err)
+	}
+
+	return entries
+}
+
+func TestWriteHistory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		runs      map[int][]string
+		want      []HistoryEdge
+	}{
+		{
+			name:      "edge added, removed and added again",
+			retention: 0,
+			runs: map[int][]string{
+				1: {historyVendor},
+				2: {historyVendor, historyDeployer},
+				3: {historyVendor, historyDeployer},
+				4: {historyVendor},
+				5: {historyVendor, historyDeployer},
+			},
+			want: []HistoryEdge{
+				{
+					Principal: historyVendor,
+					Role:      historyRole,
+					FirstSeen: historyDay(1),
+					LastSeen:  historyDay(5),
+					RemovedAt: nil,
+				},
+				{
+					Principal: historyDeployer,
+					Role:      historyRole,
+					FirstSeen: historyDay(2),
+					LastSeen:  historyDay(3),
+					RemovedAt: historyTime(4),
+				},
+				{
+					Principal: historyDeployer,
+					Role:      historyRole,
+					FirstSeen: historyDay(5),
+					LastSeen:  historyDay(5),
+					RemovedAt: nil,
+				},
+			},
+		},
+		{
+			name:      "retention forgets removed edges but not the first seen of trusted ones",
+			retention: 7 * 24 * time.Hour,
+			runs: map[int][]string{
+				1:  {historyVendor, historyDeployer},
+				2:  {historyVendor},
+				20: {historyVendor},
+				21: {},
+			},
+			want: []HistoryEdge{
+				{
+					Principal: historyVendor,
+					Role:      historyRole,
+					FirstSeen: historyDay(1),
+					LastSeen:  historyDay(20),
+					RemovedAt: historyTime(21),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := recordRuns(t, tt.retention, tt.runs)
+
+			got := historyEdges(mustReadHistory(t, path))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historyEdges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteHistory_appends(t *testing.T) {
+	t.Parallel()
+
+	path := recordRuns(t, 0, map[int][]string{1: {historyVendor}, 2: {historyVendor}, 3: {historyDeployer}})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read history store: %v", err)
+	}
+
+	want := `{"version":1}
+{"time":"2025-01-01T00:00:00Z","change":"scan"}
+{"time":"2025-01-01T00:00:00Z","change":"added","principal":"arn:aws:iam::210987654321:root",` +
+		`"role":"arn:aws:iam::0123456789:role/vendor"}
+{"time":"2025-01-02T00:00:00Z","change":"scan"}
+{"time":"2025-01-03T00:00:00Z","change":"scan"}
+{"time":"2025-01-03T00:00:00Z","change":"added","principal":"arn:aws:iam::210987654321:role/deployer",` +
+		`"role":"arn:aws:iam::0123456789:role/vendor"}
+{"time":"2025-01-03T00:00:00Z","change":"removed","principal":"arn:aws:iam::210987654321:root",` +
+		`"role":"arn:aws:iam::0123456789:role/vendor"}
+`
+	if string(data) != want {
+		t.Errorf("history store = %s, want %s", data, want)
+	}
+}
+
+func Test_readHistory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		input        string
+		want         []HistoryEdge
+		wantMigrated bool
+		wantErr      error
+	}{
+		{
+			name: "current version",
+			input: `{"version":1}
+{"time":"2025-01-01T00:00:00Z","change":"scan"}
+{"time":"2025-01-01T00:00:00Z","change":"added","principal":"210987654321","role":"` + historyRole + `"}
+{"time":"2025-01-02T00:00:00Z","change":"scan"}
+`,
+			want: []HistoryEdge{
+				{
+					Principal: "210987654321",
+					Role:      historyRole,
+					FirstSeen: historyDay(1),
+					LastSeen:  historyDay(2),
+					RemovedAt: nil,
+				},
+			},
+			wantMigrated: false,
+			wantErr:      nil,
+		},
+		{
+			name: "version 0 without scans",
+			input: `{"time":"2025-01-03T00:00:00Z","change":"removed","principal":"210987654321","role":"` + historyRole + `"}
+{"time":"2025-01-01T00:00:00Z","change":"added","principal":"210987654321","role":"` + historyRole + `"}
+`,
+			want: []HistoryEdge{
+				{
+					Principal: "210987654321",
+					Role:      historyRole,
+					FirstSeen: historyDay(1),
+					LastSeen:  historyDay(1),
+					RemovedAt: historyTime(3),
+				},
+			},
+			wantMigrated: true,
+			wantErr:      nil,
+		},
+		{
+			name:         "newer version",
+			input:        `{"version":2}`,
+			want:         nil,
+			wantMigrated: false,
+			wantErr:      errHistoryVersion,
+		},
+		{
+			name:         "not JSON lines",
+			input:        "principal,role\n",
+			want:         nil,
+			wantMigrated: false,
+			wantErr:      errInvalidHistory,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			entries, migrated, err := readHistory(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if migrated != tt.wantMigrated {
+				t.Errorf("readHistory() migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+
+			if got := historyEdges(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historyEdges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeHistory_migrates(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	err := os.WriteFile(
+		path,
+		[]byte(`{"time":"2025-01-01T00:00:00Z","change":"added","principal":"210987654321","role":"`+historyRole+`"}`),
+		0o600,
+	)
+	if err != nil {
+		t.Fatalf("failed to write history store: %v", err)
+	}
+
+	err = writeHistory(path, map[string][]string{historyRole: {"210987654321"}}, 0, historyDay(2))
+	if err != nil {
+		t.Fatalf("writeHistory() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read history store: %v", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(`{"version":1}`+"\n")) {
+		t.Errorf("writeHistory() did not rewrite the store with a version header: %s", data)
+	}
+
+	want := []HistoryEdge{
+		{
+			Principal: "210987654321",
+			Role:      historyRole,
+			FirstSeen: historyDay(1),
+			LastSeen:  historyDay(2),
+			RemovedAt: nil,
+		},
+	}
+	if got := historyEdges(mustReadHistory(t, path)); !reflect.DeepEqual(got, want) {
+		t.Errorf("historyEdges() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_runHistoryCommand(t *testing.T) {
+	t.Parallel()
+
+	path := recordRuns(t, 0, map[int][]string{1: {historyVendor}, 2: {historyVendor, historyDeployer}, 3: {}})
+
+	tests := []struct {
+		name      string
+		args      []string
+		path      string
+		wantEdges int
+		wantErr   error
+	}{
+		{
+			name:      "by role",
+			args:      []string{historyRole},
+			path:      path,
+			wantEdges: 2,
+			wantErr:   nil,
+		},
+		{
+			name:      "by principal",
+			args:      []string{"-history-db", path, historyDeployer},
+			path:      "",
+			wantEdges: 1,
+			wantErr:   nil,
+		},
+		{
+			name:      "unknown",
+			args:      []string{"arn:aws:iam::0123456789:role/admin"},
+			path:      path,
+			wantEdges: 0,
+			wantErr:   nil,
+		},
+		{
+			name:      "without store",
+			args:      []string{historyRole},
+			path:      "",
+			wantEdges: 0,
+			wantErr:   errHistoryArgs,
+		},
+		{
+			name:      "without query",
+			args:      nil,
+			path:      path,
+			wantEdges: 0,
+			wantErr:   errHistoryArgs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+
+			err := runHistoryCommand(tt.args, &out, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runHistoryCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := strings.Count(out.String(), `"firstSeen"`); got != tt.wantEdges {
+				t.Errorf("runHistoryCommand() edges = %d, want %d: %s", got, tt.wantEdges, out.String())
+			}
+		})
+	}
+}
+
+func TestApp_recordHistory(t *testing.T) {
+	t.Parallel()
+
+	const ecsRole = "arn:aws:iam::0123456789:role/ecs"
+
+	trusts := map[string]roleTrust{
+		historyRole: mustDecodeTrust(t, historyRole, fixtureCrossAccountTagSession),
+		ecsRole:     mustDecodeTrust(t, ecsRole, fixtureAWSServiceRoleForECS),
+	}
+	everyEdge := []string{
+		historyVendor + " " + historyRole,
+		"ecs.amazonaws.com " + ecsRole,
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(a *App)
+		skipped []string
+		want    []string
+	}{
+		{
+			name:    "full scan",
+			setup:   func(*App) {},
+			skipped: nil,
+			want:    everyEdge,
+		},
+		{
+			name: "focus and minimum counts are ignored",
+			setup: func(a *App) {
+				WithFocus(historyVendor, 1)(a)
+				WithMinPrincipals(2)(a)
+			},
+			skipped: nil,
+			want:    everyEdge,
+		},
+		{
+			name:    "interrupted scan",
+			setup:   func(a *App) { a.truncated.Store(true) },
+			skipped: nil,
+			want:    nil,
+		},
+		{
+			name:    "roles failed to decode",
+			setup:   func(*App) {},
+			skipped: []string{"arn:aws:iam::0123456789:role/broken"},
+			want:    nil,
+		},
+		{
+			name:    "role names",
+			setup:   WithRoleNames([]string{"vendor"}),
+			skipped: nil,
+			want:    nil,
+		},
+		{
+			name:    "path prefixes",
+			setup:   WithPathPrefixes([]string{"/team-a/"}),
+			skipped: nil,
+			want:    nil,
+		},
+		{
+			name:    "role accounts",
+			setup:   func(a *App) { a.excludeAccounts = []string{"210987654321"} },
+			skipped: nil,
+			want:    nil,
+		},
+		{
+			name:    "role age",
+			setup:   func(a *App) { a.roleMinAge = time.Hour },
+			skipped: nil,
+			want:    nil,
+		},
+		{
+			name:    "only unbounded roles",
+			setup:   func(a *App) { a.onlyUnbounded = true },
+			skipped: nil,
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			app := &App{logger: slog.New(slog.NewTextHandler(&buf, nil))}
+			tt.setup(app)
+			app.recordRoles(trusts, tt.skipped)
+
+			path := filepath.Join(t.TempDir(), "history.jsonl")
+
+			err := app.recordHistory(path, 0, historyDay(1))
+			if err != nil {
+				t.Fatalf("recordHistory() unexpected error: %v", err)
+			}
+
+			if tt.want == nil {
+				if _, errStat := os.Stat(path); !errors.Is(errStat, os.ErrNotExist) {
+					t.Errorf("recordHistory() recorded a partial scan")
+				}
+
+				if !strings.Contains(buf.String(), "not recording history") {
+					t.Errorf("recordHistory() did not log why the scan was not recorded, got %q", buf.String())
+				}
+
+				return
+			}
+
+			got := make([]string, 0)
+			for _, edge := range historyEdges(mustReadHistory(t, path)) {
+				got = append(got, edge.Principal+" "+edge.Role)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recordHistory() recorded %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
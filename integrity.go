@@ -0,0 +1,199 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	digestSuffix    = ".sha256"
+	signatureSuffix = ".sig"
+)
+
+var (
+	errIntegrityOutput = errors.New(
+		"-digest and -sign-key require -output and cannot be combined with -output-per-account, " +
+			"-split-output-by-principal-type or -accounts",
+	)
+	errInvalidKey        = errors.New("expected a PEM-encoded Ed25519 key")
+	errDigestMismatch    = errors.New("output does not match its digest")
+	errSignatureMismatch = errors.New("signature does not match the output")
+	errMissingVerifyFile = errors.New("verify requires the path of an output file")
+)
+
+// outputDigest returns the sidecar digest of the output in the sha256sum format, so it can also be checked with
+// sha256sum -c from the output directory.
+func outputDigest(path string, data []byte) []byte {
+	sum := sha256.Sum256(data)
+
+	return []byte(hex.EncodeToString(sum[:]) + "  " + filepath.Base(path) + "\n")
+}
+
+// signOutput returns the detached, base64-encoded Ed25519 signature of the output.
+func signOutput(key ed25519.PrivateKey, data []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n")
+}
+
+// loadPEMBlock reads the single PEM block of the given type from a file.
+func loadPEMBlock(path string, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%w: %s has no %s block", errInvalidKey, path, blockType)
+	}
+
+	return block.Bytes, nil
+}
+
+// loadEd25519PrivateKey reads a PKCS #8 Ed25519 private key, as written by openssl genpkey -algorithm ed25519.
+func loadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := loadPEMBlock(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidKey, err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s holds a %T", errInvalidKey, path, key)
+	}
+
+	return privateKey, nil
+}
+
+// loadEd25519PublicKey reads a PKIX Ed25519 public key, as written by openssl pkey -pubout.
+func loadEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := loadPEMBlock(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidKey, err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s holds a %T", errInvalidKey, path, key)
+	}
+
+	return publicKey, nil
+}
+
+// writeIntegrity writes the digest of the output next to it, and its signature when a key is given.
+func writeIntegrity(path string, data []byte, key ed25519.PrivateKey) error {
+	err := os.WriteFile(path+digestSuffix, outputDigest(path, data), 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to write digest: %w", err)
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	err = os.WriteFile(path+signatureSuffix, signOutput(key, data), 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	return nil
+}
+
+// verifyIntegrity checks the output against its digest and, when a public key is given, its signature.
+func verifyIntegrity(path string, key ed25519.PublicKey) error {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to read output: %w", err)
+	}
+
+	digest, err := os.ReadFile(path + digestSuffix) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to read digest: %w", err)
+	}
+
+	wantSum, _, _ := strings.Cut(string(digest), " ")
+	gotSum, _, _ := strings.Cut(string(outputDigest(path, data)), " ")
+
+	if wantSum != gotSum {
+		return fmt.Errorf("%w: %s", errDigestMismatch, path)
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	encoded, err := os.ReadFile(path + signatureSuffix) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil || !ed25519.Verify(key, data, signature) {
+		return fmt.Errorf("%w: %s", errSignatureMismatch, path)
+	}
+
+	return nil
+}
+
+// runVerifyCommand checks the digest, and optionally the signature, of an output file written with -digest or
+// -sign-key.
+func runVerifyCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	keyPath := flags.String("key", "", "path to the PEM-encoded Ed25519 public key to check the signature with")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse verify flags: %w", err)
+	}
+
+	if flags.NArg() != 1 {
+		return errMissingVerifyFile
+	}
+
+	var key ed25519.PublicKey
+	if *keyPath != "" {
+		key, err = loadEd25519PublicKey(*keyPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = verifyIntegrity(flags.Arg(0), key)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("verified output", slog.String("path", flags.Arg(0)), slog.Bool("signature", key != nil))
+
+	_, err = fmt.Fprintln(out, flags.Arg(0)+": OK")
+	if err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeKeyPair writes a new Ed25519 key pair as PEM files to dir, returning their paths.
+func writeKeyPair(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() unexpected error: %v", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() unexpected error: %v", err)
+	}
+
+	privatePath := filepath.Join(dir, name+".pem")
+	publicPath := filepath.Join(dir, name+".pub.pem")
+	writeFile(t, privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	writeFile(t, publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+
+	return privatePath, publicPath
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func Test_loadEd25519PrivateKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	privatePath, publicPath := writeKeyPair(t, dir, "signer")
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	ecdsaDER, err := x509.MarshalPKCS8PrivateKey(ecdsaKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() unexpected error: %v", err)
+	}
+
+	ecdsaPath := filepath.Join(dir, "ecdsa.pem")
+	writeFile(t, ecdsaPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecdsaDER}))
+
+	garbagePath := filepath.Join(dir, "garbage.pem")
+	writeFile(t, garbagePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}))
+
+	notPEMPath := filepath.Join(dir, "key.txt")
+	writeFile(t, notPEMPath, []byte("not a key"))
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{name: "Ed25519 private key", path: privatePath, wantErr: nil},
+		{name: "public key instead", path: publicPath, wantErr: errInvalidKey},
+		{name: "ECDSA private key", path: ecdsaPath, wantErr: errInvalidKey},
+		{name: "corrupt key", path: garbagePath, wantErr: errInvalidKey},
+		{name: "not PEM", path: notPEMPath, wantErr: errInvalidKey},
+		{name: "missing file", path: filepath.Join(dir, "missing.pem"), wantErr: os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := loadEd25519PrivateKey(tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("loadEd25519PrivateKey() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_verifyIntegrity(t *testing.T) {
+	t.Parallel()
+
+	keys := t.TempDir()
+	privatePath, publicPath := writeKeyPair(t, keys, "signer")
+	_, otherPublicPath := writeKeyPair(t, keys, "other")
+
+	privateKey, err := loadEd25519PrivateKey(privatePath)
+	if err != nil {
+		t.Fatalf("loadEd25519PrivateKey() unexpected error: %v", err)
+	}
+
+	output := []byte(`{"ecs.amazonaws.com": ["arn:aws:iam::123456789012:role/ecs"]}`)
+
+	tests := []struct {
+		name      string
+		sign      bool
+		tamper    func(t *testing.T, path string)
+		publicKey string
+		wantErr   error
+	}{
+		{name: "digest only", sign: false, tamper: nil, publicKey: "", wantErr: nil},
+		{name: "digest and signature", sign: true, tamper: nil, publicKey: publicPath, wantErr: nil},
+		{
+			name: "tampered output",
+			sign: true,
+			tamper: func(t *testing.T, path string) {
+				t.Helper()
+				writeFile(t, path, bytes.Replace(output, []byte("ecs"), []byte("ec2"), 1))
+			},
+			publicKey: publicPath,
+			wantErr:   errDigestMismatch,
+		},
+		{
+			name: "tampered output and digest",
+			sign: true,
+			tamper: func(t *testing.T, path string) {
+				t.Helper()
+				tampered := bytes.Replace(output, []byte("ecs"), []byte("ec2"), 1)
+				writeFile(t, path, tampered)
+				writeFile(t, path+digestSuffix, outputDigest(path, tampered))
+			},
+			publicKey: publicPath,
+			wantErr:   errSignatureMismatch,
+		},
+		{
+			name: "corrupt signature",
+			sign: true,
+			tamper: func(t *testing.T, path string) {
+				t.Helper()
+				writeFile(t, path+signatureSuffix, []byte("not base64"))
+			},
+			publicKey: publicPath,
+			wantErr:   errSignatureMismatch,
+		},
+		{name: "other public key", sign: true, tamper: nil, publicKey: otherPublicPath, wantErr: errSignatureMismatch},
+		{name: "missing signature", sign: false, tamper: nil, publicKey: publicPath, wantErr: os.ErrNotExist},
+		{
+			name: "missing digest",
+			sign: false,
+			tamper: func(t *testing.T, path string) {
+				t.Helper()
+
+				err := os.Remove(path + digestSuffix)
+				if err != nil {
+					t.Fatalf("failed to remove digest: %v", err)
+				}
+			},
+			publicKey: "",
+			wantErr:   os.ErrNotExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "trust.json")
+			writeFile(t, path, output)
+
+			var key ed25519.PrivateKey
+			if tt.sign {
+				key = privateKey
+			}
+
+			err := writeIntegrity(path, output, key)
+			if err != nil {
+				t.Fatalf("writeIntegrity() unexpected error: %v", err)
+			}
+
+			if tt.tamper != nil {
+				tt.tamper(t, path)
+			}
+
+			args := []string{path}
+			if tt.publicKey != "" {
+				args = []string{"-key", tt.publicKey, path}
+			}
+
+			var out bytes.Buffer
+
+			err = runVerifyCommand(args, &out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runVerifyCommand() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && out.String() != path+": OK\n" {
+				t.Errorf("runVerifyCommand() output = %q", out.String())
+			}
+		})
+	}
+}
+
+func Test_outputDigest(t *testing.T) {
+	t.Parallel()
+
+	got := string(outputDigest("/tmp/reports/trust.json", []byte("{}")))
+	want := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a  trust.json\n"
+
+	if got != want {
+		t.Errorf("outputDigest() = %q, want %q", got, want)
+	}
+}
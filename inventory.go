@@ -0,0 +1,111 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+const (
+	inventorySchema        = "veil-inventory"
+	inventorySchemaVersion = 1
+
+	componentTypeRole      = "iam-role"
+	componentTypePrincipal = "trust-principal"
+)
+
+// Inventory is the scan reframed for asset inventories and CMDBs, in the manner of an SBOM: every role and principal
+// is a component, and each role depends on the principals its trust policy allows to assume it.
+type Inventory struct {
+	// Schema and SchemaVersion identify the document shape, veil-inventory version 1.
+	Schema        string `json:"schema"`
+	SchemaVersion int    `json:"schemaVersion"`
+	// Components lists the roles followed by the principals, each sorted by ID. IDs are unique.
+	Components []InventoryComponent `json:"components"`
+	// Relationships lists, for each role, the principals it depends on.
+	Relationships []InventoryRelationship `json:"relationships"`
+}
+
+// InventoryComponent is a role or a principal, identified by its ARN, or its name for principals without one.
+type InventoryComponent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Name is the role name, for roles only.
+	Name string `json:"name,omitempty"`
+	// Account is the account owning the role or principal, when it has one.
+	Account string `json:"account,omitempty"`
+	// PrincipalType is the type of a principal, see ClassifyPrincipal.
+	PrincipalType string `json:"principalType,omitempty"`
+}
+
+// InventoryRelationship records that the component Ref, a role, depends on the components DependsOn, the principals
+// trusted to assume it.
+type InventoryRelationship struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// buildInventory renders the given role to principals mapping as an inventory document.
+func buildInventory(roles map[string][]string) Inventory {
+	output := Inventory{
+		Schema:        inventorySchema,
+		SchemaVersion: inventorySchemaVersion,
+		Components:    make([]InventoryComponent, 0),
+		Relationships: make([]InventoryRelationship, 0),
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		output.Components = append(output.Components, InventoryComponent{
+			ID:            role,
+			Type:          componentTypeRole,
+			Name:          roleNameFromARN(role),
+			Account:       accountFromARN(role),
+			PrincipalType: "",
+		})
+
+		dependsOn := slices.Sorted(slices.Values(roles[role]))
+		output.Relationships = append(output.Relationships, InventoryRelationship{
+			Ref:       role,
+			DependsOn: slices.Compact(dependsOn),
+		})
+	}
+
+	for _, principal := range slices.Sorted(maps.Keys(mapFlip(roles))) {
+		// A principal that is itself a scanned role is already listed as a role.
+		if _, ok := roles[principal]; ok {
+			continue
+		}
+
+		output.Components = append(output.Components, InventoryComponent{
+			ID:            principal,
+			Type:          componentTypePrincipal,
+			Name:          "",
+			Account:       accountFromARN(principal),
+			PrincipalType: ClassifyPrincipal(principal),
+		})
+	}
+
+	return output
+}
+
+func (a *App) runInventory(ctx context.Context) ([]byte, error) {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := buildInventory(a.redactRoles(roles))
+	a.logger.Debug("built inventory of IAM roles", slog.Int("components", len(output.Components)))
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_buildInventory(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+		"arn:aws:iam::0123456789:role/ecs":    {"ecs.amazonaws.com", "ecs.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/chain":  {"arn:aws:iam::0123456789:role/ecs", "210987654321"},
+	}
+
+	want := Inventory{
+		Schema:        inventorySchema,
+		SchemaVersion: inventorySchemaVersion,
+		Components: []InventoryComponent{
+			{
+				ID:            "arn:aws:iam::0123456789:role/chain",
+				Type:          componentTypeRole,
+				Name:          "chain",
+				Account:       "0123456789",
+				PrincipalType: "",
+			},
+			{
+				ID:            "arn:aws:iam::0123456789:role/ecs",
+				Type:          componentTypeRole,
+				Name:          "ecs",
+				Account:       "0123456789",
+				PrincipalType: "",
+			},
+			{
+				ID:            "arn:aws:iam::0123456789:role/vendor",
+				Type:          componentTypeRole,
+				Name:          "vendor",
+				Account:       "0123456789",
+				PrincipalType: "",
+			},
+			{
+				ID:            "210987654321",
+				Type:          componentTypePrincipal,
+				Name:          "",
+				Account:       "",
+				PrincipalType: principalTypeAWS,
+			},
+			{
+				ID:            "arn:aws:iam::210987654321:root",
+				Type:          componentTypePrincipal,
+				Name:          "",
+				Account:       "210987654321",
+				PrincipalType: principalTypeAWS,
+			},
+			{
+				ID:            "ecs.amazonaws.com",
+				Type:          componentTypePrincipal,
+				Name:          "",
+				Account:       "",
+				PrincipalType: principalTypeService,
+			},
+		},
+		Relationships: []InventoryRelationship{
+			{
+				Ref:       "arn:aws:iam::0123456789:role/chain",
+				DependsOn: []string{"210987654321", "arn:aws:iam::0123456789:role/ecs"},
+			},
+			{
+				Ref:       "arn:aws:iam::0123456789:role/ecs",
+				DependsOn: []string{"ecs.amazonaws.com"},
+			},
+			{
+				Ref:       "arn:aws:iam::0123456789:role/vendor",
+				DependsOn: []string{"arn:aws:iam::210987654321:root"},
+			},
+		},
+	}
+
+	if got := buildInventory(roles); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildInventory() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	labelCriticality     = "criticality"
+	labelCriticalityHigh = "high"
+
+	labelColumns = 3
+)
+
+var errInvalidLabels = errors.New("invalid labels file")
+
+// labelRule attaches the label Key=Value to the roles and principals matching its glob pattern. A bare account ID
+// matches everything in that account.
+type labelRule struct {
+	Pattern string `json:"pattern"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+
+	// origin locates the rule in the labels file for error and unused-row messages, e.g. labels.csv:3.
+	origin      string
+	match       *regexp.Regexp
+	specificity int
+}
+
+// labelSet matches roles and principals against label rules.
+type labelSet struct {
+	rules []labelRule
+}
+
+// loadLabels reads label rules from a JSON file, i.e. a list of objects with pattern, key and value fields, or
+// otherwise from a CSV file of pattern, key and value columns with an optional header row.
+func loadLabels(path string) (*labelSet, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return parseJSONLabels(filepath.Base(path), data)
+	}
+
+	return parseCSVLabels(filepath.Base(path), data)
+}
+
+func parseJSONLabels(name string, data []byte) (*labelSet, error) {
+	var rules []labelRule
+
+	err := json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errInvalidLabels, name, err)
+	}
+
+	for index := range rules {
+		rules[index].origin = name + "[" + strconv.Itoa(index) + "]"
+	}
+
+	return newLabelSet(rules)
+}
+
+func parseCSVLabels(name string, data []byte) (*labelSet, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = labelColumns
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	rules := make([]labelRule, 0)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", errInvalidLabels, name, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(rules) == 0 && strings.EqualFold(strings.Join(record, ","), "pattern,key,value") {
+			continue
+		}
+
+		rules = append(rules, labelRule{
+			Pattern:     record[0],
+			Key:         record[1],
+			Value:       record[2],
+			origin:      name + ":" + strconv.Itoa(line),
+			match:       nil,
+			specificity: 0,
+		})
+	}
+
+	return newLabelSet(rules)
+}
+
+// newLabelSet validates and compiles the rules. The specificity of a rule is the number of literal characters of its
+// pattern, after expanding a bare account ID to the ARNs of that account.
+func newLabelSet(rules []labelRule) (*labelSet, error) {
+	for index, rule := range rules {
+		if rule.Pattern == "" || rule.Key == "" {
+			return nil, fmt.Errorf("%w: %s: pattern and key must not be empty", errInvalidLabels, rule.origin)
+		}
+
+		pattern := rule.Pattern
+		if accountIDRegex.MatchString(pattern) {
+			pattern = "arn:*:*::" + pattern + ":*"
+		}
+
+		rules[index].match = globRegexp(pattern)
+		rules[index].specificity = len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?")
+	}
+
+	return &labelSet{rules: rules}, nil
+}
+
+// labelsOf returns the labels of the given role or principal. When several rules set the same key, the most specific
+// one wins, and the one listed last if they are equally specific.
+func (l *labelSet) labelsOf(name string) map[string]string {
+	if l == nil {
+		return nil
+	}
+
+	name = labelTarget(name)
+	winners := make(map[string]int)
+
+	for index, rule := range l.rules {
+		if !rule.match.MatchString(name) {
+			continue
+		}
+
+		if winner, ok := winners[rule.Key]; ok && l.rules[winner].specificity > rule.specificity {
+			continue
+		}
+
+		winners[rule.Key] = index
+	}
+
+	if len(winners) == 0 {
+		return nil
+	}
+
+	output := make(map[string]string, len(winners))
+	for key, index := range winners {
+		output[key] = l.rules[index].Value
+	}
+
+	return output
+}
+
+// labelTarget returns the name matched against label patterns: the principal without any via-condition suffix, with
+// a bare account ID expanded to its account root.
+func labelTarget(name string) string {
+	return normalisePrincipal(strings.TrimSuffix(name, viaConditionSuffix))
+}
+
+// unused returns the rules matching none of the given roles and principals, in file order.
+func (l *labelSet) unused(names []string) []labelRule {
+	if l == nil {
+		return nil
+	}
+
+	output := make([]labelRule, 0)
+
+	for _, rule := range l.rules {
+		if !slices.ContainsFunc(names, func(name string) bool {
+			return rule.match.MatchString(labelTarget(name))
+		}) {
+			output = append(output, rule)
+		}
+	}
+
+	return output
+}
+
+// principalLabels returns the labels of each of the given principals having any, or nil if none has.
+func (l *labelSet) principalLabels(principals []string) map[string]map[string]string {
+	var output map[string]map[string]string
+
+	for _, principal := range principals {
+		labels := l.labelsOf(principal)
+		if labels == nil {
+			continue
+		}
+
+		if output == nil {
+			output = make(map[string]map[string]string)
+		}
+
+		output[principal] = labels
+	}
+
+	return output
+}
+
+// raiseLabelledFindings raises the findings about roles or principals labelled criticality=high to high severity.
+func raiseLabelledFindings(labels *labelSet, findings []Finding) {
+	if labels == nil {
+		return
+	}
+
+	for index, finding := range findings {
+		if finding.Severity == severityHigh {
+			continue
+		}
+
+		critical := labels.labelsOf(finding.Role)[labelCriticality] == labelCriticalityHigh
+		if finding.Principal != "" && labels.labelsOf(finding.Principal)[labelCriticality] == labelCriticalityHigh {
+			critical = true
+		}
+
+		if critical {
+			findings[index].Severity = severityHigh
+			findings[index].Message += " (raised from " + finding.Severity + ", labelled " + labelCriticality + "=" +
+				labelCriticalityHigh + ")"
+		}
+	}
+}
+
+// reportUnusedLabels logs the label rules matching none of the roles and principals of the last scan, which usually
+// point at a stale inventory or a mistyped pattern.
+func (a *App) reportUnusedLabels() {
+	roles := a.scannedRoles()
+
+	names := make([]string, 0, len(roles))
+	for role, principals := range roles {
+		names = append(names, role)
+		names = append(names, principals...)
+	}
+
+	for _, rule := range a.labels.unused(names) {
+		a.logger.Warn(
+			"label rule matched no role or principal",
+			slog.String("rule", rule.origin),
+			slog.String("pattern", rule.Pattern),
+			slog.String("label", rule.Key+"="+rule.Value),
+		)
+	}
+}
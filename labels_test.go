@@ -0,0 +1,170 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func mustParseLabels(t *testing.T, data string) *labelSet {
+	t.Helper()
+
+	labels, err := parseCSVLabels("labels.csv", []byte(data))
+	if err != nil {
+		t.Fatalf("parseCSVLabels() unexpected error: %v", err)
+	}
+
+	return labels
+}
+
+func Test_labelSet_labelsOf(t *testing.T) {
+	t.Parallel()
+
+	labels := mustParseLabels(t, `pattern,key,value
+# Account-wide labels come first.
+210987654321,owner,vendor-management
+210987654321,criticality,medium
+arn:aws:iam::*:role/deploy*,criticality,high
+arn:aws:iam::*:role/deploy-sandbox,criticality,low
+*,env,unknown
+*,env,prod
+ec2.amazonaws.com,owner,platform
+`)
+
+	tests := []struct {
+		name string
+		arn  string
+		want map[string]string
+	}{
+		{
+			name: "account ID matches the account root",
+			arn:  "arn:aws:iam::210987654321:root",
+			want: map[string]string{"owner": "vendor-management", "criticality": "medium", "env": "prod"},
+		},
+		{
+			name: "bare account ID principal",
+			arn:  "210987654321",
+			want: map[string]string{"owner": "vendor-management", "criticality": "medium", "env": "prod"},
+		},
+		{
+			name: "more literal characters win over the account",
+			arn:  "arn:aws:iam::210987654321:role/deploy-app",
+			want: map[string]string{"owner": "vendor-management", "criticality": "high", "env": "prod"},
+		},
+		{
+			name: "most specific pattern wins regardless of order",
+			arn:  "arn:aws:iam::210987654321:role/deploy-sandbox",
+			want: map[string]string{"owner": "vendor-management", "criticality": "low", "env": "prod"},
+		},
+		{
+			name: "service principal",
+			arn:  "ec2.amazonaws.com",
+			want: map[string]string{"owner": "platform", "env": "prod"},
+		},
+		{
+			name: "via condition",
+			arn:  "arn:aws:iam::210987654321:root" + viaConditionSuffix,
+			want: map[string]string{"owner": "vendor-management", "criticality": "medium", "env": "prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := labels.labelsOf(tt.arn); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labelsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (*labelSet)(nil).labelsOf("ec2.amazonaws.com"); got != nil {
+		t.Errorf("labelsOf() without labels = %v, want nil", got)
+	}
+}
+
+func Test_labelSet_unused(t *testing.T) {
+	t.Parallel()
+
+	labels := mustParseLabels(t, `210987654321,owner,vendor-management
+arn:aws:iam::*:role/retired,owner,nobody
+lambda.amazonaws.com,owner,platform
+`)
+
+	got := make([]string, 0)
+	for _, rule := range labels.unused([]string{"arn:aws:iam::0123456789:role/deploy", "210987654321"}) {
+		got = append(got, rule.origin)
+	}
+
+	if want := []string{"labels.csv:2", "labels.csv:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unused() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseLabels(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		parse   func(string, []byte) (*labelSet, error)
+		data    string
+		want    int
+		wantErr error
+	}{
+		{name: "csv", parse: parseCSVLabels, data: "pattern,key,value\n*,env,prod\n", want: 1, wantErr: nil},
+		{name: "csv wrong column count", parse: parseCSVLabels, data: "*,env\n", want: 0, wantErr: errInvalidLabels},
+		{name: "csv empty key", parse: parseCSVLabels, data: "*,,prod\n", want: 0, wantErr: errInvalidLabels},
+		{
+			name:    "json",
+			parse:   parseJSONLabels,
+			data:    `[{"pattern": "*", "key": "env", "value": "prod"}]`,
+			want:    1,
+			wantErr: nil,
+		},
+		{name: "json not a list", parse: parseJSONLabels, data: `{"pattern": "*"}`, want: 0, wantErr: errInvalidLabels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.parse("labels", []byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parse() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && len(got.rules) != tt.want {
+				t.Errorf("parse() got %d rules, want %d", len(got.rules), tt.want)
+			}
+		})
+	}
+}
+
+func Test_raiseLabelledFindings(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/deploy": mustDecodeTrust(
+			t, "arn:aws:iam::0123456789:role/deploy", fixtureCrossAccountTagSession,
+		),
+	}
+	labels := mustParseLabels(t, "arn:aws:iam::0123456789:role/deploy,criticality,high\n")
+
+	unlabelled := runChecks(checkEnv{}, trusts)
+	labelled := runChecks(checkEnv{labels: labels}, trusts)
+
+	if len(unlabelled) == 0 || len(labelled) != len(unlabelled) {
+		t.Fatalf("runChecks() returned %d and %d findings", len(unlabelled), len(labelled))
+	}
+
+	for index, finding := range labelled {
+		if finding.Severity != severityHigh {
+			t.Errorf("runChecks() %s severity = %s, want %s", finding.Code, finding.Severity, severityHigh)
+		}
+
+		if unlabelled[index].Severity != severityHigh && finding.Message == unlabelled[index].Message {
+			t.Errorf("runChecks() %s message does not say it was raised: %s", finding.Code, finding.Message)
+		}
+	}
+}
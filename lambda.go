@@ -0,0 +1,263 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// lambdaUploadReserve is the part of the invocation time budget kept back for uploading the scan output.
+const lambdaUploadReserve = 5 * time.Second
+
+var (
+	errInvalidS3URI        = errors.New("output must be an S3 URI, e.g. s3://bucket/key")
+	errUnsupportedInLambda = errors.New("option is not supported in Lambda mode")
+)
+
+// lambdaUnsupportedOptions are the flags writing output the Lambda mode does not upload: local files and the scan
+// history, DynamoDB and per-account or per-principal-type outputs.
+var lambdaUnsupportedOptions = []string{ //nolint:gochecknoglobals
+	"digest",
+	"dynamodb-table",
+	"export-raw-policies",
+	"history-db",
+	"output-per-account",
+	"sign-key",
+	"split-output-by-principal-type",
+	"tee",
+}
+
+// ServiceS3 uploads objects via AWS SDK clients.
+type ServiceS3 interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// lambdaEvent is the payload of a Lambda invocation, mapping command line flag names to their values, e.g.
+// {"role-accounts": "210987654321", "findings": true, "output": "s3://bucket/findings.json"}.
+type lambdaEvent map[string]json.RawMessage
+
+// lambdaResponse summarises the scan uploaded by a Lambda invocation.
+type lambdaResponse struct {
+	Output    string    `json:"output"`
+	Bytes     int       `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+	Partial   bool      `json:"partial"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+// isLambda reports whether veil runs inside the AWS Lambda runtime.
+func isLambda() bool {
+	return os.Getenv("AWS_LAMBDA_RUNTIME_API") != ""
+}
+
+// flags applies the event options to a fresh set of scan flags, rejecting unknown ones and those the Lambda mode
+// cannot honour.
+func (e lambdaEvent) flags() (*cliFlags, error) {
+	fs := flag.NewFlagSet("lambda", flag.ContinueOnError)
+	flags := registerFlags(fs)
+
+	for _, name := range slices.Sorted(maps.Keys(e)) {
+		if slices.Contains(lambdaUnsupportedOptions, name) {
+			return nil, fmt.Errorf("%w: %q", errUnsupportedInLambda, name)
+		}
+
+		var value string
+
+		// Strings are unquoted, while booleans and numbers are passed on in their JSON form.
+		err := json.Unmarshal(e[name], &value)
+		if err != nil {
+			value = string(e[name])
+		}
+
+		err = fs.Set(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid option %q: %w", name, err)
+		}
+	}
+
+	return flags, nil
+}
+
+// parseS3URI splits an s3://bucket/key URI into its bucket and key.
+func parseS3URI(uri string) (string, string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", errInvalidS3URI, err)
+	}
+
+	key := strings.TrimPrefix(parsed.Path, "/")
+	if parsed.Scheme != "s3" || parsed.Host == "" || key == "" {
+		return "", "", fmt.Errorf("%w: %q", errInvalidS3URI, uri)
+	}
+
+	return parsed.Host, key, nil
+}
+
+// handleLambdaEvent scans with the options carried by the event and uploads the output to S3.
+func handleLambdaEvent(ctx context.Context, event lambdaEvent) (*lambdaResponse, error) {
+	flags, err := event.flags()
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = parseS3URI(*flags.outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	loader := flags.loader()
+
+	if *flags.configSnapshot != "" {
+		snapshot, errSnapshot := configSnapshotRoles(ctx, loader, *flags.configSnapshot)
+		if errSnapshot != nil {
+			return nil, errSnapshot
+		}
+
+		opts = append(opts, snapshot)
+	}
+
+	if *flags.snsTopicARN != "" {
+		notification, errSNS := snsNotification(ctx, loader, *flags.snsTopicARN)
+		if errSNS != nil {
+			return nil, errSNS
+		}
+
+		opts = append(opts, notification)
+	}
+
+	opts = append(opts, WithPartialResults())
+
+	client, err := NewApp(ctx, *flags.region, loader, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize app: %w", err)
+	}
+
+	// The output is uploaded with the function's own credentials, not those of a role assumed for scanning.
+	cfg, err := loader.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config, %w", err)
+	}
+
+	accountOpts := flags.scanOptions(loader, opts, client.logger)
+
+	return runLambdaScan(ctx, client, flags, accountOpts, s3.NewFromConfig(cfg), time.Now())
+}
+
+// runLambdaScan runs the scan selected by the flags within the invocation time budget, keeping back enough time to
+// upload the output. With -accounts, the accounts are scanned with accountOpts instead of the account of app. A scan
+// cut short by the deadline uploads the roles collected so far and is reported as partial.
+func runLambdaScan(
+	ctx context.Context,
+	app *App,
+	flags *cliFlags,
+	accountOpts ScanOptions,
+	uploader ServiceS3,
+	now time.Time,
+) (*lambdaResponse, error) {
+	bucket, key, err := parseS3URI(*flags.outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := flags.scan(app)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select output format: %w", err)
+	}
+
+	if *flags.accounts != "" {
+		scan = func(ctx context.Context) ([]byte, error) {
+			return app.scanAccountsWithin(ctx, splitList(*flags.accounts), accountOpts, *flags.dedupeAccounts)
+		}
+	}
+
+	scanCtx := ctx
+
+	deadline, ok := ctx.Deadline()
+	if ok {
+		var cancel context.CancelFunc
+
+		scanCtx, cancel = context.WithDeadline(ctx, deadline.Add(-lambdaUploadReserve))
+		defer cancel()
+	}
+
+	marshal, err := scan(scanCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan IAM roles: %w", err)
+	}
+
+	err = app.requireJSONOutput(marshal)
+	if err != nil {
+		return nil, err
+	}
+
+	marshal = app.redact(marshal)
+
+	_, err = uploader.PutObject(ctx, &s3.PutObjectInput{ //nolint:exhaustruct
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(marshal),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload output: %w", err)
+	}
+
+	output := &lambdaResponse{
+		Output:    *flags.outputPath,
+		Bytes:     len(marshal),
+		SHA256:    sha256Hex(marshal),
+		Partial:   app.truncated.Load(),
+		ScannedAt: now.UTC(),
+	}
+	app.logger.Info(
+		"uploaded scan output",
+		slog.String("output", output.Output),
+		slog.Int("bytes", output.Bytes),
+		slog.Bool("partial", output.Partial),
+	)
+
+	app.notifyScanComplete(ctx, output.Output)
+
+	return output, nil
+}
+
+// scanAccountsWithin scans the given accounts until the context is done, marshalling every account result, or the
+// roles grouped by name with dedupe. Accounts cut short by the deadline keep the roles collected so far, and those not
+// reached record the deadline as their error, marking the scan as truncated rather than failing it.
+func (a *App) scanAccountsWithin(
+	ctx context.Context,
+	accounts []string,
+	opts ScanOptions,
+	dedupe bool,
+) ([]byte, error) {
+	result, err := ScanAccounts(ctx, accounts, opts)
+	if ctx.Err() != nil {
+		a.truncated.Store(true)
+	}
+
+	if err != nil && (len(result.AccountResults) == 0 || !a.truncated.Load()) {
+		return nil, fmt.Errorf("failed to scan accounts: %w", err)
+	}
+
+	return a.marshalAccounts(result, dedupe)
+}
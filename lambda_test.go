@@ -0,0 +1,293 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/wakeful/veil/veiltest"
+)
+
+type MockServiceS3 struct {
+	uploads map[string][]byte
+	err     error
+}
+
+func (m *MockServiceS3) PutObject(
+	_ context.Context,
+	input *s3.PutObjectInput,
+	_ ...func(*s3.Options),
+) (*s3.PutObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m.uploads[aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key)] = body
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func Test_lambdaEvent_flags(t *testing.T) {
+	t.Parallel()
+
+	var event lambdaEvent
+
+	err := json.Unmarshal(
+		[]byte(`{"format": "count", "findings": true, "depth": 2, "role-accounts": "210987654321"}`),
+		&event,
+	)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+
+	flags, err := event.flags()
+	if err != nil {
+		t.Fatalf("flags() error = %v", err)
+	}
+
+	if *flags.format != formatCount || !*flags.findings || *flags.focusDepth != 2 ||
+		*flags.roleAccounts != "210987654321" {
+		t.Errorf(
+			"flags() = format %q, findings %v, depth %d, role-accounts %q",
+			*flags.format,
+			*flags.findings,
+			*flags.focusDepth,
+			*flags.roleAccounts,
+		)
+	}
+
+	if *flags.region != "eu-west-1" {
+		t.Errorf("flags() region = %q, want the default", *flags.region)
+	}
+
+	_, err = lambdaEvent{"no-such-flag": json.RawMessage(`true`)}.flags()
+	if err == nil {
+		t.Errorf("flags() with an unknown option, want error")
+	}
+
+	_, err = lambdaEvent{"history-db": json.RawMessage(`"history.jsonl"`)}.flags()
+	if !errors.Is(err, errUnsupportedInLambda) {
+		t.Errorf("flags() with an option writing a local file, error = %v, want %v", err, errUnsupportedInLambda)
+	}
+}
+
+func Test_parseS3URI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		uri        string
+		wantBucket string
+		wantKey    string
+		wantErr    bool
+	}{
+		{
+			name:       "bucket and key",
+			uri:        "s3://trust-reports/veil/trust.json",
+			wantBucket: "trust-reports",
+			wantKey:    "veil/trust.json",
+			wantErr:    false,
+		},
+		{
+			name:    "missing key",
+			uri:     "s3://trust-reports/",
+			wantErr: true,
+		},
+		{
+			name:    "local path",
+			uri:     "trust.json",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			uri:     "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bucket, key, err := parseS3URI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseS3URI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if bucket != tt.wantBucket || key != tt.wantKey {
+				t.Errorf("parseS3URI() = %q, %q, want %q, %q", bucket, key, tt.wantBucket, tt.wantKey)
+			}
+		})
+	}
+}
+
+func Test_runLambdaScan(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+		},
+	}
+
+	clients := map[string]ServiceIAM{
+		"111111111111": &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::111111111111:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		event       lambdaEvent
+		uploadErr   error
+		wantOutput  string
+		wantPartial bool
+		wantErr     bool
+	}{
+		{
+			name: "complete scan",
+			event: lambdaEvent{
+				"format": json.RawMessage(`"json-count"`),
+				"output": json.RawMessage(`"s3://trust-reports/count.json"`),
+			},
+			wantOutput:  `{"roles":1,"principals":1}`,
+			wantPartial: false,
+			wantErr:     false,
+		},
+		{
+			name: "deadline reached",
+			// A deadline within the upload reserve leaves no time to scan.
+			timeout: lambdaUploadReserve / 2,
+			event: lambdaEvent{
+				"format": json.RawMessage(`"json-count"`),
+				"output": json.RawMessage(`"s3://trust-reports/count.json"`),
+			},
+			wantOutput:  `{"roles":0,"principals":0}`,
+			wantPartial: true,
+			wantErr:     false,
+		},
+		{
+			name: "accounts",
+			event: lambdaEvent{
+				"accounts": json.RawMessage(`"111111111111"`),
+				"output":   json.RawMessage(`"s3://trust-reports/count.json"`),
+			},
+			wantOutput: `{
+  "accounts": {
+    "111111111111": {
+      "data": {
+        "arn:aws:iam::111111111111:role/vendor": [
+          "arn:aws:iam::210987654321:root"
+        ]
+      }
+    }
+  }
+}`,
+			wantPartial: false,
+			wantErr:     false,
+		},
+		{
+			name:    "accounts deadline reached",
+			timeout: lambdaUploadReserve / 2,
+			event: lambdaEvent{
+				"accounts": json.RawMessage(`"111111111111"`),
+				"output":   json.RawMessage(`"s3://trust-reports/count.json"`),
+			},
+			wantOutput: `{
+  "accounts": {
+    "111111111111": {}
+  }
+}`,
+			wantPartial: true,
+			wantErr:     false,
+		},
+		{
+			name: "every account failing",
+			event: lambdaEvent{
+				"accounts": json.RawMessage(`"222222222222"`),
+				"output":   json.RawMessage(`"s3://trust-reports/count.json"`),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing output",
+			event:   lambdaEvent{},
+			wantErr: true,
+		},
+		{
+			name: "failed upload",
+			event: lambdaEvent{
+				"output": json.RawMessage(`"s3://trust-reports/trust.json"`),
+			},
+			uploadErr: errors.New("access denied"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			flags, err := tt.event.flags()
+			if err != nil {
+				t.Fatalf("flags() error = %v", err)
+			}
+
+			app := &App{client: &veiltest.FakeIAM{Roles: roles}, partialResults: true, logger: slog.New(slog.DiscardHandler)}
+			uploader := &MockServiceS3{uploads: map[string][]byte{}, err: tt.uploadErr}
+
+			ctx := t.Context()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			accountOpts := flags.scanOptions(
+				staticConfigLoader{},
+				[]Option{withAccountClients(clients), WithPartialResults()},
+				slog.Default(),
+			)
+
+			got, err := runLambdaScan(ctx, app, flags, accountOpts, uploader, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runLambdaScan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			uploaded := string(uploader.uploads["trust-reports/count.json"])
+			if uploaded != tt.wantOutput {
+				t.Errorf("runLambdaScan() uploaded %s, want %s", uploaded, tt.wantOutput)
+			}
+
+			if got.Partial != tt.wantPartial || got.Bytes != len(tt.wantOutput) || !got.ScannedAt.Equal(now) {
+				t.Errorf("runLambdaScan() = %+v", got)
+			}
+		})
+	}
+}
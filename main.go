@@ -9,31 +9,39 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/ed25519"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"golang.org/x/sync/errgroup"
 )
 
 var version = "dev"
 
 func main() {
-	region := flag.String("region", "eu-west-1", "AWS region used for IAM communication")
-	showVersion := flag.Bool("version", false, "show version")
-	verbose := flag.Bool("verbose", false, "verbose log output")
+	flags := registerFlags(flag.CommandLine)
 	flag.Parse()
 
-	slog.SetDefault(getLogger(os.Stderr, verbose))
+	logger := getLogger(os.Stderr, flags.verbose)
+	slog.SetDefault(logger)
 
-	if *showVersion {
+	if *flags.showVersion {
 		slog.Info(
 			"veil",
 			slog.String("repo", "https://github.com/wakeful/veil"),
@@ -43,38 +51,409 @@ func main() {
 		return
 	}
 
+	if *flags.outputSchema {
+		schema, err := outputSchema(flags.schema())
+		if err != nil {
+			slog.Error("failed to print output schema", slog.String("error", err.Error()))
+
+			return
+		}
+
+		_, err = os.Stdout.Write(schema)
+		if err != nil {
+			slog.Error("failed to write output schema", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if *flags.lambda || isLambda() {
+		lambda.Start(handleLambdaEvent)
+
+		return
+	}
+
+	if flag.Arg(0) == "verify" {
+		err := runVerifyCommand(flag.Args()[1:], os.Stdout)
+		if err != nil {
+			slog.Error("failed to verify output", slog.String("error", err.Error()))
+			os.Exit(exitCodeFindings)
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "history" {
+		err := runHistoryCommand(flag.Args()[1:], os.Stdout, *flags.historyDB)
+		if err != nil {
+			slog.Error("failed to read scan history", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
 	ctx := context.Background()
 
-	client, err := NewApp(ctx, *region, &DefaultConfigLoader{})
+	opts, err := flags.options()
+	if err != nil {
+		slog.Error("failed to load configuration", slog.String("error", err.Error()))
+
+		return
+	}
+
+	var signingKey ed25519.PrivateKey
+	if *flags.signKey != "" {
+		signingKey, err = loadEd25519PrivateKey(*flags.signKey)
+		if err != nil {
+			slog.Error("failed to load signing key", slog.String("error", err.Error()))
+
+			return
+		}
+	}
+
+	if flag.Arg(0) == "analyze" {
+		failed, errAnalyze := runAnalyzeCommand(ctx, flag.Args()[1:], flags, append(opts, WithLogger(logger)), signingKey)
+		if errAnalyze != nil {
+			slog.Error("failed to analyse trust policies", slog.String("error", errAnalyze.Error()))
+
+			return
+		}
+
+		if failed {
+			os.Exit(exitCodeFindings)
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "compare" {
+		scanProfile := func(ctx context.Context, profile string) (map[string][]string, error) {
+			profileOpts := append(slices.Clone(opts), WithLogger(logger), WithProfile(profile))
+
+			app, errApp := NewApp(ctx, *flags.region, flags.loader(), profileOpts...)
+			if errApp != nil {
+				return nil, fmt.Errorf("failed to initialize app for profile %s: %w", profile, errApp)
+			}
+
+			return app.getRolesWithTrust(ctx)
+		}
+
+		err = runCompareCommand(ctx, logger, flag.Args()[1:], os.Stdout, scanProfile)
+		if err != nil {
+			slog.Error("failed to compare accounts", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if *flags.configSnapshot != "" {
+		snapshot, errSnapshot := configSnapshotRoles(ctx, flags.loader(), *flags.configSnapshot)
+		if errSnapshot != nil {
+			slog.Error("failed to load Config snapshot", slog.String("error", errSnapshot.Error()))
+
+			return
+		}
+
+		opts = append(opts, snapshot)
+	}
+
+	if *flags.snsTopicARN != "" {
+		notification, errSNS := snsNotification(ctx, flags.loader(), *flags.snsTopicARN)
+		if errSNS != nil {
+			slog.Error("failed to configure SNS notification", slog.String("error", errSNS.Error()))
+
+			return
+		}
+
+		opts = append(opts, notification)
+	}
+
+	if *flags.dynamoDBTable != "" {
+		output, errDynamoDB := dynamoDBOutput(ctx, flags.loader(), *flags.region, *flags.dynamoDBTable)
+		if errDynamoDB != nil {
+			slog.Error("failed to configure DynamoDB output", slog.String("error", errDynamoDB.Error()))
+
+			return
+		}
+
+		opts = append(opts, output)
+	}
+
+	client, err := NewApp(ctx, *flags.region, flags.loader(), append(opts, WithLogger(logger))...)
 	if err != nil {
 		slog.Error("failed to initialize app", slog.String("error", err.Error()))
 
 		return
 	}
 
-	marshal, err := client.runScanIAM(ctx)
+	if flag.Arg(0) == "serve" {
+		err = runServeCommand(ctx, client, flag.Args()[1:])
+		if err != nil {
+			slog.Error("failed to serve scan results", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "mcp" {
+		err = runMCPCommand(ctx, client, flag.Args()[1:], os.Stdin, os.Stdout)
+		if err != nil {
+			slog.Error("failed to serve MCP requests", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "fix" {
+		err = runFixCommand(ctx, client, flag.Args()[1:], os.Stdin, os.Stdout)
+		if err != nil {
+			slog.Error("failed to fix IAM roles", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if *flags.accounts != "" {
+		err = runAccounts(ctx, client, flags, opts)
+		if err != nil {
+			slog.Error("failed to scan accounts", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if *flags.outputPerAccount {
+		writer, errWriter := NewMultiAccountWriter(*flags.outputPath, *flags.region, time.Now())
+		if errWriter != nil {
+			slog.Error("failed to prepare output", slog.String("error", errWriter.Error()))
+
+			return
+		}
+
+		err = client.writePerAccount(ctx, writer)
+		if err != nil {
+			slog.Error("failed to write output per account", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if *flags.splitByPrincipal {
+		err = client.writePerPrincipalType(ctx, *flags.outputPath)
+		if err != nil {
+			slog.Error("failed to write output per principal type", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	if *flags.exportRawPolicies != "" {
+		err = client.exportRawPolicies(ctx, *flags.exportRawPolicies)
+		if err != nil {
+			slog.Error("failed to export trust policies", slog.String("error", err.Error()))
+		}
+
+		return
+	}
+
+	scan, err := flags.scan(client)
+	if err != nil {
+		slog.Error("failed to select output format", slog.String("error", err.Error()))
+
+		return
+	}
+
+	marshal, err := scan(ctx)
 	if err != nil {
 		slog.Error("failed to scan IAM roles", slog.String("error", err.Error()))
 
 		return
 	}
 
-	_, _ = os.Stdout.Write(marshal)
+	client.reportUnusedLabels()
+
+	err = client.requireJSONOutput(marshal)
+	if err != nil {
+		slog.Error("failed to rename output keys", slog.String("error", err.Error()))
+
+		return
+	}
+
+	marshal = client.redact(marshal)
+
+	err = writeOutput(marshal, os.Stdout, *flags.outputPath, *flags.tee)
+	if err != nil {
+		slog.Error("failed to write output", slog.String("error", err.Error()))
+
+		return
+	}
+
+	if *flags.digest || signingKey != nil {
+		err = writeIntegrity(*flags.outputPath, marshal, signingKey)
+		if err != nil {
+			slog.Error("failed to write output integrity files", slog.String("error", err.Error()))
+
+			return
+		}
+	}
+
+	if *flags.historyDB != "" {
+		err = client.recordHistory(*flags.historyDB, *flags.historyRetention, time.Now().UTC().Truncate(time.Second))
+		if err != nil {
+			slog.Error("failed to record scan history", slog.String("error", err.Error()))
+
+			return
+		}
+	}
+
+	err = client.writeDynamoDB(ctx)
+	if err != nil {
+		slog.Error("failed to write trust graph to DynamoDB", slog.String("error", err.Error()))
+
+		return
+	}
+
+	client.notifyScanComplete(ctx, "")
+
+	if client.failed.Load() {
+		os.Exit(exitCodeFindings)
+	}
 }
 
-// ServiceIAM lists IAM roles via AWS SDK clients.
+// ServiceIAM lists, reads, simulates and updates IAM roles, lists their tags, and reads federated providers, via AWS
+// SDK clients.
 type ServiceIAM interface {
 	iam.ListRolesAPIClient
+	iam.GetRoleAPIClient
+	iam.ListRoleTagsAPIClient
+	iam.SimulatePrincipalPolicyAPIClient
+	UpdateAssumeRolePolicy(
+		ctx context.Context,
+		params *iam.UpdateAssumeRolePolicyInput,
+		optFns ...func(*iam.Options),
+	) (*iam.UpdateAssumeRolePolicyOutput, error)
+	GetSAMLProvider(
+		ctx context.Context,
+		params *iam.GetSAMLProviderInput,
+		optFns ...func(*iam.Options),
+	) (*iam.GetSAMLProviderOutput, error)
+	GetOpenIDConnectProvider(
+		ctx context.Context,
+		params *iam.GetOpenIDConnectProviderInput,
+		optFns ...func(*iam.Options),
+	) (*iam.GetOpenIDConnectProviderOutput, error)
+}
+
+// ServiceSTS assumes IAM roles, with credentials or a web identity token, via AWS SDK clients.
+type ServiceSTS interface {
+	stscreds.AssumeRoleAPIClient
+	stscreds.AssumeRoleWithWebIdentityAPIClient
 }
 
 // App represents a struct that provides functionality for interacting with the AWS IAM service.
 type App struct {
-	client ServiceIAM
+	client                 ServiceIAM
+	stsClient              ServiceSTS
+	assumeRoleARN          string
+	webIdentityRoleARN     string
+	webIdentityTokenFile   string
+	webIdentitySessionName string
+	roleSessionName        string
+	roleDuration           time.Duration
+	sourceIdentity         string
+	rawPolicy              bool
+	rawPolicyLimit         int
+	prettyPolicies         bool
+	orgStructure           orgStructure
+	fips                   bool
+	dualStack              bool
+	endpointURL            string
+	credentials            aws.CredentialsProvider
+	lazyInit               bool
+	expectedTrust          expectedTrust
+	strict                 bool
+	continueOnError        bool
+	quietErrors            bool
+	includeMetadata        bool
+	simulateActions        []string
+	denyServices           []string
+	orgID                  string
+	focus                  string
+	focusDepth             int
+	minPrincipals          int
+	minRoles               int
+	roleAccounts           []string
+	excludeAccounts        []string
+	temporal               bool
+	redactAccounts         bool
+	redactor               *accountRedactor
+	partialResults         bool
+	verifyProviders        bool
+	newSince               time.Time
+	roleMinAge             time.Duration
+	roleMaxAge             time.Duration
+	invertMatch            bool
+	roleNames              []string
+	pathPrefixes           []string
+	roleConcurrency        int
+	includeTags            bool
+	groupByTag             string
+	effective              bool
+	derivedPrincipals      bool
+	labels                 *labelSet
+	withBoundary           bool
+	countByRegion          bool
+	onlyUnbounded          bool
+	samlCertWindow         time.Duration
+	gitlabHosts            []string
+	failOn                 string
+	shortNames             bool
+	renameKeyMap           map[string]string
+	profile                string
+	snsClient              ServiceSNS
+	snsTopicARN            string
+	dynamoDB               *DynamoDBWriter
+	notificationMutex      sync.Mutex
+	notification           scanNotification
+	scannedTrusts          map[string]roleTrust
+	skippedRoles           []string
+	snsRetryDelay          time.Duration
+	logger                 *slog.Logger
+	truncated              atomic.Bool
+	failed                 atomic.Bool
+	cfg                    aws.Config
+	clientOnce             sync.Once
 }
 
+const (
+	defaultRoleSessionName    = "veil-scan"
+	defaultAssumeRoleDuration = time.Hour
+	minAssumeRoleDuration     = 15 * time.Minute
+	maxAssumeRoleDuration     = 12 * time.Hour
+)
+
 var _ iam.ListRolesAPIClient = (ServiceIAM)(nil)
 
-var errEmptyRegion = errors.New("region cannot be empty")
+var (
+	errEmptyRegion               = errors.New("region cannot be empty")
+	errRolePanic                 = errors.New("panic processing IAM role")
+	errInvalidAssumeRoleDuration = errors.New("assume role duration must be between 15m and 12h")
+	errInvalidRoleSessionName    = errors.New(
+		"role session name must be 2 to 64 letters, digits or any of _+=,.@- characters",
+	)
+	errInvalidSourceIdentity = errors.New(
+		"source identity must be 2 to 64 letters, digits or any of _+=,.@- characters",
+	)
+	errQuietErrors         = errors.New("-quiet-errors requires -continue-on-error")
+	errPrettyPrintPolicies = errors.New("-pretty-print-policies requires -include-raw-policy")
+	errIncludeMetadata     = errors.New(
+		"-include-metadata requires the json format and cannot be combined with a mode flag, -accounts or " +
+			"-dedupe-across-accounts",
+	)
+)
+
+// roleSessionNameRegex matches the session names accepted by STS, which accepts source identities of the same form.
+var roleSessionNameRegex = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
 
 // ConfigLoader defines an interface for loading AWS SDK configurations with customisable options.
 type ConfigLoader interface {
@@ -100,8 +479,62 @@ func (d DefaultConfigLoader) LoadDefaultConfig(
 
 var _ ConfigLoader = (*DefaultConfigLoader)(nil)
 
-// NewApp initialises and returns a new App instance configured with the provided region and context.
-func NewApp(ctx context.Context, region string, loader ConfigLoader) (*App, error) {
+const irsaRoleSessionName = "veil"
+
+var errMissingWebIdentity = errors.New("web identity role ARN and token file are required")
+
+// IRSAConfigLoader loads AWS SDK configurations using web identity credentials, as provided to Kubernetes pods via
+// IAM Roles for Service Accounts. RoleARN and TokenFile fall back to the AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE
+// environment variables when empty.
+type IRSAConfigLoader struct {
+	RoleARN   string
+	TokenFile string
+}
+
+// LoadDefaultConfig loads the default AWS SDK configuration and replaces its credentials with a web identity role
+// provider.
+//
+//nolint:nonamedreturns
+func (i IRSAConfigLoader) LoadDefaultConfig(
+	ctx context.Context,
+	optFns ...func(*config.LoadOptions) error,
+) (cfg aws.Config, err error) {
+	roleARN := i.RoleARN
+	if roleARN == "" {
+		roleARN = os.Getenv("AWS_ROLE_ARN")
+	}
+
+	tokenFile := i.TokenFile
+	if tokenFile == "" {
+		tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+
+	if roleARN == "" || tokenFile == "" {
+		return aws.Config{}, errMissingWebIdentity
+	}
+
+	cfg, err = config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load base config: %w", err)
+	}
+
+	provider := stscreds.NewWebIdentityRoleProvider(
+		sts.NewFromConfig(cfg),
+		roleARN,
+		stscreds.IdentityTokenFile(tokenFile),
+		func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = irsaRoleSessionName
+		},
+	)
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+
+	return cfg, nil
+}
+
+var _ ConfigLoader = (*IRSAConfigLoader)(nil)
+
+// NewApp initialises and returns a new App instance configured with the provided region, context and options.
+func NewApp(ctx context.Context, region string, loader ConfigLoader, opts ...Option) (*App, error) {
 	if region == "" {
 		return nil, errEmptyRegion
 	}
@@ -110,83 +543,643 @@ func NewApp(ctx context.Context, region string, loader ConfigLoader) (*App, erro
 		loader = DefaultConfigLoader{}
 	}
 
-	cfg, err := loader.LoadDefaultConfig(
-		ctx,
-		config.WithRegion(region),
-	)
+	app := &App{
+		client:                 nil,
+		stsClient:              nil,
+		assumeRoleARN:          "",
+		webIdentityRoleARN:     "",
+		webIdentityTokenFile:   "",
+		webIdentitySessionName: "",
+		roleSessionName:        defaultRoleSessionName,
+		roleDuration:           defaultAssumeRoleDuration,
+		sourceIdentity:         "",
+		rawPolicy:              false,
+		rawPolicyLimit:         0,
+		prettyPolicies:         false,
+		orgStructure:           nil,
+		fips:                   false,
+		dualStack:              false,
+		endpointURL:            "",
+		credentials:            nil,
+		lazyInit:               false,
+		expectedTrust:          nil,
+		strict:                 false,
+		continueOnError:        false,
+		quietErrors:            false,
+		includeMetadata:        false,
+		simulateActions:        nil,
+		denyServices:           nil,
+		orgID:                  "",
+		focus:                  "",
+		focusDepth:             defaultFocusDepth,
+		minPrincipals:          0,
+		minRoles:               0,
+		roleAccounts:           nil,
+		excludeAccounts:        nil,
+		temporal:               false,
+		redactAccounts:         false,
+		redactor:               nil,
+		partialResults:         false,
+		verifyProviders:        false,
+		newSince:               time.Time{},
+		roleMinAge:             0,
+		roleMaxAge:             0,
+		invertMatch:            false,
+		roleNames:              nil,
+		pathPrefixes:           nil,
+		roleConcurrency:        0,
+		includeTags:            false,
+		groupByTag:             "",
+		effective:              false,
+		derivedPrincipals:      false,
+		labels:                 nil,
+		withBoundary:           false,
+		countByRegion:          false,
+		onlyUnbounded:          false,
+		samlCertWindow:         defaultSAMLCertWindow,
+		gitlabHosts:            nil,
+		failOn:                 "",
+		shortNames:             false,
+		renameKeyMap:           nil,
+		profile:                "",
+		snsClient:              nil,
+		snsTopicARN:            "",
+		dynamoDB:               nil,
+		notificationMutex:      sync.Mutex{},
+		notification:           scanNotification{Account: "", Roles: 0, Findings: nil, Results: ""},
+		scannedTrusts:          nil,
+		skippedRoles:           nil,
+		snsRetryDelay:          defaultRetryInitialDelay,
+		logger:                 slog.Default(),
+		truncated:              atomic.Bool{},
+		failed:                 atomic.Bool{},
+		cfg:                    aws.Config{},
+		clientOnce:             sync.Once{},
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+
+	if app.roleDuration < minAssumeRoleDuration || app.roleDuration > maxAssumeRoleDuration {
+		return nil, fmt.Errorf("%w: %s", errInvalidAssumeRoleDuration, app.roleDuration)
+	}
+
+	if app.roleMinAge < 0 || app.roleMaxAge < 0 || (app.roleMaxAge > 0 && app.roleMinAge > app.roleMaxAge) {
+		return nil, fmt.Errorf("%w: %s to %s", errInvalidRoleAge, app.roleMinAge, app.roleMaxAge)
+	}
+
+	if !roleSessionNameRegex.MatchString(app.roleSessionName) {
+		return nil, fmt.Errorf("%w: %q", errInvalidRoleSessionName, app.roleSessionName)
+	}
+
+	if app.sourceIdentity != "" && !roleSessionNameRegex.MatchString(app.sourceIdentity) {
+		return nil, fmt.Errorf("%w: %q", errInvalidSourceIdentity, app.sourceIdentity)
+	}
+
+	if app.focusDepth < 1 {
+		return nil, fmt.Errorf("%w: %d", errInvalidFocusDepth, app.focusDepth)
+	}
+
+	if app.minPrincipals < 0 || app.minRoles < 0 {
+		return nil, fmt.Errorf("%w: %d, %d", errInvalidMinCount, app.minPrincipals, app.minRoles)
+	}
+
+	if (app.webIdentityRoleARN == "") != (app.webIdentityTokenFile == "") {
+		return nil, errMissingWebIdentity
+	}
+
+	cfg, err := loader.LoadDefaultConfig(ctx, app.loadOptions(region)...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load SDK config, %w", err)
 	}
 
-	return &App{
-		client: iam.NewFromConfig(cfg),
-	}, nil
+	if app.webIdentityRoleARN != "" {
+		app.stsClient = sts.NewFromConfig(cfg)
+		cfg.Credentials = aws.NewCredentialsCache(app.webIdentityProvider())
+	}
+
+	if app.assumeRoleARN != "" {
+		app.stsClient = sts.NewFromConfig(cfg)
+		cfg.Credentials = aws.NewCredentialsCache(app.assumeRoleProvider())
+	}
+
+	app.cfg = cfg
+	if !app.lazyInit {
+		app.iamClient()
+	}
+
+	return app, nil
 }
 
-func (a *App) getRolesWithTrust(ctx context.Context) (map[string][]string, error) {
+// loadOptions returns the SDK config load options for the given region and the configured endpoint settings.
+// They apply to every client created from the loaded config, including IAM and STS.
+func (a *App) loadOptions(region string) []func(*config.LoadOptions) error {
+	output := []func(*config.LoadOptions) error{config.WithRegion(region)}
+
+	if a.fips {
+		output = append(output, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+
+	if a.dualStack {
+		output = append(output, config.WithUseDualStackEndpoint(aws.DualStackEndpointStateEnabled))
+	}
+
+	if a.endpointURL != "" {
+		if a.fips || a.dualStack {
+			a.logger.Warn(
+				"explicit endpoint URL takes precedence over FIPS and dual-stack endpoint resolution",
+				slog.String("endpoint", a.endpointURL),
+			)
+		}
+
+		output = append(output, config.WithBaseEndpoint(a.endpointURL))
+	}
+
+	if a.credentials != nil {
+		output = append(output, config.WithCredentialsProvider(a.credentials))
+	}
+
+	if a.profile != "" {
+		output = append(output, config.WithSharedConfigProfile(a.profile))
+	}
+
+	return output
+}
+
+// iamClient returns the IAM client, creating it from the loaded config on first use.
+func (a *App) iamClient() ServiceIAM {
+	a.clientOnce.Do(func() {
+		if a.client == nil {
+			a.client = iam.NewFromConfig(a.cfg)
+		}
+	})
+
+	return a.client
+}
+
+// assumeRoleProvider returns a credentials provider that assumes the configured role using the App STS client, setting
+// the source identity if one is configured.
+func (a *App) assumeRoleProvider() *stscreds.AssumeRoleProvider {
+	return stscreds.NewAssumeRoleProvider(a.stsClient, a.assumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = a.roleSessionName
+		o.Duration = a.roleDuration
+
+		if a.sourceIdentity != "" {
+			o.SourceIdentity = aws.String(a.sourceIdentity)
+		}
+	})
+}
+
+// webIdentityProvider returns a credentials provider that assumes the configured web identity role with the
+// service account token, using the App STS client.
+func (a *App) webIdentityProvider() *stscreds.WebIdentityRoleProvider {
+	sessionName := a.webIdentitySessionName
+	if sessionName == "" {
+		sessionName = irsaRoleSessionName
+	}
+
+	return stscreds.NewWebIdentityRoleProvider(
+		a.stsClient,
+		a.webIdentityRoleARN,
+		stscreds.IdentityTokenFile(a.webIdentityTokenFile),
+		func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = sessionName
+		},
+	)
+}
+
+// roleTrust pairs an IAM role with its decoded trust policy.
+type roleTrust struct {
+	role   types.Role
+	policy TrustPolicy
+}
+
+func (a *App) getRoleTrusts(ctx context.Context) (map[string]roleTrust, error) {
 	var mutex sync.Mutex
 
-	output := make(map[string][]string)
+	output := make(map[string]roleTrust)
+	undecodable := make([]string, 0)
 	group, gCtx := errgroup.WithContext(ctx)
 
-	paginator := iam.NewListRolesPaginator(a.client, &iam.ListRolesInput{
+	if a.roleConcurrency > 0 {
+		group.SetLimit(a.roleConcurrency)
+	}
+
+	skip := func(role types.Role, err error) {
+		a.skipUndecodable(gCtx, role, err)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		undecodable = append(undecodable, aws.ToString(role.Arn))
+	}
+
+	decode := func(role types.Role) (err error) { //nolint:nonamedreturns
+		// Deferred calls run in reverse, so a recovered panic is skipped like an undecodable policy.
+		defer func() {
+			if errors.Is(err, errRolePanic) && a.continueOnError {
+				skip(role, err)
+
+				err = nil
+			}
+		}()
+		defer a.recoverRolePanic(role, &err)
+
+		select {
+		case <-gCtx.Done():
+			if a.keepPartial(ctx) {
+				return nil
+			}
+
+			return gCtx.Err()
+		default:
+			a.logger.Debug("decoding trust policy", slog.String("role", aws.ToString(role.Arn)))
+
+			policy, errDecodeTrust := decodeRoleTrust(role, a.strict)
+			if errDecodeTrust != nil && a.continueOnError {
+				skip(role, errDecodeTrust)
+
+				return nil
+			}
+
+			if errDecodeTrust != nil {
+				return fmt.Errorf("failed to decode role trust policy: %w", errDecodeTrust)
+			}
+
+			if a.includeTags {
+				tags, errTags := a.roleTags(gCtx, role)
+				if errTags != nil {
+					return errTags
+				}
+
+				role.Tags = tags
+			}
+
+			if a.withBoundary {
+				boundary, errBoundary := a.roleBoundary(gCtx, role)
+				if errBoundary != nil {
+					return errBoundary
+				}
+
+				if boundary != nil && a.onlyUnbounded {
+					return nil
+				}
+
+				role.PermissionsBoundary = boundary
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			output[*role.Arn] = roleTrust{
+				role:   role,
+				policy: policy,
+			}
+
+			return nil
+		}
+	}
+
+	includes := a.includesRole()
+	visit := func(role types.Role) {
+		if !includes(role) {
+			return
+		}
+
+		// Without per-role API calls, decoding is cheap and CPU-bound, so it runs on the listing goroutine: a goroutine
+		// per role spends more on growing its stack than on decoding.
+		if !a.includeTags && !a.withBoundary {
+			err := decode(role)
+			if err != nil {
+				group.Go(func() error { return err })
+			}
+
+			return
+		}
+
+		group.Go(func() error { return decode(role) })
+	}
+
+	err := a.visitRoles(ctx, gCtx, visit)
+	if err != nil {
+		// A role failing to process cancels gCtx, failing the listing of the next page: report the role's error.
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil && gCtx.Err() != nil {
+			errWait := group.Wait()
+			if errWait != nil {
+				return nil, fmt.Errorf("failed to process IAM roles trust policies: %w", errWait)
+			}
+		}
+
+		return nil, err
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return nil, fmt.Errorf("failed to process IAM roles trust policies: %w", err)
+	}
+
+	if len(undecodable) > 0 && a.quietErrors {
+		a.logger.Warn(fmt.Sprintf("%d roles failed to decode (use -verbose to see details)", len(undecodable)))
+	}
+
+	slices.Sort(undecodable)
+	a.recordRoles(output, undecodable)
+
+	return output, nil
+}
+
+// visitRoles passes the roles to scan to visit: those with the configured names if any, or every role of the account.
+// Listing or fetching uses gCtx, while ctx decides whether an interrupted scan keeps its partial results.
+func (a *App) visitRoles(ctx context.Context, gCtx context.Context, visit func(types.Role)) error {
+	if len(a.roleNames) > 0 {
+		return a.getNamedRoles(ctx, gCtx, visit)
+	}
+
+	return a.listRoles(ctx, gCtx, visit)
+}
+
+// listRoles passes every role of the account to visit, page by page. With path prefixes, the roles under each of
+// them are listed concurrently, and passed to visit one at a time.
+func (a *App) listRoles(ctx context.Context, gCtx context.Context, visit func(types.Role)) error {
+	if len(a.pathPrefixes) == 0 {
+		return a.listRolesUnder(ctx, gCtx, nil, visit)
+	}
+
+	var mutex sync.Mutex
+
+	group, lCtx := errgroup.WithContext(gCtx)
+
+	for _, prefix := range outermostPrefixes(a.pathPrefixes) {
+		group.Go(func() error {
+			return a.listRolesUnder(ctx, lCtx, aws.String(prefix), func(role types.Role) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				visit(role)
+			})
+		})
+	}
+
+	return group.Wait() //nolint:wrapcheck
+}
+
+// outermostPrefixes returns the sorted, distinct path prefixes that are not within another of the given prefixes, so
+// that no role is listed twice, e.g. /team/ out of /team/ and /team/ci/.
+func outermostPrefixes(prefixes []string) []string {
+	output := make([]string, 0, len(prefixes))
+
+	for _, prefix := range uniqSlice(prefixes) {
+		if len(output) > 0 && strings.HasPrefix(prefix, output[len(output)-1]) {
+			continue
+		}
+
+		output = append(output, prefix)
+	}
+
+	return output
+}
+
+// listRolesUnder passes the roles whose path starts with the prefix, or every role if it is nil, to visit.
+func (a *App) listRolesUnder(
+	ctx context.Context,
+	gCtx context.Context,
+	prefix *string,
+	visit func(types.Role),
+) error {
+	paginator := iam.NewListRolesPaginator(a.iamClient(), &iam.ListRolesInput{
 		Marker:     nil,
 		MaxItems:   nil,
-		PathPrefix: nil,
+		PathPrefix: prefix,
 	})
 	for paginator.HasMorePages() {
-		page, errListRoles := paginator.NextPage(gCtx)
-		if errListRoles != nil {
-			return nil, fmt.Errorf("failed to list roles: %w", errListRoles)
+		page, err := retryWithBackoff(
+			gCtx,
+			a.logger,
+			defaultRetryMaxAttempts,
+			defaultRetryInitialDelay,
+			func() (*iam.ListRolesOutput, error) {
+				return paginator.NextPage(gCtx)
+			},
+		)
+		if err != nil {
+			if a.keepPartial(ctx) {
+				break
+			}
+
+			return fmt.Errorf("failed to list roles: %w", err)
 		}
 
 		for _, role := range page.Roles {
-			group.Go(func() error {
-				select {
-				case <-gCtx.Done():
-					return gCtx.Err()
-				default:
-					policy, errDecodeTrust := decodeRoleTrust(role)
-					if errDecodeTrust != nil {
-						return fmt.Errorf("failed to decode role trust policy: %w", errDecodeTrust)
-					}
+			visit(role)
+		}
+	}
+
+	return nil
+}
 
-					mutex.Lock()
-					defer mutex.Unlock()
+// getNamedRoles fetches the roles with the configured names one by one, passing them to visit. Roles that do not
+// exist are logged and skipped, while other failures are collected per name.
+func (a *App) getNamedRoles(ctx context.Context, gCtx context.Context, visit func(types.Role)) error {
+	failures := make([]error, 0)
 
-					output[*role.Arn] = policy.getAllPrincipals()
+	for _, name := range a.roleNames {
+		result, err := retryWithBackoff(
+			gCtx,
+			a.logger,
+			defaultRetryMaxAttempts,
+			defaultRetryInitialDelay,
+			func() (*iam.GetRoleOutput, error) {
+				return a.iamClient().GetRole(gCtx, &iam.GetRoleInput{RoleName: aws.String(name)})
+			},
+		)
 
-					return nil
-				}
-			})
+		var notFound *types.NoSuchEntityException
+		if errors.As(err, &notFound) {
+			a.logger.Warn("role not found", slog.String("role", name))
+
+			continue
 		}
+
+		if err != nil {
+			if a.keepPartial(ctx) {
+				break
+			}
+
+			failures = append(failures, fmt.Errorf("%s: %w", name, err))
+
+			continue
+		}
+
+		visit(*result.Role)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to get roles: %w", errors.Join(failures...))
 	}
 
-	err := group.Wait()
+	return nil
+}
+
+// GetRolePaths returns the path of every IAM role keyed by role ARN, without decoding any trust policy.
+func (a *App) GetRolePaths(ctx context.Context) (map[string]string, error) {
+	output := make(map[string]string)
+
+	includes := a.includesRole()
+
+	err := a.visitRoles(ctx, ctx, func(role types.Role) {
+		if includes(role) {
+			output[aws.ToString(role.Arn)] = aws.ToString(role.Path)
+		}
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to process IAM roles trust policies: %w", err)
+		return nil, err
 	}
 
 	return output, nil
 }
 
+// keepPartial reports whether a scan interrupted by the deadline of ctx should return the roles collected so far
+// instead of failing, marking the result as truncated.
+func (a *App) keepPartial(ctx context.Context) bool {
+	if !a.partialResults || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+
+	if !a.truncated.Swap(true) {
+		a.logger.Warn("scan deadline reached, returning partial results")
+	}
+
+	return true
+}
+
+// recoverRolePanic turns a panic while processing the role into an error naming it, so that a malformed document fails
+// the scan with an error instead of crashing the process. It must be deferred by the goroutine processing the role.
+func (a *App) recoverRolePanic(role types.Role, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	arn := aws.ToString(role.Arn)
+	a.logger.Error("recovered from panic processing IAM role", slog.String("role", arn), slog.Any("panic", recovered))
+
+	*err = fmt.Errorf("%w: %s: %v", errRolePanic, arn, recovered)
+}
+
+// skipUndecodable logs a role skipped with -continue-on-error because its trust policy failed to decode, at debug
+// level only when quiet, leaving a single summary line for the whole scan.
+func (a *App) skipUndecodable(ctx context.Context, role types.Role, err error) {
+	level := slog.LevelWarn
+	if a.quietErrors {
+		level = slog.LevelDebug
+	}
+
+	a.logger.Log(
+		ctx,
+		level,
+		"skipping role with undecodable trust policy",
+		slog.String("role", aws.ToString(role.Arn)),
+		slog.String("error", err.Error()),
+	)
+}
+
+func (a *App) getRolesWithTrust(ctx context.Context) (map[string][]string, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.rolePrincipals(trusts), nil
+}
+
+// trustPrincipals returns the principals trusted by the policy, including those derived from conditions if set, or
+// only those effectively trusted, once Deny statements are applied, if set.
+func (a *App) trustPrincipals(policy TrustPolicy) []string {
+	if a.effective {
+		return policy.getEffectivePrincipals(a.derivedPrincipals)
+	}
+
+	if a.derivedPrincipals {
+		return slices.Concat(policy.getAllPrincipals(), policy.getDerivedPrincipals(), policy.getOrgPrincipals())
+	}
+
+	return slices.Concat(policy.getAllPrincipals(), policy.getOrgPrincipals())
+}
+
+// trustedPrincipals returns the principals trusted by each role, as listed by trustPrincipals.
+// Assumed-role sessions are listed as the roles they were assumed from.
+func (a *App) trustedPrincipals(trusts map[string]roleTrust) map[string][]string {
+	sessions := newSessionIndex(trusts)
+
+	output := make(map[string][]string, len(trusts))
+	for arn, trust := range trusts {
+		output[arn], _ = sessions.resolve(a.trustPrincipals(trust.policy))
+	}
+
+	return output
+}
+
+// rolePrincipals returns the principals trusted by each role as trustedPrincipals does, restricted to the focus and
+// the minimum counts if set.
+func (a *App) rolePrincipals(trusts map[string]roleTrust) map[string][]string {
+	output := a.trustedPrincipals(trusts)
+
+	if a.focus != "" {
+		output = focusRoles(output, a.focus, a.focusDepth)
+	}
+
+	if a.minPrincipals > 1 || a.minRoles > 1 {
+		output = minCountRoles(output, a.minPrincipals, a.minRoles)
+	}
+
+	return output
+}
+
+func (a *App) runScanPaths(ctx context.Context) ([]byte, error) {
+	output, err := a.GetRolePaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM role paths: %w", err)
+	}
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
 func (a *App) runScanIAM(ctx context.Context) ([]byte, error) {
 	output, err := a.getRolesWithTrust(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
 	}
 
-	flip := mapFlip(output)
-	slog.Debug(
+	flip := mapFlip(a.redactRoles(output))
+	a.logger.Debug(
 		"found IAM roles and principals",
 		slog.Int("roles", len(output)),
 		slog.Int("principals", len(flip)),
 	)
 
-	marshal, err := json.MarshalIndent(flip, "", "  ")
+	var envelope any = flip
+	if a.includeMetadata {
+		a.notificationMutex.Lock()
+		envelope = scanEnvelope{Principals: flip, DecodeErrors: append(make([]string, 0), a.skippedRoles...)}
+		a.notificationMutex.Unlock()
+	}
+
+	marshal, err := a.marshalOutput(envelope)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal output: %w", err)
 	}
 
 	return marshal, nil
 }
+
+// scanEnvelope is the json output with -include-metadata: the principals mapped to the roles trusting them, and the
+// ARNs of the roles skipped with -continue-on-error because their trust policy failed to decode.
+type scanEnvelope struct {
+	Principals   map[string][]string `json:"principals"`
+	DecodeErrors []string            `json:"decode_errors"`
+}
@@ -4,32 +4,36 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/wakeful/veil/veiltest"
 )
 
-type MockServiceIAM struct {
-	mockRoles    []types.Role
-	mockRolesErr error
-}
-
-func (m MockServiceIAM) ListRoles(
-	_ context.Context,
-	_ *iam.ListRolesInput,
-	_ ...func(*iam.Options),
-) (*iam.ListRolesOutput, error) {
-	return &iam.ListRolesOutput{Roles: m.mockRoles}, m.mockRolesErr
-}
-
-var _ ServiceIAM = (*MockServiceIAM)(nil)
+var _ ServiceIAM = (*veiltest.FakeIAM)(nil)
 
 func TestApp_getRolesWithTrust(t *testing.T) {
 	t.Parallel()
@@ -50,9 +54,9 @@ func TestApp_getRolesWithTrust(t *testing.T) {
 		{
 			name: "failed to list roles",
 			ctx:  t.Context(),
-			client: &MockServiceIAM{
-				mockRoles:    []types.Role{},
-				mockRolesErr: errors.New("test error"),
+			client: &veiltest.FakeIAM{
+				Roles:  []types.Role{},
+				Errors: map[string]error{veiltest.OperationListRoles: errors.New("test error")},
 			},
 			want:    nil,
 			wantErr: true,
@@ -60,15 +64,15 @@ func TestApp_getRolesWithTrust(t *testing.T) {
 		{
 			name:    "no roles found",
 			ctx:     t.Context(),
-			client:  &MockServiceIAM{},
+			client:  &veiltest.FakeIAM{},
 			want:    map[string][]string{},
 			wantErr: false,
 		},
 		{
 			name: "found roles with invalid trust policy",
 			ctx:  t.Context(),
-			client: &MockServiceIAM{
-				mockRoles: invalidRoles,
+			client: &veiltest.FakeIAM{
+				Roles: invalidRoles,
 			},
 			want:    nil,
 			wantErr: true,
@@ -76,8 +80,8 @@ func TestApp_getRolesWithTrust(t *testing.T) {
 		{
 			name: "fail with ctx timeout",
 			ctx:  withTimeout,
-			client: &MockServiceIAM{
-				mockRoles: invalidRoles,
+			client: &veiltest.FakeIAM{
+				Roles: invalidRoles,
 			},
 			want:    nil,
 			wantErr: true,
@@ -85,8 +89,8 @@ func TestApp_getRolesWithTrust(t *testing.T) {
 		{
 			name: "success with decoding",
 			ctx:  t.Context(),
-			client: &MockServiceIAM{
-				mockRoles: []types.Role{
+			client: &veiltest.FakeIAM{
+				Roles: []types.Role{
 					{
 						Arn: aws.String(
 							"arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_FullAdmin",
@@ -110,6 +114,7 @@ func TestApp_getRolesWithTrust(t *testing.T) {
 			t.Parallel()
 
 			a := &App{
+				logger: slog.New(slog.DiscardHandler),
 				client: tt.client,
 			}
 
@@ -127,150 +132,1774 @@ func TestApp_getRolesWithTrust(t *testing.T) {
 	}
 }
 
-type mockConfigLoader struct {
-	mockConfig    aws.Config
-	mockConfigErr error
-}
+// pagedRoles returns the given number of roles of an ECS trust policy, named ecs-1 and so on.
+func pagedRoles(count int) []types.Role {
+	output := make([]types.Role, 0, count)
 
-//nolint:nonamedreturns
-func (m *mockConfigLoader) LoadDefaultConfig(
-	_ context.Context,
-	_ ...func(*config.LoadOptions) error,
-) (cfg aws.Config, err error) {
-	return m.mockConfig, m.mockConfigErr
-}
+	for index := 1; index <= count; index++ {
+		name := "ecs-" + strconv.Itoa(index)
+		output = append(output, types.Role{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/" + name),
+			RoleName:                 aws.String(name),
+			Path:                     aws.String("/"),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(fixtureAWSServiceRoleForECS)),
+		})
+	}
 
-var _ ConfigLoader = (*mockConfigLoader)(nil)
+	return output
+}
 
-func TestNewApp(t *testing.T) {
+func TestApp_getRolesWithTrust_pages(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name    string
-		loader  ConfigLoader
-		region  string
-		wantApp bool
-		wantErr bool
+		name      string
+		fake      *veiltest.FakeIAM
+		wantRoles int
+		wantCalls int
+		wantErr   bool
 	}{
 		{
-			name:    "empty region",
-			loader:  nil,
-			region:  "",
-			wantApp: false,
-			wantErr: true,
+			name:      "pages of one role",
+			fake:      &veiltest.FakeIAM{Roles: pagedRoles(3), PageSize: 1},
+			wantRoles: 3,
+			wantCalls: 3,
+			wantErr:   false,
 		},
 		{
-			name: "config loader error",
-			loader: &mockConfigLoader{
-				mockConfigErr: errors.New("test error"),
-			},
-			region:  "eu-west-1",
-			wantApp: false,
-			wantErr: true,
+			name:      "last page partially filled",
+			fake:      &veiltest.FakeIAM{Roles: pagedRoles(5), PageSize: 2},
+			wantRoles: 5,
+			wantCalls: 3,
+			wantErr:   false,
 		},
 		{
-			name: "success setup with mock config",
-			loader: &mockConfigLoader{
-				mockConfig: aws.Config{},
+			name:      "exactly filled pages",
+			fake:      &veiltest.FakeIAM{Roles: pagedRoles(4), PageSize: 2},
+			wantRoles: 4,
+			wantCalls: 2,
+			wantErr:   false,
+		},
+		{
+			name: "throttled page retried",
+			fake: &veiltest.FakeIAM{
+				Roles:    pagedRoles(3),
+				PageSize: 2,
+				Throttle: map[string]int{veiltest.OperationListRoles: 1},
 			},
-			region:  "eu-west-1",
-			wantApp: true,
-			wantErr: false,
+			wantRoles: 3,
+			wantCalls: 3,
+			wantErr:   false,
 		},
 		{
-			name:    "success setup with default config",
-			loader:  nil,
-			region:  "eu-west-1",
-			wantApp: true,
-			wantErr: false,
+			name: "access denied",
+			fake: &veiltest.FakeIAM{
+				Roles:  pagedRoles(3),
+				Errors: map[string]error{veiltest.OperationListRoles: veiltest.AccessDeniedError("ListRoles")},
+			},
+			wantRoles: 0,
+			wantCalls: 1,
+			wantErr:   true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := NewApp(t.Context(), tt.region, tt.loader)
+			a := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: tt.fake,
+			}
+
+			got, err := a.getRolesWithTrust(t.Context())
 			if (err != nil) != tt.wantErr {
-				t.Errorf("NewApp() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("getRolesWithTrust() error = %v, wantErr %v", err, tt.wantErr)
+			}
 
-				return
+			if len(got) != tt.wantRoles {
+				t.Errorf("getRolesWithTrust() roles = %d, want %d", len(got), tt.wantRoles)
 			}
 
-			if (got != nil) != tt.wantApp {
-				t.Errorf("got app = %v, want non-nil: %v", got, tt.wantApp)
+			if calls := len(tt.fake.Calls(veiltest.OperationListRoles)); calls != tt.wantCalls {
+				t.Errorf("getRolesWithTrust() listed %d pages, want %d", calls, tt.wantCalls)
 			}
 		})
 	}
 }
 
-func TestApp_runScanIAM(t *testing.T) {
+// stallingIAM serves the first pages of ListRoles, then stalls until the context is done, as a scan interrupted while
+// listing roles. It cancels the context first if cancel is set.
+type stallingIAM struct {
+	*veiltest.FakeIAM
+
+	pages  int
+	cancel context.CancelFunc
+}
+
+func (s *stallingIAM) ListRoles(
+	ctx context.Context,
+	input *iam.ListRolesInput,
+	opts ...func(*iam.Options),
+) (*iam.ListRolesOutput, error) {
+	if len(s.Calls(veiltest.OperationListRoles)) < s.pages {
+		return s.FakeIAM.ListRoles(ctx, input, opts...)
+	}
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func TestApp_getRolesWithTrust_interrupted(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name    string
-		client  ServiceIAM
-		want    []byte
-		wantErr bool
+		name      string
+		cancel    bool
+		partial   bool
+		wantRoles int
+		wantErr   error
 	}{
+		{name: "cancelled", cancel: true, partial: false, wantRoles: 0, wantErr: context.Canceled},
+		{name: "deadline", cancel: false, partial: false, wantRoles: 0, wantErr: context.DeadlineExceeded},
+		{name: "deadline with partial results", cancel: false, partial: true, wantRoles: 4, wantErr: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+			defer cancel()
+
+			fake := &stallingIAM{FakeIAM: &veiltest.FakeIAM{Roles: pagedRoles(7), PageSize: 2}, pages: 2}
+			if tt.cancel {
+				fake.cancel = cancel
+			}
+
+			a := &App{logger: slog.New(slog.DiscardHandler), client: fake}
+			if tt.partial {
+				WithPartialResults()(a)
+			}
+
+			got, err := a.getRolesWithTrust(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("getRolesWithTrust() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(got) != tt.wantRoles {
+				t.Errorf("getRolesWithTrust() roles = %d, want %d", len(got), tt.wantRoles)
+			}
+
+			if calls := len(fake.Calls(veiltest.OperationListRoles)); calls != 2 {
+				t.Errorf("getRolesWithTrust() listed %d pages, want the 2 served before stalling", calls)
+			}
+
+			if a.truncated.Load() != tt.partial {
+				t.Errorf("getRolesWithTrust() truncated = %v, want %v", a.truncated.Load(), tt.partial)
+			}
+		})
+	}
+}
+
+func TestApp_getRolesWithTrust_decodeErrorWhileListing(t *testing.T) {
+	t.Parallel()
+
+	roles := append([]types.Role{
 		{
-			name: "success",
-			client: &MockServiceIAM{
-				mockRoles: []types.Role{
-					{
-						Arn: aws.String(
-							"arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_FullAdmin",
-						),
-						AssumeRolePolicyDocument: aws.String(fixtureAWSReservedSSOFullAdmin),
-					},
-				},
-			},
-			want: []byte{
-				123, 10, 32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53,
-				54, 55, 56, 57, 58, 115, 97, 109, 108, 45, 112, 114, 111, 118, 105, 100, 101, 114, 47, 65, 87, 83, 83,
-				83, 79, 95, 50, 52, 95, 68, 79, 95, 78, 79, 84, 95, 68, 69, 76, 69, 84, 69, 34, 58, 32, 91, 10, 32, 32,
-				32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53, 54, 55,
-				56, 57, 58, 114, 111, 108, 101, 47, 97, 119, 115, 45, 114, 101, 115, 101, 114, 118, 101, 100, 47, 115,
-				115, 111, 46, 97, 109, 97, 122, 111, 110, 97, 119, 115, 46, 99, 111, 109, 47, 65, 87, 83, 82, 101, 115,
-				101, 114, 118, 101, 100, 83, 83, 79, 95, 70, 117, 108, 108, 65, 100, 109, 105, 110, 34, 10, 32, 32, 93,
-				44, 10, 32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53,
-				54, 55, 56, 57, 58, 115, 97, 109, 108, 45, 112, 114, 111, 118, 105, 100, 101, 114, 47, 65, 87, 83, 83,
-				83, 79, 95, 52, 50, 95, 68, 79, 95, 78, 79, 84, 95, 68, 69, 76, 69, 84, 69, 34, 58, 32, 91, 10, 32, 32,
-				32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53, 54, 55,
-				56, 57, 58, 114, 111, 108, 101, 47, 97, 119, 115, 45, 114, 101, 115, 101, 114, 118, 101, 100, 47, 115,
-				115, 111, 46, 97, 109, 97, 122, 111, 110, 97, 119, 115, 46, 99, 111, 109, 47, 65, 87, 83, 82, 101, 115,
-				101, 114, 118, 101, 100, 83, 83, 79, 95, 70, 117, 108, 108, 65, 100, 109, 105, 110, 34, 10, 32, 32, 93,
-				10, 125,
-			},
-			wantErr: false,
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/broken"),
+			RoleName:                 aws.String("broken"),
+			Path:                     aws.String("/"),
+			AssumeRolePolicyDocument: aws.String("{"),
+		},
+	}, pagedRoles(3)...)
+
+	a := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &stallingIAM{FakeIAM: &veiltest.FakeIAM{Roles: roles, PageSize: 1}, pages: 1, cancel: nil},
+	}
+
+	got, err := a.getRolesWithTrust(t.Context())
+	if err == nil || errors.Is(err, context.Canceled) {
+		t.Fatalf("getRolesWithTrust() error = %v, want the decode error", err)
+	}
+
+	if !strings.Contains(err.Error(), "failed to decode role trust policy") {
+		t.Errorf("getRolesWithTrust() error = %v, want the decode error", err)
+	}
+
+	if got != nil {
+		t.Errorf("getRolesWithTrust() got = %v, want nil", got)
+	}
+}
+
+func TestApp_getRolesWithTrust_pathPrefixes(t *testing.T) {
+	t.Parallel()
+
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/team-a/deploy"),
+			Path:                     aws.String("/team-a/"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
 		},
 		{
-			name: "failed to list roles",
-			client: &MockServiceIAM{
-				mockRoles:    []types.Role{},
-				mockRolesErr: errors.New("test error"),
-			},
-			want:    nil,
-			wantErr: true,
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/team-b/ecs"),
+			Path:                     aws.String("/team-b/"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/team-b/ci/ecs"),
+			Path:                     aws.String("/team-b/ci/"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/other/ecs"),
+			Path:                     aws.String("/other/"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+	}
+	fake := &veiltest.FakeIAM{Roles: roles, PageSize: 1}
+
+	app := &App{logger: slog.New(slog.DiscardHandler), client: fake}
+	WithPathPrefixes([]string{"/team-b/", "/team-a/"})(app)
+
+	got, err := app.getRolesWithTrust(t.Context())
+	if err != nil {
+		t.Fatalf("getRolesWithTrust() unexpected error: %v", err)
+	}
+
+	want := map[string][]string{
+		"ecs.amazonaws.com": {
+			"arn:aws:iam::0123456789:role/team-b/ci/ecs",
+			"arn:aws:iam::0123456789:role/team-b/ecs",
+		},
+		"arn:aws:iam::210987654321:root": {"arn:aws:iam::0123456789:role/team-a/deploy"},
+	}
+	if got := mapFlip(got); !reflect.DeepEqual(got, want) {
+		t.Errorf("getRolesWithTrust() principals = %v, want %v", got, want)
+	}
+
+	prefixes := make([]string, 0)
+	for _, call := range fake.Calls(veiltest.OperationListRoles) {
+		prefixes = append(prefixes, aws.ToString(call.(*iam.ListRolesInput).PathPrefix))
+	}
+
+	slices.Sort(prefixes)
+
+	if want := []string{"/team-a/", "/team-b/", "/team-b/"}; !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("getRolesWithTrust() listed pages under %v, want %v", prefixes, want)
+	}
+}
+
+func TestWithContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+			RoleName:                 aws.String("vendor"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/broken-a"),
+			RoleName:                 aws.String("broken-a"),
+			AssumeRolePolicyDocument: aws.String(fixtureInvalidDataTypeNumber),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/broken-b"),
+			RoleName:                 aws.String("broken-b"),
+			AssumeRolePolicyDocument: aws.String("{"),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/panics"),
+			RoleName:                 aws.String("panics"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+		},
+	}
+
+	skipped := []string{
+		"arn:aws:iam::0123456789:role/broken-a",
+		"arn:aws:iam::0123456789:role/broken-b",
+		"arn:aws:iam::0123456789:role/panics",
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantErr     bool
+		wantSkipped []string
+		wantLog     []string
+		skipLog     []string
+	}{
+		{
+			name:        "fails without",
+			opts:        nil,
+			wantErr:     true,
+			wantSkipped: nil,
+			wantLog:     nil,
+			skipLog:     nil,
+		},
+		{
+			name:        "logs each skipped role",
+			opts:        []Option{WithContinueOnError(false)},
+			wantErr:     false,
+			wantSkipped: skipped,
+			wantLog:     []string{"level=WARN msg=\"skipping role", "role/broken-a", "role/broken-b", "role/panics"},
+			skipLog:     []string{"roles failed to decode"},
+		},
+		{
+			name:        "quiet logs a summary",
+			opts:        []Option{WithContinueOnError(true)},
+			wantErr:     false,
+			wantSkipped: skipped,
+			wantLog:     []string{"level=WARN msg=\"3 roles failed to decode (use -verbose to see details)\""},
+			skipLog:     []string{"skipping role"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			a := &App{
-				client: tt.client,
+			var buf bytes.Buffer
+
+			app := &App{
+				logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})),
+				client: &panickingIAM{FakeIAM: &veiltest.FakeIAM{Roles: roles}, role: "panics"},
+				// Listing tags processes each role on its own goroutine, where the panic is recovered.
+				includeTags: true,
+			}
+			for _, opt := range tt.opts {
+				opt(app)
 			}
 
-			got, err := a.runScanIAM(t.Context())
+			got, err := app.getRolesWithTrust(t.Context())
 			if (err != nil) != tt.wantErr {
-				t.Errorf("runScanIAM() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("getRolesWithTrust() error = %v, wantErr %v", err, tt.wantErr)
+			}
 
+			if tt.wantErr {
 				return
 			}
 
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("runScanIAM() got = %v, want %v", got, tt.want)
+			want := map[string][]string{"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getRolesWithTrust() = %v, want %v", got, want)
+			}
+
+			if !reflect.DeepEqual(app.skippedRoles, tt.wantSkipped) {
+				t.Errorf("getRolesWithTrust() skipped %v, want %v", app.skippedRoles, tt.wantSkipped)
+			}
+
+			for _, line := range tt.wantLog {
+				if !strings.Contains(buf.String(), line) {
+					t.Errorf("getRolesWithTrust() log does not contain %q, got %q", line, buf.String())
+				}
+			}
+
+			for _, line := range tt.skipLog {
+				if strings.Contains(buf.String(), line) {
+					t.Errorf("getRolesWithTrust() log contains %q, got %q", line, buf.String())
+				}
 			}
 		})
 	}
 }
+
+func TestWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/broken"),
+					AssumeRolePolicyDocument: aws.String("{"),
+				},
+			},
+		},
+	}
+	WithContinueOnError(true)(app)
+	WithMetadata()(app)
+
+	output, err := app.runScanIAM(t.Context())
+	if err != nil {
+		t.Fatalf("runScanIAM() unexpected error: %v", err)
+	}
+
+	var got scanEnvelope
+
+	err = json.Unmarshal(output, &got)
+	if err != nil {
+		t.Fatalf("runScanIAM() returned invalid JSON: %v", err)
+	}
+
+	want := scanEnvelope{
+		Principals:   map[string][]string{"arn:aws:iam::210987654321:root": {"arn:aws:iam::0123456789:role/vendor"}},
+		DecodeErrors: []string{"arn:aws:iam::0123456789:role/broken"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runScanIAM() = %+v, want %+v", got, want)
+	}
+
+	data, err := outputSchema(schemaMetadata)
+	if err != nil {
+		t.Fatalf("outputSchema() unexpected error: %v", err)
+	}
+
+	var schema, value map[string]any
+
+	err = errors.Join(json.Unmarshal(data, &schema), json.Unmarshal(output, &value))
+	if err != nil {
+		t.Fatalf("failed to unmarshal schema or output: %v", err)
+	}
+
+	err = validateSchema(schema, value, "$")
+	if err != nil {
+		t.Errorf("runScanIAM() output does not match its schema: %v\n%s", err, output)
+	}
+}
+
+func Test_outermostPrefixes(t *testing.T) {
+	t.Parallel()
+
+	got := outermostPrefixes([]string{"/team-b/ci/", "/team-a/", "/team-b/", "/team-a/"})
+	if want := []string{"/team-a/", "/team-b/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outermostPrefixes() = %v, want %v", got, want)
+	}
+}
+
+func TestNewApp_fakeEndpoint(t *testing.T) {
+	t.Parallel()
+
+	fake := &veiltest.FakeIAM{Roles: pagedRoles(3), PageSize: 2}
+
+	server := veiltest.NewServer(fake)
+	defer server.Close()
+
+	app, err := NewApp(
+		t.Context(),
+		"eu-west-1",
+		DefaultConfigLoader{},
+		WithEndpointURL(server.URL),
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKIA0123456789", "secret", "")),
+		WithLogger(slog.New(slog.DiscardHandler)),
+	)
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	got, err := app.getRolesWithTrust(t.Context())
+	if err != nil {
+		t.Fatalf("getRolesWithTrust() unexpected error: %v", err)
+	}
+
+	want := map[string][]string{
+		"arn:aws:iam::0123456789:role/ecs-1": {"ecs.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/ecs-2": {"ecs.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/ecs-3": {"ecs.amazonaws.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getRolesWithTrust() got = %v, want %v", got, want)
+	}
+
+	if calls := len(fake.Calls(veiltest.OperationListRoles)); calls != 2 {
+		t.Errorf("getRolesWithTrust() listed %d pages over HTTP, want 2", calls)
+	}
+}
+
+// panickingIAM panics when the tags of the given role are listed, standing in for a bug in processing a role.
+type panickingIAM struct {
+	*veiltest.FakeIAM
+
+	role string
+}
+
+func (p *panickingIAM) ListRoleTags(
+	ctx context.Context,
+	input *iam.ListRoleTagsInput,
+	opts ...func(*iam.Options),
+) (*iam.ListRoleTagsOutput, error) {
+	if aws.ToString(input.RoleName) == p.role {
+		panic("injected panic")
+	}
+
+	return p.FakeIAM.ListRoleTags(ctx, input, opts...)
+}
+
+func TestApp_getRolesWithTrust_recoversPanic(t *testing.T) {
+	t.Parallel()
+
+	a := &App{
+		logger:      slog.New(slog.DiscardHandler),
+		includeTags: true,
+		client: &panickingIAM{
+			FakeIAM: &veiltest.FakeIAM{
+				Roles: []types.Role{
+					{
+						Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+						RoleName:                 aws.String("ecs"),
+						AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+					},
+					{
+						Arn:                      aws.String("arn:aws:iam::0123456789:role/broken"),
+						RoleName:                 aws.String("broken"),
+						AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+					},
+				},
+			},
+			role: "broken",
+		},
+	}
+
+	got, err := a.getRolesWithTrust(t.Context())
+	if !errors.Is(err, errRolePanic) {
+		t.Fatalf("getRolesWithTrust() error = %v, want %v", err, errRolePanic)
+	}
+
+	if !strings.Contains(err.Error(), "arn:aws:iam::0123456789:role/broken") ||
+		!strings.Contains(err.Error(), "injected panic") {
+		t.Errorf("getRolesWithTrust() error = %v, want the role ARN and the panic", err)
+	}
+
+	if got != nil {
+		t.Errorf("getRolesWithTrust() got = %v, want nil", got)
+	}
+}
+
+type MockServiceSTS struct {
+	input            *sts.AssumeRoleInput
+	webIdentityInput *sts.AssumeRoleWithWebIdentityInput
+}
+
+func (m *MockServiceSTS) AssumeRole(
+	_ context.Context,
+	input *sts.AssumeRoleInput,
+	_ ...func(*sts.Options),
+) (*sts.AssumeRoleOutput, error) {
+	m.input = input
+
+	return &sts.AssumeRoleOutput{
+		Credentials: &ststypes.Credentials{
+			AccessKeyId:     aws.String("AKIA0123456789"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+		},
+	}, nil
+}
+
+func (m *MockServiceSTS) AssumeRoleWithWebIdentity(
+	_ context.Context,
+	input *sts.AssumeRoleWithWebIdentityInput,
+	_ ...func(*sts.Options),
+) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	m.webIdentityInput = input
+
+	return &sts.AssumeRoleWithWebIdentityOutput{
+		Credentials: &ststypes.Credentials{
+			AccessKeyId:     aws.String("AKIA0123456789"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+		},
+	}, nil
+}
+
+var _ ServiceSTS = (*MockServiceSTS)(nil)
+
+func TestWithRoleSessionName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "default session name",
+			opts:    nil,
+			want:    defaultRoleSessionName,
+			wantErr: nil,
+		},
+		{
+			name:    "custom session name",
+			opts:    []Option{WithRoleSessionName("security-audit")},
+			want:    "security-audit",
+			wantErr: nil,
+		},
+		{
+			name:    "session name with spaces",
+			opts:    []Option{WithRoleSessionName("security audit")},
+			want:    "",
+			wantErr: errInvalidRoleSessionName,
+		},
+		{
+			name:    "session name too long",
+			opts:    []Option{WithRoleSessionName(strings.Repeat("a", 65))},
+			want:    "",
+			wantErr: errInvalidRoleSessionName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]Option{WithAssumeRole("arn:aws:iam::0123456789:role/audit")}, tt.opts...)
+
+			app, err := NewApp(t.Context(), "eu-west-1", &mockConfigLoader{}, opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewApp() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			mock := &MockServiceSTS{}
+			app.stsClient = mock
+
+			_, err = app.assumeRoleProvider().Retrieve(t.Context())
+			if err != nil {
+				t.Fatalf("Retrieve() unexpected error: %v", err)
+			}
+
+			if got := aws.ToString(mock.input.RoleSessionName); got != tt.want {
+				t.Errorf("AssumeRole() RoleSessionName = %v, want %v", got, tt.want)
+			}
+
+			if got := aws.ToString(mock.input.RoleArn); got != "arn:aws:iam::0123456789:role/audit" {
+				t.Errorf("AssumeRole() RoleArn = %v", got)
+			}
+		})
+	}
+}
+
+func TestWithSourceIdentity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "no source identity",
+			opts:    nil,
+			want:    "",
+			wantErr: nil,
+		},
+		{
+			name:    "source identity",
+			opts:    []Option{WithSourceIdentity("jane.doe@example.com")},
+			want:    "jane.doe@example.com",
+			wantErr: nil,
+		},
+		{
+			name:    "source identity with a colon",
+			opts:    []Option{WithSourceIdentity("aws:admin")},
+			want:    "",
+			wantErr: errInvalidSourceIdentity,
+		},
+		{
+			name:    "source identity too short",
+			opts:    []Option{WithSourceIdentity("a")},
+			want:    "",
+			wantErr: errInvalidSourceIdentity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]Option{WithAssumeRole("arn:aws:iam::0123456789:role/audit")}, tt.opts...)
+
+			app, err := NewApp(t.Context(), "eu-west-1", &mockConfigLoader{}, opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewApp() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			mock := &MockServiceSTS{}
+			app.stsClient = mock
+
+			_, err = app.assumeRoleProvider().Retrieve(t.Context())
+			if err != nil {
+				t.Fatalf("Retrieve() unexpected error: %v", err)
+			}
+
+			if got := aws.ToString(mock.input.SourceIdentity); got != tt.want {
+				t.Errorf("AssumeRole() SourceIdentity = %v, want %v", got, tt.want)
+			}
+
+			if (mock.input.SourceIdentity == nil) != (tt.want == "") {
+				t.Errorf("AssumeRole() SourceIdentity set = %v, want %v", mock.input.SourceIdentity != nil, tt.want != "")
+			}
+		})
+	}
+}
+
+func TestRegisterFlags_sessionAliases(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("veil", flag.ContinueOnError)
+	flags := registerFlags(fs)
+
+	err := fs.Parse([]string{
+		"-assume-role", "arn:aws:iam::0123456789:role/audit",
+		"-session-name", "nightly-audit",
+		"-session-duration", "4h",
+	})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		t.Fatalf("options() unexpected error: %v", err)
+	}
+
+	app, err := NewApp(t.Context(), "eu-west-1", &mockConfigLoader{}, opts...)
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	mock := &MockServiceSTS{}
+	app.stsClient = mock
+
+	_, err = app.assumeRoleProvider().Retrieve(t.Context())
+	if err != nil {
+		t.Fatalf("Retrieve() unexpected error: %v", err)
+	}
+
+	if got := aws.ToString(mock.input.RoleSessionName); got != "nightly-audit" {
+		t.Errorf("AssumeRole() RoleSessionName = %v, want nightly-audit", got)
+	}
+
+	if got := aws.ToInt32(mock.input.DurationSeconds); got != 4*60*60 {
+		t.Errorf("AssumeRole() DurationSeconds = %v, want %v", got, 4*60*60)
+	}
+}
+
+func TestWithIRSA(t *testing.T) {
+	t.Parallel()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+
+	err := os.WriteFile(tokenFile, []byte("eyJhbGciOiJSUzI1NiJ9.service-account"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		roleARN     string
+		tokenFile   string
+		sessionName string
+		wantSession string
+		wantErr     bool
+	}{
+		{
+			name:        "default session name",
+			roleARN:     "arn:aws:iam::0123456789:role/veil",
+			tokenFile:   tokenFile,
+			sessionName: "",
+			wantSession: irsaRoleSessionName,
+			wantErr:     false,
+		},
+		{
+			name:        "custom session name",
+			roleARN:     "arn:aws:iam::0123456789:role/veil",
+			tokenFile:   tokenFile,
+			sessionName: "eks-audit",
+			wantSession: "eks-audit",
+			wantErr:     false,
+		},
+		{
+			name:      "missing token file",
+			roleARN:   "arn:aws:iam::0123456789:role/veil",
+			tokenFile: "",
+			wantErr:   true,
+		},
+		{
+			name:      "missing role ARN",
+			roleARN:   "",
+			tokenFile: tokenFile,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithIRSA(tt.roleARN, tt.tokenFile, tt.sessionName),
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewApp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if app.cfg.Credentials == nil {
+				t.Fatalf("NewApp() expected credentials provider to be set")
+			}
+
+			mock := &MockServiceSTS{}
+			app.stsClient = mock
+
+			_, err = app.webIdentityProvider().Retrieve(t.Context())
+			if err != nil {
+				t.Fatalf("Retrieve() unexpected error: %v", err)
+			}
+
+			got := aws.ToString(mock.webIdentityInput.WebIdentityToken)
+			if got != "eyJhbGciOiJSUzI1NiJ9.service-account" {
+				t.Errorf("AssumeRoleWithWebIdentity() WebIdentityToken = %v", got)
+			}
+
+			if got := aws.ToString(mock.webIdentityInput.RoleArn); got != tt.roleARN {
+				t.Errorf("AssumeRoleWithWebIdentity() RoleArn = %v, want %v", got, tt.roleARN)
+			}
+
+			if got := aws.ToString(mock.webIdentityInput.RoleSessionName); got != tt.wantSession {
+				t.Errorf("AssumeRoleWithWebIdentity() RoleSessionName = %v, want %v", got, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestApp_GetRolePaths(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		client  ServiceIAM
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "failed to list roles",
+			client: &veiltest.FakeIAM{
+				Errors: map[string]error{veiltest.OperationListRoles: errors.New("test error")},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid trust policy is not decoded",
+			client: &veiltest.FakeIAM{
+				Roles: []types.Role{
+					{
+						Arn:                      aws.String("arn:aws:iam::0123456789:role/service/test"),
+						AssumeRolePolicyDocument: aws.String("invalid policy"),
+						Path:                     aws.String("/service/"),
+						RoleName:                 aws.String("test"),
+					},
+				},
+			},
+			want: map[string]string{
+				"arn:aws:iam::0123456789:role/service/test": "/service/",
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: tt.client,
+			}
+
+			got, err := a.GetRolePaths(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRolePaths() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRolePaths() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAssumeRoleDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		duration    time.Duration
+		wantSeconds int32
+		wantErr     bool
+	}{
+		{
+			name:        "too short",
+			duration:    time.Minute,
+			wantSeconds: 0,
+			wantErr:     true,
+		},
+		{
+			name:        "too long",
+			duration:    13 * time.Hour,
+			wantSeconds: 0,
+			wantErr:     true,
+		},
+		{
+			name:        "minimum",
+			duration:    15 * time.Minute,
+			wantSeconds: 900,
+			wantErr:     false,
+		},
+		{
+			name:        "maximum",
+			duration:    12 * time.Hour,
+			wantSeconds: 43200,
+			wantErr:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithAssumeRole("arn:aws:iam::0123456789:role/audit"),
+				WithAssumeRoleDuration(tt.duration),
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewApp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			mock := &MockServiceSTS{}
+			app.stsClient = mock
+
+			_, err = app.assumeRoleProvider().Retrieve(t.Context())
+			if err != nil {
+				t.Fatalf("Retrieve() unexpected error: %v", err)
+			}
+
+			if got := aws.ToInt32(mock.input.DurationSeconds); got != tt.wantSeconds {
+				t.Errorf("AssumeRole() DurationSeconds = %v, want %v", got, tt.wantSeconds)
+			}
+		})
+	}
+}
+
+type mockConfigLoader struct {
+	mockConfig    aws.Config
+	mockConfigErr error
+	loadOptions   config.LoadOptions
+}
+
+//nolint:nonamedreturns
+func (m *mockConfigLoader) LoadDefaultConfig(
+	_ context.Context,
+	optFns ...func(*config.LoadOptions) error,
+) (cfg aws.Config, err error) {
+	for _, optFn := range optFns {
+		errOpt := optFn(&m.loadOptions)
+		if errOpt != nil {
+			return aws.Config{}, errOpt
+		}
+	}
+
+	return m.mockConfig, m.mockConfigErr
+}
+
+var _ ConfigLoader = (*mockConfigLoader)(nil)
+
+func TestNewApp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		loader  ConfigLoader
+		region  string
+		wantApp bool
+		wantErr bool
+	}{
+		{
+			name:    "empty region",
+			loader:  nil,
+			region:  "",
+			wantApp: false,
+			wantErr: true,
+		},
+		{
+			name: "config loader error",
+			loader: &mockConfigLoader{
+				mockConfigErr: errors.New("test error"),
+			},
+			region:  "eu-west-1",
+			wantApp: false,
+			wantErr: true,
+		},
+		{
+			name: "success setup with mock config",
+			loader: &mockConfigLoader{
+				mockConfig: aws.Config{},
+			},
+			region:  "eu-west-1",
+			wantApp: true,
+			wantErr: false,
+		},
+		{
+			name:    "success setup with default config",
+			loader:  nil,
+			region:  "eu-west-1",
+			wantApp: true,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewApp(t.Context(), tt.region, tt.loader)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewApp() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if (got != nil) != tt.wantApp {
+				t.Errorf("got app = %v, want non-nil: %v", got, tt.wantApp)
+			}
+		})
+	}
+}
+
+type stubCredentialsProvider struct {
+	calls atomic.Int32
+}
+
+func (s *stubCredentialsProvider) Retrieve(_ context.Context) (aws.Credentials, error) {
+	s.calls.Add(1)
+
+	return aws.Credentials{
+		AccessKeyID:     "AKIAVAULT",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Source:          "vault",
+	}, nil
+}
+
+func TestWithCredentialsProvider(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubCredentialsProvider{}
+
+	loader := &mockConfigLoader{}
+
+	_, err := NewApp(t.Context(), "eu-west-1", loader, WithCredentialsProvider(stub))
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	if loader.loadOptions.Credentials != stub {
+		t.Errorf("NewApp() credentials = %v, want the stub provider", loader.loadOptions.Credentials)
+	}
+
+	app, err := NewApp(t.Context(), "eu-west-1", DefaultConfigLoader{}, WithCredentialsProvider(stub), WithLazyInit())
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	creds, err := app.cfg.Credentials.Retrieve(t.Context())
+	if err != nil {
+		t.Fatalf("Retrieve() unexpected error: %v", err)
+	}
+
+	if creds.AccessKeyID != "AKIAVAULT" || stub.calls.Load() != 1 {
+		t.Errorf("Retrieve() = %v after %d calls, want the stub credentials", creds.AccessKeyID, stub.calls.Load())
+	}
+}
+
+func TestNewApp_endpointOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantFIPS     aws.FIPSEndpointState
+		wantDual     aws.DualStackEndpointState
+		wantEndpoint string
+	}{
+		{
+			name:         "defaults",
+			opts:         nil,
+			wantFIPS:     aws.FIPSEndpointStateUnset,
+			wantDual:     aws.DualStackEndpointStateUnset,
+			wantEndpoint: "",
+		},
+		{
+			name:         "fips and dual-stack",
+			opts:         []Option{WithFIPS(), WithDualStack()},
+			wantFIPS:     aws.FIPSEndpointStateEnabled,
+			wantDual:     aws.DualStackEndpointStateEnabled,
+			wantEndpoint: "",
+		},
+		{
+			name:         "explicit endpoint with fips",
+			opts:         []Option{WithFIPS(), WithEndpointURL("http://localhost:4566")},
+			wantFIPS:     aws.FIPSEndpointStateEnabled,
+			wantDual:     aws.DualStackEndpointStateUnset,
+			wantEndpoint: "http://localhost:4566",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			loader := &mockConfigLoader{}
+
+			_, err := NewApp(t.Context(), "us-gov-west-1", loader, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewApp() unexpected error: %v", err)
+			}
+
+			if loader.loadOptions.Region != "us-gov-west-1" {
+				t.Errorf("NewApp() region = %v", loader.loadOptions.Region)
+			}
+
+			if loader.loadOptions.UseFIPSEndpoint != tt.wantFIPS {
+				t.Errorf("NewApp() FIPS = %v, want %v", loader.loadOptions.UseFIPSEndpoint, tt.wantFIPS)
+			}
+
+			if loader.loadOptions.UseDualStackEndpoint != tt.wantDual {
+				t.Errorf("NewApp() dual-stack = %v, want %v", loader.loadOptions.UseDualStackEndpoint, tt.wantDual)
+			}
+
+			if loader.loadOptions.BaseEndpoint != tt.wantEndpoint {
+				t.Errorf("NewApp() endpoint = %v, want %v", loader.loadOptions.BaseEndpoint, tt.wantEndpoint)
+			}
+		})
+	}
+}
+
+type failingHTTPClient struct{}
+
+func (failingHTTPClient) Do(_ *http.Request) (*http.Response, error) {
+	return nil, errors.New("test error")
+}
+
+func TestWithLazyInit(t *testing.T) {
+	t.Parallel()
+
+	loader := &mockConfigLoader{
+		mockConfig: aws.Config{
+			Region:      "eu-west-1",
+			Credentials: credentials.NewStaticCredentialsProvider("AKIA0123456789", "secret", ""),
+			HTTPClient:  failingHTTPClient{},
+			Retryer: func() aws.Retryer {
+				return aws.NopRetryer{}
+			},
+		},
+	}
+
+	app, err := NewApp(t.Context(), "eu-west-1", loader, WithLazyInit())
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	if app.client != nil {
+		t.Fatalf("NewApp() created the IAM client before the first scan")
+	}
+
+	_, err = app.runScanIAM(t.Context())
+	if err == nil {
+		t.Errorf("runScanIAM() expected error from failing HTTP client")
+	}
+
+	if app.client == nil {
+		t.Errorf("runScanIAM() did not create the IAM client")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	app, err := NewApp(t.Context(), "eu-west-1", &mockConfigLoader{}, WithLazyInit(), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	app.client = &veiltest.FakeIAM{
+		Roles: []types.Role{
+			{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSReservedSSOFullAdmin),
+			},
+		},
+	}
+
+	_, err = app.runScanIAM(t.Context())
+	if err != nil {
+		t.Fatalf("runScanIAM() unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "found IAM roles and principals") {
+		t.Errorf("runScanIAM() did not log to the App logger, got %q", buf.String())
+	}
+
+	defaultApp, err := NewApp(t.Context(), "eu-west-1", &mockConfigLoader{}, WithLazyInit())
+	if err != nil {
+		t.Fatalf("NewApp() unexpected error: %v", err)
+	}
+
+	if defaultApp.logger != slog.Default() {
+		t.Errorf("NewApp() without WithLogger did not use the default logger")
+	}
+}
+
+func TestIRSAConfigLoader_LoadDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		roleARN   string
+		tokenFile string
+		wantErr   bool
+	}{
+		{
+			name:      "missing token file",
+			roleARN:   "arn:aws:iam::0123456789:role/veil",
+			tokenFile: "",
+			wantErr:   true,
+		},
+		{
+			name:      "missing role ARN",
+			roleARN:   "",
+			tokenFile: "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+			wantErr:   true,
+		},
+		{
+			name:      "success",
+			roleARN:   "arn:aws:iam::0123456789:role/veil",
+			tokenFile: "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+			wantErr:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			loader := IRSAConfigLoader{
+				RoleARN:   tt.roleARN,
+				TokenFile: tt.tokenFile,
+			}
+
+			if tt.roleARN == "" && os.Getenv("AWS_ROLE_ARN") != "" {
+				t.Skip("AWS_ROLE_ARN is set in the environment")
+			}
+
+			if tt.tokenFile == "" && os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" {
+				t.Skip("AWS_WEB_IDENTITY_TOKEN_FILE is set in the environment")
+			}
+
+			cfg, err := loader.LoadDefaultConfig(t.Context(), config.WithRegion("eu-west-1"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadDefaultConfig() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !tt.wantErr && cfg.Credentials == nil {
+				t.Errorf("LoadDefaultConfig() expected credentials provider to be set")
+			}
+		})
+	}
+}
+
+func TestApp_runScanIAM(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		client  ServiceIAM
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name: "success",
+			client: &veiltest.FakeIAM{
+				Roles: []types.Role{
+					{
+						Arn: aws.String(
+							"arn:aws:iam::0123456789:role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_FullAdmin",
+						),
+						AssumeRolePolicyDocument: aws.String(fixtureAWSReservedSSOFullAdmin),
+					},
+				},
+			},
+			want: []byte{
+				123, 10, 32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53,
+				54, 55, 56, 57, 58, 115, 97, 109, 108, 45, 112, 114, 111, 118, 105, 100, 101, 114, 47, 65, 87, 83, 83,
+				83, 79, 95, 50, 52, 95, 68, 79, 95, 78, 79, 84, 95, 68, 69, 76, 69, 84, 69, 34, 58, 32, 91, 10, 32, 32,
+				32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53, 54, 55,
+				56, 57, 58, 114, 111, 108, 101, 47, 97, 119, 115, 45, 114, 101, 115, 101, 114, 118, 101, 100, 47, 115,
+				115, 111, 46, 97, 109, 97, 122, 111, 110, 97, 119, 115, 46, 99, 111, 109, 47, 65, 87, 83, 82, 101, 115,
+				101, 114, 118, 101, 100, 83, 83, 79, 95, 70, 117, 108, 108, 65, 100, 109, 105, 110, 34, 10, 32, 32, 93,
+				44, 10, 32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53,
+				54, 55, 56, 57, 58, 115, 97, 109, 108, 45, 112, 114, 111, 118, 105, 100, 101, 114, 47, 65, 87, 83, 83,
+				83, 79, 95, 52, 50, 95, 68, 79, 95, 78, 79, 84, 95, 68, 69, 76, 69, 84, 69, 34, 58, 32, 91, 10, 32, 32,
+				32, 32, 34, 97, 114, 110, 58, 97, 119, 115, 58, 105, 97, 109, 58, 58, 48, 49, 50, 51, 52, 53, 54, 55,
+				56, 57, 58, 114, 111, 108, 101, 47, 97, 119, 115, 45, 114, 101, 115, 101, 114, 118, 101, 100, 47, 115,
+				115, 111, 46, 97, 109, 97, 122, 111, 110, 97, 119, 115, 46, 99, 111, 109, 47, 65, 87, 83, 82, 101, 115,
+				101, 114, 118, 101, 100, 83, 83, 79, 95, 70, 117, 108, 108, 65, 100, 109, 105, 110, 34, 10, 32, 32, 93,
+				10, 125,
+			},
+			wantErr: false,
+		},
+		{
+			name: "failed to list roles",
+			client: &veiltest.FakeIAM{
+				Roles:  []types.Role{},
+				Errors: map[string]error{veiltest.OperationListRoles: errors.New("test error")},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: tt.client,
+			}
+
+			got, err := a.runScanIAM(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runScanIAM() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runScanIAM() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRoleAccounts(t *testing.T) {
+	t.Parallel()
+
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::111111111111:role/ecs"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::222222222222:role/ecs"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::333333333333:role/ecs"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name:    "no filter",
+			include: nil,
+			exclude: nil,
+			want: []string{
+				"arn:aws:iam::111111111111:role/ecs",
+				"arn:aws:iam::222222222222:role/ecs",
+				"arn:aws:iam::333333333333:role/ecs",
+			},
+		},
+		{
+			name:    "include",
+			include: []string{"111111111111", "333333333333"},
+			exclude: nil,
+			want: []string{
+				"arn:aws:iam::111111111111:role/ecs",
+				"arn:aws:iam::333333333333:role/ecs",
+			},
+		},
+		{
+			name:    "exclude",
+			include: nil,
+			exclude: []string{"222222222222"},
+			want: []string{
+				"arn:aws:iam::111111111111:role/ecs",
+				"arn:aws:iam::333333333333:role/ecs",
+			},
+		},
+		{
+			name:    "exclusion wins",
+			include: []string{"111111111111", "222222222222"},
+			exclude: []string{"222222222222"},
+			want:    []string{"arn:aws:iam::111111111111:role/ecs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithLazyInit(),
+				WithRoleAccounts(tt.include, tt.exclude),
+			)
+			if err != nil {
+				t.Fatalf("NewApp() unexpected error: %v", err)
+			}
+
+			app.client = &veiltest.FakeIAM{Roles: roles}
+
+			got, err := app.getRolesWithTrust(t.Context())
+			if err != nil {
+				t.Fatalf("getRolesWithTrust() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(slices.Sorted(maps.Keys(got)), tt.want) {
+				t.Errorf("getRolesWithTrust() roles = %v, want %v", slices.Sorted(maps.Keys(got)), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithNewSince(t *testing.T) {
+	t.Parallel()
+
+	marker := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/old"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			CreateDate:               aws.Time(marker.AddDate(-1, 0, 0)),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/at-marker"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			CreateDate:               aws.Time(marker),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/new"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			CreateDate:               aws.Time(marker.Add(time.Hour)),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/undated"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		marker time.Time
+		want   []string
+	}{
+		{
+			name:   "no marker",
+			marker: time.Time{},
+			want: []string{
+				"arn:aws:iam::0123456789:role/at-marker",
+				"arn:aws:iam::0123456789:role/new",
+				"arn:aws:iam::0123456789:role/old",
+				"arn:aws:iam::0123456789:role/undated",
+			},
+		},
+		{
+			name:   "created after the marker",
+			marker: marker,
+			want:   []string{"arn:aws:iam::0123456789:role/new"},
+		},
+		{
+			name:   "marker after every role",
+			marker: marker.AddDate(1, 0, 0),
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithLazyInit(),
+				WithNewSince(tt.marker),
+			)
+			if err != nil {
+				t.Fatalf("NewApp() unexpected error: %v", err)
+			}
+
+			app.client = &veiltest.FakeIAM{Roles: roles}
+
+			got, err := app.GetRolePaths(t.Context())
+			if err != nil {
+				t.Fatalf("GetRolePaths() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(slices.Sorted(maps.Keys(got)), tt.want) {
+				t.Errorf("GetRolePaths() roles = %v, want %v", slices.Sorted(maps.Keys(got)), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRoleAge(t *testing.T) {
+	t.Parallel()
+
+	const day = 24 * time.Hour
+
+	now := time.Now()
+	roles := make([]types.Role, 0)
+
+	for _, age := range []int{10, 40, 100} {
+		roles = append(roles, types.Role{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/" + strconv.Itoa(age) + "-days"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			CreateDate:               aws.Time(now.Add(-time.Duration(age) * day)),
+		})
+	}
+
+	roles = append(roles, types.Role{
+		Arn:                      aws.String("arn:aws:iam::0123456789:role/undated"),
+		AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+	})
+
+	tests := []struct {
+		name    string
+		minAge  time.Duration
+		maxAge  time.Duration
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "max age",
+			minAge:  0,
+			maxAge:  30 * day,
+			want:    []string{"arn:aws:iam::0123456789:role/10-days"},
+			wantErr: nil,
+		},
+		{
+			name:   "min age",
+			minAge: 30 * day,
+			maxAge: 0,
+			want: []string{
+				"arn:aws:iam::0123456789:role/100-days",
+				"arn:aws:iam::0123456789:role/40-days",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "between",
+			minAge:  30 * day,
+			maxAge:  90 * day,
+			want:    []string{"arn:aws:iam::0123456789:role/40-days"},
+			wantErr: nil,
+		},
+		{
+			name:    "min age above max age",
+			minAge:  90 * day,
+			maxAge:  30 * day,
+			want:    nil,
+			wantErr: errInvalidRoleAge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithLazyInit(),
+				WithRoleAge(tt.minAge, tt.maxAge),
+			)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewApp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			app.client = &veiltest.FakeIAM{Roles: roles}
+
+			got, err := app.GetRolePaths(t.Context())
+			if err != nil {
+				t.Fatalf("GetRolePaths() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(slices.Sorted(maps.Keys(got)), tt.want) {
+				t.Errorf("GetRolePaths() roles = %v, want %v", slices.Sorted(maps.Keys(got)), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRoleNames(t *testing.T) {
+	t.Parallel()
+
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+			RoleName:                 aws.String("ecs"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+			RoleName:                 aws.String("vendor"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/unlisted"),
+			RoleName:                 aws.String("unlisted"),
+			AssumeRolePolicyDocument: aws.String(fixtureWildcardPrincipal),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		names   []string
+		getErrs map[string]error
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "present names",
+			names: []string{"ecs", "vendor"},
+			want: []string{
+				"arn:aws:iam::0123456789:role/ecs",
+				"arn:aws:iam::0123456789:role/vendor",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "missing names are skipped",
+			names:   []string{"ecs", "deleted", "renamed"},
+			want:    []string{"arn:aws:iam::0123456789:role/ecs"},
+			wantErr: false,
+		},
+		{
+			name:    "failures are collected per name",
+			names:   []string{"ecs", "vendor"},
+			getErrs: map[string]error{"vendor": errors.New("test error")},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				WithLazyInit(),
+				WithRoleNames(tt.names),
+				WithLogger(slog.New(slog.DiscardHandler)),
+			)
+			if err != nil {
+				t.Fatalf("NewApp() unexpected error: %v", err)
+			}
+
+			fake := &veiltest.FakeIAM{Roles: roles, RoleErrors: tt.getErrs}
+			app.client = fake
+
+			got, err := app.getRoleTrusts(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRoleTrusts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), "vendor: test error") {
+					t.Errorf("getRoleTrusts() error = %v, want the failing role name", err)
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(slices.Sorted(maps.Keys(got)), tt.want) {
+				t.Errorf("getRoleTrusts() roles = %v, want %v", slices.Sorted(maps.Keys(got)), tt.want)
+			}
+
+			if calls := len(fake.Calls(veiltest.OperationListRoles)); calls != 0 {
+				t.Errorf("getRoleTrusts() listed roles %d times, want GetRole only", calls)
+			}
+		})
+	}
+}
+
+// syntheticRoles returns count roles in the URL-encoded form IAM returns trust policies in, alternating between
+// cross-account, service and federated trust.
+func syntheticRoles(count int) []types.Role {
+	documents := []string{fixtureCrossAccountTagSession, fixtureAWSServiceRoleForECS, fixtureGitHubOIDCUnpinned}
+	output := make([]types.Role, 0, count)
+
+	for index := range count {
+		output = append(output, types.Role{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/synthetic-" + strconv.Itoa(index)),
+			Path:                     aws.String("/"),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(documents[index%len(documents)])),
+		})
+	}
+
+	return output
+}
+
+func BenchmarkGetRolesWithTrust(b *testing.B) {
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{Roles: syntheticRoles(50_000), PageSize: 1000},
+	}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_, err := app.getRolesWithTrust(b.Context())
+		if err != nil {
+			b.Fatalf("getRolesWithTrust() unexpected error: %v", err)
+		}
+	}
+}
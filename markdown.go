@@ -0,0 +1,285 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// markdownEscaper escapes characters with a meaning in Markdown text or table cells.
+var markdownEscaper = strings.NewReplacer( //nolint:gochecknoglobals
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"|", `\|`,
+	"<", `\<`,
+	">", `\>`,
+)
+
+// markdownText escapes the given value for use as plain Markdown text.
+func markdownText(value string) string {
+	return markdownEscaper.Replace(value)
+}
+
+// markdownCode renders the given value, typically an ARN, as an inline code span safe to use in a table cell.
+// The code span delimiter is made longer than any run of backticks inside the value.
+func markdownCode(value string) string {
+	longest, run := 0, 0
+	for _, char := range value {
+		if char != '`' {
+			run = 0
+
+			continue
+		}
+
+		run++
+		longest = max(longest, run)
+	}
+
+	fence := strings.Repeat("`", longest+1)
+	value = strings.ReplaceAll(value, "|", `\|`)
+
+	if longest > 0 {
+		return fence + " " + value + " " + fence
+	}
+
+	return fence + value + fence
+}
+
+// crossAccountTrust describes an AWS principal of another account trusted by a role.
+type crossAccountTrust struct {
+	role       string
+	principal  string
+	externalID bool
+}
+
+// findCrossAccountTrusts returns the principals of other accounts trusted by each role, sorted by role and principal.
+func findCrossAccountTrusts(trusts map[string]roleTrust) []crossAccountTrust {
+	output := make([]crossAccountTrust, 0)
+
+	for role, trust := range trusts {
+		roleAccount := accountFromARN(role)
+
+		for _, statement := range trust.policy.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			for _, principal := range statement.effectiveAWSPrincipals() {
+				account := accountFromARN(normalisePrincipal(principal))
+				if account == "" || account == roleAccount || strings.ContainsAny(account, "*?") {
+					continue
+				}
+
+				output = append(output, crossAccountTrust{
+					role:       role,
+					principal:  principal,
+					externalID: statement.hasConditionKey(conditionExternalID),
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(output, func(a, b crossAccountTrust) int {
+		if a.role != b.role {
+			return strings.Compare(a.role, b.role)
+		}
+
+		return strings.Compare(a.principal, b.principal)
+	})
+
+	return output
+}
+
+// buildMarkdownReport renders a Markdown report summarising the trust policies and the findings raised against them.
+// Roles are shown by their label, if they have one, with a closing table mapping labels back to ARNs.
+func buildMarkdownReport(trusts map[string]roleTrust, findings []Finding, labels map[string]string) []byte {
+	var buf bytes.Buffer
+
+	principals := make([]string, 0)
+	for _, trust := range trusts {
+		principals = append(principals, trust.policy.getAllPrincipals()...)
+	}
+
+	public := make([]Finding, 0)
+	warnings := make([]Finding, 0)
+	severities := make(map[string]int)
+	orgWide := 0
+
+	for _, finding := range findings {
+		severities[finding.Severity]++
+
+		if finding.Code == codeOrgWideTrust {
+			orgWide++
+		}
+
+		if finding.Code == codeWildcardPrincipal {
+			public = append(public, finding)
+
+			continue
+		}
+
+		warnings = append(warnings, finding)
+	}
+
+	crossAccount := findCrossAccountTrusts(trusts)
+
+	buf.WriteString("# IAM trust report\n\n")
+
+	buf.WriteString("## Summary\n\n")
+	buf.WriteString("| Metric | Count |\n")
+	buf.WriteString("| --- | ---: |\n")
+	writeMarkdownRow(&buf, "Roles", strconv.Itoa(len(trusts)))
+	writeMarkdownRow(&buf, "Principals", strconv.Itoa(len(uniqSlice(principals))))
+	writeMarkdownRow(&buf, "Public roles", strconv.Itoa(len(public)))
+	writeMarkdownRow(&buf, "Cross-account trusts", strconv.Itoa(len(crossAccount)))
+	writeMarkdownRow(&buf, "Organisation-wide trusts", strconv.Itoa(orgWide))
+	writeMarkdownRow(&buf, "High findings", strconv.Itoa(severities[severityHigh]))
+	writeMarkdownRow(&buf, "Medium findings", strconv.Itoa(severities[severityMedium]))
+	writeMarkdownRow(&buf, "Info findings", strconv.Itoa(severities[severityInfo]))
+
+	buf.WriteString("\n## Public roles\n\n")
+
+	if len(public) == 0 {
+		buf.WriteString("None.\n")
+	} else {
+		buf.WriteString("| Role | Principal | Severity |\n")
+		buf.WriteString("| --- | --- | --- |\n")
+
+		for _, finding := range public {
+			writeMarkdownRow(
+				&buf,
+				markdownCode(roleLabel(labels, finding.Role)),
+				markdownCode(finding.Principal),
+				finding.Severity,
+			)
+		}
+	}
+
+	buf.WriteString("\n## Cross-account trust\n\n")
+
+	if len(crossAccount) == 0 {
+		buf.WriteString("None.\n")
+	} else {
+		buf.WriteString("| Role | Principal | External ID |\n")
+		buf.WriteString("| --- | --- | --- |\n")
+
+		for _, trust := range crossAccount {
+			externalID := "no"
+			if trust.externalID {
+				externalID = "yes"
+			}
+
+			writeMarkdownRow(
+				&buf,
+				markdownCode(roleLabel(labels, trust.role)),
+				markdownCode(trust.principal),
+				externalID,
+			)
+		}
+	}
+
+	buf.WriteString("\n## Warnings\n\n")
+
+	if len(warnings) == 0 {
+		buf.WriteString("None.\n")
+	} else {
+		buf.WriteString("| Code | Severity | Role | Principal | Message |\n")
+		buf.WriteString("| --- | --- | --- | --- | --- |\n")
+
+		for _, finding := range warnings {
+			principal := ""
+			if finding.Principal != "" {
+				principal = markdownCode(finding.Principal)
+			}
+
+			writeMarkdownRow(
+				&buf,
+				markdownCode(finding.Code),
+				finding.Severity,
+				markdownCode(roleLabel(labels, finding.Role)),
+				principal,
+				markdownText(finding.Message),
+			)
+		}
+	}
+
+	writeMarkdownTags(&buf, trusts, labels)
+
+	if len(labels) > 0 {
+		buf.WriteString("\n## Roles\n\n")
+		buf.WriteString("| Role | ARN |\n")
+		buf.WriteString("| --- | --- |\n")
+
+		for _, role := range slices.Sorted(maps.Keys(labels)) {
+			writeMarkdownRow(&buf, markdownCode(labels[role]), markdownCode(role))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// writeMarkdownTags writes the tags of the roles having any, when they were fetched.
+func writeMarkdownTags(buf *bytes.Buffer, trusts map[string]roleTrust, labels map[string]string) {
+	tagged := make([]string, 0)
+
+	for role, trust := range trusts {
+		if len(trust.role.Tags) > 0 {
+			tagged = append(tagged, role)
+		}
+	}
+
+	if len(tagged) == 0 {
+		return
+	}
+
+	slices.Sort(tagged)
+
+	buf.WriteString("\n## Tags\n\n")
+	buf.WriteString("| Role | Tags |\n")
+	buf.WriteString("| --- | --- |\n")
+
+	for _, role := range tagged {
+		writeMarkdownRow(
+			buf,
+			markdownCode(roleLabel(labels, role)),
+			markdownText(formatTags(tagMap(trusts[role].role.Tags))),
+		)
+	}
+}
+
+// writeMarkdownRow writes a single table row made of already escaped cells.
+func writeMarkdownRow(buf *bytes.Buffer, cells ...string) {
+	buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+}
+
+func (a *App) runMarkdownReport(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	env, err := a.checkEnv(ctx, trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := runChecks(env, trusts)
+
+	a.logger.Debug(
+		"rendered IAM roles trust report",
+		slog.Int("roles", len(trusts)),
+		slog.Int("findings", len(findings)),
+	)
+
+	return buildMarkdownReport(trusts, findings, a.roleLabels(slices.Collect(maps.Keys(trusts)))), nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+func Test_buildMarkdownReport(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/public": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/public",
+			fixtureWildcardPrincipal,
+		),
+		"arn:aws:iam::0123456789:role/vendor": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/vendor",
+			fixtureCrossAccountTagSession,
+		),
+		"arn:aws:iam::0123456789:role/org": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/org",
+			fixtureOrgWideTrust,
+		),
+		"arn:aws:iam::0123456789:role/ecs": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/ecs",
+			fixtureAWSServiceRoleForECS,
+		),
+	}
+
+	got := string(buildMarkdownReport(trusts, runChecks(checkEnv{orgID: ""}, trusts), nil))
+
+	for _, want := range []string{
+		"# IAM trust report\n",
+		"## Summary\n",
+		"| Roles | 4 |\n",
+		"| Public roles | 1 |\n",
+		"| Organisation-wide trusts | 1 |\n",
+		"| High findings | 1 |\n",
+		"## Public roles\n",
+		"| `arn:aws:iam::0123456789:role/public` | `*` | high |\n",
+		"## Cross-account trust\n",
+		"| `arn:aws:iam::0123456789:role/vendor` | `arn:aws:iam::210987654321:root` | no |\n",
+		"## Warnings\n",
+		"| `" + codeCrossAccountNoExternalID + "` | medium | `arn:aws:iam::0123456789:role/vendor` |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("buildMarkdownReport() missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func Test_buildMarkdownReport_shortNames(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/service-role/vendor": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/service-role/vendor",
+			fixtureCrossAccountTagSession,
+		),
+	}
+	labels := shortRoleNames([]string{"arn:aws:iam::0123456789:role/service-role/vendor"})
+
+	got := string(buildMarkdownReport(trusts, runChecks(checkEnv{orgID: ""}, trusts), labels))
+
+	for _, want := range []string{
+		"| `/service-role/vendor` | `arn:aws:iam::210987654321:root` | no |\n",
+		"| `" + codeCrossAccountNoExternalID + "` | medium | `/service-role/vendor` |",
+		"## Roles\n",
+		"| `/service-role/vendor` | `arn:aws:iam::0123456789:role/service-role/vendor` |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("buildMarkdownReport() missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func Test_buildMarkdownReport_tags(t *testing.T) {
+	t.Parallel()
+
+	vendor := mustDecodeTrust(t, "arn:aws:iam::0123456789:role/vendor", fixtureCrossAccountTagSession)
+	vendor.role.Tags = []types.Tag{
+		{Key: aws.String("owner"), Value: aws.String("platform_team")},
+		{Key: aws.String("app"), Value: aws.String("billing")},
+	}
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/vendor": vendor,
+		"arn:aws:iam::0123456789:role/ecs": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/ecs",
+			fixtureAWSServiceRoleForECS,
+		),
+	}
+
+	got := string(buildMarkdownReport(trusts, runChecks(checkEnv{orgID: ""}, trusts), nil))
+
+	want := "## Tags\n\n| Role | Tags |\n| --- | --- |\n" +
+		"| `arn:aws:iam::0123456789:role/vendor` | app=billing,owner=platform\\_team |\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("buildMarkdownReport() missing %q in\n%s", want, got)
+	}
+}
+
+func Test_markdownCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "ARN",
+			value: "arn:aws:iam::0123456789:role/test",
+			want:  "`arn:aws:iam::0123456789:role/test`",
+		},
+		{
+			name:  "backtick",
+			value: "role/`test`",
+			want:  "`` role/`test` ``",
+		},
+		{
+			name:  "pipe",
+			value: "a|b",
+			want:  "`a\\|b`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := markdownCode(tt.value); got != tt.want {
+				t.Errorf("markdownCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,498 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	mcpProtocolVersion = "2025-06-18"
+	jsonRPCVersion     = "2.0"
+	maxMCPMessageSize  = 4 << 20
+
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+var (
+	errUnknownTool     = errors.New("unknown tool")
+	errMissingArgument = errors.New("missing required argument")
+	errInvalidSeverity = errors.New("severity must be info, medium or high")
+	errRoleNotFound    = errors.New("role not found in the latest scan")
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification when it carries no ID.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response carrying either a result or an error.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool describes a tool offered to MCP clients, along with the function answering its calls.
+type mcpTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	call        func(ctx context.Context, s *mcpServer, arguments json.RawMessage) (any, error)
+}
+
+// mcpToolResult is the result of a tool call, with the structured output repeated as text for older clients.
+type mcpToolResult struct {
+	Content           []mcpContent `json:"content"`
+	StructuredContent any          `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// scanSummary describes a scan result without its contents.
+type scanSummary struct {
+	Roles       int       `json:"roles"`
+	Principals  int       `json:"principals"`
+	Findings    int       `json:"findings"`
+	ScannedAt   time.Time `json:"scannedAt"`
+	Fingerprint string    `json:"fingerprint"`
+}
+
+// mcpTools lists the tools offered by the mcp subcommand.
+var mcpTools = []mcpTool{ //nolint:gochecknoglobals
+	{
+		Name:        "scan_account",
+		Description: "Scan the IAM roles of the AWS account again and summarise the result.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{},"additionalProperties":false}`),
+		call:        callScanAccount,
+	},
+	{
+		Name: "who_can_assume",
+		Description: "List the roles a principal can assume. Account IDs match their account root, and * and ? " +
+			"match any characters, e.g. arn:aws:iam::210987654321:*.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"principal":{"type":"string",` +
+			`"description":"principal ARN, account ID or glob"}},"required":["principal"],"additionalProperties":false}`),
+		call: callWhoCanAssume,
+	},
+	{
+		Name:        "list_findings",
+		Description: "List the findings of the built-in trust checks, optionally only those of a given severity.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"severity":{"type":"string",` +
+			`"enum":["info","medium","high"]}},"additionalProperties":false}`),
+		call: callListFindings,
+	},
+	{
+		Name:        "get_role",
+		Description: "Get the principals trusted by a role and the findings about it.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"arn":{"type":"string",` +
+			`"description":"role ARN"}},"required":["arn"],"additionalProperties":false}`),
+		call: callGetRole,
+	},
+}
+
+// mcpServer answers MCP requests from the latest scan result, scanning on first use. Requests are handled
+// concurrently, while scans are serialised so concurrent tool calls share a single scan.
+type mcpServer struct {
+	app     *App
+	now     func() time.Time
+	result  atomic.Pointer[ScanResult]
+	scanMu  sync.Mutex
+	writeMu sync.Mutex
+}
+
+// serve reads newline-delimited JSON-RPC messages from in and writes the responses to out, until in is exhausted.
+func (s *mcpServer) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxMCPMessageSize)
+
+	var wg sync.WaitGroup
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		message := bytes.Clone(line)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			response := s.handle(ctx, message)
+			if response != nil {
+				s.write(out, response)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+
+	return nil
+}
+
+// write writes a single response line, keeping concurrent responses from interleaving.
+func (s *mcpServer) write(out io.Writer, response *rpcResponse) {
+	marshal, err := json.Marshal(response)
+	if err != nil {
+		s.app.logger.Error("failed to marshal response", slog.String("error", err.Error()))
+
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_, err = out.Write(append(marshal, '\n'))
+	if err != nil {
+		s.app.logger.Debug("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+// handle answers a single message, returning nil for notifications.
+func (s *mcpServer) handle(ctx context.Context, message []byte) *rpcResponse {
+	var request rpcRequest
+
+	err := json.Unmarshal(message, &request)
+	if err != nil {
+		return rpcFailure(json.RawMessage("null"), rpcParseError, err.Error())
+	}
+
+	if request.JSONRPC != jsonRPCVersion || request.Method == "" {
+		return rpcFailure(request.ID, rpcInvalidRequest, "invalid JSON-RPC request")
+	}
+
+	if len(request.ID) == 0 {
+		s.app.logger.Debug("received notification", slog.String("method", request.Method))
+
+		return nil
+	}
+
+	switch request.Method {
+	case "initialize":
+		return rpcSuccess(request.ID, map[string]any{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "veil", "version": version},
+		})
+	case "ping":
+		return rpcSuccess(request.ID, map[string]any{})
+	case "tools/list":
+		return rpcSuccess(request.ID, map[string]any{"tools": mcpTools})
+	case "tools/call":
+		return s.callTool(ctx, request)
+	default:
+		return rpcFailure(request.ID, rpcMethodNotFound, "method not found: "+request.Method)
+	}
+}
+
+// callTool runs the requested tool. Invalid arguments are protocol errors, while failures of the tool itself are
+// reported in the result, so the assistant can see and react to them.
+func (s *mcpServer) callTool(ctx context.Context, request rpcRequest) *rpcResponse {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+
+	err := json.Unmarshal(request.Params, &params)
+	if err != nil {
+		return rpcFailure(request.ID, rpcInvalidParams, err.Error())
+	}
+
+	index := slices.IndexFunc(mcpTools, func(tool mcpTool) bool { return tool.Name == params.Name })
+	if index < 0 {
+		return rpcFailure(request.ID, rpcInvalidParams, fmt.Sprintf("%s: %q", errUnknownTool, params.Name))
+	}
+
+	if len(params.Arguments) == 0 || bytes.Equal(params.Arguments, []byte("null")) {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	output, err := mcpTools[index].call(ctx, s, params.Arguments)
+
+	var argumentErr *mcpArgumentError
+	if errors.As(err, &argumentErr) {
+		return rpcFailure(request.ID, rpcInvalidParams, err.Error())
+	}
+
+	if err != nil {
+		return rpcSuccess(request.ID, mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: err.Error()}},
+			StructuredContent: nil,
+			IsError:           true,
+		})
+	}
+
+	marshal, err := json.Marshal(output)
+	if err != nil {
+		return rpcFailure(request.ID, rpcInternalError, err.Error())
+	}
+
+	return rpcSuccess(request.ID, mcpToolResult{
+		Content:           []mcpContent{{Type: "text", Text: string(marshal)}},
+		StructuredContent: output,
+		IsError:           false,
+	})
+}
+
+// current returns the latest scan result, scanning first if there is none yet.
+func (s *mcpServer) current(ctx context.Context) (*ScanResult, error) {
+	result := s.result.Load()
+	if result != nil {
+		return result, nil
+	}
+
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	// Another call may have completed a scan while this one was waiting.
+	result = s.result.Load()
+	if result != nil {
+		return result, nil
+	}
+
+	return s.scanLocked(ctx)
+}
+
+// rescan replaces the latest scan result with a fresh scan.
+func (s *mcpServer) rescan(ctx context.Context) (*ScanResult, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	return s.scanLocked(ctx)
+}
+
+func (s *mcpServer) scanLocked(ctx context.Context) (*ScanResult, error) {
+	result, err := s.app.scanResult(ctx, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.result.Store(result)
+
+	return result, nil
+}
+
+// mcpArgumentError marks tool arguments failing validation.
+type mcpArgumentError struct {
+	err error
+}
+
+func (e *mcpArgumentError) Error() string {
+	return "invalid arguments: " + e.err.Error()
+}
+
+func (e *mcpArgumentError) Unwrap() error {
+	return e.err
+}
+
+// decodeArguments decodes tool arguments into target, rejecting unknown ones.
+func decodeArguments(arguments json.RawMessage, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(arguments))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(target)
+	if err != nil {
+		return &mcpArgumentError{err: err}
+	}
+
+	return nil
+}
+
+func callScanAccount(ctx context.Context, s *mcpServer, arguments json.RawMessage) (any, error) {
+	err := decodeArguments(arguments, &struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.rescan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSummary{
+		Roles:       len(result.Roles),
+		Principals:  len(result.Principals),
+		Findings:    len(result.Findings),
+		ScannedAt:   result.ScannedAt,
+		Fingerprint: result.Fingerprint,
+	}, nil
+}
+
+func callWhoCanAssume(ctx context.Context, s *mcpServer, arguments json.RawMessage) (any, error) {
+	var input struct {
+		Principal string `json:"principal"`
+	}
+
+	err := decodeArguments(arguments, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Principal == "" {
+		return nil, &mcpArgumentError{err: fmt.Errorf("%w: principal", errMissingArgument)}
+	}
+
+	result, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := globRegexp(normaliseIdentity(principalGlob(input.Principal), defaultPartition))
+	roles := make([]string, 0)
+
+	for principal, trusting := range result.Principals {
+		if match.MatchString(normaliseIdentity(principal, defaultPartition)) {
+			roles = append(roles, trusting...)
+		}
+	}
+
+	return map[string]any{
+		"principal": input.Principal,
+		"roles":     uniqSlice(roles),
+	}, nil
+}
+
+func callListFindings(ctx context.Context, s *mcpServer, arguments json.RawMessage) (any, error) {
+	var input struct {
+		Severity string `json:"severity"`
+	}
+
+	err := decodeArguments(arguments, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Severity != "" && !slices.Contains([]string{severityInfo, severityMedium, severityHigh}, input.Severity) {
+		return nil, &mcpArgumentError{err: fmt.Errorf("%w: %q", errInvalidSeverity, input.Severity)}
+	}
+
+	result, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]Finding, 0, len(result.Findings))
+	for _, finding := range result.Findings {
+		if input.Severity == "" || finding.Severity == input.Severity {
+			findings = append(findings, finding)
+		}
+	}
+
+	return map[string]any{"findings": findings}, nil
+}
+
+func callGetRole(ctx context.Context, s *mcpServer, arguments json.RawMessage) (any, error) {
+	var input struct {
+		ARN string `json:"arn"`
+	}
+
+	err := decodeArguments(arguments, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ARN == "" {
+		return nil, &mcpArgumentError{err: fmt.Errorf("%w: arn", errMissingArgument)}
+	}
+
+	result, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	principals, ok := result.Roles[input.ARN]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errRoleNotFound, input.ARN)
+	}
+
+	findings := make([]Finding, 0)
+	for _, finding := range result.Findings {
+		if finding.Role == input.ARN {
+			findings = append(findings, finding)
+		}
+	}
+
+	return map[string]any{
+		"arn":        input.ARN,
+		"principals": principals,
+		"findings":   findings,
+	}, nil
+}
+
+func rpcSuccess(id json.RawMessage, result any) *rpcResponse {
+	return &rpcResponse{JSONRPC: jsonRPCVersion, ID: id, Result: result, Error: nil}
+}
+
+func rpcFailure(id json.RawMessage, code int, message string) *rpcResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	return &rpcResponse{JSONRPC: jsonRPCVersion, ID: id, Result: nil, Error: &rpcError{Code: code, Message: message}}
+}
+
+// runMCPCommand parses the mcp subcommand flags, then serves MCP requests over stdin and stdout until stdin is closed
+// or the process is interrupted.
+func runMCPCommand(ctx context.Context, app *App, args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("mcp", flag.ContinueOnError)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse mcp flags: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &mcpServer{
+		app:     app,
+		now:     time.Now,
+		result:  atomic.Pointer[ScanResult]{},
+		scanMu:  sync.Mutex{},
+		writeMu: sync.Mutex{},
+	}
+
+	app.logger.Info("serving MCP over stdio")
+
+	return srv.serve(ctx, in, out)
+}
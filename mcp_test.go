@@ -0,0 +1,216 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func newTestMCPServer(t *testing.T, fake *veiltest.FakeIAM) *mcpServer {
+	t.Helper()
+
+	fake.Roles = []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+	}
+
+	return &mcpServer{
+		app: &App{
+			logger: slog.New(slog.DiscardHandler),
+			client: fake,
+		},
+		now: func() time.Time { return time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+// mcpExchange sends the given requests to the server over an in-memory pipe and returns the responses keyed by ID.
+func mcpExchange(t *testing.T, srv *mcpServer, requests ...string) map[string]map[string]any {
+	t.Helper()
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- srv.serve(t.Context(), inReader, outWriter)
+
+		_ = outWriter.Close()
+	}()
+
+	go func() {
+		for _, request := range requests {
+			_, _ = io.WriteString(inWriter, request+"\n")
+		}
+
+		_ = inWriter.Close()
+	}()
+
+	output := make(map[string]map[string]any)
+	scanner := bufio.NewScanner(outReader)
+
+	for scanner.Scan() {
+		var response map[string]any
+
+		err := json.Unmarshal(scanner.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("serve() wrote invalid JSON %q: %v", scanner.Text(), err)
+		}
+
+		output[fmt.Sprint(response["id"])] = response
+	}
+
+	err := <-done
+	if err != nil {
+		t.Fatalf("serve() unexpected error: %v", err)
+	}
+
+	return output
+}
+
+func toolCall(id int, name string, arguments string) string {
+	return fmt.Sprintf(
+		`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`,
+		id,
+		name,
+		arguments,
+	)
+}
+
+func Test_mcpServer_serve(t *testing.T) {
+	t.Parallel()
+
+	responses := mcpExchange(
+		t,
+		newTestMCPServer(t, &veiltest.FakeIAM{}),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		toolCall(3, "who_can_assume", `{"principal":"210987654321"}`),
+		toolCall(4, "get_role", `{"arn":"arn:aws:iam::0123456789:role/vendor"}`),
+		toolCall(5, "list_findings", `{"severity":"high"}`),
+		toolCall(6, "list_findings", `{"severity":"critical"}`),
+		toolCall(7, "get_role", `{"arn":"arn:aws:iam::0123456789:role/missing"}`),
+		toolCall(8, "get_role", `{"role":"arn:aws:iam::0123456789:role/vendor"}`),
+		toolCall(9, "drop_tables", `{}`),
+		`{"jsonrpc":"2.0","id":10,"method":"resources/list"}`,
+		`not json`,
+	)
+
+	if len(responses) != 11 {
+		t.Fatalf("serve() answered %d messages, want 11 as notifications get no response", len(responses))
+	}
+
+	if got := responses["1"]["result"].(map[string]any)["protocolVersion"]; got != mcpProtocolVersion {
+		t.Errorf("initialize protocolVersion = %v, want %v", got, mcpProtocolVersion)
+	}
+
+	tools := responses["2"]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != len(mcpTools) {
+		t.Errorf("tools/list returned %d tools, want %d", len(tools), len(mcpTools))
+	}
+
+	structured := func(id string) any {
+		t.Helper()
+
+		result, ok := responses[id]["result"].(map[string]any)
+		if !ok || result["isError"] != false {
+			t.Fatalf("tools/call %s = %v, want a successful result", id, responses[id])
+		}
+
+		return result["structuredContent"]
+	}
+
+	wantWho := map[string]any{
+		"principal": "210987654321",
+		"roles":     []any{"arn:aws:iam::0123456789:role/vendor"},
+	}
+	if got := structured("3"); !reflect.DeepEqual(got, wantWho) {
+		t.Errorf("who_can_assume = %v, want %v", got, wantWho)
+	}
+
+	role := structured("4").(map[string]any)
+	if !reflect.DeepEqual(role["principals"], []any{"arn:aws:iam::210987654321:root"}) {
+		t.Errorf("get_role principals = %v", role["principals"])
+	}
+
+	if findings := role["findings"].([]any); len(findings) != 1 {
+		t.Errorf("get_role findings = %v, want the missing external ID", findings)
+	}
+
+	if findings := structured("5").(map[string]any)["findings"].([]any); len(findings) != 0 {
+		t.Errorf("list_findings high = %v, want none", findings)
+	}
+
+	for id, wantCode := range map[string]float64{
+		"6":     rpcInvalidParams,
+		"8":     rpcInvalidParams,
+		"9":     rpcInvalidParams,
+		"10":    rpcMethodNotFound,
+		"<nil>": rpcParseError,
+	} {
+		rpcErr, ok := responses[id]["error"].(map[string]any)
+		if !ok || rpcErr["code"] != wantCode {
+			t.Errorf("response %s = %v, want error code %v", id, responses[id], wantCode)
+		}
+	}
+
+	missing := responses["7"]["result"].(map[string]any)
+	if missing["isError"] != true || !strings.Contains(fmt.Sprint(missing["content"]), errRoleNotFound.Error()) {
+		t.Errorf("get_role of a missing role = %v, want a tool error", missing)
+	}
+}
+
+func Test_mcpServer_concurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	fake := &veiltest.FakeIAM{}
+	srv := newTestMCPServer(t, fake)
+	requests := make([]string, 0)
+
+	for id := range 20 {
+		requests = append(requests, toolCall(id, "who_can_assume", `{"principal":"ecs.amazonaws.com"}`))
+	}
+
+	responses := mcpExchange(t, srv, requests...)
+	if len(responses) != len(requests) {
+		t.Fatalf("serve() answered %d calls, want %d", len(responses), len(requests))
+	}
+
+	for id, response := range responses {
+		result, ok := response["result"].(map[string]any)
+		if !ok || result["isError"] != false {
+			t.Errorf("who_can_assume %s = %v, want a successful result", id, response)
+		}
+	}
+
+	// Concurrent calls arriving before the first scan completes share it.
+	if got := len(fake.Calls(veiltest.OperationListRoles)); got != 1 {
+		t.Errorf("serve() scanned %d times, want 1", got)
+	}
+
+	mcpExchange(t, srv, toolCall(0, "scan_account", `{}`))
+
+	if got := len(fake.Calls(veiltest.OperationListRoles)); got != 2 {
+		t.Errorf("scan_account scanned %d times in total, want 2", got)
+	}
+}
@@ -0,0 +1,462 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultAccountRoleName    = "OrganizationAccountAccessRole"
+	defaultAccountConcurrency = 5
+	defaultRoleConcurrency    = 10
+)
+
+var (
+	errOutputPerAccount = errors.New(
+		"-output-per-account requires -output, the json format and no account redaction",
+	)
+	errDedupeAcrossAccounts = errors.New("-dedupe-across-accounts requires the json format and merged output")
+	errAccounts             = errors.New("-accounts requires the json format and cannot be combined with -assume-role")
+	errAccountsMode         = errors.New(
+		"-accounts outputs the trust graph of each account and cannot be combined with mode flags, e.g. -findings",
+	)
+	errNoAccounts        = errors.New("no accounts to scan")
+	errInvalidAccountID  = errors.New("expected a 12-digit account ID")
+	errAllAccountsFailed = errors.New("every account failed to scan")
+)
+
+// RoleGroup is a role name with the ARNs of the roles sharing it across accounts and their combined principals.
+type RoleGroup struct {
+	Name       string   `json:"name"`
+	ARNs       []string `json:"arns"`
+	Principals []string `json:"principals"`
+}
+
+// MultiAccountWriter writes the trust graph of each account to its own file in Dir, named after the account, the
+// region and the scan time.
+type MultiAccountWriter struct {
+	Dir       string
+	Region    string
+	Timestamp time.Time
+}
+
+// NewMultiAccountWriter returns a MultiAccountWriter for the given directory, creating it if needed.
+func NewMultiAccountWriter(dir string, region string, timestamp time.Time) (*MultiAccountWriter, error) {
+	err := os.MkdirAll(dir, 0o750) //nolint:mnd
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	return &MultiAccountWriter{
+		Dir:       dir,
+		Region:    region,
+		Timestamp: timestamp,
+	}, nil
+}
+
+// path returns the file the given account is written to, e.g. 0123456789-eu-west-1-20250102T030405Z.json.
+func (w *MultiAccountWriter) path(accountID string) string {
+	return filepath.Join(
+		w.Dir,
+		fmt.Sprintf("%s-%s-%s.json", accountID, w.Region, w.Timestamp.UTC().Format("20060102T150405Z")),
+	)
+}
+
+// WriteAccount writes the given role to principals mapping of an account in the json output format, listing the
+// roles trusting each principal.
+func (w *MultiAccountWriter) WriteAccount(accountID string, data map[string][]string) error {
+	marshal, err := json.MarshalIndent(mapFlip(data), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output of %s: %w", accountID, err)
+	}
+
+	err = os.WriteFile(w.path(accountID), marshal, 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to write output of %s: %w", accountID, err)
+	}
+
+	return nil
+}
+
+// WriteAccountError writes the error that prevented the scan of an account next to the files of the other accounts,
+// e.g. 0123456789-eu-west-1-20250102T030405Z.error.json, so a failed account is not mistaken for a missing one.
+func (w *MultiAccountWriter) WriteAccountError(accountID string, scanErr error) error {
+	marshal, err := json.MarshalIndent(AccountScanResult{Data: nil, Error: scanErr}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal error of %s: %w", accountID, err)
+	}
+
+	err = os.WriteFile(strings.TrimSuffix(w.path(accountID), ".json")+".error.json", marshal, 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to write error of %s: %w", accountID, err)
+	}
+
+	return nil
+}
+
+// WriteResult writes every account of a multi-account scan: the trust graph of each account scanned successfully, and
+// only the error of each account that failed, so it does not read as an account without roles.
+func (w *MultiAccountWriter) WriteResult(result MultiAccountResult) error {
+	for account, accountResult := range result.AccountResults {
+		if accountResult.Error != nil {
+			err := w.WriteAccountError(account, accountResult.Error)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err := w.WriteAccount(account, accountResult.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitByAccount groups the given role to principals mapping by the account owning each role.
+func splitByAccount(roles map[string][]string) map[string]map[string][]string {
+	output := make(map[string]map[string][]string)
+
+	for role, principals := range roles {
+		account := accountFromARN(role)
+		if output[account] == nil {
+			output[account] = make(map[string][]string)
+		}
+
+		output[account][role] = principals
+	}
+
+	return output
+}
+
+// writePerAccount scans the roles and writes those of each owning account to its own file.
+func (a *App) writePerAccount(ctx context.Context, writer *MultiAccountWriter) error {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	for account, data := range splitByAccount(roles) {
+		err = writer.WriteAccount(account, data)
+		if err != nil {
+			return err
+		}
+
+		a.logger.Debug("wrote account output", slog.String("account", account), slog.Int("roles", len(data)))
+	}
+
+	return nil
+}
+
+// roleNameFromARN returns the role name of the given role ARN, its last path segment.
+func roleNameFromARN(role string) string {
+	return role[strings.LastIndex(role, "/")+1:]
+}
+
+// DedupeByRoleName groups the given role to principals mapping by role name, so that a role deployed to several
+// accounts is listed once with all of its ARNs and the principals trusted by any of them.
+func DedupeByRoleName(data map[string][]string) map[string]RoleGroup {
+	output := make(map[string]RoleGroup)
+
+	for role, principals := range data {
+		name := roleNameFromARN(role)
+		group := output[name]
+		group.Name = name
+		group.ARNs = append(group.ARNs, role)
+		group.Principals = append(group.Principals, principals...)
+		output[name] = group
+	}
+
+	for name, group := range output {
+		slices.Sort(group.ARNs)
+		group.Principals = uniqSlice(group.Principals)
+		output[name] = group
+	}
+
+	return output
+}
+
+func (a *App) runDedupedScan(ctx context.Context) ([]byte, error) {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := DedupeByRoleName(roles)
+	a.logger.Debug("grouped IAM roles by name", slog.Int("roles", len(roles)), slog.Int("names", len(output)))
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+// ScanOptions configures how ScanAccounts reaches the accounts it scans.
+type ScanOptions struct {
+	// Region is the AWS region used for IAM communication.
+	Region string
+	// RoleName is the role assumed in every account, OrganizationAccountAccessRole when empty.
+	RoleName string
+	// Partition is the partition of the accounts, aws when empty.
+	Partition string
+	// Loader loads the base configuration the roles are assumed from.
+	Loader ConfigLoader
+	// Options are applied to the App of every account, after the role to assume.
+	Options []Option
+	// Concurrency is the number of accounts scanned at once, defaultAccountConcurrency when not positive.
+	Concurrency int
+	// RoleConcurrency is the number of roles of each account processed at once, defaultRoleConcurrency when not
+	// positive, so that at most Concurrency * RoleConcurrency roles are processed at once overall.
+	RoleConcurrency int
+	// PathPrefixes restricts the scan of every account to the roles under any of these paths, e.g. /team-a/.
+	PathPrefixes []string
+	// Logger receives the progress and problems of the scan and of every account, the default slog logger when nil.
+	Logger *slog.Logger
+}
+
+// AccountScanResult is the role to principals mapping of a single account, or the error that prevented its scan.
+type AccountScanResult struct {
+	Data  map[string][]string
+	Error error
+}
+
+// MarshalJSON renders the result with its error as a message, omitting whichever of data and error is empty.
+func (r AccountScanResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.jsonValue()) //nolint:wrapcheck
+}
+
+func (r AccountScanResult) jsonValue() any {
+	output := struct {
+		Data  map[string][]string `json:"data,omitempty"`
+		Error string              `json:"error,omitempty"`
+	}{
+		Data:  r.Data,
+		Error: "",
+	}
+	if r.Error != nil {
+		output.Error = r.Error.Error()
+	}
+
+	return output
+}
+
+// MultiAccountResult holds the scan result of every account, keyed by account ID.
+type MultiAccountResult struct {
+	AccountResults map[string]AccountScanResult `json:"accounts"`
+}
+
+// Merged returns the role to principals mapping of every account scanned successfully.
+func (r MultiAccountResult) Merged() map[string][]string {
+	output := make(map[string][]string)
+
+	for _, result := range r.AccountResults {
+		maps.Copy(output, result.Data)
+	}
+
+	return output
+}
+
+// ScanAccounts scans the given accounts concurrently, assuming opts.RoleName in each of them. An account failing to
+// scan records its error in its result rather than aborting the others; an error is only returned if no account
+// could be scanned.
+func ScanAccounts(ctx context.Context, accounts []string, opts ScanOptions) (MultiAccountResult, error) {
+	accounts = uniqSlice(accounts)
+	if len(accounts) == 0 {
+		return MultiAccountResult{AccountResults: nil}, errNoAccounts
+	}
+
+	if opts.RoleName == "" {
+		opts.RoleName = defaultAccountRoleName
+	}
+
+	if opts.Partition == "" {
+		opts.Partition = defaultPartition
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultAccountConcurrency
+	}
+
+	if opts.RoleConcurrency <= 0 {
+		opts.RoleConcurrency = defaultRoleConcurrency
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	var mutex sync.Mutex
+
+	output := MultiAccountResult{AccountResults: make(map[string]AccountScanResult, len(accounts))}
+
+	group := new(errgroup.Group)
+	group.SetLimit(opts.Concurrency)
+
+	for _, account := range accounts {
+		group.Go(func() error {
+			data, err := scanAccount(ctx, account, opts)
+			if err != nil {
+				opts.Logger.Warn(
+					"failed to scan account",
+					slog.String("account", account),
+					slog.String("error", err.Error()),
+				)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			output.AccountResults[account] = AccountScanResult{Data: data, Error: err}
+
+			return nil
+		})
+	}
+
+	_ = group.Wait()
+
+	failed := 0
+
+	for _, result := range output.AccountResults {
+		if result.Error != nil {
+			failed++
+		}
+	}
+
+	opts.Logger.Debug("scanned accounts", slog.Int("accounts", len(accounts)), slog.Int("failed", failed))
+
+	if failed == len(accounts) {
+		return output, errAllAccountsFailed
+	}
+
+	return output, nil
+}
+
+// scanAccount assumes the scan role in the given account and returns its role to principals mapping.
+func scanAccount(ctx context.Context, account string, opts ScanOptions) (map[string][]string, error) {
+	if !accountIDRegex.MatchString(account) {
+		return nil, fmt.Errorf("%w: %q", errInvalidAccountID, account)
+	}
+
+	role := fmt.Sprintf("arn:%s:iam::%s:role/%s", opts.Partition, account, opts.RoleName)
+
+	app, err := NewApp(
+		ctx,
+		opts.Region,
+		opts.Loader,
+		append(
+			[]Option{
+				WithLogger(opts.Logger),
+				WithAssumeRole(role),
+				WithRoleConcurrency(opts.RoleConcurrency),
+				WithPathPrefixes(opts.PathPrefixes),
+			},
+			opts.Options...,
+		)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize app: %w", err)
+	}
+
+	data, err := app.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	return data, nil
+}
+
+// scanOptions returns the options of the multi-account scan selected by the flags, loading the configuration of every
+// account with loader, applying opts to its App and logging to logger.
+func (f *cliFlags) scanOptions(loader ConfigLoader, opts []Option, logger *slog.Logger) ScanOptions {
+	return ScanOptions{
+		Region:          *f.region,
+		RoleName:        *f.accountRole,
+		Partition:       partitionFromRegion(*f.region),
+		Loader:          loader,
+		Options:         opts,
+		Concurrency:     *f.accountConcurrency,
+		RoleConcurrency: *f.roleConcurrency,
+		PathPrefixes:    splitList(*f.pathPrefixes),
+		Logger:          logger,
+	}
+}
+
+// marshalAccounts marshals every account result of a multi-account scan, or its roles grouped by name with dedupe.
+func (a *App) marshalAccounts(result MultiAccountResult, dedupe bool) ([]byte, error) {
+	var output any = result
+	if dedupe {
+		output = DedupeByRoleName(result.Merged())
+	}
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
+
+// runAccounts scans the accounts selected by the flags and writes their output: one file per account with
+// -output-per-account, one file per principal type with -split-output-by-principal-type, the roles grouped by name with
+// -dedupe-across-accounts, or every account result otherwise.
+func runAccounts(ctx context.Context, client *App, flags *cliFlags, opts []Option) error {
+	result, err := ScanAccounts(ctx, splitList(*flags.accounts), flags.scanOptions(flags.loader(), opts, client.logger))
+	if err != nil && len(result.AccountResults) == 0 {
+		return err
+	}
+
+	if *flags.outputPerAccount {
+		writer, errWriter := NewMultiAccountWriter(*flags.outputPath, *flags.region, time.Now())
+		if errWriter != nil {
+			return errWriter
+		}
+
+		errWrite := writer.WriteResult(result)
+		if errWrite != nil {
+			return errWrite
+		}
+
+		return err
+	}
+
+	if *flags.splitByPrincipal {
+		errWrite := writeSplitByPrincipalType(*flags.outputPath, result.Merged())
+		if errWrite != nil {
+			return errWrite
+		}
+
+		return err
+	}
+
+	marshal, errMarshal := client.marshalAccounts(result, *flags.dedupeAccounts)
+	if errMarshal != nil {
+		return errMarshal
+	}
+
+	errWrite := writeOutput(client.redact(marshal), os.Stdout, *flags.outputPath, *flags.tee)
+	if errWrite != nil {
+		return fmt.Errorf("failed to write output: %w", errWrite)
+	}
+
+	return err
+}
@@ -0,0 +1,406 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func TestMultiAccountWriter_WriteAccount(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "reports")
+
+	writer, err := NewMultiAccountWriter(dir, "eu-west-1", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("NewMultiAccountWriter() unexpected error: %v", err)
+	}
+
+	err = writer.WriteAccount("0123456789", map[string][]string{
+		"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+	})
+	if err != nil {
+		t.Fatalf("WriteAccount() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "0123456789-eu-west-1-20250102T030405Z.json"))
+	if err != nil {
+		t.Fatalf("WriteAccount() did not write the account file: %v", err)
+	}
+
+	var got map[string][]string
+
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("WriteAccount() wrote invalid JSON: %v", err)
+	}
+
+	want := map[string][]string{
+		"arn:aws:iam::210987654321:root": {"arn:aws:iam::0123456789:role/vendor"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteAccount() wrote %v, want %v", got, want)
+	}
+}
+
+func TestMultiAccountWriter_WriteAccountError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writer := &MultiAccountWriter{Dir: dir, Region: "eu-west-1", Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	err := writer.WriteAccountError("0123456789", errAllAccountsFailed)
+	if err != nil {
+		t.Fatalf("WriteAccountError() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "0123456789-eu-west-1-20250102T030405Z.error.json"))
+	if err != nil {
+		t.Fatalf("WriteAccountError() did not write the error file: %v", err)
+	}
+
+	var got map[string]string
+
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("WriteAccountError() wrote invalid JSON: %v", err)
+	}
+
+	want := map[string]string{"error": errAllAccountsFailed.Error()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteAccountError() wrote %v, want %v", got, want)
+	}
+}
+
+func TestMultiAccountWriter_WriteResult(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writer := &MultiAccountWriter{Dir: dir, Region: "eu-west-1", Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	err := writer.WriteResult(MultiAccountResult{
+		AccountResults: map[string]AccountScanResult{
+			"0123456789": {
+				Data:  map[string][]string{"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"}},
+				Error: nil,
+			},
+			"1234567890": {Data: nil, Error: errAllAccountsFailed},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteResult() unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+
+	got := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+
+	want := []string{
+		"0123456789-eu-west-1-20250102T030405Z.json",
+		"1234567890-eu-west-1-20250102T030405Z.error.json",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("WriteResult() wrote %v, want %v", got, want)
+	}
+}
+
+func TestApp_writePerAccount(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::210987654321:role/ecs"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+		},
+	}
+
+	writer := &MultiAccountWriter{Dir: dir, Region: "eu-west-1", Timestamp: time.Unix(0, 0)}
+
+	err := app.writePerAccount(t.Context(), writer)
+	if err != nil {
+		t.Fatalf("writePerAccount() unexpected error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list output files: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "0123456789-eu-west-1-19700101T000000Z.json"),
+		filepath.Join(dir, "210987654321-eu-west-1-19700101T000000Z.json"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("writePerAccount() wrote %v, want %v", files, want)
+	}
+}
+
+func TestDedupeByRoleName(t *testing.T) {
+	t.Parallel()
+
+	got := DedupeByRoleName(map[string][]string{
+		"arn:aws:iam::210987654321:role/deploy":        {"arn:aws:iam::111122223333:root"},
+		"arn:aws:iam::0123456789:role/ci/deploy":       {"arn:aws:iam::111122223333:root", "ec2.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/vendor":          {"arn:aws:iam::444455556666:root"},
+		"arn:aws:iam::210987654321:role/deploy-legacy": {},
+	})
+
+	want := map[string]RoleGroup{
+		"deploy": {
+			Name: "deploy",
+			ARNs: []string{
+				"arn:aws:iam::0123456789:role/ci/deploy",
+				"arn:aws:iam::210987654321:role/deploy",
+			},
+			Principals: []string{"arn:aws:iam::111122223333:root", "ec2.amazonaws.com"},
+		},
+		"deploy-legacy": {
+			Name:       "deploy-legacy",
+			ARNs:       []string{"arn:aws:iam::210987654321:role/deploy-legacy"},
+			Principals: []string{},
+		},
+		"vendor": {
+			Name:       "vendor",
+			ARNs:       []string{"arn:aws:iam::0123456789:role/vendor"},
+			Principals: []string{"arn:aws:iam::444455556666:root"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupeByRoleName() = %v, want %v", got, want)
+	}
+}
+
+// staticConfigLoader returns an empty config, and is safe to share between the accounts scanned concurrently.
+type staticConfigLoader struct{}
+
+//nolint:nonamedreturns
+func (staticConfigLoader) LoadDefaultConfig(
+	_ context.Context,
+	_ ...func(*config.LoadOptions) error,
+) (cfg aws.Config, err error) {
+	return aws.Config{}, nil
+}
+
+// withAccountClients selects the IAM client of the account whose role is assumed.
+func withAccountClients(clients map[string]ServiceIAM) Option {
+	return func(a *App) {
+		a.client = clients[accountFromARN(a.assumeRoleARN)]
+	}
+}
+
+func TestScanAccounts(t *testing.T) {
+	t.Parallel()
+
+	errDenied := errors.New("access denied")
+	clients := map[string]ServiceIAM{
+		"111111111111": &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::111111111111:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+			},
+		},
+		"222222222222": &veiltest.FakeIAM{Errors: map[string]error{veiltest.OperationListRoles: errDenied}},
+	}
+
+	tests := []struct {
+		name       string
+		accounts   []string
+		wantErr    error
+		wantData   []string
+		wantFailed []string
+	}{
+		{
+			name:       "failing accounts kept apart",
+			accounts:   []string{"111111111111", "222222222222", "12345"},
+			wantErr:    nil,
+			wantData:   []string{"111111111111"},
+			wantFailed: []string{"12345", "222222222222"},
+		},
+		{
+			name:       "every account failing",
+			accounts:   []string{"222222222222", "12345"},
+			wantErr:    errAllAccountsFailed,
+			wantData:   []string{},
+			wantFailed: []string{"12345", "222222222222"},
+		},
+		{
+			name:       "no accounts",
+			accounts:   []string{},
+			wantErr:    errNoAccounts,
+			wantData:   []string{},
+			wantFailed: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ScanAccounts(t.Context(), tt.accounts, ScanOptions{
+				Region:          "eu-west-1",
+				RoleName:        "",
+				Partition:       "",
+				Loader:          staticConfigLoader{},
+				Options:         []Option{withAccountClients(clients)},
+				Concurrency:     2,
+				RoleConcurrency: 0,
+				PathPrefixes:    nil,
+				Logger:          nil,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ScanAccounts() error = %v, want %v", err, tt.wantErr)
+			}
+
+			data, failed := make([]string, 0), make([]string, 0)
+
+			for account, result := range got.AccountResults {
+				if result.Error != nil {
+					failed = append(failed, account)
+
+					continue
+				}
+
+				data = append(data, account)
+			}
+
+			slices.Sort(data)
+			slices.Sort(failed)
+
+			if !reflect.DeepEqual(data, tt.wantData) || !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("ScanAccounts() scanned %v and failed %v, want %v and %v", data, failed, tt.wantData, tt.wantFailed)
+			}
+		})
+	}
+}
+
+// concurrencyIAM tracks the most calls to list role tags in flight at once across every account.
+type concurrencyIAM struct {
+	*veiltest.FakeIAM
+
+	inFlight *atomic.Int32
+	peak     *atomic.Int32
+}
+
+func (c concurrencyIAM) ListRoleTags(
+	ctx context.Context,
+	params *iam.ListRoleTagsInput,
+	optFns ...func(*iam.Options),
+) (*iam.ListRoleTagsOutput, error) {
+	current := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+
+	for {
+		peak := c.peak.Load()
+		if current <= peak || c.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	return c.FakeIAM.ListRoleTags(ctx, params, optFns...) //nolint:wrapcheck
+}
+
+func TestScanAccounts_concurrency(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, peak atomic.Int32
+
+	accounts := []string{"111111111111", "222222222222", "333333333333", "444444444444", "555555555555"}
+	clients := make(map[string]ServiceIAM, len(accounts))
+
+	for _, account := range accounts {
+		roles := make([]types.Role, 0)
+		for index := range 12 {
+			roles = append(roles, types.Role{
+				Arn:                      aws.String("arn:aws:iam::" + account + ":role/ecs-" + strconv.Itoa(index)),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			})
+		}
+
+		clients[account] = concurrencyIAM{FakeIAM: &veiltest.FakeIAM{Roles: roles}, inFlight: &inFlight, peak: &peak}
+	}
+
+	_, err := ScanAccounts(t.Context(), accounts, ScanOptions{
+		Region:          "eu-west-1",
+		RoleName:        "",
+		Partition:       "",
+		Loader:          staticConfigLoader{},
+		Options:         []Option{withAccountClients(clients), WithRoleTags()},
+		Concurrency:     2,
+		RoleConcurrency: 3,
+		PathPrefixes:    nil,
+		Logger:          nil,
+	})
+	if err != nil {
+		t.Fatalf("ScanAccounts() unexpected error: %v", err)
+	}
+
+	if got := peak.Load(); got > 2*3 {
+		t.Errorf("ScanAccounts() processed up to %d roles at once, want at most 6", got)
+	}
+}
+
+func TestAccountScanResult_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	result := MultiAccountResult{
+		AccountResults: map[string]AccountScanResult{
+			"111111111111": {
+				Data:  map[string][]string{"arn:aws:iam::111111111111:role/ecs": {"ecs.amazonaws.com"}},
+				Error: nil,
+			},
+			"222222222222": {Data: nil, Error: errors.New("access denied")},
+		},
+	}
+
+	got, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+
+	want := `{"accounts":{"111111111111":{"data":{"arn:aws:iam::111111111111:role/ecs":["ecs.amazonaws.com"]}},` +
+		`"222222222222":{"error":"access denied"}}}`
+	if string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// shortRoleName returns the path and name of a role ARN, e.g. /service-role/MyAppRole, or the value unchanged if it
+// is not the ARN of a role.
+func shortRoleName(role string) string {
+	parsed, err := arn.Parse(role)
+	if err != nil {
+		return role
+	}
+
+	name, found := strings.CutPrefix(parsed.Resource, "role/")
+	if !found {
+		return role
+	}
+
+	return "/" + name
+}
+
+// shortRoleNames maps each role ARN to its short name. Names shared by roles of several accounts are suffixed with
+// the account ID, so every role keeps a distinct label.
+func shortRoleNames(roles []string) map[string]string {
+	owners := make(map[string]map[string]bool)
+
+	for _, role := range roles {
+		name := shortRoleName(role)
+		if owners[name] == nil {
+			owners[name] = make(map[string]bool)
+		}
+
+		owners[name][role] = true
+	}
+
+	output := make(map[string]string, len(roles))
+
+	for _, role := range roles {
+		name := shortRoleName(role)
+		if len(owners[name]) > 1 {
+			name += " (" + accountFromARN(role) + ")"
+		}
+
+		output[role] = name
+	}
+
+	return output
+}
+
+// roleLabel returns the label of the given ARN, which is the ARN itself unless the labels rename it.
+func roleLabel(labels map[string]string, arn string) string {
+	if label, ok := labels[arn]; ok {
+		return label
+	}
+
+	return arn
+}
+
+// roleLabels returns the labels of the given roles in human-oriented formats: their short names with WithShortNames,
+// or nil to keep their ARNs.
+func (a *App) roleLabels(roles []string) map[string]string {
+	if !a.shortNames {
+		return nil
+	}
+
+	return shortRoleNames(roles)
+}
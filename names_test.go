@@ -0,0 +1,91 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_shortRoleName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		role string
+		want string
+	}{
+		{name: "role", role: "arn:aws:iam::0123456789:role/MyAppRole", want: "/MyAppRole"},
+		{
+			name: "role with path",
+			role: "arn:aws:iam::0123456789:role/service-role/MyAppRole",
+			want: "/service-role/MyAppRole",
+		},
+		{name: "account root", role: "arn:aws:iam::0123456789:root", want: "arn:aws:iam::0123456789:root"},
+		{name: "not an ARN", role: "policies/vendor.json", want: "policies/vendor.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := shortRoleName(tt.role); got != tt.want {
+				t.Errorf("shortRoleName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shortRoleNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		roles []string
+		want  map[string]string
+	}{
+		{
+			name: "distinct names",
+			roles: []string{
+				"arn:aws:iam::0123456789:role/deploy",
+				"arn:aws:iam::0123456789:role/ci/deploy",
+			},
+			want: map[string]string{
+				"arn:aws:iam::0123456789:role/deploy":    "/deploy",
+				"arn:aws:iam::0123456789:role/ci/deploy": "/ci/deploy",
+			},
+		},
+		{
+			name: "same name in several accounts",
+			roles: []string{
+				"arn:aws:iam::0123456789:role/deploy",
+				"arn:aws:iam::210987654321:role/deploy",
+				"arn:aws:iam::210987654321:role/vendor",
+			},
+			want: map[string]string{
+				"arn:aws:iam::0123456789:role/deploy":   "/deploy (0123456789)",
+				"arn:aws:iam::210987654321:role/deploy": "/deploy (210987654321)",
+				"arn:aws:iam::210987654321:role/vendor": "/vendor",
+			},
+		},
+		{
+			name: "same role listed twice",
+			roles: []string{
+				"arn:aws:iam::0123456789:role/deploy",
+				"arn:aws:iam::0123456789:role/deploy",
+			},
+			want: map[string]string{
+				"arn:aws:iam::0123456789:role/deploy": "/deploy",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := shortRoleNames(tt.roles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shortRoleNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	codeGitLabOIDCUnpinned    = "GITLAB_OIDC_UNPINNED_SUBJECT"
+	codeBitbucketOIDCUnpinned = "BITBUCKET_OIDC_UNPINNED_SUBJECT"
+
+	gitlabOIDCProvider       = "gitlab.com"
+	bitbucketOIDCHostPrefix  = "api.bitbucket.org/2.0/workspaces/"
+	bitbucketOIDCHostSuffix  = "/pipelines-config/identity/oidc"
+	placeholderGitLabSubject = "project_path:GROUP/PROJECT:ref_type:branch:ref:BRANCH"
+	placeholderBitbucketRepo = "{REPOSITORY_UUID}:*"
+)
+
+// bitbucketRepositoryRegex matches the repository UUID, in braces, that Bitbucket subjects start with.
+var bitbucketRepositoryRegex = regexp.MustCompile(`^\{[0-9a-fA-F-]{36}\}$`) //nolint:gochecknoglobals
+
+// oidcProviderHost returns the host, and path, of an OIDC provider ARN, e.g. gitlab.com, or an empty string if the
+// principal is not an OIDC provider.
+func oidcProviderHost(principal string) string {
+	_, host, found := strings.Cut(principal, oidcProviderResource)
+	if !found {
+		return ""
+	}
+
+	return host
+}
+
+// OIDCFinding is an OIDC provider trusted by a role without a condition on the subject of its tokens.
+type OIDCFinding struct {
+	RoleARN           string `json:"roleArn"`
+	FederatedProvider string `json:"federatedProvider"`
+	Recommendation    string `json:"recommendation"`
+}
+
+// DetectUnconditionedOIDCTrust returns the principals of the given OIDC provider, e.g.
+// token.actions.githubusercontent.com, trusted by statements of the role allowing sts:AssumeRoleWithWebIdentity
+// without a StringEquals or StringLike condition on its sub claim. Any token of the provider, e.g. of any GitHub
+// Actions workflow, can assume such a role.
+func DetectUnconditionedOIDCTrust(role string, policy TrustPolicy, providerURL string) []OIDCFinding {
+	host := strings.TrimSuffix(strings.TrimPrefix(providerURL, "https://"), "/")
+	output := make([]OIDCFinding, 0)
+
+	for _, statement := range policy.Statement {
+		if !statement.allowsAction(actionAssumeRoleWithWebIdentity) ||
+			len(statement.stringConditionValues(host+":sub")) > 0 {
+			continue
+		}
+
+		for _, principal := range statement.Principal.Federated {
+			if oidcProviderHost(principal) != host {
+				continue
+			}
+
+			output = append(output, OIDCFinding{
+				RoleARN:           role,
+				FederatedProvider: principal,
+				Recommendation: "Add a StringEquals or StringLike condition on " + host + ":sub pinning the " +
+					"subjects allowed to assume the role, e.g. a single repository.",
+			})
+		}
+	}
+
+	return output
+}
+
+// isGitLabHost reports whether the OIDC provider host is gitlab.com or one of the given self-hosted GitLab hosts.
+func isGitLabHost(host string, selfHosted []string) bool {
+	return host == gitlabOIDCProvider || slices.Contains(selfHosted, host)
+}
+
+// isCIOIDCHost reports whether the OIDC provider host is the one of a CI service with a check of its own, GitHub
+// Actions, GitLab CI or Bitbucket Pipelines.
+func isCIOIDCHost(host string, gitlabHosts []string) bool {
+	return host == githubOIDCProvider || isGitLabHost(host, gitlabHosts) || isBitbucketHost(host)
+}
+
+// isBitbucketHost reports whether the OIDC provider host is the one of a Bitbucket Pipelines workspace.
+func isBitbucketHost(host string) bool {
+	return strings.HasPrefix(host, bitbucketOIDCHostPrefix) && strings.HasSuffix(host, bitbucketOIDCHostSuffix)
+}
+
+// gitlabSubjectProblem describes how a GitLab subject such as project_path:acme/api:ref_type:branch:ref:main fails
+// to pin a project and ref, with the severity of the gap, or returns empty strings if it pins both.
+func gitlabSubjectProblem(subject string) (string, string) {
+	claims := make(map[string]string)
+
+	rest, ok := strings.CutPrefix(subject, "project_path:")
+	if !ok {
+		return severityHigh, "subject " + subject + " does not pin a project_path"
+	}
+
+	project, rest, _ := strings.Cut(rest, ":")
+	for rest != "" {
+		var name, value string
+
+		name, rest, _ = strings.Cut(rest, ":")
+		value, rest, _ = strings.Cut(rest, ":")
+		claims[name] = value
+	}
+
+	if project == "" || strings.ContainsAny(project, "*?") || !strings.Contains(project, "/") {
+		return severityHigh, "subject " + subject + " matches any project of a group"
+	}
+
+	if ref := claims["ref"]; ref == "" || strings.ContainsAny(ref, "*?") {
+		return severityMedium, "subject " + subject + " does not pin a ref"
+	}
+
+	return "", ""
+}
+
+// bitbucketSubjectProblem describes how a Bitbucket subject such as {REPOSITORY_UUID}:{STEP_UUID} fails to pin a
+// repository, with the severity of the gap, or returns empty strings if it pins one.
+func bitbucketSubjectProblem(subject string) (string, string) {
+	repository, _, _ := strings.Cut(subject, ":")
+	if !bitbucketRepositoryRegex.MatchString(repository) {
+		return severityHigh, "subject " + subject + " matches any repository of the workspace"
+	}
+
+	return "", ""
+}
+
+// ciOIDCProblems checks the subject and audience conditions of a CI provider host, returning the most severe gap and
+// the description of every gap found.
+func ciOIDCProblems(
+	statement Statement,
+	host string,
+	subjectProblem func(string) (string, string),
+) (string, []string) {
+	severity := ""
+	problems := make([]string, 0)
+
+	report := func(gapSeverity string, problem string) {
+		if severityRank[gapSeverity] > severityRank[severity] {
+			severity = gapSeverity
+		}
+
+		problems = append(problems, problem)
+	}
+
+	subjects := statement.stringConditionValues(host + ":sub")
+	if len(subjects) == 0 {
+		report(severityHigh, "no "+host+":sub condition pins the subject")
+	}
+
+	for _, subject := range subjects {
+		gapSeverity, problem := subjectProblem(subject)
+		if problem != "" {
+			report(gapSeverity, problem)
+		}
+	}
+
+	if len(statement.stringConditionValues(host+":aud")) == 0 {
+		report(severityMedium, "no "+host+":aud condition pins the audience")
+	}
+
+	return severity, problems
+}
+
+func checkGitLabOIDCSubject(env checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		host := oidcProviderHost(principal)
+		if !isGitLabHost(host, env.gitlabHosts) {
+			continue
+		}
+
+		severity, problems := ciOIDCProblems(statement, host, gitlabSubjectProblem)
+		if len(problems) == 0 {
+			continue
+		}
+
+		output = append(output, Finding{
+			Code:      codeGitLabOIDCUnpinned,
+			Severity:  severity,
+			Role:      role,
+			Principal: principal,
+			Message: "GitLab CI jobs outside a pinned project and ref can assume the role: " +
+				strings.Join(problems, "; "),
+			Remediation: newRemediation(
+				"Pin the "+host+":sub condition to a single project_path and ref, and "+host+
+					":aud to the audience of the ID token.",
+				withCondition(statement, "StringLike", host+":sub", placeholderGitLabSubject),
+			),
+		})
+	}
+
+	return output
+}
+
+func checkBitbucketOIDCSubject(_ checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		host := oidcProviderHost(principal)
+		if !isBitbucketHost(host) {
+			continue
+		}
+
+		severity, problems := ciOIDCProblems(statement, host, bitbucketSubjectProblem)
+		if len(problems) == 0 {
+			continue
+		}
+
+		output = append(output, Finding{
+			Code:      codeBitbucketOIDCUnpinned,
+			Severity:  severity,
+			Role:      role,
+			Principal: principal,
+			Message: "Bitbucket Pipelines outside a pinned repository can assume the role: " +
+				strings.Join(problems, "; "),
+			Remediation: newRemediation(
+				"Pin the "+host+":sub condition to the UUID of a single repository, and "+host+
+					":aud to the audience of the workspace.",
+				withCondition(statement, "StringLike", host+":sub", placeholderBitbucketRepo),
+			),
+		})
+	}
+
+	return output
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_gitlabSubjectProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		subject      string
+		wantSeverity string
+	}{
+		{name: "project and branch", subject: "project_path:acme/api:ref_type:branch:ref:main", wantSeverity: ""},
+		{name: "project and tag", subject: "project_path:acme/platform/api:ref_type:tag:ref:v1.2.3", wantSeverity: ""},
+		{name: "group wildcard", subject: "project_path:acme/*:ref_type:branch:ref:main", wantSeverity: severityHigh},
+		{name: "bare group", subject: "project_path:acme:ref_type:branch:ref:main", wantSeverity: severityHigh},
+		{name: "wildcard subject", subject: "*", wantSeverity: severityHigh},
+		{name: "any ref", subject: "project_path:acme/api:ref_type:branch:ref:*", wantSeverity: severityMedium},
+		{name: "no ref", subject: "project_path:acme/api:*", wantSeverity: severityMedium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, _ := gitlabSubjectProblem(tt.subject); got != tt.wantSeverity {
+				t.Errorf("gitlabSubjectProblem(%q) severity = %q, want %q", tt.subject, got, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func Test_bitbucketSubjectProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		subject      string
+		wantSeverity string
+	}{
+		{
+			name:         "repository, any step",
+			subject:      "{0b9e2c3a-5f7d-4e1b-8a6c-2d4f6e8a0b1c}:*",
+			wantSeverity: "",
+		},
+		{
+			name:         "repository and step",
+			subject:      "{0b9e2c3a-5f7d-4e1b-8a6c-2d4f6e8a0b1c}:{5f0a1b2c-3d4e-4f5a-8b6c-7d8e9f0a1b2c}",
+			wantSeverity: "",
+		},
+		{name: "workspace wildcard", subject: "*", wantSeverity: severityHigh},
+		{name: "wildcard repository", subject: "{*}:*", wantSeverity: severityHigh},
+		{name: "repository name", subject: "acme/api:*", wantSeverity: severityHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, _ := bitbucketSubjectProblem(tt.subject); got != tt.wantSeverity {
+				t.Errorf("bitbucketSubjectProblem(%q) severity = %q, want %q", tt.subject, got, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func Test_ciOIDCChecks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      checkEnv
+		document string
+		want     int
+	}{
+		{name: "pinned GitLab", env: checkEnv{}, document: fixtureGitLabOIDCPinned, want: 0},
+		{
+			name:     "pinned GitLab with qualified operators",
+			env:      checkEnv{},
+			document: fixtureGitLabOIDCPinnedQualified,
+			want:     0,
+		},
+		{name: "pinned Bitbucket", env: checkEnv{}, document: fixtureBitbucketOIDCPinned, want: 0},
+		{name: "unknown self-hosted GitLab", env: checkEnv{}, document: fixtureGitLabOIDCSelfHosted, want: 0},
+		{
+			name:     "configured self-hosted GitLab",
+			env:      checkEnv{gitlabHosts: []string{"gitlab.example.com"}},
+			document: fixtureGitLabOIDCSelfHosted,
+			want:     1,
+		},
+		{name: "GitLab group wildcard", env: checkEnv{}, document: fixtureGitLabOIDCGroupWildcard, want: 1},
+		{name: "Bitbucket workspace wildcard", env: checkEnv{}, document: fixtureBitbucketOIDCWorkspaceWildcard, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trust := mustDecodeTrust(t, "arn:aws:iam::123456789012:role/ci", tt.document)
+
+			got := 0
+			for _, statement := range trust.policy.Statement {
+				got += len(checkGitLabOIDCSubject(tt.env, "arn:aws:iam::123456789012:role/ci", statement))
+				got += len(checkBitbucketOIDCSubject(tt.env, "arn:aws:iam::123456789012:role/ci", statement))
+			}
+
+			if got != tt.want {
+				t.Errorf("checks found %d findings, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectUnconditionedOIDCTrust(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		document    string
+		providerURL string
+		want        []OIDCFinding
+	}{
+		{
+			name:        "GitHub OIDC without subject",
+			document:    fixtureGitHubOIDCNoSubject,
+			providerURL: "https://token.actions.githubusercontent.com",
+			want: []OIDCFinding{
+				{
+					RoleARN:           "arn:aws:iam::123456789012:role/test",
+					FederatedProvider: "arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com",
+					Recommendation: "Add a StringEquals or StringLike condition on " +
+						"token.actions.githubusercontent.com:sub pinning the subjects allowed to assume the role, " +
+						"e.g. a single repository.",
+				},
+			},
+		},
+		{
+			name:        "GitHub OIDC with a wildcard subject",
+			document:    fixtureGitHubOIDCUnpinned,
+			providerURL: githubOIDCProvider,
+			want:        []OIDCFinding{},
+		},
+		{
+			name:        "other provider",
+			document:    fixtureGitHubOIDCNoSubject,
+			providerURL: gitlabOIDCProvider,
+			want:        []OIDCFinding{},
+		},
+		{
+			name:        "unscoped EKS provider",
+			document:    fixtureUnscopedOIDCTrust,
+			providerURL: "oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE",
+			want: []OIDCFinding{
+				{
+					RoleARN: "arn:aws:iam::123456789012:role/test",
+					FederatedProvider: "arn:aws:iam::123456789012:oidc-provider/" +
+						"oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE",
+					Recommendation: "Add a StringEquals or StringLike condition on " +
+						"oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE:sub pinning the " +
+						"subjects allowed to assume the role, e.g. a single repository.",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			role := "arn:aws:iam::123456789012:role/test"
+
+			trust := mustDecodeTrust(t, role, tt.document)
+			if got := DetectUnconditionedOIDCTrust(role, trust.policy, tt.providerURL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectUnconditionedOIDCTrust() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
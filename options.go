@@ -0,0 +1,411 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// Option configures optional App behaviour.
+type Option func(*App)
+
+// WithAssumeRole makes the App scan IAM using temporary credentials of the given role, e.g. in another account.
+func WithAssumeRole(roleARN string) Option {
+	return func(a *App) {
+		a.assumeRoleARN = roleARN
+	}
+}
+
+// WithIRSA makes the App authenticate with the given Kubernetes service account token file through
+// AssumeRoleWithWebIdentity, without relying on the AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE environment
+// variables. An empty session name defaults to veil. Combined with WithAssumeRole, the web identity role assumes it.
+func WithIRSA(roleARN string, tokenFile string, sessionName string) Option {
+	return func(a *App) {
+		a.webIdentityRoleARN = roleARN
+		a.webIdentityTokenFile = tokenFile
+		a.webIdentitySessionName = sessionName
+	}
+}
+
+// WithRoleSessionName sets the session name used when assuming a role, which identifies veil sessions in CloudTrail.
+func WithRoleSessionName(name string) Option {
+	return func(a *App) {
+		a.roleSessionName = name
+	}
+}
+
+// WithSourceIdentity sets the source identity of assumed role sessions, which is kept through role chaining and
+// logged in CloudTrail, so actions can be traced back to whoever ran the scan.
+func WithSourceIdentity(identity string) Option {
+	return func(a *App) {
+		a.sourceIdentity = identity
+	}
+}
+
+// WithOrgStructure sets the OU to account IDs mapping used by the org output format.
+func WithOrgStructure(structure orgStructure) Option {
+	return func(a *App) {
+		a.orgStructure = structure
+	}
+}
+
+// WithAssumeRoleDuration sets the lifetime of the assumed role credentials, which must be between 15m and 12h and
+// within the maximum session duration of the role.
+func WithAssumeRoleDuration(d time.Duration) Option {
+	return func(a *App) {
+		a.roleDuration = d
+	}
+}
+
+// WithRawPolicy includes the URL-decoded trust policy document in role-oriented output, truncated to limit bytes.
+// A non-positive limit disables truncation.
+func WithRawPolicy(limit int) Option {
+	return func(a *App) {
+		a.rawPolicy = true
+		a.rawPolicyLimit = limit
+	}
+}
+
+// WithPrettyPolicies indents the raw trust policy documents included by WithRawPolicy with two spaces.
+func WithPrettyPolicies() Option {
+	return func(a *App) {
+		a.prettyPolicies = true
+	}
+}
+
+// WithFIPS makes the App use FIPS endpoints, as mandated in GovCloud.
+func WithFIPS() Option {
+	return func(a *App) {
+		a.fips = true
+	}
+}
+
+// WithDualStack makes the App use IPv6 dual-stack endpoints.
+func WithDualStack() Option {
+	return func(a *App) {
+		a.dualStack = true
+	}
+}
+
+// WithEndpointURL sets an explicit endpoint URL, which takes precedence over FIPS and dual-stack endpoints.
+func WithEndpointURL(endpointURL string) Option {
+	return func(a *App) {
+		a.endpointURL = endpointURL
+	}
+}
+
+// WithCredentialsProvider makes the App load its credentials from the given provider instead of the default chain,
+// e.g. one backed by HashiCorp Vault. With WithAssumeRole, these credentials are used to assume the role.
+func WithCredentialsProvider(provider aws.CredentialsProvider) Option {
+	return func(a *App) {
+		a.credentials = provider
+	}
+}
+
+// WithLazyInit defers creating the IAM client until the first scan, so the App can be set up without touching IAM.
+func WithLazyInit() Option {
+	return func(a *App) {
+		a.lazyInit = true
+	}
+}
+
+// WithLabels attaches the labels of the matching rules to roles and principals in the role-oriented output, and raises
+// the findings about those labelled criticality=high.
+func WithLabels(labels *labelSet) Option {
+	return func(a *App) {
+		a.labels = labels
+	}
+}
+
+// WithEffectivePrincipals lists only the principals effectively trusted by each role, leaving out those denied by a
+// Deny statement, and the principals of Deny statements themselves.
+func WithEffectivePrincipals() Option {
+	return func(a *App) {
+		a.effective = true
+	}
+}
+
+// WithDerivedPrincipals also lists the aws:PrincipalArn patterns constraining an account root or wildcard principal,
+// marked as via-condition, alongside the principal they constrain.
+func WithDerivedPrincipals() Option {
+	return func(a *App) {
+		a.derivedPrincipals = true
+	}
+}
+
+// WithExpectedTrust sets the desired-state spec that roles are checked against.
+func WithExpectedTrust(spec expectedTrust) Option {
+	return func(a *App) {
+		a.expectedTrust = spec
+	}
+}
+
+// WithStrict rejects trust policies containing keys outside the IAM policy grammar, e.g. a misspelled Principal,
+// instead of silently ignoring them.
+func WithStrict() Option {
+	return func(a *App) {
+		a.strict = true
+	}
+}
+
+// WithContinueOnError skips the roles whose trust policy fails to decode instead of failing the scan, logging each of
+// them unless quiet, in which case a single summary line is logged once the scan completes.
+func WithContinueOnError(quiet bool) Option {
+	return func(a *App) {
+		a.continueOnError = true
+		a.quietErrors = quiet
+	}
+}
+
+// WithMetadata wraps the json output in an envelope listing the roles skipped because their trust policy failed to
+// decode.
+func WithMetadata() Option {
+	return func(a *App) {
+		a.includeMetadata = true
+	}
+}
+
+// WithDenyServices sets the globs of the service principals forbidden from assuming roles, e.g. ec2.amazonaws.com.
+func WithDenyServices(patterns []string) Option {
+	return func(a *App) {
+		a.denyServices = patterns
+	}
+}
+
+// WithSimulateActions sets the IAM actions simulated against the policies of every scanned role.
+func WithSimulateActions(actions []string) Option {
+	return func(a *App) {
+		a.simulateActions = actions
+	}
+}
+
+// WithOrgID sets the ID of our own AWS organisation, so trust granted to other organisations stands out.
+func WithOrgID(orgID string) Option {
+	return func(a *App) {
+		a.orgID = orgID
+	}
+}
+
+// WithFocus restricts the trust graph to the roles and principals within depth hops of the identities matching focus.
+func WithFocus(focus string, depth int) Option {
+	return func(a *App) {
+		a.focus = focus
+		a.focusDepth = depth
+	}
+}
+
+// WithMinPrincipals keeps only the roles trusted by at least count distinct principals.
+func WithMinPrincipals(count int) Option {
+	return func(a *App) {
+		a.minPrincipals = count
+	}
+}
+
+// WithMinRoles keeps only the principals trusted by at least count roles, applied after WithMinPrincipals.
+func WithMinRoles(count int) Option {
+	return func(a *App) {
+		a.minRoles = count
+	}
+}
+
+// WithRoleAccounts restricts scanning to roles owned by the include accounts, if any, and skips roles owned by the
+// exclude accounts.
+func WithRoleAccounts(include []string, exclude []string) Option {
+	return func(a *App) {
+		a.roleAccounts = include
+		a.excludeAccounts = exclude
+	}
+}
+
+// WithTemporal stamps graph output with the scan time, so successive scans can be analysed as a timeline.
+func WithTemporal() Option {
+	return func(a *App) {
+		a.temporal = true
+	}
+}
+
+// WithRedactAccounts replaces account IDs in the output with pseudonyms, numbered per run or, given a key, masked with
+// an HMAC keyed with it so they are stable across runs. Raw trust policies are suppressed entirely, as their contents
+// cannot be redacted reliably.
+func WithRedactAccounts(key []byte) Option {
+	return func(a *App) {
+		a.redactAccounts = true
+		a.redactor = newAccountRedactor(key)
+	}
+}
+
+// WithPartialResults makes scans interrupted by a context deadline return the roles collected so far instead of
+// failing, e.g. to make the most of a Lambda invocation.
+func WithPartialResults() Option {
+	return func(a *App) {
+		a.partialResults = true
+	}
+}
+
+// WithVerifyProviders looks up the SAML and OIDC providers trusted by federated principals, so trust in providers
+// that no longer exist, or SAML providers whose certificates expire, is reported by the built-in checks.
+func WithVerifyProviders() Option {
+	return func(a *App) {
+		a.verifyProviders = true
+	}
+}
+
+// WithSAMLCertWindow sets how close to expiry the certificates of verified SAML providers are flagged, see
+// WithVerifyProviders.
+func WithSAMLCertWindow(window time.Duration) Option {
+	return func(a *App) {
+		a.samlCertWindow = window
+	}
+}
+
+// WithGitLabHosts adds self-hosted GitLab instances, e.g. gitlab.example.com, to gitlab.com as the OIDC providers
+// whose trust conditions are checked for pinned projects and refs.
+func WithGitLabHosts(hosts []string) Option {
+	return func(a *App) {
+		a.gitlabHosts = hosts
+	}
+}
+
+// WithNewSince restricts scanning to roles created after the given marker time.
+func WithNewSince(marker time.Time) Option {
+	return func(a *App) {
+		a.newSince = marker
+	}
+}
+
+// WithRoleAge restricts scanning to roles created at least minAge and, unless maxAge is zero, at most maxAge ago.
+// Roles without a creation date are skipped.
+func WithRoleAge(minAge time.Duration, maxAge time.Duration) Option {
+	return func(a *App) {
+		a.roleMinAge = minAge
+		a.roleMaxAge = maxAge
+	}
+}
+
+// WithInvertMatch inverts the role filters, scanning only the roles they would otherwise skip.
+func WithInvertMatch() Option {
+	return func(a *App) {
+		a.invertMatch = true
+	}
+}
+
+// WithRoleConcurrency limits the number of roles processed at once, e.g. while fetching their tags. A limit that is
+// not positive processes every role at once.
+func WithRoleConcurrency(limit int) Option {
+	return func(a *App) {
+		a.roleConcurrency = limit
+	}
+}
+
+// WithPathPrefixes restricts scanning to the roles whose path starts with any of the given prefixes, e.g. /team-a/,
+// listing the roles under each prefix concurrently. It has no effect on roles fetched by name.
+func WithPathPrefixes(prefixes []string) Option {
+	return func(a *App) {
+		a.pathPrefixes = prefixes
+	}
+}
+
+// WithRoleNames restricts scanning to the roles with the given names, fetched one by one instead of listing every
+// role of the account. Names of roles that do not exist are reported and skipped.
+func WithRoleNames(names []string) Option {
+	return func(a *App) {
+		a.roleNames = names
+	}
+}
+
+// WithRoleTags fetches the tags of every scanned role, so the rich output formats can show what each role is for.
+func WithRoleTags() Option {
+	return func(a *App) {
+		a.includeTags = true
+	}
+}
+
+// WithGroupByTag fetches the tags of every scanned role, and groups the roles, their principals and findings by the
+// value of the tag with the given key, e.g. owner.
+func WithGroupByTag(key string) Option {
+	return func(a *App) {
+		a.includeTags = true
+		a.groupByTag = key
+	}
+}
+
+// WithPermissionsBoundary fetches the permissions boundary of every scanned role, so the role-oriented output and the
+// counts show which roles are constrained by one. With onlyUnbounded, roles with a boundary are skipped.
+func WithPermissionsBoundary(onlyUnbounded bool) Option {
+	return func(a *App) {
+		a.withBoundary = true
+		a.onlyUnbounded = onlyUnbounded
+	}
+}
+
+// WithRegionCounts breaks the counts down by the region encoded in the path of IAM Identity Center roles, counting
+// every other role as global.
+func WithRegionCounts() Option {
+	return func(a *App) {
+		a.countByRegion = true
+	}
+}
+
+// WithSnapshotRoles scans the given roles, e.g. those recorded by an AWS Config snapshot, instead of the roles of the
+// live account.
+func WithSnapshotRoles(roles []types.Role) Option {
+	return func(a *App) {
+		a.client = snapshotIAM{roles: roles}
+	}
+}
+
+// WithFailOn fails the scan when its findings include one of the given severity or higher.
+func WithFailOn(severity string) Option {
+	return func(a *App) {
+		a.failOn = severity
+	}
+}
+
+// WithShortNames renders roles by their path and name instead of their ARN in human-oriented output formats.
+func WithShortNames() Option {
+	return func(a *App) {
+		a.shortNames = true
+	}
+}
+
+// WithRenameKeys renames the keys of JSON output, e.g. principal to subject, to fit the schema of a downstream
+// consumer.
+func WithRenameKeys(mapping map[string]string) Option {
+	return func(a *App) {
+		a.renameKeyMap = mapping
+	}
+}
+
+// WithSNSNotification publishes a summary of the scan to the given SNS topic once it completes.
+func WithSNSNotification(client ServiceSNS, topicARN string) Option {
+	return func(a *App) {
+		a.snsClient = client
+		a.snsTopicARN = topicARN
+	}
+}
+
+// WithDynamoDBTable writes the principals of each scanned role to the given DynamoDB table once the scan completes.
+func WithDynamoDBTable(client DynamoDBBatchAPI, table string) Option {
+	return func(a *App) {
+		a.dynamoDB = NewDynamoDBWriter(client, table)
+	}
+}
+
+// WithProfile loads the AWS configuration and credentials of the given shared config profile.
+func WithProfile(profile string) Option {
+	return func(a *App) {
+		a.profile = profile
+	}
+}
+
+// WithLogger sets the logger the App reports progress and problems to, instead of the default slog logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const unassignedOU = "unassigned"
+
+var errMissingOrgStructure = errors.New("org structure file is required for the org format")
+
+// orgStructure maps each organisational unit to the AWS account IDs it contains.
+type orgStructure map[string][]string
+
+// loadOrgStructure reads an OU to account IDs mapping from a JSON file.
+func loadOrgStructure(path string) (orgStructure, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to read org structure: %w", err)
+	}
+
+	var output orgStructure
+
+	errUnmarshal := json.Unmarshal(data, &output)
+	if errUnmarshal != nil {
+		return nil, fmt.Errorf("failed to unmarshal org structure: %w", errUnmarshal)
+	}
+
+	return output, nil
+}
+
+// accountOUs inverts the structure into a lookup from account ID to OU.
+func (o orgStructure) accountOUs() map[string]string {
+	output := make(map[string]string)
+
+	for ou, accounts := range o {
+		for _, account := range accounts {
+			output[account] = ou
+		}
+	}
+
+	return output
+}
+
+// groupByOrg nests roles and their principals under their account and OU.
+// Accounts missing from the structure are grouped under the unassigned OU.
+func groupByOrg(
+	roles map[string][]string,
+	structure orgStructure,
+) map[string]map[string]map[string][]string {
+	lookup := structure.accountOUs()
+	output := make(map[string]map[string]map[string][]string)
+
+	for role, principals := range roles {
+		account := accountFromARN(role)
+
+		ou, ok := lookup[account]
+		if !ok {
+			ou = unassignedOU
+		}
+
+		if output[ou] == nil {
+			output[ou] = make(map[string]map[string][]string)
+		}
+
+		if output[ou][account] == nil {
+			output[ou][account] = make(map[string][]string)
+		}
+
+		output[ou][account][role] = principals
+	}
+
+	return output
+}
+
+func (a *App) runScanOrg(ctx context.Context) ([]byte, error) {
+	if a.orgStructure == nil {
+		return nil, errMissingOrgStructure
+	}
+
+	output, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	marshal, err := a.marshalOutput(groupByOrg(a.redactRoles(output), a.redactOrg(a.orgStructure)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
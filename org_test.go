@@ -0,0 +1,100 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_loadOrgStructure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    orgStructure
+		wantErr bool
+	}{
+		{
+			name:    "missing file",
+			path:    "fixtures/missing.json",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid structure",
+			path:    "fixtures/InvalidDataTypeNumber.json",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "valid structure",
+			path: "fixtures/OrgStructure.json",
+			want: orgStructure{
+				"Security":  {"111111111111"},
+				"Workloads": {"222222222222", "333333333333"},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := loadOrgStructure(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadOrgStructure() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadOrgStructure() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_groupByOrg(t *testing.T) {
+	t.Parallel()
+
+	structure := orgStructure{
+		"Security":  {"111111111111"},
+		"Workloads": {"222222222222", "333333333333"},
+	}
+	roles := map[string][]string{
+		"arn:aws:iam::111111111111:role/audit":    {"arn:aws:iam::999999999999:root"},
+		"arn:aws:iam::222222222222:role/app":      {"ecs.amazonaws.com"},
+		"arn:aws:iam::333333333333:role/deploy":   {"arn:aws:iam::111111111111:root"},
+		"arn:aws:iam::333333333333:role/lambda":   {"lambda.amazonaws.com"},
+		"arn:aws:iam::444444444444:role/outsider": {"ec2.amazonaws.com"},
+	}
+
+	want := map[string]map[string]map[string][]string{
+		"Security": {
+			"111111111111": {
+				"arn:aws:iam::111111111111:role/audit": {"arn:aws:iam::999999999999:root"},
+			},
+		},
+		"Workloads": {
+			"222222222222": {
+				"arn:aws:iam::222222222222:role/app": {"ecs.amazonaws.com"},
+			},
+			"333333333333": {
+				"arn:aws:iam::333333333333:role/deploy": {"arn:aws:iam::111111111111:root"},
+				"arn:aws:iam::333333333333:role/lambda": {"lambda.amazonaws.com"},
+			},
+		},
+		unassignedOU: {
+			"444444444444": {
+				"arn:aws:iam::444444444444:role/outsider": {"ec2.amazonaws.com"},
+			},
+		},
+	}
+
+	if got := groupByOrg(roles, structure); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByOrg() = %v, want %v", got, want)
+	}
+}
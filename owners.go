@@ -0,0 +1,114 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// untaggedOwner groups the roles without the tag the output is grouped by.
+const untaggedOwner = "(untagged)"
+
+// ownerGroup holds the roles of one owner, the principals they trust and their findings.
+type ownerGroup struct {
+	Roles      []string  `json:"roles"`
+	Principals []string  `json:"principals"`
+	Findings   []Finding `json:"findings"`
+}
+
+// ownerSummary counts the owners and roles of the grouped output, and the roles without the tag.
+type ownerSummary struct {
+	Owners   int `json:"owners"`
+	Roles    int `json:"roles"`
+	Untagged int `json:"untagged"`
+}
+
+// ownerReport is the scan grouped by the value of a role tag, e.g. owner=platform-team.
+type ownerReport struct {
+	Tag     string                `json:"tag"`
+	Owners  map[string]ownerGroup `json:"owners"`
+	Summary ownerSummary          `json:"summary"`
+}
+
+// groupByOwner groups the roles, the principals they trust and their findings by the value of the given tag key.
+// Roles without the tag, or with an empty value, are grouped under (untagged). A principal trusted by roles of
+// several owners is listed under each of them.
+func groupByOwner(key string, trusts map[string]roleTrust, roles map[string][]string, findings []Finding) ownerReport {
+	output := ownerReport{
+		Tag:     key,
+		Owners:  make(map[string]ownerGroup),
+		Summary: ownerSummary{Owners: 0, Roles: 0, Untagged: 0},
+	}
+
+	owners := make(map[string]string, len(roles))
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		owner := tagMap(trusts[role].role.Tags)[key]
+		if owner == "" {
+			owner = untaggedOwner
+			output.Summary.Untagged++
+		}
+
+		owners[role] = owner
+		group, ok := output.Owners[owner]
+		if !ok {
+			group = ownerGroup{Roles: make([]string, 0), Principals: make([]string, 0), Findings: make([]Finding, 0)}
+		}
+
+		group.Roles = append(group.Roles, role)
+		group.Principals = append(group.Principals, roles[role]...)
+		output.Owners[owner] = group
+		output.Summary.Roles++
+	}
+
+	for _, finding := range findings {
+		owner, ok := owners[finding.Role]
+		if !ok {
+			continue
+		}
+
+		group := output.Owners[owner]
+		group.Findings = append(group.Findings, finding)
+		output.Owners[owner] = group
+	}
+
+	for owner, group := range output.Owners {
+		group.Principals = uniqSlice(group.Principals)
+		output.Owners[owner] = group
+	}
+
+	output.Summary.Owners = len(output.Owners)
+
+	return output
+}
+
+func (a *App) runGroupByTag(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	env, err := a.checkEnv(ctx, trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	output := groupByOwner(a.groupByTag, trusts, a.rolePrincipals(trusts), runChecks(env, trusts))
+	a.logger.Debug(
+		"grouped IAM roles by tag",
+		slog.String("tag", a.groupByTag),
+		slog.Int("owners", output.Summary.Owners),
+		slog.Int("untagged", output.Summary.Untagged),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func Test_groupByOwner(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/deploy": {
+			role:   types.Role{Tags: []types.Tag{{Key: aws.String("owner"), Value: aws.String("platform-team")}}},
+			policy: TrustPolicy{Version: "", Statement: nil},
+		},
+		"arn:aws:iam::0123456789:role/billing": {
+			role:   types.Role{Tags: []types.Tag{{Key: aws.String("owner"), Value: aws.String("finance")}}},
+			policy: TrustPolicy{Version: "", Statement: nil},
+		},
+		"arn:aws:iam::0123456789:role/ecs": {
+			role:   types.Role{Tags: []types.Tag{{Key: aws.String("owner"), Value: aws.String("platform-team")}}},
+			policy: TrustPolicy{Version: "", Statement: nil},
+		},
+		"arn:aws:iam::0123456789:role/legacy": {
+			role:   types.Role{Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("finance")}}},
+			policy: TrustPolicy{Version: "", Statement: nil},
+		},
+		"arn:aws:iam::0123456789:role/blank": {
+			role:   types.Role{Tags: []types.Tag{{Key: aws.String("owner"), Value: aws.String("")}}},
+			policy: TrustPolicy{Version: "", Statement: nil},
+		},
+	}
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/deploy":  {"arn:aws:iam::111122223333:root"},
+		"arn:aws:iam::0123456789:role/billing": {"arn:aws:iam::111122223333:root"},
+		"arn:aws:iam::0123456789:role/ecs":     {"ecs-tasks.amazonaws.com", "arn:aws:iam::111122223333:root"},
+		"arn:aws:iam::0123456789:role/legacy":  {"ec2.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/blank":   {},
+	}
+	finding := Finding{
+		Code:        codeCrossAccountNoExternalID,
+		Severity:    severityMedium,
+		Role:        "arn:aws:iam::0123456789:role/billing",
+		Principal:   "arn:aws:iam::111122223333:root",
+		Message:     "principal in another account can assume the role without an external ID",
+		Remediation: nil,
+	}
+
+	got := groupByOwner("owner", trusts, roles, []Finding{finding})
+
+	want := ownerReport{
+		Tag: "owner",
+		Owners: map[string]ownerGroup{
+			"finance": {
+				Roles:      []string{"arn:aws:iam::0123456789:role/billing"},
+				Principals: []string{"arn:aws:iam::111122223333:root"},
+				Findings:   []Finding{finding},
+			},
+			"platform-team": {
+				Roles:      []string{"arn:aws:iam::0123456789:role/deploy", "arn:aws:iam::0123456789:role/ecs"},
+				Principals: []string{"arn:aws:iam::111122223333:root", "ecs-tasks.amazonaws.com"},
+				Findings:   []Finding{},
+			},
+			untaggedOwner: {
+				Roles:      []string{"arn:aws:iam::0123456789:role/blank", "arn:aws:iam::0123456789:role/legacy"},
+				Principals: []string{"ec2.amazonaws.com"},
+				Findings:   []Finding{},
+			},
+		},
+		Summary: ownerSummary{Owners: 3, Roles: 5, Untagged: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByOwner() = %+v, want %+v", got, want)
+	}
+}
+
+func TestApp_runGroupByTag(t *testing.T) {
+	t.Parallel()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					RoleName:                 aws.String("vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					RoleName:                 aws.String("ecs"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+			Tags: map[string][]types.Tag{
+				"vendor": {{Key: aws.String("owner"), Value: aws.String("partners")}},
+			},
+		},
+	}
+	WithGroupByTag("owner")(app)
+
+	data, err := app.runGroupByTag(t.Context())
+	if err != nil {
+		t.Fatalf("runGroupByTag() unexpected error: %v", err)
+	}
+
+	var got ownerReport
+
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("runGroupByTag() wrote invalid JSON: %v", err)
+	}
+
+	owners := make(map[string][]string, len(got.Owners))
+	for owner, group := range got.Owners {
+		owners[owner] = group.Roles
+	}
+
+	want := map[string][]string{
+		"partners":    {"arn:aws:iam::0123456789:role/vendor"},
+		untaggedOwner: {"arn:aws:iam::0123456789:role/ecs"},
+	}
+	if !reflect.DeepEqual(owners, want) || got.Summary != (ownerSummary{Owners: 2, Roles: 2, Untagged: 1}) {
+		t.Errorf("runGroupByTag() grouped %v with summary %+v, want %v", owners, got.Summary, want)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	codeLegacyPolicyVersion = "LEGACY_POLICY_VERSION"
+
+	// currentPolicyVersion is the policy language version supporting policy variables and the newer grammar.
+	currentPolicyVersion = "2012-10-17"
+)
+
+// LegacyPolicyFinding is a role whose trust policy is written in the legacy policy language version.
+type LegacyPolicyFinding struct {
+	Role        string      `json:"role"`
+	Version     string      `json:"version"`
+	Remediation Remediation `json:"remediation"`
+}
+
+// legacyPolicyRemediation suggests upgrading the trust policy to the current policy language version.
+func legacyPolicyRemediation() Remediation {
+	return Remediation{
+		Summary:   "Set the policy Version to " + currentPolicyVersion + ", the current policy language version.",
+		Fragment:  nil,
+		Statement: 0,
+	}
+}
+
+// DetectLegacyPolicyVersion returns the roles whose trust policy uses policy version 2008-10-17, under which e.g.
+// policy variables such as ${aws:username} are not interpolated. Policies that are not valid JSON are skipped.
+func DetectLegacyPolicyVersion(entries []RoleEntry) []LegacyPolicyFinding {
+	output := make([]LegacyPolicyFinding, 0)
+
+	for _, entry := range entries {
+		var policy struct {
+			Version string `json:"Version"`
+		}
+
+		err := json.Unmarshal([]byte(entry.Policy), &policy)
+		if err != nil || policy.Version != legacyPolicyVersion {
+			continue
+		}
+
+		output = append(output, LegacyPolicyFinding{
+			Role:        entry.ARN,
+			Version:     policy.Version,
+			Remediation: legacyPolicyRemediation(),
+		})
+	}
+
+	return output
+}
+
+// checkPolicyVersion flags a trust policy written in the legacy policy language version.
+func checkPolicyVersion(role string, policy TrustPolicy) []Finding {
+	if policy.Version != legacyPolicyVersion {
+		return nil
+	}
+
+	remediation := legacyPolicyRemediation()
+
+	return []Finding{
+		{
+			Code:      codeLegacyPolicyVersion,
+			Severity:  severityInfo,
+			Role:      role,
+			Principal: "",
+			Message: "the trust policy uses policy version " + legacyPolicyVersion + ", whose grammar does not " +
+				"interpolate policy variables",
+			Remediation: &remediation,
+		},
+	}
+}
+
+func (a *App) runPolicyVersionCheck(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	entries, err := roleEntries(trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	output := DetectLegacyPolicyVersion(entries)
+	a.logger.Debug(
+		"checked IAM roles trust policy versions",
+		slog.Int("roles", len(entries)),
+		slog.Int("legacy", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func TestDetectLegacyPolicyVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		entries []RoleEntry
+		want    []LegacyPolicyFinding
+	}{
+		{
+			name: "legacy version",
+			entries: []RoleEntry{
+				{ARN: "arn:aws:iam::123456789012:role/legacy", Name: "legacy", Path: "/", Policy: fixturePolicyVariableUsername},
+			},
+			want: []LegacyPolicyFinding{
+				{
+					Role:        "arn:aws:iam::123456789012:role/legacy",
+					Version:     legacyPolicyVersion,
+					Remediation: legacyPolicyRemediation(),
+				},
+			},
+		},
+		{
+			name: "current version",
+			entries: []RoleEntry{
+				{ARN: "arn:aws:iam::123456789012:role/ecs", Name: "ecs", Path: "/", Policy: fixtureAWSServiceRoleForECS},
+			},
+			want: []LegacyPolicyFinding{},
+		},
+		{
+			name: "missing version and invalid policy",
+			entries: []RoleEntry{
+				{ARN: "arn:aws:iam::123456789012:role/bare", Name: "bare", Path: "/", Policy: `{"Statement":[]}`},
+				{ARN: "arn:aws:iam::123456789012:role/broken", Name: "broken", Path: "/", Policy: `{`},
+			},
+			want: []LegacyPolicyFinding{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := DetectLegacyPolicyVersion(tt.entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectLegacyPolicyVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_runPolicyVersionCheck(t *testing.T) {
+	t.Parallel()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::123456789012:role/legacy"),
+					AssumeRolePolicyDocument: aws.String(fixtureLegacyPolicyVersion),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::123456789012:role/current"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+		},
+	}
+
+	output, err := app.runPolicyVersionCheck(t.Context())
+	if err != nil {
+		t.Fatalf("runPolicyVersionCheck() unexpected error: %v", err)
+	}
+
+	var got []LegacyPolicyFinding
+
+	err = json.Unmarshal(output, &got)
+	if err != nil {
+		t.Fatalf("runPolicyVersionCheck() returned invalid JSON: %v", err)
+	}
+
+	if len(got) != 1 || got[0].Role != "arn:aws:iam::123456789012:role/legacy" || got[0].Version != legacyPolicyVersion {
+		t.Errorf("runPolicyVersionCheck() = %+v, want only the legacy role flagged", got)
+	}
+
+	for document, want := range map[string]int{fixtureLegacyPolicyVersion: 1, fixtureAWSServiceRoleForECS: 0} {
+		trust := mustDecodeTrust(t, "arn:aws:iam::123456789012:role/test", document)
+
+		findings := checkPolicyVersion("arn:aws:iam::123456789012:role/test", trust.policy)
+		if len(findings) != want {
+			t.Errorf("checkPolicyVersion() of version %s = %v, want %d findings", trust.policy.Version, findings, want)
+		}
+	}
+}
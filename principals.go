@@ -0,0 +1,199 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+const (
+	codeMalformedPrincipal = "MALFORMED_PRINCIPAL"
+
+	arnSections = 6
+)
+
+var (
+	// knownPartitions lists the AWS partitions principal ARNs can belong to.
+	knownPartitions = []string{ //nolint:gochecknoglobals
+		"aws",
+		"aws-cn",
+		"aws-us-gov",
+		"aws-iso",
+		"aws-iso-b",
+		"aws-iso-e",
+		"aws-iso-f",
+		"aws-eusc",
+	}
+	// awsPrincipalResources lists the resource types of AWS principal ARNs, by service.
+	awsPrincipalResources = map[string][]string{ //nolint:gochecknoglobals
+		"iam": {"role/", "user/"},
+		"sts": {"assumed-role/", "federated-user/"},
+	}
+	// federatedPrincipalResources lists the resource types of federated principal ARNs, by service.
+	federatedPrincipalResources = map[string][]string{ //nolint:gochecknoglobals
+		"iam": {samlProviderResource[1:], oidcProviderResource[1:]},
+	}
+	// principalBuckets names the key of the Principal element each type of principal belongs under.
+	principalBuckets = map[string]string{ //nolint:gochecknoglobals
+		principalTypeService:       "Service",
+		principalTypeAWS:           "AWS",
+		principalTypeFederated:     "Federated",
+		principalTypeCanonicalUser: "CanonicalUser",
+	}
+	// uniqueIDRegex matches the unique ID IAM shows in place of a deleted role or user.
+	uniqueIDRegex = regexp.MustCompile(`^(AROA|AIDA)[A-Z0-9]+$`)
+)
+
+// misplacedPrincipal describes a principal of another type placed under the given key of the Principal element, e.g.
+// a service name under AWS, or returns an empty string if it is not recognisably of another type.
+func misplacedPrincipal(principal string, principalType string) string {
+	bucket, ok := principalBuckets[ClassifyPrincipal(principal)]
+	if !ok || bucket == principalBuckets[principalType] {
+		return ""
+	}
+
+	return "belongs under " + bucket + ", not " + principalBuckets[principalType]
+}
+
+// malformedAWSPrincipal describes what is wrong with the structure of an AWS principal, or returns an empty string
+// if it is well-formed. Wildcards are left to checkWildcardPrincipal.
+func malformedAWSPrincipal(principal string) string {
+	switch {
+	case strings.ContainsAny(principal, "*?"),
+		accountIDRegex.MatchString(principal),
+		uniqueIDRegex.MatchString(principal):
+		return ""
+	case misplacedPrincipal(principal, principalTypeAWS) != "":
+		return misplacedPrincipal(principal, principalTypeAWS)
+	case !strings.HasPrefix(principal, "arn:"):
+		return "not an ARN or a 12-digit account ID"
+	}
+
+	return malformedARN(principal, awsPrincipalResources, true)
+}
+
+// malformedFederatedPrincipal describes what is wrong with the structure of a federated principal, or returns an
+// empty string if it is well-formed.
+func malformedFederatedPrincipal(principal string) string {
+	switch {
+	case isWebIdentityProvider(principal):
+		return ""
+	case misplacedPrincipal(principal, principalTypeFederated) != "":
+		return misplacedPrincipal(principal, principalTypeFederated)
+	case !strings.HasPrefix(principal, "arn:"):
+		return "not a SAML or OIDC provider ARN, nor a known web identity provider"
+	}
+
+	return malformedARN(principal, federatedPrincipalResources, false)
+}
+
+// malformedServicePrincipal describes what is wrong with a service principal, or returns an empty string if it is
+// a service name such as ec2.amazonaws.com.
+func malformedServicePrincipal(principal string) string {
+	switch {
+	case ClassifyPrincipal(principal) == principalTypeService:
+		return ""
+	case misplacedPrincipal(principal, principalTypeService) != "":
+		return misplacedPrincipal(principal, principalTypeService)
+	default:
+		return "not a service name such as ec2.amazonaws.com"
+	}
+}
+
+// malformedARN describes what is wrong with the partition, service, region, account or resource of the given ARN,
+// or returns an empty string if it names one of the resources allowed for its service. The account root is allowed
+// for IAM when root is set.
+func malformedARN(principal string, resources map[string][]string, root bool) string {
+	parsed, err := arn.Parse(principal)
+	if err != nil {
+		return fmt.Sprintf("ARN has %d of %d colon-separated sections", strings.Count(principal, ":")+1, arnSections)
+	}
+
+	if !slices.Contains(knownPartitions, parsed.Partition) {
+		return fmt.Sprintf("unknown partition %q", parsed.Partition)
+	}
+
+	prefixes, ok := resources[parsed.Service]
+	if !ok {
+		return fmt.Sprintf("service %q cannot be a principal here", parsed.Service)
+	}
+
+	if parsed.Region != "" {
+		return fmt.Sprintf("region %q is set, %s ARNs are global", parsed.Region, parsed.Service)
+	}
+
+	if !accountIDRegex.MatchString(parsed.AccountID) {
+		return fmt.Sprintf("account ID %q is not 12 digits", parsed.AccountID)
+	}
+
+	if root && parsed.Service == "iam" && parsed.Resource == "root" {
+		return ""
+	}
+
+	for _, prefix := range prefixes {
+		name, found := strings.CutPrefix(parsed.Resource, prefix)
+		if found && name != "" && !strings.HasSuffix(name, "/") {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("resource %q is not one of %s", parsed.Resource, resourceTypes(parsed.Service, prefixes, root))
+}
+
+// resourceTypes lists the resource types allowed for a service, for error messages, e.g. root, role/NAME or user/NAME.
+func resourceTypes(service string, prefixes []string, root bool) string {
+	output := make([]string, 0, len(prefixes)+1)
+	if root && service == "iam" {
+		output = append(output, "root")
+	}
+
+	for _, prefix := range prefixes {
+		output = append(output, prefix+"NAME")
+	}
+
+	return strings.Join(output, ", ")
+}
+
+func checkMalformedPrincipal(_ checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	report := func(principal string, problem string) {
+		if problem == "" {
+			return
+		}
+
+		output = append(output, Finding{
+			Code:      codeMalformedPrincipal,
+			Severity:  severityMedium,
+			Role:      role,
+			Principal: principal,
+			Message:   "principal is malformed: " + problem,
+			Remediation: &Remediation{
+				Summary: "Correct the principal to the intended ARN, or remove it if it is a leftover, as a " +
+					"malformed principal never matches the caller it was meant for.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		})
+	}
+
+	for _, principal := range statement.Principal.AWS {
+		report(principal, malformedAWSPrincipal(principal))
+	}
+
+	for _, principal := range statement.Principal.Federated {
+		report(principal, malformedFederatedPrincipal(principal))
+	}
+
+	for _, principal := range statement.Principal.Service {
+		report(principal, malformedServicePrincipal(principal))
+	}
+
+	return output
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_malformedAWSPrincipal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+	}{
+		{name: "wildcard", principal: "*", want: ""},
+		{name: "account ID", principal: "123456789012", want: ""},
+		{name: "account root", principal: "arn:aws:iam::123456789012:root", want: ""},
+		{name: "role", principal: "arn:aws:iam::123456789012:role/deployer", want: ""},
+		{name: "role with path", principal: "arn:aws:iam::123456789012:role/ci/deployer", want: ""},
+		{name: "user", principal: "arn:aws:iam::123456789012:user/alice", want: ""},
+		{
+			name:      "assumed role session",
+			principal: "arn:aws:sts::123456789012:assumed-role/deployer/session",
+			want:      "",
+		},
+		{name: "federated user", principal: "arn:aws:sts::123456789012:federated-user/alice", want: ""},
+		{name: "China partition", principal: "arn:aws-cn:iam::123456789012:root", want: ""},
+		{name: "GovCloud partition", principal: "arn:aws-us-gov:iam::123456789012:role/deployer", want: ""},
+		{name: "unique ID of a deleted role", principal: "AROA1234567890EXAMPLE", want: ""},
+		{name: "unique ID of a deleted user", principal: "AIDA1234567890EXAMPLE", want: ""},
+		{name: "wildcard account left to the wildcard check", principal: "arn:aws:iam::*:root", want: ""},
+		{name: "role name without ARN", principal: "deployer", want: "not an ARN"},
+		{name: "short account ID", principal: "12345678901", want: "not an ARN"},
+		{name: "empty", principal: "", want: "not an ARN"},
+		{name: "missing colon", principal: "arn:aws:iam:123456789012:root", want: "5 of 6"},
+		{name: "missing resource", principal: "arn:aws:iam::123456789012", want: "5 of 6"},
+		{name: "unknown partition", principal: "arn:amazon:iam::123456789012:root", want: `partition "amazon"`},
+		{name: "empty partition", principal: "arn::iam::123456789012:root", want: `partition ""`},
+		{name: "service not IAM or STS", principal: "arn:aws:s3::123456789012:root", want: `service "s3"`},
+		{name: "region set", principal: "arn:aws:iam:eu-west-1:123456789012:root", want: `region "eu-west-1"`},
+		{name: "short account", principal: "arn:aws:iam::12345678901:root", want: `account ID "12345678901"`},
+		{name: "empty account", principal: "arn:aws:iam:::root", want: `account ID ""`},
+		{name: "non-digit account", principal: "arn:aws:iam::12345678901O:root", want: "not 12 digits"},
+		{name: "unknown resource type", principal: "arn:aws:iam::123456789012:group/admins", want: "group/admins"},
+		{name: "role without name", principal: "arn:aws:iam::123456789012:role/", want: `resource "role/"`},
+		{name: "role path without name", principal: "arn:aws:iam::123456789012:role/ci/", want: "role/ci/"},
+		{name: "STS root", principal: "arn:aws:sts::123456789012:root", want: `resource "root"`},
+		{
+			name:      "IAM assumed role",
+			principal: "arn:aws:iam::123456789012:assumed-role/deployer/session",
+			want:      "root, role/NAME, user/NAME",
+		},
+		{
+			name:      "provider as AWS principal",
+			principal: "arn:aws:iam::123456789012:saml-provider/CorporateIdP",
+			want:      "belongs under Federated, not AWS",
+		},
+		{name: "service as AWS principal", principal: "lambda.amazonaws.com", want: "belongs under Service, not AWS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := malformedAWSPrincipal(tt.principal)
+			if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("malformedAWSPrincipal(%q) = %q, want %q", tt.principal, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_malformedFederatedPrincipal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+	}{
+		{name: "SAML provider", principal: "arn:aws:iam::123456789012:saml-provider/CorporateIdP", want: ""},
+		{
+			name:      "OIDC provider",
+			principal: "arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com",
+			want:      "",
+		},
+		{
+			name:      "EKS OIDC provider",
+			principal: "arn:aws:iam::123456789012:oidc-provider/oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLE",
+			want:      "",
+		},
+		{name: "web identity provider", principal: "accounts.google.com", want: ""},
+		{name: "Cognito", principal: "cognito-identity.amazonaws.com", want: ""},
+		{name: "unknown web identity provider", principal: "login.example.com", want: "not a SAML or OIDC"},
+		{name: "provider name without ARN", principal: "CorporateIdP", want: "not a SAML or OIDC"},
+		{
+			name:      "missing colon",
+			principal: "arn:aws:iam:123456789012:saml-provider/CorporateIdP",
+			want:      "5 of 6",
+		},
+		{
+			name:      "short account",
+			principal: "arn:aws:iam::1234567890:saml-provider/CorporateIdP",
+			want:      `account ID "1234567890"`,
+		},
+		{name: "account root", principal: "arn:aws:iam::123456789012:root", want: "belongs under AWS, not Federated"},
+		{name: "role", principal: "arn:aws:iam::123456789012:role/deployer", want: "belongs under AWS, not Federated"},
+		{name: "provider without name", principal: "arn:aws:iam::123456789012:oidc-provider/", want: "oidc-provider/"},
+		{
+			name:      "STS service",
+			principal: "arn:aws:sts::123456789012:saml-provider/CorporateIdP",
+			want:      `service "sts"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := malformedFederatedPrincipal(tt.principal)
+			if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("malformedFederatedPrincipal(%q) = %q, want %q", tt.principal, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_malformedServicePrincipal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+	}{
+		{name: "service", principal: "ec2.amazonaws.com", want: ""},
+		{name: "China service", principal: "ec2.amazonaws.com.cn", want: ""},
+		{name: "role", principal: "arn:aws:iam::123456789012:role/deployer", want: "belongs under AWS, not Service"},
+		{name: "account ID", principal: "123456789012", want: "belongs under AWS, not Service"},
+		{
+			name:      "SAML provider",
+			principal: "arn:aws:iam::123456789012:saml-provider/CorporateIdP",
+			want:      "belongs under Federated, not Service",
+		},
+		{name: "service name without domain", principal: "ec2", want: "not a service name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := malformedServicePrincipal(tt.principal)
+			if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("malformedServicePrincipal(%q) = %q, want %q", tt.principal, got, tt.want)
+			}
+		})
+	}
+}
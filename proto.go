@@ -0,0 +1,132 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wakeful/veil/veilpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var errProtoOutput = errors.New("-format proto cannot be combined with -redact-accounts or -rename-keys")
+
+// protoSchemaVersion is the version of veil.proto the output is written with, bumped on every change of the schema.
+const protoSchemaVersion = 1
+
+// newProtoScanResult converts the scan result into its protobuf message, listing roles and principals once each and
+// linking them by edges in the order the trust policies list the principals.
+func newProtoScanResult(result *ScanResult) *veilpb.ScanResult {
+	output := &veilpb.ScanResult{
+		Metadata: &veilpb.Metadata{
+			SchemaVersion: protoSchemaVersion,
+			ScannedAt:     timestamppb.New(result.ScannedAt),
+			Fingerprint:   result.Fingerprint,
+		},
+		Roles:      make([]*veilpb.Role, 0, len(result.Roles)),
+		Principals: make([]*veilpb.Principal, 0, len(result.Principals)),
+		Edges:      make([]*veilpb.Edge, 0),
+		Findings:   make([]*veilpb.Finding, 0, len(result.Findings)),
+	}
+
+	principalIDs := make(map[string]uint32, len(result.Principals))
+	for _, principal := range slices.Sorted(maps.Keys(result.Principals)) {
+		principalIDs[principal] = uint32(len(output.Principals) + 1) //nolint:gosec
+		output.Principals = append(output.Principals, &veilpb.Principal{
+			Id:        principalIDs[principal],
+			Principal: principal,
+			Type:      ClassifyPrincipal(principal),
+			Account:   accountFromARN(normalisePrincipal(strings.TrimSuffix(principal, viaConditionSuffix))),
+		})
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(result.Roles)) {
+		roleID := uint32(len(output.Roles) + 1) //nolint:gosec
+		output.Roles = append(output.Roles, &veilpb.Role{
+			Id:      roleID,
+			Arn:     role,
+			Account: accountFromARN(role),
+		})
+
+		for _, principal := range result.Roles[role] {
+			output.Edges = append(output.Edges, &veilpb.Edge{RoleId: roleID, PrincipalId: principalIDs[principal]})
+		}
+	}
+
+	for _, finding := range result.Findings {
+		output.Findings = append(output.Findings, newProtoFinding(finding))
+	}
+
+	return output
+}
+
+// newProtoFinding converts the finding into its protobuf message.
+func newProtoFinding(finding Finding) *veilpb.Finding {
+	output := &veilpb.Finding{
+		Code:        finding.Code,
+		Severity:    finding.Severity,
+		Role:        finding.Role,
+		Principal:   finding.Principal,
+		Message:     finding.Message,
+		Remediation: nil,
+	}
+
+	if finding.Remediation != nil {
+		output.Remediation = &veilpb.Remediation{
+			Summary:   finding.Remediation.Summary,
+			Fragment:  string(finding.Remediation.Fragment),
+			Statement: int32(finding.Remediation.Statement), //nolint:gosec
+		}
+	}
+
+	return output
+}
+
+func (a *App) runProto(ctx context.Context) ([]byte, error) {
+	result, err := a.scanResult(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	message := newProtoScanResult(result)
+	a.logger.Debug(
+		"converted scan result to protobuf",
+		slog.Int("roles", len(message.GetRoles())),
+		slog.Int("principals", len(message.GetPrincipals())),
+		slog.Int("edges", len(message.GetEdges())),
+	)
+
+	var buf bytes.Buffer
+
+	_, err = protodelim.MarshalTo(&buf, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (a *App) runProtoJSON(ctx context.Context) ([]byte, error) {
+	result, err := a.scanResult(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	marshal, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(newProtoScanResult(result))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veilpb"
+	"github.com/wakeful/veil/veiltest"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// scanResultFromProto converts the protobuf message back into the scan result it was built from.
+func scanResultFromProto(t *testing.T, message *veilpb.ScanResult) *ScanResult {
+	t.Helper()
+
+	roles := make(map[uint32]string, len(message.GetRoles()))
+	principals := make(map[uint32]string, len(message.GetPrincipals()))
+	output := &ScanResult{
+		Roles:       make(map[string][]string, len(message.GetRoles())),
+		Principals:  nil,
+		Findings:    make([]Finding, 0, len(message.GetFindings())),
+		ScannedAt:   message.GetMetadata().GetScannedAt().AsTime(),
+		Fingerprint: message.GetMetadata().GetFingerprint(),
+	}
+
+	for _, role := range message.GetRoles() {
+		roles[role.GetId()] = role.GetArn()
+		output.Roles[role.GetArn()] = make([]string, 0)
+	}
+
+	for _, principal := range message.GetPrincipals() {
+		principals[principal.GetId()] = principal.GetPrincipal()
+	}
+
+	for _, edge := range message.GetEdges() {
+		role := roles[edge.GetRoleId()]
+		output.Roles[role] = append(output.Roles[role], principals[edge.GetPrincipalId()])
+	}
+
+	output.Principals = mapFlip(output.Roles)
+
+	for _, finding := range message.GetFindings() {
+		var remediation *Remediation
+		if finding.GetRemediation() != nil {
+			remediation = &Remediation{
+				Summary:   finding.GetRemediation().GetSummary(),
+				Fragment:  nil,
+				Statement: int(finding.GetRemediation().GetStatement()),
+			}
+			if fragment := finding.GetRemediation().GetFragment(); fragment != "" {
+				remediation.Fragment = json.RawMessage(fragment)
+			}
+		}
+
+		output.Findings = append(output.Findings, Finding{
+			Code:        finding.GetCode(),
+			Severity:    finding.GetSeverity(),
+			Role:        finding.GetRole(),
+			Principal:   finding.GetPrincipal(),
+			Message:     finding.GetMessage(),
+			Remediation: remediation,
+		})
+	}
+
+	return output
+}
+
+func Test_newProtoScanResult_parity(t *testing.T) {
+	t.Parallel()
+
+	fixtures := map[string]string{
+		"AWSReservedSSOFullAdmin": fixtureAWSReservedSSOFullAdmin,
+		"AWSServiceRoleForECS":    fixtureAWSServiceRoleForECS,
+		"CrossAccountTagSession":  fixtureCrossAccountTagSession,
+		"GitHubOIDCUnpinned":      fixtureGitHubOIDCUnpinned,
+		"MixedPartitions":         fixtureMixedPartitions,
+		"OrgWideTrust":            fixtureOrgWideTrust,
+		"PrincipalArnLike":        fixturePrincipalArnLike,
+		"UnscopedSAMLTrust":       fixtureUnscopedSAMLTrust,
+		"WildcardAction":          fixtureWildcardAction,
+		"WildcardPrincipal":       fixtureWildcardPrincipal,
+	}
+
+	roles := make([]types.Role, 0, len(fixtures))
+	for _, name := range slices.Sorted(maps.Keys(fixtures)) {
+		roles = append(roles, types.Role{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/" + name),
+			AssumeRolePolicyDocument: aws.String(fixtures[name]),
+		})
+	}
+
+	app := &App{logger: slog.New(slog.DiscardHandler), client: &veiltest.FakeIAM{Roles: roles}}
+
+	result, err := app.scanResult(t.Context(), time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("scanResult() unexpected error: %v", err)
+	}
+
+	want, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("failed to marshal scan result: %v", err)
+	}
+
+	message := newProtoScanResult(result)
+
+	t.Run("length-prefixed binary", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		_, errMarshal := protodelim.MarshalTo(&buf, message)
+		if errMarshal != nil {
+			t.Fatalf("MarshalTo() unexpected error: %v", errMarshal)
+		}
+
+		decoded := &veilpb.ScanResult{}
+
+		errUnmarshal := protodelim.UnmarshalFrom(bufio.NewReader(&buf), decoded)
+		if errUnmarshal != nil {
+			t.Fatalf("UnmarshalFrom() unexpected error: %v", errUnmarshal)
+		}
+
+		got, errJSON := json.Marshal(scanResultFromProto(t, decoded))
+		if errJSON != nil {
+			t.Fatalf("failed to marshal decoded scan result: %v", errJSON)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("newProtoScanResult() round-trips to %s, want %s", got, want)
+		}
+	})
+
+	t.Run("protojson", func(t *testing.T) {
+		t.Parallel()
+
+		data, errMarshal := protojson.Marshal(message)
+		if errMarshal != nil {
+			t.Fatalf("protojson.Marshal() unexpected error: %v", errMarshal)
+		}
+
+		decoded := &veilpb.ScanResult{}
+
+		errUnmarshal := protojson.Unmarshal(data, decoded)
+		if errUnmarshal != nil {
+			t.Fatalf("protojson.Unmarshal() unexpected error: %v", errUnmarshal)
+		}
+
+		if !proto.Equal(decoded, message) {
+			t.Errorf("protojson round-trips to %v, want %v", decoded, message)
+		}
+	})
+}
+
+func Test_newProtoScanResult_edges(t *testing.T) {
+	t.Parallel()
+
+	message := newProtoScanResult(&ScanResult{
+		Roles: map[string][]string{
+			"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::111122223333:root", "ec2.amazonaws.com"},
+			"arn:aws:iam::0123456789:role/ecs":    {"ec2.amazonaws.com"},
+		},
+		Principals: map[string][]string{
+			"arn:aws:iam::111122223333:root": {"arn:aws:iam::0123456789:role/vendor"},
+			"ec2.amazonaws.com":              {"arn:aws:iam::0123456789:role/ecs", "arn:aws:iam::0123456789:role/vendor"},
+		},
+		Findings:    nil,
+		ScannedAt:   time.Unix(0, 0),
+		Fingerprint: "",
+	})
+
+	want := []*veilpb.Edge{
+		{RoleId: 1, PrincipalId: 2},
+		{RoleId: 2, PrincipalId: 1},
+		{RoleId: 2, PrincipalId: 2},
+	}
+	if !slices.EqualFunc(message.GetEdges(), want, func(a, b *veilpb.Edge) bool { return proto.Equal(a, b) }) {
+		t.Errorf("newProtoScanResult() edges = %v, want %v", message.GetEdges(), want)
+	}
+
+	if got := message.GetMetadata().GetSchemaVersion(); got != protoSchemaVersion {
+		t.Errorf("newProtoScanResult() schema version = %d, want %d", got, protoSchemaVersion)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+const (
+	codeMissingFederatedProvider = "MISSING_FEDERATED_PROVIDER"
+
+	samlProviderResource = ":saml-provider/"
+	oidcProviderResource = ":oidc-provider/"
+)
+
+// federatedProviders returns the SAML and OIDC providers trusted by allowing statements of the given trust
+// policies. Only providers in the account of the trusting role are returned, as IAM cannot look up the others.
+func federatedProviders(trusts map[string]roleTrust) []string {
+	output := make([]string, 0)
+
+	for role, trust := range trusts {
+		for _, statement := range trust.policy.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			for _, principal := range statement.Principal.Federated {
+				if !isProviderARN(principal) || accountFromARN(principal) != accountFromARN(role) {
+					continue
+				}
+
+				output = append(output, principal)
+			}
+		}
+	}
+
+	return uniqSlice(output)
+}
+
+// isProviderARN reports whether the principal is the ARN of an IAM SAML or OIDC provider.
+func isProviderARN(principal string) bool {
+	return strings.Contains(principal, samlProviderResource) || strings.Contains(principal, oidcProviderResource)
+}
+
+// lookupProvider reports whether the IAM SAML or OIDC provider with the given ARN exists, returning the metadata
+// document of existing SAML providers.
+func (a *App) lookupProvider(ctx context.Context, providerARN string) (bool, string, error) {
+	output, err := retryWithBackoff(
+		ctx,
+		a.logger,
+		defaultRetryMaxAttempts,
+		defaultRetryInitialDelay,
+		func() (any, error) {
+			if strings.Contains(providerARN, samlProviderResource) {
+				return a.iamClient().GetSAMLProvider(ctx, &iam.GetSAMLProviderInput{
+					SAMLProviderArn: aws.String(providerARN),
+				})
+			}
+
+			return a.iamClient().GetOpenIDConnectProvider(ctx, &iam.GetOpenIDConnectProviderInput{
+				OpenIDConnectProviderArn: aws.String(providerARN),
+			})
+		},
+	)
+
+	var notFound *types.NoSuchEntityException
+	if errors.As(err, &notFound) {
+		return false, "", nil
+	}
+
+	if err != nil {
+		return false, "", fmt.Errorf("failed to get provider %s: %w", providerARN, err)
+	}
+
+	if saml, ok := output.(*iam.GetSAMLProviderOutput); ok && saml != nil {
+		return true, aws.ToString(saml.SAMLMetadataDocument), nil
+	}
+
+	return true, "", nil
+}
+
+// missingProviders looks up every federated provider trusted by the given trust policies, returning those that do
+// not exist along with the metadata documents of the SAML providers that do.
+func (a *App) missingProviders(
+	ctx context.Context,
+	trusts map[string]roleTrust,
+) (map[string]bool, map[string]string, error) {
+	output := make(map[string]bool)
+	metadata := make(map[string]string)
+
+	for _, provider := range federatedProviders(trusts) {
+		exists, document, err := a.lookupProvider(ctx, provider)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !exists {
+			output[provider] = true
+		}
+
+		if document != "" {
+			metadata[provider] = document
+		}
+	}
+
+	a.logger.Debug(
+		"verified federated providers",
+		slog.Int("missing", len(output)),
+		slog.Int("saml_metadata", len(metadata)),
+	)
+
+	return output, metadata, nil
+}
+
+func checkMissingProvider(env checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		if !env.missingProviders[principal] {
+			continue
+		}
+
+		output = append(output, Finding{
+			Code:      codeMissingFederatedProvider,
+			Severity:  severityHigh,
+			Role:      role,
+			Principal: principal,
+			Message:   "trusted identity provider does not exist, the trust relationship is broken",
+			Remediation: &Remediation{
+				Summary: "Remove the principal, or recreate the provider only if federation through it is " +
+					"still needed, as a new provider with the same name regains the trust.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		})
+	}
+
+	return output
+}
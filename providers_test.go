@@ -0,0 +1,136 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/wakeful/veil/veiltest"
+)
+
+func Test_federatedProviders(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/sso": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/sso",
+			fixtureAWSReservedSSOFullAdmin,
+		),
+		"arn:aws:iam::0123456789:role/github": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/github",
+			fixtureGitHubOIDCUnpinned,
+		),
+		// Providers in other accounts cannot be looked up, so they are skipped.
+		"arn:aws:iam::210987654321:role/sso": mustDecodeTrust(
+			t,
+			"arn:aws:iam::210987654321:role/sso",
+			fixtureAWSReservedSSOFullAdmin,
+		),
+	}
+
+	want := []string{
+		"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+		"arn:aws:iam::0123456789:saml-provider/AWSSSO_24_DO_NOT_DELETE",
+		"arn:aws:iam::0123456789:saml-provider/AWSSSO_42_DO_NOT_DELETE",
+	}
+
+	got := federatedProviders(trusts)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("federatedProviders() = %v, want %v", got, want)
+	}
+}
+
+func TestApp_missingProviders(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/sso": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/sso",
+			fixtureAWSReservedSSOFullAdmin,
+		),
+		"arn:aws:iam::0123456789:role/github": mustDecodeTrust(
+			t,
+			"arn:aws:iam::0123456789:role/github",
+			fixtureGitHubOIDCUnpinned,
+		),
+	}
+
+	tests := []struct {
+		name         string
+		client       ServiceIAM
+		want         map[string]bool
+		wantMetadata map[string]string
+		wantErr      bool
+	}{
+		{
+			name: "all providers exist",
+			client: &veiltest.FakeIAM{
+				OIDCProviders: map[string]string{
+					"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com": "token.actions.githubusercontent.com",
+				},
+				SAMLProviders: map[string]string{
+					"arn:aws:iam::0123456789:saml-provider/AWSSSO_24_DO_NOT_DELETE": "",
+					"arn:aws:iam::0123456789:saml-provider/AWSSSO_42_DO_NOT_DELETE": fixtureSAMLMetadata,
+				},
+			},
+			want: map[string]bool{},
+			wantMetadata: map[string]string{
+				"arn:aws:iam::0123456789:saml-provider/AWSSSO_42_DO_NOT_DELETE": fixtureSAMLMetadata,
+			},
+			wantErr: false,
+		},
+		{
+			name: "deleted providers",
+			client: &veiltest.FakeIAM{
+				SAMLProviders: map[string]string{
+					"arn:aws:iam::0123456789:saml-provider/AWSSSO_24_DO_NOT_DELETE": "",
+				},
+			},
+			want: map[string]bool{
+				"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com": true,
+				"arn:aws:iam::0123456789:saml-provider/AWSSSO_42_DO_NOT_DELETE":             true,
+			},
+			wantMetadata: map[string]string{},
+			wantErr:      false,
+		},
+		{
+			name: "failed lookup",
+			client: &veiltest.FakeIAM{
+				Errors: map[string]error{
+					veiltest.OperationGetSAMLProvider:          errors.New("access denied"),
+					veiltest.OperationGetOpenIDConnectProvider: errors.New("access denied"),
+				},
+			},
+			want:         nil,
+			wantMetadata: nil,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{client: tt.client, logger: slog.New(slog.DiscardHandler)}
+
+			got, metadata, err := app.missingProviders(t.Context(), trusts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("missingProviders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingProviders() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(metadata, tt.wantMetadata) {
+				t.Errorf("missingProviders() metadata = %v, want %v", metadata, tt.wantMetadata)
+			}
+		})
+	}
+}
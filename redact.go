@@ -0,0 +1,178 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	sequenceMaskPrefix    = "ACCOUNT-"
+	consistentMaskPrefix  = "ACCT"
+	consistentMaskHashLen = 12
+	// redactKeyEnv names the environment variable holding the key of the consistent masks, kept out of the flags so
+	// it does not end up in shell histories or process listings.
+	redactKeyEnv = "VEIL_REDACT_KEY"
+)
+
+var errRedactKey = errors.New("-redact-consistent requires a key in the " + redactKeyEnv + " environment variable")
+
+var accountIDInTextRegex = regexp.MustCompile(`\b\d{12}\b`) //nolint:gochecknoglobals
+
+// accountRedactor replaces account IDs with pseudonyms, giving distinct accounts distinct pseudonyms. Without a key
+// the accounts are numbered in the order they are met, e.g. ACCOUNT-1, so the pseudonyms only hold for a single run.
+// With a key each account is masked with a keyed HMAC of its ID instead, stable across runs sharing the key but not
+// reversible without it.
+type accountRedactor struct {
+	key   []byte
+	mutex sync.Mutex
+	masks map[string]string
+}
+
+// newAccountRedactor returns a redactor numbering the accounts, or masking them with an HMAC keyed with key if set.
+func newAccountRedactor(key []byte) *accountRedactor {
+	return &accountRedactor{
+		key:   key,
+		mutex: sync.Mutex{},
+		masks: make(map[string]string),
+	}
+}
+
+// mask returns the pseudonym of the given account ID.
+func (r *accountRedactor) mask(account string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if mask, ok := r.masks[account]; ok {
+		return mask
+	}
+
+	mask := sequenceMaskPrefix + strconv.Itoa(len(r.masks)+1)
+	if r.key != nil {
+		digest := hmac.New(sha256.New, r.key)
+		digest.Write([]byte(account))
+		mask = consistentMaskPrefix + strings.ToUpper(hex.EncodeToString(digest.Sum(nil))[:consistentMaskHashLen])
+	}
+
+	r.masks[account] = mask
+
+	return mask
+}
+
+// redactString replaces every 12-digit account ID in the given string with its pseudonym.
+func (r *accountRedactor) redactString(input string) string {
+	return accountIDInTextRegex.ReplaceAllStringFunc(input, r.mask)
+}
+
+// redactRoles returns the roles and their principals with account IDs replaced by their pseudonyms. Roles are visited
+// in order, so the accounts are numbered the same way on every run over the same roles.
+func (r *accountRedactor) redactRoles(roles map[string][]string) map[string][]string {
+	output := make(map[string][]string, len(roles))
+
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		output[r.redactString(role)] = r.redactList(roles[role])
+	}
+
+	return output
+}
+
+// redactList returns the given ARNs or account IDs with account IDs replaced by their pseudonyms.
+func (r *accountRedactor) redactList(input []string) []string {
+	output := make([]string, 0, len(input))
+	for _, item := range input {
+		output = append(output, r.redactString(item))
+	}
+
+	return output
+}
+
+// redactOrg returns the organisation structure with account IDs replaced by their pseudonyms.
+func (r *accountRedactor) redactOrg(structure orgStructure) orgStructure {
+	output := make(orgStructure, len(structure))
+
+	for _, ou := range slices.Sorted(maps.Keys(structure)) {
+		output[ou] = r.redactList(structure[ou])
+	}
+
+	return output
+}
+
+// redactRoles applies the configured redaction to the roles and their principals before they are rendered.
+func (a *App) redactRoles(roles map[string][]string) map[string][]string {
+	if a.redactor == nil {
+		return roles
+	}
+
+	return a.redactor.redactRoles(roles)
+}
+
+// redactOrg applies the configured redaction to the organisation structure the roles are grouped by.
+func (a *App) redactOrg(structure orgStructure) orgStructure {
+	if a.redactor == nil {
+		return structure
+	}
+
+	return a.redactor.redactOrg(structure)
+}
+
+// redactReports applies the configured redaction to the role-oriented reports before they are rendered.
+func (a *App) redactReports(reports map[string]roleReport) map[string]roleReport {
+	if a.redactor == nil {
+		return reports
+	}
+
+	output := make(map[string]roleReport, len(reports))
+
+	for _, role := range slices.Sorted(maps.Keys(reports)) {
+		report := reports[role]
+		report.Principals = a.redactor.redactList(report.Principals)
+
+		if report.PrincipalLabels != nil {
+			labels := make(map[string]map[string]string, len(report.PrincipalLabels))
+			for principal, principalLabels := range report.PrincipalLabels {
+				labels[a.redactor.redactString(principal)] = principalLabels
+			}
+
+			report.PrincipalLabels = labels
+		}
+
+		if report.DerivedFrom != nil {
+			report.DerivedFrom = a.redactor.redactRoles(report.DerivedFrom)
+		}
+
+		output[a.redactor.redactString(role)] = report
+	}
+
+	return output
+}
+
+// redactString applies the configured redaction to a single ARN or account ID.
+func (a *App) redactString(input string) string {
+	if a.redactor == nil {
+		return input
+	}
+
+	return a.redactor.redactString(input)
+}
+
+// redact masks the account IDs left in the rendered output, such as those in finding messages, with the same
+// pseudonyms as the roles.
+func (a *App) redact(data []byte) []byte {
+	if a.redactor == nil {
+		return data
+	}
+
+	return accountIDInTextRegex.ReplaceAllFunc(data, func(account []byte) []byte {
+		return []byte(a.redactor.mask(string(account)))
+	})
+}
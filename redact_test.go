@@ -0,0 +1,156 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func Test_accountRedactor_redactRoles(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::123456789012:role/vendor": {"arn:aws:iam::210987654321:root"},
+		"arn:aws:iam::123456789012:role/admin":  {"arn:aws:iam::210987654321:root", "111111111111"},
+		"arn:aws:iam::123456789012:role/ci":     {"arn:aws:iam::111111111111:role/ci"},
+		"arn:aws:iam::123456789012:role/ecs":    {"ecs.amazonaws.com"},
+	}
+	accountID := regexp.MustCompile(`\b\d{12}\b`)
+
+	tests := []struct {
+		name string
+		key  []byte
+		want map[string][]string
+	}{
+		{
+			name: "numbered per run",
+			key:  nil,
+			want: map[string][]string{
+				"arn:aws:iam::ACCOUNT-1:role/admin":  {"arn:aws:iam::ACCOUNT-2:root", "ACCOUNT-3"},
+				"arn:aws:iam::ACCOUNT-1:role/ci":     {"arn:aws:iam::ACCOUNT-3:role/ci"},
+				"arn:aws:iam::ACCOUNT-1:role/ecs":    {"ecs.amazonaws.com"},
+				"arn:aws:iam::ACCOUNT-1:role/vendor": {"arn:aws:iam::ACCOUNT-2:root"},
+			},
+		},
+		{
+			name: "keyed",
+			key:  []byte("secret"),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := newAccountRedactor(tt.key).redactRoles(roles)
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redactRoles() = %v, want %v", got, tt.want)
+			}
+
+			marshal, err := json.Marshal(mapFlip(got))
+			if err != nil {
+				t.Fatalf("failed to marshal output: %v", err)
+			}
+
+			if accountID.Match(marshal) {
+				t.Errorf("redactRoles() left an account ID in %s", marshal)
+			}
+
+			if flipped := mapFlip(got); len(flipped) != len(mapFlip(roles)) {
+				t.Errorf("redactRoles() merged distinct principals, got %d want %d", len(flipped), len(mapFlip(roles)))
+			}
+
+			if again := newAccountRedactor(tt.key).redactRoles(roles); !reflect.DeepEqual(again, got) {
+				t.Errorf("redactRoles() is not stable across runs: %v and %v", got, again)
+			}
+		})
+	}
+}
+
+func Test_accountRedactor_mask(t *testing.T) {
+	t.Parallel()
+
+	keyed := newAccountRedactor([]byte("secret"))
+	other := newAccountRedactor([]byte("other"))
+
+	vendor, ci := keyed.mask("210987654321"), keyed.mask("111111111111")
+	if vendor == ci {
+		t.Errorf("mask() gave distinct accounts the same mask %s", vendor)
+	}
+
+	if !strings.HasPrefix(vendor, consistentMaskPrefix) || len(vendor) != len(consistentMaskPrefix)+consistentMaskHashLen {
+		t.Errorf("mask() = %s, want %s followed by %d hex digits", vendor, consistentMaskPrefix, consistentMaskHashLen)
+	}
+
+	if got := other.mask("210987654321"); got == vendor {
+		t.Errorf("mask() did not depend on the key, got %s for both", got)
+	}
+
+	if got := keyed.mask("210987654321"); got != vendor {
+		t.Errorf("mask() = %s, want the earlier %s", got, vendor)
+	}
+}
+
+func TestApp_runScanIAM_redacted(t *testing.T) {
+	t.Parallel()
+
+	document := func(account string) *string {
+		return aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow",` +
+			`"Principal":{"AWS":"arn:aws:iam::` + account + `:root"},"Action":"sts:AssumeRole"}]}`)
+	}
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{Roles: []types.Role{
+			{Arn: aws.String("arn:aws:iam::123456789012:role/vendor"), AssumeRolePolicyDocument: document("210987654321")},
+			{Arn: aws.String("arn:aws:iam::123456789012:role/partner"), AssumeRolePolicyDocument: document("111111111111")},
+		}},
+	}
+	WithRedactAccounts(nil)(app)
+
+	marshal, err := app.runScanIAM(t.Context())
+	if err != nil {
+		t.Fatalf("runScanIAM() unexpected error: %v", err)
+	}
+
+	var got map[string][]string
+
+	err = json.Unmarshal(app.redact(marshal), &got)
+	if err != nil {
+		t.Fatalf("runScanIAM() produced invalid JSON: %v", err)
+	}
+
+	want := map[string][]string{
+		"arn:aws:iam::ACCOUNT-2:root": {"arn:aws:iam::ACCOUNT-1:role/partner"},
+		"arn:aws:iam::ACCOUNT-3:root": {"arn:aws:iam::ACCOUNT-1:role/vendor"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runScanIAM() = %v, want %v", got, want)
+	}
+}
+
+func TestApp_buildRoleReports_redacted(t *testing.T) {
+	t.Parallel()
+
+	arn := "arn:aws:iam::0123456789:role/ecs"
+	a := &App{rawPolicy: true, redactAccounts: true, logger: slog.New(slog.DiscardHandler)}
+
+	got, err := a.buildRoleReports(map[string]roleTrust{arn: mustDecodeTrust(t, arn, fixtureAWSServiceRoleForECS)})
+	if err != nil {
+		t.Fatalf("buildRoleReports() unexpected error: %v", err)
+	}
+
+	if got[arn].RawPolicy != nil {
+		t.Errorf("buildRoleReports() included a raw policy while redacting")
+	}
+}
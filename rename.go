@@ -0,0 +1,285 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var (
+	errInvalidRenameKeys = errors.New("expected -rename-keys as comma-separated from=to pairs")
+	errRenameCollision   = errors.New("renamed key collides with another key")
+	errRenameNotJSON     = errors.New("-rename-keys requires JSON output")
+	errRenameProtoJSON   = errors.New("-format protojson cannot be combined with -rename-keys")
+)
+
+// parseRenameKeys parses comma-separated from=to pairs, e.g. principal=subject,role=target, rejecting mappings that
+// rename a key twice or rename two keys to the same name.
+func parseRenameKeys(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	sources := make(map[string]string)
+
+	for _, pair := range splitList(value) {
+		from, to, found := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+
+		if !found || from == "" || to == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidRenameKeys, pair)
+		}
+
+		if _, ok := mapping[from]; ok {
+			return nil, fmt.Errorf("%w: %s is renamed twice", errInvalidRenameKeys, from)
+		}
+
+		if other, ok := sources[to]; ok {
+			return nil, fmt.Errorf("%w: %s and %s are both renamed to %s", errRenameCollision, other, from, to)
+		}
+
+		mapping[from] = to
+		sources[to] = from
+	}
+
+	return mapping, nil
+}
+
+// jsonRenderer is implemented by values with a custom JSON form, returning the value that form is marshalled from so
+// that its field names can be renamed as well.
+type jsonRenderer interface {
+	jsonValue() any
+}
+
+// renamedObject is a JSON object whose keys were renamed, keeping the order of the struct fields it was built from.
+type renamedObject struct {
+	keys   []string
+	values []any
+}
+
+// MarshalJSON renders the object with its keys in order.
+func (o *renamedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal output: %w", err)
+		}
+
+		encoded, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal output: %w", err)
+		}
+
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// renameJSONFields returns the given value with the JSON names of its struct fields renamed, at any depth. Map keys
+// are data, e.g. role ARNs, and values with their own JSON form, e.g. policy fragments, are kept as they are. Renaming
+// a field to one the struct already has is rejected rather than silently dropping either value.
+func renameJSONFields(value reflect.Value, mapping map[string]string) (any, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+
+	if value.CanInterface() {
+		switch typed := value.Interface().(type) {
+		case jsonRenderer:
+			return renameJSONFields(reflect.ValueOf(typed.jsonValue()), mapping)
+		case json.Marshaler:
+			return typed, nil
+		}
+	}
+
+	switch value.Kind() { //nolint:exhaustive
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+
+		return renameJSONFields(value.Elem(), mapping)
+	case reflect.Struct:
+		object := &renamedObject{keys: nil, values: nil}
+
+		err := renameStructFields(value, mapping, object)
+		if err != nil {
+			return nil, err
+		}
+
+		return object, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return nil, nil
+		}
+
+		renamed := reflect.MakeMapWithSize(reflect.MapOf(value.Type().Key(), reflect.TypeFor[any]()), value.Len())
+
+		iter := value.MapRange()
+		for iter.Next() {
+			elem, err := renameJSONFields(iter.Value(), mapping)
+			if err != nil {
+				return nil, err
+			}
+
+			renamed.SetMapIndex(iter.Key(), reflect.ValueOf(&elem).Elem())
+		}
+
+		return renamed.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface(), nil
+		}
+
+		renamed := make([]any, value.Len())
+
+		for i := range value.Len() {
+			elem, err := renameJSONFields(value.Index(i), mapping)
+			if err != nil {
+				return nil, err
+			}
+
+			renamed[i] = elem
+		}
+
+		return renamed, nil
+	default:
+		return value.Interface(), nil
+	}
+}
+
+// renameStructFields appends the fields encoding/json would write for the given struct to the object, flattening
+// embedded structs and honouring the omitempty and omitzero options.
+func renameStructFields(value reflect.Value, mapping map[string]string, object *renamedObject) error {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		fieldValue := value.Field(i)
+
+		if field.Anonymous && name == "" {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				err := renameStructFields(embedded, mapping, object)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() || omitJSONField(fieldValue, strings.Split(options, ",")) {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if renamed, ok := mapping[name]; ok {
+			name = renamed
+		}
+
+		if slices.Contains(object.keys, name) {
+			return fmt.Errorf("%w: %s", errRenameCollision, name)
+		}
+
+		elem, err := renameJSONFields(fieldValue, mapping)
+		if err != nil {
+			return err
+		}
+
+		object.keys = append(object.keys, name)
+		object.values = append(object.values, elem)
+	}
+
+	return nil
+}
+
+// omitJSONField tells whether encoding/json leaves the field out given its tag options.
+func omitJSONField(value reflect.Value, options []string) bool {
+	if slices.Contains(options, "omitzero") && value.IsZero() {
+		return true
+	}
+
+	if !slices.Contains(options, "omitempty") {
+		return false
+	}
+
+	switch value.Kind() { //nolint:exhaustive
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return value.IsZero()
+	case reflect.Interface, reflect.Pointer:
+		return value.IsNil()
+	default:
+		return false
+	}
+}
+
+// renameFields applies the configured key renaming to the given output value, returning it as is without one.
+func (a *App) renameFields(output any) (any, error) {
+	if len(a.renameKeyMap) == 0 {
+		return output, nil
+	}
+
+	return renameJSONFields(reflect.ValueOf(output), a.renameKeyMap)
+}
+
+// marshalOutput renders the given output as indented JSON, with the configured key renaming applied.
+func (a *App) marshalOutput(output any) ([]byte, error) {
+	renamed, err := a.renameFields(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(renamed, "", "  ") //nolint:wrapcheck
+}
+
+// requireJSONOutput rejects output that is not JSON when keys are renamed, as only JSON output has keys to rename.
+func (a *App) requireJSONOutput(data []byte) error {
+	if len(a.renameKeyMap) == 0 || json.Valid(data) {
+		return nil
+	}
+
+	return errRenameNotJSON
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_parseRenameKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name:    "pairs",
+			value:   "principal=subject, role=target",
+			want:    map[string]string{"principal": "subject", "role": "target"},
+			wantErr: nil,
+		},
+		{
+			name:    "swap",
+			value:   "principal=role,role=principal",
+			want:    map[string]string{"principal": "role", "role": "principal"},
+			wantErr: nil,
+		},
+		{name: "missing separator", value: "principal", want: nil, wantErr: errInvalidRenameKeys},
+		{name: "empty target", value: "principal=", want: nil, wantErr: errInvalidRenameKeys},
+		{name: "renamed twice", value: "role=target,role=subject", want: nil, wantErr: errInvalidRenameKeys},
+		{name: "same target", value: "principal=subject,role=subject", want: nil, wantErr: errRenameCollision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseRenameKeys(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseRenameKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRenameKeys() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_marshalOutput(t *testing.T) {
+	t.Parallel()
+
+	findings := []Finding{
+		{
+			Code:      codeWildcardPrincipal,
+			Severity:  severityHigh,
+			Role:      "arn:aws:iam::0123456789:role/open",
+			Principal: "*",
+			Message:   "anyone can assume the role",
+			Remediation: &Remediation{
+				Summary:   "Pin the principal.",
+				Fragment:  json.RawMessage(`{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::0123456789:root"}}`),
+				Statement: 0,
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		output  any
+		mapping map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "findings",
+			output:  findings,
+			mapping: map[string]string{"principal": "subject", "role": "target", "statement": "index", "Effect": "effect"},
+			want: `[
+  {
+    "code": "WILDCARD_PRINCIPAL",
+    "severity": "high",
+    "target": "arn:aws:iam::0123456789:role/open",
+    "subject": "*",
+    "message": "anyone can assume the role",
+    "remediation": {
+      "summary": "Pin the principal.",
+      "fragment": {
+        "Effect": "Allow",
+        "Principal": {
+          "AWS": "arn:aws:iam::0123456789:root"
+        }
+      },
+      "index": 0
+    }
+  }
+]`,
+			wantErr: nil,
+		},
+		{
+			name:    "map keys are kept",
+			output:  map[string][]string{"role": {"principal"}},
+			mapping: map[string]string{"role": "target", "principal": "subject"},
+			want: `{
+  "role": [
+    "principal"
+  ]
+}`,
+			wantErr: nil,
+		},
+		{
+			name: "custom JSON form",
+			output: MultiAccountResult{AccountResults: map[string]AccountScanResult{
+				"0123456789": {Data: nil, Error: errRenameNotJSON},
+			}},
+			mapping: map[string]string{"accounts": "results", "error": "failure"},
+			want: `{
+  "results": {
+    "0123456789": {
+      "failure": "-rename-keys requires JSON output"
+    }
+  }
+}`,
+			wantErr: nil,
+		},
+		{
+			name:    "without renaming",
+			output:  map[string][]string{"role": nil},
+			mapping: nil,
+			want: `{
+  "role": null
+}`,
+			wantErr: nil,
+		},
+		{
+			name:    "collision with an existing field",
+			output:  findings,
+			mapping: map[string]string{"principal": "role"},
+			want:    "",
+			wantErr: errRenameCollision,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{renameKeyMap: tt.mapping}
+
+			got, err := app.marshalOutput(tt.output)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("marshalOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("marshalOutput() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_requireJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		mapping map[string]string
+		wantErr error
+	}{
+		{name: "JSON", data: []byte(`{"role": []}`), mapping: map[string]string{"role": "target"}, wantErr: nil},
+		{
+			name:    "not JSON",
+			data:    []byte("# Trust report\n"),
+			mapping: map[string]string{"role": "target"},
+			wantErr: errRenameNotJSON,
+		},
+		{name: "without renaming", data: []byte("# Trust report\n"), mapping: nil, wantErr: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{renameKeyMap: tt.mapping}
+
+			err := app.requireJSONOutput(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("requireJSONOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+const defaultRawPolicyLimit = 10240
+
+// roleReport describes the trust configuration of a single IAM role.
+type roleReport struct {
+	Principals      []string                     `json:"principals"`
+	Tags            map[string]string            `json:"tags,omitempty"`
+	Labels          map[string]string            `json:"labels,omitempty"`
+	PrincipalLabels map[string]map[string]string `json:"principalLabels,omitempty"`
+	// DerivedFrom lists, for each role trusted through assumed-role sessions, the session ARNs named by the policy.
+	DerivedFrom map[string][]string `json:"derivedFrom,omitempty"`
+	RawPolicy   *rawPolicy          `json:"rawPolicy,omitempty"`
+	Boundary    *boundaryReport     `json:"boundary,omitempty"`
+}
+
+// rawPolicy holds the URL-decoded trust policy document exactly as returned by IAM, or indented when pretty-printed.
+// SHA256 and Size always describe the full document as returned, even when Document has been indented or truncated.
+type rawPolicy struct {
+	Document  string `json:"document"`
+	SHA256    string `json:"sha256"`
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// newRawPolicy URL-decodes the given trust policy document, indents it when pretty is set, and truncates it to limit
+// bytes. A non-positive limit disables truncation.
+func newRawPolicy(document string, limit int, pretty bool) (*rawPolicy, error) {
+	data, err := url.QueryUnescape(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unescape URL: %w", err)
+	}
+
+	output := &rawPolicy{
+		Document:  data,
+		SHA256:    sha256Hex([]byte(data)),
+		Size:      len(data),
+		Truncated: false,
+	}
+
+	if pretty {
+		output.Document, err = indentPolicy(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limit > 0 && len(output.Document) > limit {
+		output.Document = strings.ToValidUTF8(output.Document[:limit], "")
+		output.Truncated = true
+	}
+
+	return output, nil
+}
+
+// PrettyPrintPolicy URL-decodes a trust policy document as returned by IAM and indents it with two spaces.
+func PrettyPrintPolicy(raw string) (string, error) {
+	data, err := url.QueryUnescape(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to unescape URL: %w", err)
+	}
+
+	return indentPolicy(data)
+}
+
+// indentPolicy indents a decoded policy document with two spaces.
+func indentPolicy(data string) (string, error) {
+	var buffer bytes.Buffer
+
+	err := json.Indent(&buffer, []byte(strings.TrimSpace(data)), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to indent JSON: %w", err)
+	}
+
+	return buffer.String(), nil
+}
+
+// buildRoleReports returns the role-oriented view of the scanned trust policies.
+func (a *App) buildRoleReports(trusts map[string]roleTrust) (map[string]roleReport, error) {
+	output := make(map[string]roleReport, len(trusts))
+	sessions := newSessionIndex(trusts)
+
+	for arn, trust := range trusts {
+		principals, derivedFrom := sessions.resolve(a.trustPrincipals(trust.policy))
+		report := roleReport{
+			Principals:      principals,
+			Tags:            tagMap(trust.role.Tags),
+			Labels:          a.labels.labelsOf(arn),
+			PrincipalLabels: a.labels.principalLabels(principals),
+			DerivedFrom:     derivedFrom,
+			RawPolicy:       nil,
+			Boundary:        nil,
+		}
+
+		if a.withBoundary {
+			report.Boundary = newBoundaryReport(trust.role)
+		}
+
+		if a.rawPolicy && !a.redactAccounts && trust.role.AssumeRolePolicyDocument != nil {
+			raw, err := newRawPolicy(*trust.role.AssumeRolePolicyDocument, a.rawPolicyLimit, a.prettyPolicies)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read raw trust policy of %s: %w", arn, err)
+			}
+
+			if raw.Truncated {
+				a.logger.Warn(
+					"raw trust policy truncated",
+					slog.String("role", arn),
+					slog.Int("size", raw.Size),
+					slog.Int("limit", a.rawPolicyLimit),
+				)
+			}
+
+			report.RawPolicy = raw
+		}
+
+		output[arn] = report
+	}
+
+	return output, nil
+}
+
+func (a *App) runScanRoles(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output, err := a.buildRoleReports(trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	marshal, err := a.marshalOutput(a.redactReports(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
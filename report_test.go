@@ -0,0 +1,213 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_newRawPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		limit    int
+		pretty   bool
+		want     *rawPolicy
+		wantErr  bool
+	}{
+		{
+			name:     "invalid escape",
+			document: "test%2x",
+			limit:    0,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "decoded without limit",
+			document: "%7B%22Version%22%3A%222012-10-17%22%7D",
+			limit:    0,
+			want: &rawPolicy{
+				Document:  `{"Version":"2012-10-17"}`,
+				SHA256:    "52279714d77bcfad953e90e091f01cf2b8df980cb4c43eea600ce15103933a2c",
+				Size:      24,
+				Truncated: false,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "truncated beyond limit",
+			document: "%7B%22Version%22%3A%222012-10-17%22%7D",
+			limit:    10,
+			want: &rawPolicy{
+				Document:  `{"Version"`,
+				SHA256:    "52279714d77bcfad953e90e091f01cf2b8df980cb4c43eea600ce15103933a2c",
+				Size:      24,
+				Truncated: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "pretty-printed",
+			document: "%7B%22Version%22%3A%222012-10-17%22%7D",
+			limit:    0,
+			pretty:   true,
+			want: &rawPolicy{
+				Document:  "{\n  \"Version\": \"2012-10-17\"\n}",
+				SHA256:    "52279714d77bcfad953e90e091f01cf2b8df980cb4c43eea600ce15103933a2c",
+				Size:      24,
+				Truncated: false,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "pretty-printed and truncated beyond limit",
+			document: "%7B%22Version%22%3A%222012-10-17%22%7D",
+			limit:    12,
+			pretty:   true,
+			want: &rawPolicy{
+				Document:  "{\n  \"Version",
+				SHA256:    "52279714d77bcfad953e90e091f01cf2b8df980cb4c43eea600ce15103933a2c",
+				Size:      24,
+				Truncated: true,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "pretty-printing invalid JSON",
+			document: "%7B%22Version%22",
+			limit:    0,
+			pretty:   true,
+			want:     nil,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := newRawPolicy(tt.document, tt.limit, tt.pretty)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newRawPolicy() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newRawPolicy() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyPrintPolicy(t *testing.T) {
+	t.Parallel()
+
+	var compact bytes.Buffer
+
+	err := json.Compact(&compact, []byte(fixtureCrossAccountTagSession))
+	if err != nil {
+		t.Fatalf("failed to compact fixture: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{name: "compact", raw: url.QueryEscape(compact.String()), wantErr: false},
+		{name: "already indented", raw: url.QueryEscape(fixtureCrossAccountTagSession), wantErr: false},
+		{name: "invalid escape", raw: "test%2x", wantErr: true},
+		{name: "invalid JSON", raw: url.QueryEscape(`{"Version":`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := PrettyPrintPolicy(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PrettyPrintPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !json.Valid([]byte(got)) {
+				t.Fatalf("PrettyPrintPolicy() = %q, not valid JSON", got)
+			}
+
+			if got != strings.TrimSpace(fixtureCrossAccountTagSession) {
+				t.Errorf("PrettyPrintPolicy() = %q, want %q", got, fixtureCrossAccountTagSession)
+			}
+
+			for line := range strings.Lines(got) {
+				indent := len(line) - len(strings.TrimLeft(line, " "))
+				if indent%2 != 0 || strings.HasPrefix(line[indent:], "\t") {
+					t.Errorf("PrettyPrintPolicy() line %q is not indented with two spaces", line)
+				}
+			}
+		})
+	}
+}
+
+func TestApp_buildRoleReports(t *testing.T) {
+	t.Parallel()
+
+	arn := "arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS"
+	trusts := map[string]roleTrust{
+		arn: mustDecodeTrust(t, arn, fixtureAWSServiceRoleForECS),
+	}
+
+	tests := []struct {
+		name    string
+		app     *App
+		wantRaw bool
+	}{
+		{
+			name:    "principals only",
+			app:     &App{logger: slog.New(slog.DiscardHandler)},
+			wantRaw: false,
+		},
+		{
+			name: "with raw policy",
+			app: &App{
+				logger:         slog.New(slog.DiscardHandler),
+				rawPolicy:      true,
+				rawPolicyLimit: defaultRawPolicyLimit,
+			},
+			wantRaw: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.app.buildRoleReports(trusts)
+			if err != nil {
+				t.Fatalf("buildRoleReports() unexpected error: %v", err)
+			}
+
+			report := got[arn]
+			if !reflect.DeepEqual(report.Principals, []string{"ecs.amazonaws.com"}) {
+				t.Errorf("buildRoleReports() principals = %v", report.Principals)
+			}
+
+			if (report.RawPolicy != nil) != tt.wantRaw {
+				t.Fatalf("buildRoleReports() raw policy = %v, want %v", report.RawPolicy, tt.wantRaw)
+			}
+
+			if tt.wantRaw && report.RawPolicy.Document != fixtureAWSServiceRoleForECS {
+				t.Errorf("buildRoleReports() raw document = %q", report.RawPolicy.Document)
+			}
+		})
+	}
+}
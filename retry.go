@@ -0,0 +1,81 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const (
+	defaultRetryMaxAttempts  = 3
+	defaultRetryInitialDelay = 500 * time.Millisecond
+)
+
+// retryWithBackoff calls fn until it succeeds, returns a non-retryable error, or maxAttempts is reached.
+// The delay between attempts starts at initialDelay and doubles after every retry, each retry logged to logger.
+func retryWithBackoff[T any](
+	ctx context.Context,
+	logger *slog.Logger,
+	maxAttempts int,
+	initialDelay time.Duration,
+	fn func() (T, error),
+) (T, error) {
+	var zero T
+
+	delay := initialDelay
+
+	for attempt := 1; ; attempt++ {
+		output, err := fn()
+		if err == nil {
+			return output, nil
+		}
+
+		if attempt >= maxAttempts || !isRetryable(err) {
+			return zero, err
+		}
+
+		logger.Debug(
+			"retrying after transient error",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return zero, fmt.Errorf("retry interrupted: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// isRetryable reports whether err is an AWS SDK operation error with a transient HTTP status code.
+func isRetryable(err error) bool {
+	var operationErr *smithy.OperationError
+	if !errors.As(err, &operationErr) {
+		return false
+	}
+
+	var responseErr *smithyhttp.ResponseError
+	if !errors.As(operationErr, &responseErr) {
+		return false
+	}
+
+	switch responseErr.HTTPStatusCode() {
+	case http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
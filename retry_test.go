@@ -0,0 +1,136 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func operationError(statusCode int) error {
+	return &smithy.OperationError{
+		ServiceID:     "IAM",
+		OperationName: "ListRoles",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{
+					Response: &http.Response{StatusCode: statusCode},
+				},
+				Err: errors.New("test error"),
+			},
+			RequestID: "test",
+		},
+	}
+}
+
+func Test_retryWithBackoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		errs         []error
+		maxAttempts  int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "success on first attempt",
+			errs:         []error{nil},
+			maxAttempts:  3,
+			wantAttempts: 1,
+			wantErr:      false,
+		},
+		{
+			name:         "success after service unavailable and throttling",
+			errs:         []error{operationError(503), operationError(429), nil},
+			maxAttempts:  3,
+			wantAttempts: 3,
+			wantErr:      false,
+		},
+		{
+			name:         "max attempts reached",
+			errs:         []error{operationError(500), operationError(500), operationError(500), nil},
+			maxAttempts:  3,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "client error is not retried",
+			errs:         []error{operationError(403), nil},
+			maxAttempts:  3,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "plain error is not retried",
+			errs:         []error{errors.New("test error"), nil},
+			maxAttempts:  3,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			attempts := 0
+
+			got, err := retryWithBackoff(t.Context(), logger, tt.maxAttempts, time.Millisecond, func() (int, error) {
+				attempts++
+
+				return attempts, tt.errs[attempts-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retryWithBackoff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("retryWithBackoff() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+
+			if !tt.wantErr && got != tt.wantAttempts {
+				t.Errorf("retryWithBackoff() got = %d, want %d", got, tt.wantAttempts)
+			}
+
+			if retries := strings.Count(buf.String(), "retrying after transient error"); retries != tt.wantAttempts-1 {
+				t.Errorf("retryWithBackoff() logged %d retries, want %d", retries, tt.wantAttempts-1)
+			}
+		})
+	}
+}
+
+func Test_retryWithBackoff_contextCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	attempts := 0
+
+	_, err := retryWithBackoff(ctx, slog.New(slog.DiscardHandler), 3, time.Hour, func() (int, error) {
+		attempts++
+
+		cancel()
+
+		return 0, operationError(503)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("retryWithBackoff() error = %v, want %v", err, context.Canceled)
+	}
+
+	if attempts != 1 {
+		t.Errorf("retryWithBackoff() attempts = %d, want 1", attempts)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	codeSAMLProviderCertExpiring = "SAML_PROVIDER_CERT_EXPIRING"
+	codeSAMLTrustUnconditioned   = "SAML_TRUST_UNCONDITIONED"
+
+	defaultSAMLCertWindow = 30 * 24 * time.Hour
+)
+
+// samlEntityDescriptor holds the parts of SAML metadata veil reads: the entity ID of the identity provider and its
+// base64-encoded signing certificates.
+type samlEntityDescriptor struct {
+	EntityID     string   `xml:"entityID,attr"`
+	Certificates []string `xml:"IDPSSODescriptor>KeyDescriptor>KeyInfo>X509Data>X509Certificate"`
+}
+
+// samlProvider describes a SAML provider by the entity ID of its identity provider and the expiry of its signing
+// certificates.
+type samlProvider struct {
+	entityID string
+	// notAfter is the latest expiry of the provider certificates, the zero time if none could be parsed.
+	notAfter time.Time
+	expired  bool
+}
+
+// parseSAMLMetadata reads the entity ID and the latest certificate expiry from SAML metadata. Certificates that
+// cannot be parsed are logged and skipped, so a single bad certificate does not hide the others.
+func parseSAMLMetadata(provider string, document string) (samlProvider, error) {
+	var descriptor samlEntityDescriptor
+
+	err := xml.Unmarshal([]byte(document), &descriptor)
+	if err != nil {
+		return samlProvider{}, fmt.Errorf("failed to decode SAML metadata: %w", err)
+	}
+
+	output := samlProvider{entityID: descriptor.EntityID, notAfter: time.Time{}, expired: false}
+
+	for _, encoded := range descriptor.Certificates {
+		certificate, errParse := parseSAMLCertificate(encoded)
+		if errParse != nil {
+			slog.Warn(
+				"skipping unparseable SAML provider certificate",
+				slog.String("provider", provider),
+				slog.String("error", errParse.Error()),
+			)
+
+			continue
+		}
+
+		if certificate.NotAfter.After(output.notAfter) {
+			output.notAfter = certificate.NotAfter
+		}
+	}
+
+	return output, nil
+}
+
+// parseSAMLCertificate decodes a base64-encoded DER certificate, ignoring the whitespace metadata wraps it with.
+func parseSAMLCertificate(encoded string) (*x509.Certificate, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode certificate: %w", err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	return certificate, nil
+}
+
+// expiringSAMLProviders returns the SAML providers whose latest certificate expires before now plus the window,
+// keyed by provider ARN. Metadata that cannot be decoded is logged and skipped.
+func expiringSAMLProviders(metadata map[string]string, now time.Time, window time.Duration) map[string]samlProvider {
+	output := make(map[string]samlProvider)
+
+	for provider, document := range metadata {
+		parsed, err := parseSAMLMetadata(provider, document)
+		if err != nil {
+			slog.Warn(
+				"skipping SAML provider certificate check",
+				slog.String("provider", provider),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		if parsed.notAfter.IsZero() || parsed.notAfter.After(now.Add(window)) {
+			continue
+		}
+
+		parsed.expired = !parsed.notAfter.After(now)
+		output[provider] = parsed
+	}
+
+	return output
+}
+
+func checkSAMLProviderCert(env checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, principal := range statement.Principal.Federated {
+		provider, ok := env.expiringSAMLProviders[principal]
+		if !ok {
+			continue
+		}
+
+		severity, state := severityMedium, "expires"
+		if provider.expired {
+			severity, state = severityHigh, "expired"
+		}
+
+		output = append(output, Finding{
+			Code:      codeSAMLProviderCertExpiring,
+			Severity:  severity,
+			Role:      role,
+			Principal: principal,
+			Message: fmt.Sprintf(
+				"certificate of SAML provider for %s %s on %s, the provider may be abandoned",
+				provider.entityID,
+				state,
+				provider.notAfter.UTC().Format(time.DateOnly),
+			),
+			Remediation: &Remediation{
+				Summary: "Upload the current metadata of the identity provider if it is still in use, otherwise " +
+					"remove the principal and delete the provider.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		})
+	}
+
+	return output
+}
+
+// samlTrustRecommendation suggests pinning the audience of the SAML assertions accepted by the role.
+const samlTrustRecommendation = "Add a StringEquals condition on SAML:aud pinning " +
+	"https://signin.aws.amazon.com/saml, so that only assertions issued for AWS sign-in can assume the role."
+
+// SAMLFinding is a SAML provider trusted by a role without any condition on the assertions it accepts.
+type SAMLFinding struct {
+	RoleARN           string `json:"roleArn"`
+	FederatedProvider string `json:"federatedProvider"`
+	Recommendation    string `json:"recommendation"`
+}
+
+// DetectUnconditionedSAMLTrust returns the federated providers of the statements of the trust policy of the given
+// role allowing sts:AssumeRoleWithSAML without a Condition block.
+func DetectUnconditionedSAMLTrust(role string, policy TrustPolicy) []SAMLFinding {
+	output := make([]SAMLFinding, 0)
+
+	for _, statement := range policy.Statement {
+		for _, finding := range unconditionedSAMLTrust(statement) {
+			finding.RoleARN = role
+			output = append(output, finding)
+		}
+	}
+
+	return output
+}
+
+// unconditionedSAMLTrust returns a finding for each federated principal of a statement allowing
+// sts:AssumeRoleWithSAML without any condition.
+func unconditionedSAMLTrust(statement Statement) []SAMLFinding {
+	if !statement.allowsAction(actionAssumeRoleWithSAML) || len(statement.Condition) > 0 {
+		return nil
+	}
+
+	output := make([]SAMLFinding, 0, len(statement.Principal.Federated))
+	for _, principal := range statement.Principal.Federated {
+		output = append(output, SAMLFinding{
+			RoleARN:           "",
+			FederatedProvider: principal,
+			Recommendation:    samlTrustRecommendation,
+		})
+	}
+
+	return output
+}
+
+func checkSAMLTrustUnconditioned(_ checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, finding := range unconditionedSAMLTrust(statement) {
+		output = append(output, Finding{
+			Code:      codeSAMLTrustUnconditioned,
+			Severity:  severityHigh,
+			Role:      role,
+			Principal: finding.FederatedProvider,
+			Message:   "the role accepts any SAML assertion of the provider, the statement has no condition",
+			Remediation: newRemediation(
+				finding.Recommendation,
+				withCondition(statement, "StringEquals", samlAudience, placeholderAudience(finding.FederatedProvider)),
+			),
+		})
+	}
+
+	return output
+}
+
+func (a *App) runSAMLCheck(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := make([]SAMLFinding, 0)
+
+	for _, arn := range slices.Sorted(maps.Keys(trusts)) {
+		output = append(output, DetectUnconditionedSAMLTrust(arn, trusts[arn].policy)...)
+	}
+
+	a.logger.Debug(
+		"checked IAM roles SAML trust",
+		slog.Int("roles", len(trusts)),
+		slog.Int("unconditioned", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_parseSAMLMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     samlProvider
+		wantErr  bool
+	}{
+		{
+			name:     "unparseable certificate skipped",
+			document: fixtureSAMLMetadata,
+			want: samlProvider{
+				entityID: "https://idp.example.com/saml",
+				notAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+				expired:  false,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "no certificates",
+			document: `<EntityDescriptor entityID="https://idp.example.com/saml"/>`,
+			want:     samlProvider{entityID: "https://idp.example.com/saml", notAfter: time.Time{}, expired: false},
+			wantErr:  false,
+		},
+		{
+			name:     "not XML",
+			document: "{}",
+			want:     samlProvider{},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSAMLMetadata("arn:aws:iam::0123456789:saml-provider/idp", tt.document)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSAMLMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSAMLMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expiringSAMLProviders(t *testing.T) {
+	t.Parallel()
+
+	provider := "arn:aws:iam::0123456789:saml-provider/idp"
+	metadata := map[string]string{
+		provider: fixtureSAMLMetadata,
+		"arn:aws:iam::0123456789:saml-provider/broken": "not metadata",
+	}
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want map[string]samlProvider
+	}{
+		{
+			name: "valid beyond the window",
+			now:  time.Date(2029, 11, 1, 0, 0, 0, 0, time.UTC),
+			want: map[string]samlProvider{},
+		},
+		{
+			name: "expiring within the window",
+			now:  time.Date(2029, 12, 15, 0, 0, 0, 0, time.UTC),
+			want: map[string]samlProvider{
+				provider: {entityID: "https://idp.example.com/saml", notAfter: notAfter, expired: false},
+			},
+		},
+		{
+			name: "expired",
+			now:  time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC),
+			want: map[string]samlProvider{
+				provider: {entityID: "https://idp.example.com/saml", notAfter: notAfter, expired: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := expiringSAMLProviders(metadata, tt.now, defaultSAMLCertWindow)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expiringSAMLProviders() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectUnconditionedSAMLTrust(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []SAMLFinding
+	}{
+		{
+			name:     "no condition",
+			document: fixtureUnscopedSAMLTrust,
+			want: []SAMLFinding{
+				{
+					RoleARN:           "arn:aws:iam::123456789012:role/test",
+					FederatedProvider: "arn:aws:iam::123456789012:saml-provider/CorporateIdP",
+					Recommendation:    samlTrustRecommendation,
+				},
+			},
+		},
+		{
+			name:     "wildcard action",
+			document: fixtureStarAction,
+			want: []SAMLFinding{
+				{
+					RoleARN:           "arn:aws:iam::123456789012:role/test",
+					FederatedProvider: "arn:aws:iam::123456789012:saml-provider/CorporateIdP",
+					Recommendation:    samlTrustRecommendation,
+				},
+			},
+		},
+		{name: "audience pinned", document: fixtureAWSReservedSSOFullAdmin, want: []SAMLFinding{}},
+		{name: "web identity", document: fixtureUnscopedOIDCTrust, want: []SAMLFinding{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			role := "arn:aws:iam::123456789012:role/test"
+
+			trust := mustDecodeTrust(t, role, tt.document)
+			if got := DetectUnconditionedSAMLTrust(role, trust.policy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectUnconditionedSAMLTrust() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
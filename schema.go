@@ -0,0 +1,43 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+)
+
+// CurrentSchemaVersion is the version of the JSON Schemas describing the output formats, bumped on every incompatible
+// change of an output structure. The schemas of each version live in schemas/v<version>.
+const CurrentSchemaVersion = 1
+
+// schemaRoleReport names the schema of the role-oriented report, which replaces the json format output with
+// -include-raw-policy, -with-boundary, -only-unbounded or -labels-file.
+const schemaRoleReport = "role-report"
+
+// schemaMetadata names the schema of the json format output wrapped in an envelope with -include-metadata.
+const schemaMetadata = "json-metadata"
+
+var errNoSchema = errors.New("no JSON Schema for output format")
+
+//go:embed schemas
+var schemas embed.FS
+
+// outputSchema returns the JSON Schema document describing the output of the given format, or of the role report,
+// for the current schema version. Formats not written as JSON have none.
+func outputSchema(format string) ([]byte, error) {
+	data, err := schemas.ReadFile("schemas/v" + strconv.Itoa(CurrentSchemaVersion) + "/" + format + ".schema.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", errNoSchema, format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,359 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+// schemaFormats lists the output formats written as JSON, each described by a schema.
+var schemaFormats = []string{ //nolint:gochecknoglobals
+	formatJSON,
+	formatOrg,
+	formatJSONCount,
+	formatDistribution,
+	formatTables,
+	formatAccessAnalyzer,
+	formatInventory,
+}
+
+// validateSchema checks value against the subset of JSON Schema used by the output schemas: type, const, enum,
+// required, properties, additionalProperties and items.
+func validateSchema(schema map[string]any, value any, path string) error {
+	if kinds, ok := schema["type"].([]any); ok {
+		for _, kind := range kinds {
+			variant := maps.Clone(schema)
+			variant["type"] = kind
+
+			if validateSchema(variant, value, path) == nil {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%s: got %v, want one of %v", path, value, kinds)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: got %T, want an object", path, value)
+		}
+
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, found := object[key.(string)]; !found { //nolint:forcetypeassert
+				return fmt.Errorf("%s: missing required %s", path, key)
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		for key, item := range object {
+			child, declared := properties[key].(map[string]any)
+			if !declared {
+				additional, isSchema := schema["additionalProperties"].(map[string]any)
+				if !isSchema {
+					if schema["additionalProperties"] == false {
+						return fmt.Errorf("%s: unexpected property %s", path, key)
+					}
+
+					continue
+				}
+
+				child = additional
+			}
+
+			err := validateSchema(child, item, path+"."+key)
+			if err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: got %T, want an array", path, value)
+		}
+
+		items, _ := schema["items"].(map[string]any)
+		for index, item := range array {
+			err := validateSchema(items, item, path+"["+strconv.Itoa(index)+"]")
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: got %T, want a string", path, value)
+		}
+	case "null":
+		if value != nil {
+			return fmt.Errorf("%s: got %v, want null", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: got %T, want a boolean", path, value)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != float64(int(number)) {
+			return fmt.Errorf("%s: got %v, want an integer", path, value)
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && constant != value {
+		return fmt.Errorf("%s: got %v, want %v", path, value, constant)
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s: got %v, want one of %v", path, value, enum)
+	}
+
+	return nil
+}
+
+func Test_outputSchema(t *testing.T) {
+	t.Parallel()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					Path:                     aws.String("/"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					Path:                     aws.String("/aws-service-role/ecs.amazonaws.com/"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/org"),
+					Path:                     aws.String("/"),
+					AssumeRolePolicyDocument: aws.String(fixtureOrgWideTrust),
+				},
+			},
+		},
+		orgStructure: orgStructure{"ou-root": {"0123456789"}},
+	}
+
+	for _, format := range schemaFormats {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := outputSchema(format)
+			if err != nil {
+				t.Fatalf("outputSchema() unexpected error: %v", err)
+			}
+
+			var schema map[string]any
+
+			err = json.Unmarshal(data, &schema)
+			if err != nil {
+				t.Fatalf("outputSchema() returned invalid JSON: %v", err)
+			}
+
+			id, _ := schema["$id"].(string)
+			want := "/schemas/v" + strconv.Itoa(CurrentSchemaVersion) + "/" + format + ".schema.json"
+
+			if schema["$schema"] != "https://json-schema.org/draft/2020-12/schema" || !strings.HasSuffix(id, want) {
+				t.Errorf("outputSchema() $schema = %v, $id = %s, want an $id ending in %s", schema["$schema"], id, want)
+			}
+
+			scan, err := app.scanner(format)
+			if err != nil {
+				t.Fatalf("scanner() unexpected error: %v", err)
+			}
+
+			output, err := scan(t.Context())
+			if err != nil {
+				t.Fatalf("scan() unexpected error: %v", err)
+			}
+
+			var value any
+
+			err = json.Unmarshal(output, &value)
+			if err != nil {
+				t.Fatalf("scan() returned invalid JSON: %v", err)
+			}
+
+			err = validateSchema(schema, value, "$")
+			if err != nil {
+				t.Errorf("%s output does not match its schema: %v\n%s", format, err, output)
+			}
+		})
+	}
+
+	for _, format := range []string{formatMarkdown, formatCount, formatGEXF, formatPublic, formatProto, formatD2, "yaml"} {
+		_, err := outputSchema(format)
+		if !errors.Is(err, errNoSchema) {
+			t.Errorf("outputSchema(%s) error = %v, want %v", format, err, errNoSchema)
+		}
+	}
+}
+
+func Test_outputSchema_roleReport(t *testing.T) {
+	t.Parallel()
+
+	labels, err := parseJSONLabels("labels.json", []byte(`[
+		{"pattern": "arn:aws:iam::0123456789:role/vendor", "key": "team", "value": "platform"},
+		{"pattern": "210987654321", "key": "owner", "value": "vendor"}
+	]`))
+	if err != nil {
+		t.Fatalf("parseJSONLabels() unexpected error: %v", err)
+	}
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					Path:                     aws.String("/"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+					Tags:                     []types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}},
+					PermissionsBoundary: &types.AttachedPermissionsBoundary{
+						PermissionsBoundaryArn: aws.String("arn:aws:iam::0123456789:policy/boundary"),
+					},
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					Path:                     aws.String("/aws-service-role/ecs.amazonaws.com/"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+		},
+		rawPolicy:    true,
+		withBoundary: true,
+		labels:       labels,
+	}
+
+	data, err := outputSchema(schemaRoleReport)
+	if err != nil {
+		t.Fatalf("outputSchema() unexpected error: %v", err)
+	}
+
+	var schema map[string]any
+
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatalf("outputSchema() returned invalid JSON: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"-include-raw-policy"},
+		{"-with-boundary"},
+		{"-only-unbounded"},
+		{"-labels-file", "labels.json"},
+	} {
+		t.Run(args[0], func(t *testing.T) {
+			t.Parallel()
+
+			flagSet := flag.NewFlagSet("veil", flag.ContinueOnError)
+			flags := registerFlags(flagSet)
+
+			err := flagSet.Parse(args)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			if got := flags.schema(); got != schemaRoleReport {
+				t.Errorf("schema() = %s, want %s", got, schemaRoleReport)
+			}
+
+			scan, err := flags.scan(app)
+			if err != nil {
+				t.Fatalf("scan() unexpected error: %v", err)
+			}
+
+			output, err := scan(t.Context())
+			if err != nil {
+				t.Fatalf("scan() unexpected error: %v", err)
+			}
+
+			var value any
+
+			err = json.Unmarshal(output, &value)
+			if err != nil {
+				t.Fatalf("scan() returned invalid JSON: %v", err)
+			}
+
+			err = validateSchema(schema, value, "$")
+			if err != nil {
+				t.Errorf("role report does not match its schema: %v\n%s", err, output)
+			}
+		})
+	}
+}
+
+func Test_validateSchema(t *testing.T) {
+	t.Parallel()
+
+	data, err := outputSchema(formatJSONCount)
+	if err != nil {
+		t.Fatalf("outputSchema() unexpected error: %v", err)
+	}
+
+	var schema map[string]any
+
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatalf("outputSchema() returned invalid JSON: %v", err)
+	}
+
+	for _, document := range []string{
+		`{"roles": 1}`,
+		`{"roles": "1", "principals": 2}`,
+		`{"roles": 1, "principals": 2, "extra": true}`,
+		`{"roles": 1, "principals": 2, "boundaries": {"bounded": 1}}`,
+	} {
+		var value any
+
+		err = json.Unmarshal([]byte(document), &value)
+		if err != nil {
+			t.Fatalf("invalid test document %s: %v", document, err)
+		}
+
+		if validateSchema(schema, value, "$") == nil {
+			t.Errorf("validateSchema() accepted %s", document)
+		}
+	}
+}
+
+func Test_outputSchema_noOrphans(t *testing.T) {
+	t.Parallel()
+
+	dir := "schemas/v" + strconv.Itoa(CurrentSchemaVersion)
+
+	entries, err := fs.ReadDir(schemas, dir)
+	if err != nil {
+		t.Fatalf("ReadDir() unexpected error: %v", err)
+	}
+
+	for _, entry := range entries {
+		format, found := strings.CutSuffix(entry.Name(), ".schema.json")
+		if !found || !slices.Contains(schemaFormats, format) && format != schemaRoleReport && format != schemaMetadata {
+			t.Errorf("%s/%s does not describe a JSON output format", dir, entry.Name())
+		}
+	}
+
+	if len(entries) != len(schemaFormats)+2 {
+		t.Errorf("%s holds %d schemas, want %d", dir, len(entries), len(schemaFormats)+2)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultListenAddr     = ":8080"
+	defaultRefresh        = time.Hour
+	defaultCacheTTL       = time.Minute
+	serverHeaderTimeout   = 10 * time.Second
+	serverShutdownTimeout = 10 * time.Second
+)
+
+var (
+	errInvalidRefresh  = errors.New("refresh interval must be positive")
+	errInvalidCacheTTL = errors.New("cache TTL must not be negative")
+	errNoScanResult    = errors.New("no scan has completed yet")
+)
+
+// ScanResult is a point-in-time view of the trust graph and its findings, as served by the serve subcommand.
+type ScanResult struct {
+	Roles       map[string][]string `json:"roles"`
+	Principals  map[string][]string `json:"principals"`
+	Findings    []Finding           `json:"findings"`
+	ScannedAt   time.Time           `json:"scannedAt"`
+	Fingerprint string              `json:"fingerprint"`
+}
+
+// scanResult scans the account once, collecting everything the server exposes.
+func (a *App) scanResult(ctx context.Context, now time.Time) (*ScanResult, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	env, err := a.checkEnv(ctx, trusts)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := a.rolePrincipals(trusts)
+	output := &ScanResult{
+		Roles:       roles,
+		Principals:  mapFlip(roles),
+		Findings:    runChecks(env, trusts),
+		ScannedAt:   now.UTC(),
+		Fingerprint: "",
+	}
+
+	fingerprint, err := json.Marshal(struct {
+		Roles    map[string][]string `json:"roles"`
+		Findings []Finding           `json:"findings"`
+	}{
+		Roles:    output.Roles,
+		Findings: output.Findings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint scan: %w", err)
+	}
+
+	output.Fingerprint = sha256Hex(fingerprint)
+
+	return output, nil
+}
+
+// PrincipalPage is a chunk of the principal to roles mapping of a scan, for serving it page by page.
+type PrincipalPage struct {
+	Principals map[string][]string `json:"principals"`
+	Offset     int                 `json:"offset"`
+	Next       int                 `json:"next,omitempty"`
+	Total      int                 `json:"total"`
+}
+
+// PrincipalsPage returns up to limit principals of the scan, and the roles trusting them, starting at offset in the
+// sorted order of the principals. Next is the offset of the following page, or zero on the last page. A non-positive
+// limit returns every principal from offset on.
+func (r *ScanResult) PrincipalsPage(offset int, limit int) PrincipalPage {
+	principals := slices.Sorted(maps.Keys(r.Principals))
+	offset = min(max(offset, 0), len(principals))
+
+	end := len(principals)
+	if limit > 0 {
+		end = min(offset+limit, end)
+	}
+
+	output := PrincipalPage{
+		Principals: make(map[string][]string, end-offset),
+		Offset:     offset,
+		Next:       0,
+		Total:      len(principals),
+	}
+
+	for _, principal := range principals[offset:end] {
+		output.Principals[principal] = r.Principals[principal]
+	}
+
+	if end < len(principals) {
+		output.Next = end
+	}
+
+	return output
+}
+
+// server serves the latest scan result over HTTP. The result is swapped atomically on refresh, so handlers always
+// see a complete scan.
+type server struct {
+	app    *App
+	result atomic.Pointer[ScanResult]
+	// cacheTTL is how long a scan is served by /scan before a request triggers a new one.
+	cacheTTL time.Duration
+	// scanMutex serialises the scans triggered by /scan, so concurrent requests share a single scan.
+	scanMutex sync.Mutex
+}
+
+// refresh replaces the served result with a fresh scan, keeping the previous one if the scan fails.
+func (s *server) refresh(ctx context.Context) {
+	result, err := s.app.scanResult(ctx, time.Now())
+	if err != nil {
+		s.app.logger.Error("failed to refresh scan results", slog.String("error", err.Error()))
+
+		return
+	}
+
+	s.result.Store(result)
+	s.app.logger.Info(
+		"refreshed scan results",
+		slog.Int("roles", len(result.Roles)),
+		slog.Int("findings", len(result.Findings)),
+		slog.String("fingerprint", result.Fingerprint),
+	)
+}
+
+// scan returns the served result if it is younger than the cache TTL, or otherwise scans the account and serves the
+// fresh result.
+func (s *server) scan(ctx context.Context, now time.Time) (*ScanResult, error) {
+	s.scanMutex.Lock()
+	defer s.scanMutex.Unlock()
+
+	result := s.result.Load()
+	if result != nil && now.Sub(result.ScannedAt) < s.cacheTTL {
+		return result, nil
+	}
+
+	result, err := s.app.scanResult(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.result.Store(result)
+
+	return result, nil
+}
+
+// refreshLoop refreshes the result immediately and then on every interval, until ctx is done.
+func (s *server) refreshLoop(ctx context.Context, interval time.Duration) {
+	s.refresh(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /scan", s.handleScan)
+	mux.HandleFunc("GET /principals", s.withResult(func(result *ScanResult, _ *http.Request) (any, bool) {
+		return result.Principals, true
+	}))
+	mux.HandleFunc("GET /principals/{id}/roles", s.withResult(func(result *ScanResult, r *http.Request) (any, bool) {
+		roles, ok := result.Principals[r.PathValue("id")]
+
+		return roles, ok
+	}))
+	mux.HandleFunc("GET /roles/{arn}/principals", s.withResult(func(result *ScanResult, r *http.Request) (any, bool) {
+		principals, ok := result.Roles[r.PathValue("arn")]
+
+		return principals, ok
+	}))
+	mux.HandleFunc("GET /findings", s.withResult(func(result *ScanResult, _ *http.Request) (any, bool) {
+		return result.Findings, true
+	}))
+
+	return mux
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	result := s.result.Load()
+	if result == nil {
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"status":      "ok",
+		"scannedAt":   result.ScannedAt,
+		"fingerprint": result.Fingerprint,
+	})
+}
+
+// handleScan answers with the whole scan result, scanning the account first unless the served result is younger than
+// the cache TTL.
+func (s *server) handleScan(w http.ResponseWriter, r *http.Request) {
+	result, err := s.scan(r.Context(), time.Now())
+	if err != nil {
+		s.app.logger.Error("failed to scan", slog.String("error", err.Error()))
+		s.writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+
+		return
+	}
+
+	w.Header().Set("ETag", `"`+result.Fingerprint+`"`)
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// withResult wraps a view of the current scan result into a handler, answering with 404 when the view finds nothing
+// and with 304 when the client already holds the current scan.
+func (s *server) withResult(view func(*ScanResult, *http.Request) (any, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := s.result.Load()
+		if result == nil {
+			s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": errNoScanResult.Error()})
+
+			return
+		}
+
+		etag := `"` + result.Fingerprint + `"`
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		output, ok := view(result, r)
+		if !ok {
+			s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+
+			return
+		}
+
+		s.writeJSON(w, http.StatusOK, output)
+	}
+}
+
+// writeJSON writes the given value as an indented JSON response.
+func (s *server) writeJSON(w http.ResponseWriter, status int, value any) {
+	marshal, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(marshal)
+	if err != nil {
+		s.app.logger.Debug("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+// runServeCommand parses the serve subcommand flags, then scans on an interval and serves the latest result until
+// interrupted.
+func runServeCommand(ctx context.Context, app *App, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := flags.String("listen", defaultListenAddr, "address to serve scan results on")
+	refresh := flags.Duration("refresh", defaultRefresh, "interval between scans")
+	cacheTTL := flags.Duration("cache-ttl", defaultCacheTTL, "how long /scan serves a scan before scanning again")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("failed to parse serve flags: %w", err)
+	}
+
+	if *refresh <= 0 {
+		return fmt.Errorf("%w: %s", errInvalidRefresh, *refresh)
+	}
+
+	if *cacheTTL < 0 {
+		return fmt.Errorf("%w: %s", errInvalidCacheTTL, *cacheTTL)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &server{app: app, result: atomic.Pointer[ScanResult]{}, cacheTTL: *cacheTTL, scanMutex: sync.Mutex{}}
+	go srv.refreshLoop(ctx, *refresh)
+
+	httpServer := &http.Server{ //nolint:exhaustruct
+		Addr:              *listen,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: serverHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+
+		errShutdown := httpServer.Shutdown(shutdownCtx) //nolint:contextcheck
+		if errShutdown != nil {
+			app.logger.Error("failed to shut down server", slog.String("error", errShutdown.Error()))
+		}
+	}()
+
+	app.logger.Info("serving scan results", slog.String("listen", *listen), slog.Duration("refresh", *refresh))
+
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func newTestServer(t *testing.T) *server {
+	t.Helper()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+		},
+	}
+
+	return &server{app: app}
+}
+
+func Test_server_handler(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	handler := srv.handler()
+
+	// Before the first scan completes, every endpoint reports it is not ready yet.
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /healthz before scan status = %d, want %d", recorder.Code, http.StatusServiceUnavailable)
+	}
+
+	srv.refresh(t.Context())
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		want   any
+	}{
+		{
+			name:   "healthz",
+			path:   "/healthz",
+			status: http.StatusOK,
+			want:   nil,
+		},
+		{
+			name:   "principals",
+			path:   "/principals",
+			status: http.StatusOK,
+			want: map[string]any{
+				"arn:aws:iam::210987654321:root": []any{"arn:aws:iam::0123456789:role/vendor"},
+				"ecs.amazonaws.com":              []any{"arn:aws:iam::0123456789:role/ecs"},
+			},
+		},
+		{
+			name:   "principal roles",
+			path:   "/principals/" + url.PathEscape("arn:aws:iam::210987654321:root") + "/roles",
+			status: http.StatusOK,
+			want:   []any{"arn:aws:iam::0123456789:role/vendor"},
+		},
+		{
+			name:   "role principals",
+			path:   "/roles/" + url.PathEscape("arn:aws:iam::0123456789:role/vendor") + "/principals",
+			status: http.StatusOK,
+			want:   []any{"arn:aws:iam::210987654321:root"},
+		},
+		{
+			name:   "unknown role",
+			path:   "/roles/" + url.PathEscape("arn:aws:iam::0123456789:role/missing") + "/principals",
+			status: http.StatusNotFound,
+			want:   map[string]any{"error": "not found"},
+		},
+		{
+			name:   "findings",
+			path:   "/findings",
+			status: http.StatusOK,
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if recorder.Code != tt.status {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, recorder.Code, tt.status)
+			}
+
+			if tt.want == nil {
+				return
+			}
+
+			var got any
+
+			err := json.Unmarshal(recorder.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("GET %s returned invalid JSON: %v", tt.path, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GET %s got = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_server_etag(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	srv.refresh(t.Context())
+	handler := srv.handler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/findings", nil))
+
+	etag := recorder.Header().Get("ETag")
+	if etag != `"`+srv.result.Load().Fingerprint+`"` {
+		t.Fatalf("GET /findings ETag = %q, want the scan fingerprint", etag)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/findings", nil)
+	request.Header.Set("If-None-Match", etag)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("GET /findings with matching ETag status = %d, want %d", recorder.Code, http.StatusNotModified)
+	}
+
+	first, err := srv.app.scanResult(t.Context(), time.Now())
+	if err != nil {
+		t.Fatalf("scanResult() unexpected error: %v", err)
+	}
+
+	second, err := srv.app.scanResult(t.Context(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("scanResult() unexpected error: %v", err)
+	}
+
+	if first.Fingerprint != second.Fingerprint {
+		t.Errorf("scanResult() fingerprint changed between identical scans")
+	}
+}
+
+func Test_server_scan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cacheTTL  time.Duration
+		wantScans int
+	}{
+		{name: "cached", cacheTTL: time.Hour, wantScans: 1},
+		{name: "no cache", cacheTTL: 0, wantScans: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newTestServer(t)
+			srv.cacheTTL = tt.cacheTTL
+			handler := srv.handler()
+
+			for range 3 {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/scan", nil))
+
+				if recorder.Code != http.StatusOK {
+					t.Fatalf("GET /scan status = %d, want %d", recorder.Code, http.StatusOK)
+				}
+
+				var got ScanResult
+
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				if err != nil {
+					t.Fatalf("GET /scan returned invalid JSON: %v", err)
+				}
+
+				want := map[string][]string{
+					"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+					"arn:aws:iam::0123456789:role/ecs":    {"ecs.amazonaws.com"},
+				}
+				if !reflect.DeepEqual(got.Roles, want) {
+					t.Errorf("GET /scan roles = %v, want %v", got.Roles, want)
+				}
+
+				if etag := recorder.Header().Get("ETag"); etag != `"`+got.Fingerprint+`"` {
+					t.Errorf("GET /scan ETag = %q, want the scan fingerprint %q", etag, got.Fingerprint)
+				}
+			}
+
+			fake, _ := srv.app.client.(*veiltest.FakeIAM)
+			if got := len(fake.Calls(veiltest.OperationListRoles)); got != tt.wantScans {
+				t.Errorf("GET /scan listed roles %d times, want %d", got, tt.wantScans)
+			}
+		})
+	}
+}
+
+func Test_server_concurrentRefresh(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t)
+	srv.refresh(t.Context())
+	handler := srv.handler()
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(2) //nolint:mnd
+
+		go func() {
+			defer wg.Done()
+
+			srv.refresh(t.Context())
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/principals", nil))
+
+			if recorder.Code != http.StatusOK {
+				t.Errorf("GET /principals during refresh status = %d, want %d", recorder.Code, http.StatusOK)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestScanResult_PrincipalsPage(t *testing.T) {
+	t.Parallel()
+
+	result := &ScanResult{
+		Roles: nil,
+		Principals: map[string][]string{
+			"arn:aws:iam::111122223333:root": {"arn:aws:iam::0123456789:role/vendor"},
+			"ecs-tasks.amazonaws.com":        {"arn:aws:iam::0123456789:role/ecs"},
+			"arn:aws:iam::444455556666:root": {"arn:aws:iam::0123456789:role/vendor"},
+			"ec2.amazonaws.com":              {"arn:aws:iam::0123456789:role/ec2"},
+			"lambda.amazonaws.com":           {"arn:aws:iam::0123456789:role/lambda"},
+		},
+		Findings:    nil,
+		ScannedAt:   time.Time{},
+		Fingerprint: "",
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		limit  int
+		want   []string
+		next   int
+	}{
+		{
+			name:   "first page",
+			offset: 0,
+			limit:  2,
+			want:   []string{"arn:aws:iam::111122223333:root", "arn:aws:iam::444455556666:root"},
+			next:   2,
+		},
+		{name: "last page", offset: 4, limit: 2, want: []string{"lambda.amazonaws.com"}, next: 0},
+		{name: "past the end", offset: 7, limit: 2, want: nil, next: 0},
+		{name: "negative offset", offset: -1, limit: 1, want: []string{"arn:aws:iam::111122223333:root"}, next: 1},
+		{
+			name:   "no limit",
+			offset: 2,
+			limit:  0,
+			want:   []string{"ec2.amazonaws.com", "ecs-tasks.amazonaws.com", "lambda.amazonaws.com"},
+			next:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := result.PrincipalsPage(tt.offset, tt.limit)
+
+			principals := slices.Sorted(maps.Keys(got.Principals))
+			if !reflect.DeepEqual(principals, tt.want) || got.Next != tt.next || got.Total != 5 {
+				t.Errorf(
+					"PrincipalsPage() = %v next %d total %d, want %v next %d total 5",
+					principals, got.Next, got.Total, tt.want, tt.next,
+				)
+			}
+		})
+	}
+
+	t.Run("pages add up to the full result", func(t *testing.T) {
+		t.Parallel()
+
+		got := make(map[string][]string)
+		order := make([]string, 0)
+
+		for offset := 0; ; {
+			page := result.PrincipalsPage(offset, 2)
+			for _, principal := range slices.Sorted(maps.Keys(page.Principals)) {
+				got[principal] = page.Principals[principal]
+				order = append(order, principal)
+			}
+
+			if page.Next == 0 {
+				break
+			}
+
+			offset = page.Next
+		}
+
+		if !reflect.DeepEqual(got, result.Principals) || !slices.IsSorted(order) || len(order) != len(got) {
+			t.Errorf("PrincipalsPage() pages concatenate to %v in order %v, want %v", got, order, result.Principals)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// sessionRole returns the ARN of the role an assumed-role session ARN was assumed from, e.g.
+// arn:aws:iam::123456789012:role/deployer for arn:aws:sts::123456789012:assumed-role/deployer/session, and false for
+// any other principal. Session ARNs omit the path of the role, so the returned ARN has none.
+func sessionRole(principal string) (string, bool) {
+	if !strings.Contains(principal, ":assumed-role/") {
+		return "", false
+	}
+
+	parsed, err := arn.Parse(principal)
+	if err != nil || parsed.Service != "sts" {
+		return "", false
+	}
+
+	session, ok := strings.CutPrefix(parsed.Resource, "assumed-role/")
+	if !ok {
+		return "", false
+	}
+
+	roleName, _, _ := strings.Cut(session, "/")
+	if roleName == "" {
+		return "", false
+	}
+
+	return "arn:" + parsed.Partition + ":iam::" + parsed.AccountID + ":role/" + roleName, true
+}
+
+// sessionIndex resolves assumed-role session ARNs to the ARNs of the scanned roles they were assumed from, which
+// carry the role path the session ARNs leave out.
+type sessionIndex map[string]string
+
+// newSessionIndex indexes the given roles by their ARN without path.
+func newSessionIndex(trusts map[string]roleTrust) sessionIndex {
+	output := make(sessionIndex, len(trusts))
+
+	for role := range trusts {
+		name := roleNameFromARN(role)
+		if prefix, _, ok := strings.Cut(role, ":role/"); ok && name != "" {
+			output[prefix+":role/"+name] = role
+		}
+	}
+
+	return output
+}
+
+// resolve maps the assumed-role session ARNs among the principals to the ARNs of their roles, keeping the order of
+// the principals and dropping duplicates. It also returns the session ARNs each role was derived from, or nil if there
+// were none.
+func (s sessionIndex) resolve(principals []string) ([]string, map[string][]string) {
+	var derivedFrom map[string][]string
+
+	output := make([]string, 0, len(principals))
+
+	for _, principal := range principals {
+		role, ok := sessionRole(principal)
+		if !ok {
+			if !slices.Contains(output, principal) {
+				output = append(output, principal)
+			}
+
+			continue
+		}
+
+		if scanned, found := s[role]; found {
+			role = scanned
+		}
+
+		if derivedFrom == nil {
+			derivedFrom = make(map[string][]string)
+		}
+
+		derivedFrom[role] = append(derivedFrom[role], principal)
+
+		if !slices.Contains(output, role) {
+			output = append(output, role)
+		}
+	}
+
+	return output, derivedFrom
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_sessionRole(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+		wantOk    bool
+	}{
+		{
+			name:      "session",
+			principal: "arn:aws:sts::123456789012:assumed-role/deployer/session",
+			want:      "arn:aws:iam::123456789012:role/deployer",
+			wantOk:    true,
+		},
+		{
+			name:      "without session name",
+			principal: "arn:aws:sts::123456789012:assumed-role/deployer",
+			want:      "arn:aws:iam::123456789012:role/deployer",
+			wantOk:    true,
+		},
+		{
+			name:      "GovCloud session",
+			principal: "arn:aws-us-gov:sts::123456789012:assumed-role/deployer/session",
+			want:      "arn:aws-us-gov:iam::123456789012:role/deployer",
+			wantOk:    true,
+		},
+		{
+			name:      "missing role name",
+			principal: "arn:aws:sts::123456789012:assumed-role/",
+		},
+		{
+			name:      "iam service",
+			principal: "arn:aws:iam::123456789012:assumed-role/deployer/session",
+		},
+		{
+			name:      "role",
+			principal: "arn:aws:iam::123456789012:role/deployer",
+		},
+		{
+			name:      "service principal",
+			principal: "ecs.amazonaws.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := sessionRole(tt.principal)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("sessionRole() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_sessionIndex_resolve(t *testing.T) {
+	t.Parallel()
+
+	sessions := newSessionIndex(map[string]roleTrust{
+		"arn:aws:iam::123456789012:role/team/deployer": {},
+		"arn:aws:iam::123456789012:role/admin":         {},
+	})
+
+	tests := []struct {
+		name            string
+		principals      []string
+		want            []string
+		wantDerivedFrom map[string][]string
+	}{
+		{
+			name:            "no sessions",
+			principals:      []string{"arn:aws:iam::210987654321:root", "ecs.amazonaws.com"},
+			want:            []string{"arn:aws:iam::210987654321:root", "ecs.amazonaws.com"},
+			wantDerivedFrom: nil,
+		},
+		{
+			name:       "session of a role with a path",
+			principals: []string{"arn:aws:sts::123456789012:assumed-role/deployer/ci"},
+			want:       []string{"arn:aws:iam::123456789012:role/team/deployer"},
+			wantDerivedFrom: map[string][]string{
+				"arn:aws:iam::123456789012:role/team/deployer": {"arn:aws:sts::123456789012:assumed-role/deployer/ci"},
+			},
+		},
+		{
+			name:       "session of a role without a path",
+			principals: []string{"arn:aws:sts::123456789012:assumed-role/admin/alice"},
+			want:       []string{"arn:aws:iam::123456789012:role/admin"},
+			wantDerivedFrom: map[string][]string{
+				"arn:aws:iam::123456789012:role/admin": {"arn:aws:sts::123456789012:assumed-role/admin/alice"},
+			},
+		},
+		{
+			name:       "session of a role that was not scanned",
+			principals: []string{"arn:aws:sts::210987654321:assumed-role/vendor/session"},
+			want:       []string{"arn:aws:iam::210987654321:role/vendor"},
+			wantDerivedFrom: map[string][]string{
+				"arn:aws:iam::210987654321:role/vendor": {"arn:aws:sts::210987654321:assumed-role/vendor/session"},
+			},
+		},
+		{
+			name: "sessions and role collapse into one principal",
+			principals: []string{
+				"arn:aws:iam::123456789012:role/admin",
+				"arn:aws:sts::123456789012:assumed-role/admin/alice",
+				"arn:aws:sts::123456789012:assumed-role/admin/bob",
+				"ecs.amazonaws.com",
+			},
+			want: []string{"arn:aws:iam::123456789012:role/admin", "ecs.amazonaws.com"},
+			wantDerivedFrom: map[string][]string{
+				"arn:aws:iam::123456789012:role/admin": {
+					"arn:aws:sts::123456789012:assumed-role/admin/alice",
+					"arn:aws:sts::123456789012:assumed-role/admin/bob",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, derivedFrom := sessions.resolve(tt.principals)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() got = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(derivedFrom, tt.wantDerivedFrom) {
+				t.Errorf("resolve() derivedFrom = %v, want %v", derivedFrom, tt.wantDerivedFrom)
+			}
+		})
+	}
+}
+
+func TestApp_rolePrincipals_sessions(t *testing.T) {
+	t.Parallel()
+
+	const (
+		deployer = "arn:aws:iam::123456789012:role/team/deployer"
+		release  = "arn:aws:iam::123456789012:role/release"
+	)
+
+	trusts := map[string]roleTrust{
+		deployer: mustDecodeTrust(t, deployer, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",`+
+			`"Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"}]}`),
+		release: mustDecodeTrust(t, release, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",`+
+			`"Principal":{"AWS":"arn:aws:sts::123456789012:assumed-role/deployer/ci"},"Action":"sts:AssumeRole"}]}`),
+	}
+
+	app := &App{}
+
+	roles := app.rolePrincipals(trusts)
+	if want := []string{deployer}; !reflect.DeepEqual(roles[release], want) {
+		t.Errorf("rolePrincipals() = %v, want %v", roles[release], want)
+	}
+
+	if got, want := mapFlip(roles)[deployer], []string{release}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mapFlip() = %v, want %v", got, want)
+	}
+
+	reports, err := app.buildRoleReports(trusts)
+	if err != nil {
+		t.Fatalf("buildRoleReports() error = %v", err)
+	}
+
+	if want := map[string][]string{
+		deployer: {"arn:aws:sts::123456789012:assumed-role/deployer/ci"},
+	}; !reflect.DeepEqual(reports[release].DerivedFrom, want) {
+		t.Errorf("buildRoleReports() DerivedFrom = %v, want %v", reports[release].DerivedFrom, want)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// shellQuote quotes the value for POSIX shells, in single quotes, with embedded single quotes closed, escaped and
+// reopened.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// writeShellList writes the values as VEIL_<NAME>_1, VEIL_<NAME>_2, ... export lines, preceded by their count in
+// VEIL_<NAME>_COUNT.
+func writeShellList(w io.Writer, name string, values []string) error {
+	_, err := fmt.Fprintf(w, "export VEIL_%s_COUNT=%d\n", name, len(values))
+	if err != nil {
+		return fmt.Errorf("failed to write shell exports: %w", err)
+	}
+
+	for index, value := range values {
+		_, err = fmt.Fprintf(w, "export VEIL_%s_%d=%s\n", name, index+1, shellQuote(value))
+		if err != nil {
+			return fmt.Errorf("failed to write shell exports: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// WriteShellExports writes the distinct roles and principals of the given role to principals mapping, sorted, as
+// shell export lines to be sourced by scripts, e.g. VEIL_ROLE_COUNT=2 followed by VEIL_ROLE_1 and VEIL_ROLE_2.
+func WriteShellExports(w io.Writer, data map[string][]string) error {
+	err := writeShellList(w, "ROLE", slices.Sorted(maps.Keys(data)))
+	if err != nil {
+		return err
+	}
+
+	return writeShellList(w, "PRINCIPAL", slices.Sorted(maps.Keys(mapFlip(data))))
+}
+
+func (a *App) runShell(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	err = WriteShellExports(&buf, a.rolePrincipals(trusts))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
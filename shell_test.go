@@ -0,0 +1,113 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+// sourceShell sources the script with sh and returns the values of the given variables, one per line.
+func sourceShell(t *testing.T, script []byte, names ...string) string {
+	t.Helper()
+
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+
+	path := filepath.Join(t.TempDir(), "veil.sh")
+
+	err = os.WriteFile(path, script, 0o600)
+	if err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	output, err := exec.CommandContext(t.Context(), shell, "-n", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sh -n rejected the script: %v: %s", err, output)
+	}
+
+	command := `. "$1"`
+	for _, name := range names {
+		command += `; printf '%s\n' "$` + name + `"`
+	}
+
+	output, err = exec.CommandContext(t.Context(), shell, "-c", command, "sh", path).Output()
+	if err != nil {
+		t.Fatalf("failed to source the script: %v", err)
+	}
+
+	return string(output)
+}
+
+func TestWriteShellExports(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := WriteShellExports(&buf, map[string][]string{
+		"arn:aws:iam::0123456789:role/it's-$HOME-`id`": {"ec2.amazonaws.com"},
+		"arn:aws:iam::0123456789:role/ecs":             {"ecs-tasks.amazonaws.com", "ec2.amazonaws.com"},
+	})
+	if err != nil {
+		t.Fatalf("WriteShellExports() unexpected error: %v", err)
+	}
+
+	want := "export VEIL_ROLE_COUNT=2\n" +
+		"export VEIL_ROLE_1='arn:aws:iam::0123456789:role/ecs'\n" +
+		`export VEIL_ROLE_2='arn:aws:iam::0123456789:role/it'\''s-$HOME-` + "`id`'\n" +
+		"export VEIL_PRINCIPAL_COUNT=2\n" +
+		"export VEIL_PRINCIPAL_1='ec2.amazonaws.com'\n" +
+		"export VEIL_PRINCIPAL_2='ecs-tasks.amazonaws.com'\n"
+	if buf.String() != want {
+		t.Errorf("WriteShellExports() = %s, want %s", buf.String(), want)
+	}
+
+	got := sourceShell(t, buf.Bytes(), "VEIL_ROLE_2")
+	if got != "arn:aws:iam::0123456789:role/it's-$HOME-`id`\n" {
+		t.Errorf("sourcing WriteShellExports() output set VEIL_ROLE_2 to %q", got)
+	}
+}
+
+func TestApp_runShell(t *testing.T) {
+	t.Parallel()
+
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+		},
+	}
+
+	script, err := app.runShell(t.Context())
+	if err != nil {
+		t.Fatalf("runShell() unexpected error: %v", err)
+	}
+
+	got := strings.Fields(sourceShell(t, script, "VEIL_ROLE_COUNT", "VEIL_ROLE_1", "VEIL_ROLE_2"))
+
+	want := []string{"2", "arn:aws:iam::0123456789:role/ecs", "arn:aws:iam::0123456789:role/vendor"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("sourcing runShell() output set %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+const (
+	decisionAllowed = "allowed"
+	decisionDenied  = "denied"
+)
+
+var errNoSimulateActions = errors.New("no actions to simulate")
+
+// SimulationResult describes whether a role, once assumed, is allowed to perform an action.
+type SimulationResult struct {
+	Action   string `json:"action"`
+	Decision string `json:"decision"`
+}
+
+// SimulateRoleCapabilities evaluates the policies attached to the given role against each of the action names,
+// reporting what a principal assuming the role could do. Explicit and implicit denies are both reported as denied.
+func (a *App) SimulateRoleCapabilities(
+	ctx context.Context,
+	roleARN string,
+	actionNames []string,
+) ([]SimulationResult, error) {
+	if len(actionNames) == 0 {
+		return nil, errNoSimulateActions
+	}
+
+	output := make([]SimulationResult, 0, len(actionNames))
+
+	paginator := iam.NewSimulatePrincipalPolicyPaginator(a.iamClient(), &iam.SimulatePrincipalPolicyInput{
+		ActionNames:                        actionNames,
+		PolicySourceArn:                    aws.String(roleARN),
+		CallerArn:                          nil,
+		ContextEntries:                     nil,
+		Marker:                             nil,
+		MaxItems:                           nil,
+		PermissionsBoundaryPolicyInputList: nil,
+		PolicyInputList:                    nil,
+		ResourceArns:                       nil,
+		ResourceHandlingOption:             nil,
+		ResourceOwner:                      nil,
+		ResourcePolicy:                     nil,
+	})
+	for paginator.HasMorePages() {
+		page, err := retryWithBackoff(
+			ctx,
+			a.logger,
+			defaultRetryMaxAttempts,
+			defaultRetryInitialDelay,
+			func() (*iam.SimulatePrincipalPolicyOutput, error) {
+				return paginator.NextPage(ctx)
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate policies of %s: %w", roleARN, err)
+		}
+
+		for _, result := range page.EvaluationResults {
+			decision := decisionDenied
+			if result.EvalDecision == types.PolicyEvaluationDecisionTypeAllowed {
+				decision = decisionAllowed
+			}
+
+			output = append(output, SimulationResult{
+				Action:   aws.ToString(result.EvalActionName),
+				Decision: decision,
+			})
+		}
+	}
+
+	return output, nil
+}
+
+func (a *App) runSimulation(ctx context.Context) ([]byte, error) {
+	roles, err := a.GetRolePaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := make(map[string][]SimulationResult, len(roles))
+
+	for arn := range roles {
+		results, err := a.SimulateRoleCapabilities(ctx, arn, a.simulateActions)
+		if err != nil {
+			return nil, err
+		}
+
+		output[arn] = results
+	}
+
+	a.logger.Debug(
+		"simulated IAM roles capabilities",
+		slog.Int("roles", len(output)),
+		slog.Int("actions", len(a.simulateActions)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func TestApp_SimulateRoleCapabilities(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		client  ServiceIAM
+		actions []string
+		want    []SimulationResult
+		wantErr bool
+	}{
+		{
+			name: "allowed and denied",
+			client: &veiltest.FakeIAM{
+				Decisions: map[string]types.PolicyEvaluationDecisionType{
+					"sts:AssumeRole":  types.PolicyEvaluationDecisionTypeAllowed,
+					"s3:DeleteObject": types.PolicyEvaluationDecisionTypeExplicitDeny,
+				},
+			},
+			actions: []string{"sts:AssumeRole", "s3:GetObject", "s3:DeleteObject"},
+			want: []SimulationResult{
+				{Action: "sts:AssumeRole", Decision: decisionAllowed},
+				{Action: "s3:GetObject", Decision: decisionDenied},
+				{Action: "s3:DeleteObject", Decision: decisionDenied},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "no actions",
+			client:  &veiltest.FakeIAM{},
+			actions: nil,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "failed to simulate",
+			client: &veiltest.FakeIAM{
+				Errors: map[string]error{veiltest.OperationSimulatePrincipalPolicy: errors.New("access denied")},
+			},
+			actions: []string{"sts:AssumeRole"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &App{
+				logger: slog.New(slog.DiscardHandler),
+				client: tt.client,
+			}
+
+			got, err := a.SimulateRoleCapabilities(t.Context(), "arn:aws:iam::0123456789:role/test", tt.actions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SimulateRoleCapabilities() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SimulateRoleCapabilities() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
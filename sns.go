@@ -0,0 +1,228 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+const (
+	snsSeverityAttribute = "severity"
+	snsAccountAttribute  = "account"
+	severityNone         = "none"
+)
+
+var errInvalidSNSTopic = errors.New("expected an SNS topic ARN, e.g. arn:aws:sns:eu-west-1:123456789012:veil")
+
+// ServiceSNS publishes a message with string attributes to an SNS topic.
+type ServiceSNS interface {
+	Publish(ctx context.Context, topicARN string, message string, attributes map[string]string) error
+}
+
+// scanNotification is the completion message published to SNS: the scanned account, the number of roles scanned, the
+// findings by severity when the scan ran the checks, and where the full results were uploaded.
+type scanNotification struct {
+	Account  string         `json:"account"`
+	Roles    int            `json:"roles"`
+	Findings map[string]int `json:"findings"`
+	Results  string         `json:"results,omitempty"`
+}
+
+// parseSNSTopic validates an SNS topic ARN, returning it parsed.
+func parseSNSTopic(topic string) (arn.ARN, error) {
+	parsed, err := arn.Parse(topic)
+	if err != nil || parsed.Service != "sns" || parsed.Region == "" || parsed.Resource == "" {
+		return arn.ARN{}, fmt.Errorf("%w: %q", errInvalidSNSTopic, topic)
+	}
+
+	return parsed, nil
+}
+
+// highestSeverity returns the most severe severity with findings, or none.
+func highestSeverity(findings map[string]int) string {
+	highest := severityNone
+
+	for severity, count := range findings {
+		if count > 0 && severityRank[severity] > severityRank[highest] {
+			highest = severity
+		}
+	}
+
+	return highest
+}
+
+// recordRoles notes the account and number of roles of the scan for the completion message, the roles themselves
+// for the history store and the labels report, and the ARNs of the roles skipped because they failed to decode.
+func (a *App) recordRoles(trusts map[string]roleTrust, skipped []string) {
+	a.notificationMutex.Lock()
+	defer a.notificationMutex.Unlock()
+
+	a.notification.Roles = len(trusts)
+	a.scannedTrusts = trusts
+	a.skippedRoles = skipped
+
+	first := ""
+	for role := range trusts {
+		if first == "" || role < first {
+			first = role
+		}
+	}
+
+	if first != "" {
+		a.notification.Account = accountFromARN(first)
+	}
+}
+
+// scannedRoles returns the principals of each role of the last scan, regardless of the focus and minimum counts
+// restricting the output, or nil if no scan decoded trust policies.
+func (a *App) scannedRoles() map[string][]string {
+	a.notificationMutex.Lock()
+	trusts := a.scannedTrusts
+	a.notificationMutex.Unlock()
+
+	if trusts == nil {
+		return nil
+	}
+
+	return a.trustedPrincipals(trusts)
+}
+
+// recordFindings notes the findings of the scan by severity for the completion message.
+func (a *App) recordFindings(findings []Finding) {
+	a.notificationMutex.Lock()
+	defer a.notificationMutex.Unlock()
+
+	a.notification.Findings = make(map[string]int)
+	for _, finding := range findings {
+		a.notification.Findings[finding.Severity]++
+	}
+}
+
+// notifyScanComplete publishes the scan summary to the configured SNS topic, with the highest severity found as a
+// message attribute for subscription filter policies. Failures are retried a bounded number of times and logged,
+// without failing the scan.
+func (a *App) notifyScanComplete(ctx context.Context, results string) {
+	if a.snsClient == nil {
+		return
+	}
+
+	a.notificationMutex.Lock()
+	summary := a.notification
+	a.notificationMutex.Unlock()
+
+	summary.Results = results
+	if summary.Findings == nil {
+		summary.Findings = make(map[string]int)
+	}
+
+	message, err := json.Marshal(summary)
+	if err != nil {
+		a.logger.Warn("failed to marshal scan summary", slog.String("error", err.Error()))
+
+		return
+	}
+
+	attributes := map[string]string{snsSeverityAttribute: highestSeverity(summary.Findings)}
+	if summary.Account != "" {
+		attributes[snsAccountAttribute] = summary.Account
+	}
+
+	delay := a.snsRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err = a.snsClient.Publish(ctx, a.snsTopicARN, string(message), attributes)
+		if err == nil {
+			a.logger.Debug("published scan summary", slog.String("topic", a.snsTopicARN))
+
+			return
+		}
+
+		if attempt >= defaultRetryMaxAttempts {
+			a.logger.Warn(
+				"failed to publish scan summary",
+				slog.String("topic", a.snsTopicARN),
+				slog.String("error", err.Error()),
+			)
+
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			a.logger.Warn("failed to publish scan summary", slog.String("error", ctx.Err().Error()))
+
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// snsNotification returns the option publishing the scan summary to the topic, with the default credentials rather
+// than those of a role assumed for scanning.
+func snsNotification(ctx context.Context, loader ConfigLoader, topicARN string) (Option, error) {
+	cfg, err := loader.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config, %w", err)
+	}
+
+	return WithSNSNotification(snsPublisher{client: sns.NewFromConfig(cfg)}, topicARN), nil
+}
+
+// snsPublishAPI publishes messages to SNS topics via AWS SDK clients.
+type snsPublishAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// snsPublisher adapts an SNS SDK client to ServiceSNS, publishing to each topic in the region of its ARN.
+type snsPublisher struct {
+	client snsPublishAPI
+}
+
+var _ ServiceSNS = (*snsPublisher)(nil)
+
+// Publish sends the message to the topic in the region of its ARN, with the given attributes as strings.
+func (p snsPublisher) Publish(
+	ctx context.Context,
+	topicARN string,
+	message string,
+	attributes map[string]string,
+) error {
+	topic, err := parseSNSTopic(topicARN)
+	if err != nil {
+		return err
+	}
+
+	messageAttributes := make(map[string]types.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		messageAttributes[name] = types.MessageAttributeValue{ //nolint:exhaustruct
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	_, err = p.client.Publish(ctx, &sns.PublishInput{ //nolint:exhaustruct
+		TopicArn:          aws.String(topicARN),
+		Message:           aws.String(message),
+		MessageAttributes: messageAttributes,
+	}, func(options *sns.Options) {
+		options.Region = topic.Region
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to SNS: %w", err)
+	}
+
+	return nil
+}
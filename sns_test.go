@@ -0,0 +1,249 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+const testSNSTopic = "arn:aws:sns:eu-west-1:0123456789:veil"
+
+type MockServiceSNS struct {
+	failures   int
+	calls      int
+	message    string
+	attributes map[string]string
+}
+
+func (m *MockServiceSNS) Publish(
+	_ context.Context,
+	_ string,
+	message string,
+	attributes map[string]string,
+) error {
+	m.calls++
+	if m.calls <= m.failures {
+		return errors.New("test error")
+	}
+
+	m.message = message
+	m.attributes = attributes
+
+	return nil
+}
+
+func TestApp_notifyScanComplete(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::0123456789:role/ci":     {},
+		"arn:aws:iam::0123456789:role/vendor": {},
+	}
+	findings := []Finding{
+		{Code: codeWildcardPrincipal, Severity: severityHigh},
+		{Code: codeOverboardAction, Severity: severityMedium},
+		{Code: codeOverboardAction, Severity: severityMedium},
+	}
+
+	tests := []struct {
+		name           string
+		trusts         map[string]roleTrust
+		findings       []Finding
+		failures       int
+		wantCalls      int
+		wantMessage    string
+		wantAttributes map[string]string
+	}{
+		{
+			name:      "findings",
+			trusts:    trusts,
+			findings:  findings,
+			failures:  0,
+			wantCalls: 1,
+			wantMessage: `{"account":"0123456789","roles":2,"findings":{"high":1,"medium":2},` +
+				`"results":"s3://bucket/veil.json"}`,
+			wantAttributes: map[string]string{"severity": severityHigh, "account": "0123456789"},
+		},
+		{
+			name:           "no checks run",
+			trusts:         trusts,
+			findings:       nil,
+			failures:       0,
+			wantCalls:      1,
+			wantMessage:    `{"account":"0123456789","roles":2,"findings":{},"results":"s3://bucket/veil.json"}`,
+			wantAttributes: map[string]string{"severity": severityNone, "account": "0123456789"},
+		},
+		{
+			name:      "retried",
+			trusts:    trusts,
+			findings:  findings,
+			failures:  1,
+			wantCalls: 2,
+			wantMessage: `{"account":"0123456789","roles":2,"findings":{"high":1,"medium":2},` +
+				`"results":"s3://bucket/veil.json"}`,
+			wantAttributes: map[string]string{"severity": severityHigh, "account": "0123456789"},
+		},
+		{
+			name:           "no roles without an account",
+			trusts:         map[string]roleTrust{},
+			findings:       nil,
+			failures:       0,
+			wantCalls:      1,
+			wantMessage:    `{"account":"","roles":0,"findings":{},"results":"s3://bucket/veil.json"}`,
+			wantAttributes: map[string]string{"severity": severityNone},
+		},
+		{
+			name:           "gives up",
+			trusts:         trusts,
+			findings:       findings,
+			failures:       defaultRetryMaxAttempts,
+			wantCalls:      defaultRetryMaxAttempts,
+			wantMessage:    "",
+			wantAttributes: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &MockServiceSNS{failures: tt.failures}
+			app := &App{
+				logger:        slog.New(slog.DiscardHandler),
+				snsClient:     client,
+				snsTopicARN:   testSNSTopic,
+				snsRetryDelay: 0,
+			}
+
+			app.recordRoles(tt.trusts, nil)
+
+			if tt.findings != nil {
+				app.gateFindings(tt.findings)
+			}
+
+			app.notifyScanComplete(t.Context(), "s3://bucket/veil.json")
+
+			if client.calls != tt.wantCalls {
+				t.Errorf("notifyScanComplete() calls = %d, want %d", client.calls, tt.wantCalls)
+			}
+
+			if client.message != tt.wantMessage {
+				t.Errorf("notifyScanComplete() message = %s, want %s", client.message, tt.wantMessage)
+			}
+
+			if !reflect.DeepEqual(client.attributes, tt.wantAttributes) {
+				t.Errorf("notifyScanComplete() attributes = %v, want %v", client.attributes, tt.wantAttributes)
+			}
+		})
+	}
+}
+
+// mockSNSPublish records the input of the last Publish call and the region it was sent to.
+type mockSNSPublish struct {
+	input  *sns.PublishInput
+	region string
+	err    error
+}
+
+func (m *mockSNSPublish) Publish(
+	_ context.Context,
+	params *sns.PublishInput,
+	optFns ...func(*sns.Options),
+) (*sns.PublishOutput, error) {
+	options := sns.Options{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	m.input = params
+	m.region = options.Region
+
+	return &sns.PublishOutput{}, m.err
+}
+
+func Test_snsPublisher_Publish(t *testing.T) {
+	t.Parallel()
+
+	errPublish := errors.New("test error")
+
+	tests := []struct {
+		name       string
+		topic      string
+		publishErr error
+		wantRegion string
+		wantErr    error
+	}{
+		{name: "published", topic: testSNSTopic, publishErr: nil, wantRegion: "eu-west-1", wantErr: nil},
+		{
+			name:       "China partition",
+			topic:      "arn:aws-cn:sns:cn-north-1:0123456789:veil",
+			publishErr: nil,
+			wantRegion: "cn-north-1",
+			wantErr:    nil,
+		},
+		{name: "rejected", topic: testSNSTopic, publishErr: errPublish, wantRegion: "eu-west-1", wantErr: errPublish},
+		{
+			name:       "not a topic",
+			topic:      "arn:aws:sqs:eu-west-1:0123456789:veil",
+			publishErr: nil,
+			wantRegion: "",
+			wantErr:    errInvalidSNSTopic,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &mockSNSPublish{err: tt.publishErr}
+
+			err := snsPublisher{client: client}.Publish(
+				t.Context(),
+				tt.topic,
+				`{"roles":2}`,
+				map[string]string{"severity": severityHigh},
+			)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantRegion == "" {
+				return
+			}
+
+			if client.region != tt.wantRegion {
+				t.Errorf("Publish() region = %s, want %s", client.region, tt.wantRegion)
+			}
+
+			if aws.ToString(client.input.TopicArn) != tt.topic || aws.ToString(client.input.Message) != `{"roles":2}` {
+				t.Errorf("Publish() input = %+v", client.input)
+			}
+
+			want := map[string]types.MessageAttributeValue{
+				"severity": {DataType: aws.String("String"), StringValue: aws.String(severityHigh)},
+			}
+			if !reflect.DeepEqual(client.input.MessageAttributes, want) {
+				t.Errorf("Publish() attributes = %v, want %v", client.input.MessageAttributes, want)
+			}
+		})
+	}
+}
+
+func Test_parseSNSTopic(t *testing.T) {
+	t.Parallel()
+
+	for _, topic := range []string{"veil", "arn:aws:sns::0123456789:veil", "arn:aws:s3:::bucket"} {
+		_, err := parseSNSTopic(topic)
+		if !errors.Is(err, errInvalidSNSTopic) {
+			t.Errorf("parseSNSTopic(%q) error = %v, want %v", topic, err, errInvalidSNSTopic)
+		}
+	}
+
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+var errSplitOutput = errors.New(
+	"-split-output-by-principal-type requires -output, the json format, no account redaction " +
+		"and no -output-per-account",
+)
+
+// SplitByPrincipalType splits the given role to principals mapping by the type of each principal, see
+// ClassifyPrincipal, so that every principal of a role is listed under exactly one type.
+func SplitByPrincipalType(data map[string][]string) map[string]map[string][]string {
+	output := make(map[string]map[string][]string)
+
+	for role, principals := range data {
+		for _, principal := range principals {
+			kind := ClassifyPrincipal(principal)
+			if output[kind] == nil {
+				output[kind] = make(map[string][]string)
+			}
+
+			output[kind][role] = append(output[kind][role], principal)
+		}
+	}
+
+	return output
+}
+
+// writeSplitByPrincipalType writes the principals of each type to its own file in dir, e.g. service-principals.json,
+// in the json output format.
+func writeSplitByPrincipalType(dir string, data map[string][]string) error {
+	err := os.MkdirAll(dir, 0o750) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for kind, roles := range SplitByPrincipalType(data) {
+		marshal, errMarshal := json.MarshalIndent(mapFlip(roles), "", "  ")
+		if errMarshal != nil {
+			return fmt.Errorf("failed to marshal %s principals: %w", kind, errMarshal)
+		}
+
+		errWrite := os.WriteFile(filepath.Join(dir, kind+"-principals.json"), marshal, 0o600) //nolint:mnd
+		if errWrite != nil {
+			return fmt.Errorf("failed to write %s principals: %w", kind, errWrite)
+		}
+
+		slog.Debug("wrote principal type output", slog.String("type", kind), slog.Int("roles", len(roles)))
+	}
+
+	return nil
+}
+
+// writePerPrincipalType scans the roles and writes their principals to one file per principal type in dir.
+func (a *App) writePerPrincipalType(ctx context.Context, dir string) error {
+	roles, err := a.getRolesWithTrust(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	return writeSplitByPrincipalType(dir, roles)
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func TestSplitByPrincipalType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data map[string][]string
+		want map[string]map[string][]string
+	}{
+		{
+			name: "mixed principals",
+			data: map[string][]string{
+				"arn:aws:iam::0123456789:role/ecs": {"ecs.amazonaws.com"},
+				"arn:aws:iam::0123456789:role/ci": {
+					"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+					"arn:aws:iam::210987654321:root",
+				},
+				"arn:aws:iam::0123456789:role/public": {"*", "org:o-a1b2c3d4e5"},
+			},
+			want: map[string]map[string][]string{
+				principalTypeService: {"arn:aws:iam::0123456789:role/ecs": {"ecs.amazonaws.com"}},
+				principalTypeFederated: {
+					"arn:aws:iam::0123456789:role/ci": {
+						"arn:aws:iam::0123456789:oidc-provider/token.actions.githubusercontent.com",
+					},
+				},
+				principalTypeAWS:          {"arn:aws:iam::0123456789:role/ci": {"arn:aws:iam::210987654321:root"}},
+				principalTypeAnonymous:    {"arn:aws:iam::0123456789:role/public": {"*"}},
+				principalTypeOrganization: {"arn:aws:iam::0123456789:role/public": {"org:o-a1b2c3d4e5"}},
+			},
+		},
+		{
+			name: "no roles",
+			data: map[string][]string{},
+			want: map[string]map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := SplitByPrincipalType(tt.data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitByPrincipalType() got = %v, want %v", got, tt.want)
+			}
+
+			count := func(data map[string][]string) int {
+				total := 0
+				for _, principals := range data {
+					total += len(principals)
+				}
+
+				return total
+			}
+
+			splitTotal := 0
+			for _, split := range got {
+				splitTotal += count(split)
+			}
+
+			if splitTotal != count(tt.data) {
+				t.Errorf("SplitByPrincipalType() splits hold %d principals, want %d", splitTotal, count(tt.data))
+			}
+		})
+	}
+}
+
+func TestApp_writePerPrincipalType(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	app := &App{
+		logger: slog.New(slog.DiscardHandler),
+		client: &veiltest.FakeIAM{
+			Roles: []types.Role{
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+					AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+				},
+				{
+					Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+					AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+				},
+			},
+		},
+	}
+
+	err := app.writePerPrincipalType(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("writePerPrincipalType() unexpected error: %v", err)
+	}
+
+	want := map[string]map[string][]string{
+		"aws-principals.json": {
+			"arn:aws:iam::210987654321:root": {"arn:aws:iam::0123456789:role/vendor"},
+		},
+		"service-principals.json": {
+			"ecs.amazonaws.com": {"arn:aws:iam::0123456789:role/ecs"},
+		},
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("writePerPrincipalType() did not create the output directory: %v", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+
+	if !reflect.DeepEqual(files, slices.Sorted(maps.Keys(want))) {
+		t.Fatalf("writePerPrincipalType() wrote %v, want %v", files, slices.Sorted(maps.Keys(want)))
+	}
+
+	for file, principals := range want {
+		data, errRead := os.ReadFile(filepath.Join(dir, file))
+		if errRead != nil {
+			t.Fatalf("failed to read %s: %v", file, errRead)
+		}
+
+		var got map[string][]string
+
+		errRead = json.Unmarshal(data, &got)
+		if errRead != nil {
+			t.Fatalf("writePerPrincipalType() wrote invalid JSON to %s: %v", file, errRead)
+		}
+
+		if !reflect.DeepEqual(got, principals) {
+			t.Errorf("writePerPrincipalType() wrote %v to %s, want %v", got, file, principals)
+		}
+	}
+}
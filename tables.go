@@ -0,0 +1,117 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// principalRow is a row of the principals table, one per distinct principal.
+type principalRow struct {
+	ID        int    `json:"id"`
+	Principal string `json:"principal"`
+	Type      string `json:"type"`
+	Account   string `json:"account,omitempty"`
+}
+
+// roleRow is a row of the roles table, one per scanned role.
+type roleRow struct {
+	ID      int    `json:"id"`
+	ARN     string `json:"arn"`
+	Account string `json:"account"`
+	Path    string `json:"path"`
+	Tags    string `json:"tags,omitempty"`
+}
+
+// edgeRow is a row of the edges table, linking a principal to a role trusting it by their IDs.
+type edgeRow struct {
+	PrincipalID int `json:"principalId"`
+	RoleID      int `json:"roleId"`
+}
+
+// trustTables is the trust graph normalised into tables, ready to be loaded into a relational database.
+type trustTables struct {
+	Principals []principalRow `json:"principals"`
+	Roles      []roleRow      `json:"roles"`
+	Edges      []edgeRow      `json:"edges"`
+}
+
+// buildTrustTables normalises the given role to principals mapping into principals, roles and edges tables. IDs are
+// assigned in sorted order starting at 1, and paths and tags hold the path and the tags, formatted as
+// key=value,key2=value2, of each role keyed by ARN.
+func buildTrustTables(roles map[string][]string, paths map[string]string, tags map[string]string) trustTables {
+	output := trustTables{
+		Principals: make([]principalRow, 0),
+		Roles:      make([]roleRow, 0, len(roles)),
+		Edges:      make([]edgeRow, 0),
+	}
+
+	principalIDs := make(map[string]int)
+	for _, principal := range slices.Sorted(maps.Keys(mapFlip(roles))) {
+		principalIDs[principal] = len(output.Principals) + 1
+		output.Principals = append(output.Principals, principalRow{
+			ID:        principalIDs[principal],
+			Principal: principal,
+			Type:      ClassifyPrincipal(principal),
+			Account:   accountFromARN(normalisePrincipal(strings.TrimSuffix(principal, viaConditionSuffix))),
+		})
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		roleID := len(output.Roles) + 1
+		output.Roles = append(output.Roles, roleRow{
+			ID:      roleID,
+			ARN:     role,
+			Account: accountFromARN(role),
+			Path:    paths[role],
+			Tags:    tags[role],
+		})
+
+		for _, principal := range uniqSlice(roles[role]) {
+			output.Edges = append(output.Edges, edgeRow{
+				PrincipalID: principalIDs[principal],
+				RoleID:      roleID,
+			})
+		}
+	}
+
+	return output
+}
+
+func (a *App) runTables(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	paths := make(map[string]string, len(trusts))
+	tags := make(map[string]string, len(trusts))
+
+	for arn, trust := range trusts {
+		paths[arn] = aws.ToString(trust.role.Path)
+		tags[arn] = formatTags(tagMap(trust.role.Tags))
+	}
+
+	output := buildTrustTables(a.rolePrincipals(trusts), paths, tags)
+	a.logger.Debug(
+		"normalised trust graph",
+		slog.Int("principals", len(output.Principals)),
+		slog.Int("roles", len(output.Roles)),
+		slog.Int("edges", len(output.Edges)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
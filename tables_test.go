@@ -0,0 +1,106 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_buildTrustTables(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/vendor": {"210987654321", "arn:aws:iam::0123456789:role/admin"},
+		"arn:aws:iam::0123456789:role/admin": {
+			"arn:aws:iam::0123456789:saml-provider/AWSSSO_24_DO_NOT_DELETE",
+			"arn:aws:iam::210987654321:role/deployer (via-condition)",
+		},
+		"arn:aws:iam::0123456789:role/ecs": {"ecs.amazonaws.com"},
+	}
+	paths := map[string]string{
+		"arn:aws:iam::0123456789:role/vendor": "/vendor/",
+		"arn:aws:iam::0123456789:role/admin":  "/",
+		"arn:aws:iam::0123456789:role/ecs":    "/aws-service-role/",
+	}
+
+	tags := map[string]string{
+		"arn:aws:iam::0123456789:role/vendor": "app=billing,owner=platform",
+	}
+
+	got := buildTrustTables(roles, paths, tags)
+
+	wantPrincipals := []principalRow{
+		{ID: 1, Principal: "210987654321", Type: principalTypeAWS, Account: "210987654321"},
+		{ID: 2, Principal: "arn:aws:iam::0123456789:role/admin", Type: principalTypeAWS, Account: "0123456789"},
+		{
+			ID:        3,
+			Principal: "arn:aws:iam::0123456789:saml-provider/AWSSSO_24_DO_NOT_DELETE",
+			Type:      principalTypeFederated,
+			Account:   "0123456789",
+		},
+		{
+			ID:        4,
+			Principal: "arn:aws:iam::210987654321:role/deployer (via-condition)",
+			Type:      principalTypeAWS,
+			Account:   "210987654321",
+		},
+		{ID: 5, Principal: "ecs.amazonaws.com", Type: principalTypeService, Account: ""},
+	}
+	if !reflect.DeepEqual(got.Principals, wantPrincipals) {
+		t.Errorf("buildTrustTables() principals = %+v, want %+v", got.Principals, wantPrincipals)
+	}
+
+	wantRoles := []roleRow{
+		{ID: 1, ARN: "arn:aws:iam::0123456789:role/admin", Account: "0123456789", Path: "/"},
+		{ID: 2, ARN: "arn:aws:iam::0123456789:role/ecs", Account: "0123456789", Path: "/aws-service-role/"},
+		{
+			ID:      3,
+			ARN:     "arn:aws:iam::0123456789:role/vendor",
+			Account: "0123456789",
+			Path:    "/vendor/",
+			Tags:    "app=billing,owner=platform",
+		},
+	}
+	if !reflect.DeepEqual(got.Roles, wantRoles) {
+		t.Errorf("buildTrustTables() roles = %+v, want %+v", got.Roles, wantRoles)
+	}
+
+	// Every edge references existing rows, and together the edges reproduce the input mapping.
+	principals := make(map[int]string, len(got.Principals))
+	for _, row := range got.Principals {
+		principals[row.ID] = row.Principal
+	}
+
+	roleARNs := make(map[int]string, len(got.Roles))
+	for _, row := range got.Roles {
+		roleARNs[row.ID] = row.ARN
+	}
+
+	edges := make(map[string][]string)
+
+	for _, edge := range got.Edges {
+		principal, ok := principals[edge.PrincipalID]
+		if !ok {
+			t.Fatalf("buildTrustTables() edge %+v references unknown principal", edge)
+		}
+
+		role, ok := roleARNs[edge.RoleID]
+		if !ok {
+			t.Fatalf("buildTrustTables() edge %+v references unknown role", edge)
+		}
+
+		edges[role] = append(edges[role], principal)
+	}
+
+	for role, want := range roles {
+		if !reflect.DeepEqual(uniqSlice(edges[role]), uniqSlice(want)) {
+			t.Errorf("buildTrustTables() edges of %s = %v, want %v", role, edges[role], want)
+		}
+	}
+
+	if len(edges) != len(roles) {
+		t.Errorf("buildTrustTables() edges cover %d roles, want %d", len(edges), len(roles))
+	}
+}
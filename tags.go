@@ -0,0 +1,178 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+const codeWildcardTagCondition = "WILDCARD_TAG_CONDITION"
+
+// tagConditionKeyPrefixes lists the condition keys, or their prefixes, that constrain the tags of principals and
+// sessions in attribute-based trust.
+var tagConditionKeyPrefixes = []string{ //nolint:gochecknoglobals
+	"aws:PrincipalTag/",
+	"aws:RequestTag/",
+	"aws:ResourceTag/",
+	"aws:TagKeys",
+	"sts:TransitiveTagKeys",
+}
+
+// WildcardTagCondition describes a tag condition of a trust policy matching any value, which constrains nothing but
+// that the tag is present.
+type WildcardTagCondition struct {
+	RoleARN  string `json:"roleArn"`
+	Operator string `json:"operator"`
+	Key      string `json:"key"`
+}
+
+// DetectWildcardTagConditions returns the tag conditions of the allowing statements of the trust policy of the given
+// role that match any value.
+func DetectWildcardTagConditions(role string, policy TrustPolicy) []WildcardTagCondition {
+	output := make([]WildcardTagCondition, 0)
+
+	for _, statement := range policy.Statement {
+		for _, condition := range wildcardTagConditions(statement) {
+			condition.RoleARN = role
+			output = append(output, condition)
+		}
+	}
+
+	return output
+}
+
+// isTagConditionKey reports whether the condition key constrains tags.
+func isTagConditionKey(key string) bool {
+	for _, prefix := range tagConditionKeyPrefixes {
+		if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWildcardValue reports whether a StringLike value matches any string, e.g. * or ?*.
+func isWildcardValue(value string) bool {
+	return strings.Contains(value, "*") && strings.Trim(value, "*?") == ""
+}
+
+// wildcardTagConditions returns the tag conditions of an allowing statement with a value matching anything, sorted by
+// operator and key. Only operators interpreting wildcards are considered, with or without set and IfExists qualifiers.
+func wildcardTagConditions(statement Statement) []WildcardTagCondition {
+	if !strings.EqualFold(statement.Effect, "Allow") {
+		return nil
+	}
+
+	output := make([]WildcardTagCondition, 0)
+
+	for _, operator := range slices.Sorted(maps.Keys(statement.Condition)) {
+		if conditionOperatorBase(operator) != "StringLike" {
+			continue
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(statement.Condition[operator])) {
+			if !isTagConditionKey(key) || !slices.ContainsFunc(statement.Condition[operator][key], isWildcardValue) {
+				continue
+			}
+
+			output = append(output, WildcardTagCondition{RoleARN: "", Operator: operator, Key: key})
+		}
+	}
+
+	return output
+}
+
+func checkWildcardTagCondition(_ checkEnv, role string, statement Statement) []Finding {
+	output := make([]Finding, 0)
+
+	for _, condition := range wildcardTagConditions(statement) {
+		output = append(output, Finding{
+			Code:      codeWildcardTagCondition,
+			Severity:  severityMedium,
+			Role:      role,
+			Principal: "",
+			Message: "the " + condition.Operator + " condition on " + condition.Key +
+				" matches any value, so it does not constrain who can assume the role",
+			Remediation: &Remediation{
+				Summary: "Match " + condition.Key + " against the specific values allowed to assume the role, " +
+					"e.g. with StringEquals.",
+				Fragment:  nil,
+				Statement: 0,
+			},
+		})
+	}
+
+	return output
+}
+
+// roleTags returns the tags of the role, as already known, e.g. when fetched with GetRole, or listed otherwise.
+func (a *App) roleTags(ctx context.Context, role types.Role) ([]types.Tag, error) {
+	if len(role.Tags) > 0 {
+		return role.Tags, nil
+	}
+
+	name := aws.ToString(role.RoleName)
+	if name == "" {
+		name = roleNameFromARN(aws.ToString(role.Arn))
+	}
+
+	output := make([]types.Tag, 0)
+
+	paginator := iam.NewListRoleTagsPaginator(a.iamClient(), &iam.ListRoleTagsInput{
+		RoleName: aws.String(name),
+		Marker:   nil,
+		MaxItems: nil,
+	})
+	for paginator.HasMorePages() {
+		page, err := retryWithBackoff(
+			ctx,
+			a.logger,
+			defaultRetryMaxAttempts,
+			defaultRetryInitialDelay,
+			func() (*iam.ListRoleTagsOutput, error) {
+				return paginator.NextPage(ctx)
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of role %s: %w", name, err)
+		}
+
+		output = append(output, page.Tags...)
+	}
+
+	return output, nil
+}
+
+// tagMap returns the given tags keyed by tag key, or nil if there are none.
+func tagMap(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	output := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		output[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return output
+}
+
+// formatTags renders tags sorted by key as key=value,key2=value2.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+
+	return strings.Join(pairs, ",")
+}
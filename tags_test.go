@@ -0,0 +1,206 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wakeful/veil/veiltest"
+)
+
+func TestDetectWildcardTagConditions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []WildcardTagCondition
+	}{
+		{
+			name:     "wildcard tag values",
+			document: fixtureWildcardTagCondition,
+			want: []WildcardTagCondition{
+				{RoleARN: "arn:aws:iam::0123456789:role/test", Operator: "ForAllValues:StringLike", Key: "aws:TagKeys"},
+				{RoleARN: "arn:aws:iam::0123456789:role/test", Operator: "StringLike", Key: "aws:PrincipalTag/team"},
+			},
+		},
+		{
+			name:     "no tag conditions",
+			document: fixtureCrossAccountTagSession,
+			want:     []WildcardTagCondition{},
+		},
+		{
+			name:     "wildcard in other keys",
+			document: fixturePrincipalArnLike,
+			want:     []WildcardTagCondition{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			role := "arn:aws:iam::0123456789:role/test"
+
+			trust := mustDecodeTrust(t, role, tt.document)
+			if got := DetectWildcardTagConditions(role, trust.policy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectWildcardTagConditions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isWildcardValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "*", want: true},
+		{value: "?*", want: true},
+		{value: "team-*", want: false},
+		{value: "?", want: false},
+		{value: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isWildcardValue(tt.value); got != tt.want {
+				t.Errorf("isWildcardValue(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		tags map[string]string
+		want string
+	}{
+		{
+			name: "sorted by key",
+			tags: map[string]string{"owner": "platform", "app": "billing", "cost-centre": ""},
+			want: "app=billing,cost-centre=,owner=platform",
+		},
+		{
+			name: "no tags",
+			tags: nil,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatTags(tt.tags); got != tt.want {
+				t.Errorf("formatTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRoleTags(t *testing.T) {
+	t.Parallel()
+
+	roles := []types.Role{
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/vendor"),
+			AssumeRolePolicyDocument: aws.String(fixtureCrossAccountTagSession),
+		},
+		{
+			Arn:                      aws.String("arn:aws:iam::0123456789:role/ecs"),
+			AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+		},
+	}
+	tags := map[string][]types.Tag{
+		"vendor": {
+			{Key: aws.String("owner"), Value: aws.String("platform")},
+			{Key: aws.String("app"), Value: aws.String("billing")},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		tagsErr error
+		want    map[string]map[string]string
+		wantErr bool
+	}{
+		{
+			name: "tags included",
+			opts: []Option{WithRoleTags()},
+			want: map[string]map[string]string{
+				"arn:aws:iam::0123456789:role/vendor": {"app": "billing", "owner": "platform"},
+				"arn:aws:iam::0123456789:role/ecs":    nil,
+			},
+			wantErr: false,
+		},
+		{
+			name: "tags not fetched",
+			opts: nil,
+			want: map[string]map[string]string{
+				"arn:aws:iam::0123456789:role/vendor": nil,
+				"arn:aws:iam::0123456789:role/ecs":    nil,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "failed to list tags",
+			opts:    []Option{WithRoleTags()},
+			tagsErr: errors.New("test error"),
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, err := NewApp(
+				t.Context(),
+				"eu-west-1",
+				&mockConfigLoader{},
+				append(tt.opts, WithLazyInit(), WithLogger(slog.New(slog.DiscardHandler)))...,
+			)
+			if err != nil {
+				t.Fatalf("NewApp() unexpected error: %v", err)
+			}
+
+			app.client = &veiltest.FakeIAM{
+				Roles:  roles,
+				Tags:   tags,
+				Errors: map[string]error{veiltest.OperationListRoleTags: tt.tagsErr},
+			}
+
+			trusts, err := app.getRoleTrusts(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRoleTrusts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			reports, err := app.buildRoleReports(trusts)
+			if err != nil {
+				t.Fatalf("buildRoleReports() unexpected error: %v", err)
+			}
+
+			for role, want := range tt.want {
+				if got := reports[role].Tags; !reflect.DeepEqual(got, want) {
+					t.Errorf("buildRoleReports() tags of %s = %v, want %v", role, got, want)
+				}
+			}
+		})
+	}
+}
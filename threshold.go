@@ -0,0 +1,51 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"errors"
+	"slices"
+)
+
+var errInvalidMinCount = errors.New("-min-principals and -min-roles must not be negative")
+
+// minCountRoles keeps the roles trusted by at least minPrincipals distinct principals, then the principals trusted by
+// at least minRoles of the roles kept, dropping roles left without principals. A threshold of zero or one keeps
+// everything on its side.
+func minCountRoles(roles map[string][]string, minPrincipals, minRoles int) map[string][]string {
+	output := make(map[string][]string, len(roles))
+
+	for role, principals := range roles {
+		if len(principals) >= minPrincipals {
+			output[role] = principals
+		}
+	}
+
+	if minRoles <= 1 {
+		return output
+	}
+
+	counts := make(map[string]int)
+
+	for _, principals := range output {
+		for _, principal := range principals {
+			counts[principal]++
+		}
+	}
+
+	for role, principals := range output {
+		kept := slices.DeleteFunc(slices.Clone(principals), func(principal string) bool {
+			return counts[principal] < minRoles
+		})
+		if len(kept) == 0 {
+			delete(output, role)
+
+			continue
+		}
+
+		output[role] = kept
+	}
+
+	return output
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_minCountRoles(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string][]string{
+		"arn:aws:iam::0123456789:role/vendor": {
+			"arn:aws:iam::210987654321:root",
+		},
+		"arn:aws:iam::0123456789:role/admin": {
+			"arn:aws:iam::210987654321:root",
+			"arn:aws:iam::0123456789:role/breakglass",
+			"arn:aws:iam::0123456789:role/vendor",
+		},
+		"arn:aws:iam::0123456789:role/ecs": {
+			"ecs.amazonaws.com",
+			"arn:aws:iam::0123456789:role/vendor",
+		},
+	}
+
+	tests := []struct {
+		name          string
+		minPrincipals int
+		minRoles      int
+		want          map[string][]string
+		wantFlipped   map[string][]string
+	}{
+		{
+			name:          "no thresholds",
+			minPrincipals: 0,
+			minRoles:      0,
+			want:          roles,
+			wantFlipped:   mapFlip(roles),
+		},
+		{
+			name:          "roles trusted by at least two principals",
+			minPrincipals: 2,
+			minRoles:      0,
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/admin": roles["arn:aws:iam::0123456789:role/admin"],
+				"arn:aws:iam::0123456789:role/ecs":   roles["arn:aws:iam::0123456789:role/ecs"],
+			},
+			wantFlipped: map[string][]string{
+				"arn:aws:iam::210987654321:root":          {"arn:aws:iam::0123456789:role/admin"},
+				"arn:aws:iam::0123456789:role/breakglass": {"arn:aws:iam::0123456789:role/admin"},
+				"arn:aws:iam::0123456789:role/vendor": {
+					"arn:aws:iam::0123456789:role/admin",
+					"arn:aws:iam::0123456789:role/ecs",
+				},
+				"ecs.amazonaws.com": {"arn:aws:iam::0123456789:role/ecs"},
+			},
+		},
+		{
+			name:          "principals trusted by at least two roles",
+			minPrincipals: 0,
+			minRoles:      2,
+			want: map[string][]string{
+				"arn:aws:iam::0123456789:role/vendor": {"arn:aws:iam::210987654321:root"},
+				"arn:aws:iam::0123456789:role/admin": {
+					"arn:aws:iam::210987654321:root",
+					"arn:aws:iam::0123456789:role/vendor",
+				},
+				"arn:aws:iam::0123456789:role/ecs": {"arn:aws:iam::0123456789:role/vendor"},
+			},
+			wantFlipped: map[string][]string{
+				"arn:aws:iam::210987654321:root": {
+					"arn:aws:iam::0123456789:role/admin",
+					"arn:aws:iam::0123456789:role/vendor",
+				},
+				"arn:aws:iam::0123456789:role/vendor": {
+					"arn:aws:iam::0123456789:role/admin",
+					"arn:aws:iam::0123456789:role/ecs",
+				},
+			},
+		},
+		{
+			name:          "principals counted among the roles kept",
+			minPrincipals: 3,
+			minRoles:      2,
+			want:          map[string][]string{},
+			wantFlipped:   map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := minCountRoles(roles, tt.minPrincipals, tt.minRoles)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("minCountRoles() = %v, want %v", got, tt.want)
+			}
+
+			if flipped := mapFlip(got); !reflect.DeepEqual(flipped, tt.wantFlipped) {
+				t.Errorf("mapFlip(minCountRoles()) = %v, want %v", flipped, tt.wantFlipped)
+			}
+		})
+	}
+}
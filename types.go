@@ -6,7 +6,29 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+const (
+	conditionPrincipalArn   = "aws:PrincipalArn"
+	conditionPrincipalOrgID = "aws:PrincipalOrgID"
+	viaConditionSuffix      = " (via-condition)"
+	orgPrincipalPrefix      = "org:"
+)
+
+// Scopes of AWS principals, by the breadth of the identities they trust.
+const (
+	principalScopeWildcard    = "wildcard"
+	principalScopeAccountRoot = "account-root"
+	principalScopeSpecific    = "specific"
+	principalScopeUnknown     = "unknown"
 )
 
 // Items is a slice of strings that supports unmarshalling from JSON arrays, single strings, or null values.
@@ -29,14 +51,24 @@ func (i *Items) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
+	// The decoder hands over validated JSON, so a quoted string without escapes is its own content.
+	if len(trimmed) > 1 && trimmed[0] == '"' && bytes.IndexByte(trimmed, '\\') < 0 && utf8.Valid(trimmed) {
+		*i = []string{string(trimmed[1 : len(trimmed)-1])}
+
+		return nil
+	}
+
 	var (
 		single string
 		err    error
 	)
-	if err = json.Unmarshal(data, &single); err == nil { //nolint:noinlineerr
-		*i = []string{single}
+	// Arrays skip the string attempt, whose failure would cost an error value per list of principals or actions.
+	if trimmed[0] != '[' {
+		if err = json.Unmarshal(data, &single); err == nil { //nolint:noinlineerr
+			*i = []string{single}
 
-		return nil
+			return nil
+		}
 	}
 
 	var many []string
@@ -55,35 +87,430 @@ var _ json.Unmarshaler = (*Items)(nil)
 // including associated permissions and access control rules.
 type TrustPolicy struct {
 	Version   string      `json:"Version"`
+	ID        string      `json:"Id,omitempty"`
 	Statement []Statement `json:"Statement"`
 }
 
 // getAllPrincipals returns a deduplicated list of principals from the trust policy statements.
 func (p *TrustPolicy) getAllPrincipals() []string {
-	output := make([]string, 0)
+	output := make([]string, 0, len(p.Statement))
 	for _, statement := range p.Statement {
 		output = append(output, statement.Principal.getAll()...)
 	}
 
+	// The list is built here, so it is deduplicated in place rather than copied by uniqSlice.
+	slices.Sort(output)
+
+	return slices.Compact(output)
+}
+
+// isPublic reports whether an allowing statement of the trust policy trusts a wildcard AWS principal, letting
+// principals outside of known accounts assume the role, subject only to its conditions.
+func (p *TrustPolicy) isPublic() bool {
+	for _, statement := range p.Statement {
+		if strings.EqualFold(statement.Effect, "Allow") && statement.wildcardPrincipal() != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getDerivedPrincipals returns a deduplicated list of the principals derived from aws:PrincipalArn conditions of
+// allowing statements, each marked as via-condition.
+func (p *TrustPolicy) getDerivedPrincipals() []string {
+	output := make([]string, 0)
+	for _, statement := range p.Statement {
+		if !strings.EqualFold(statement.Effect, "Allow") {
+			continue
+		}
+
+		for _, principal := range statement.derivedPrincipals() {
+			output = append(output, principal+viaConditionSuffix)
+		}
+	}
+
+	return uniqSlice(output)
+}
+
+// getOrgPrincipals returns a deduplicated list of the organisations trusted by allowing statements through a
+// wildcard principal scoped with aws:PrincipalOrgID.
+func (p *TrustPolicy) getOrgPrincipals() []string {
+	output := make([]string, 0)
+	for _, statement := range p.Statement {
+		if strings.EqualFold(statement.Effect, "Allow") {
+			output = append(output, statement.organisationPrincipals()...)
+		}
+	}
+
 	return uniqSlice(output)
 }
 
 // Statement represents a single entry in a policy that defines permissions and access control rules.
 // It specifies the effect, principal entities, and actions that are allowed or denied.
 type Statement struct {
+	Sid       string    `json:"Sid,omitempty"`
 	Effect    string    `json:"Effect"`
 	Principal Principal `json:"Principal"`
 	Action    Items     `json:"Action"`
+	Condition Condition `json:"Condition,omitempty"`
+}
+
+// Equal reports whether the statement grants the same trust as other: the same effect, principals, actions and
+// conditions, regardless of the order of their values. The Sid is ignored, as it does not change the trust.
+func (s *Statement) Equal(other Statement) bool {
+	if !strings.EqualFold(s.Effect, other.Effect) ||
+		!sameItems(s.Action, other.Action) ||
+		!sameItems(s.Principal.Service, other.Principal.Service) ||
+		!sameItems(s.Principal.AWS, other.Principal.AWS) ||
+		!sameItems(s.Principal.Federated, other.Principal.Federated) ||
+		!sameItems(s.Principal.CanonicalUser, other.Principal.CanonicalUser) ||
+		!sameItems(s.Principal.Anonymous, other.Principal.Anonymous) ||
+		len(s.Condition) != len(other.Condition) {
+		return false
+	}
+
+	for operator, keys := range s.Condition {
+		otherKeys, ok := other.Condition[operator]
+		if !ok || len(keys) != len(otherKeys) {
+			return false
+		}
+
+		for key, values := range keys {
+			otherValues, ok := otherKeys[key]
+			if !ok || !sameItems(values, otherValues) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// sameItems reports whether both slices hold the same values, ignoring their order and duplicates.
+func sameItems(a []string, b []string) bool {
+	return slices.Equal(uniqSlice(a), uniqSlice(b))
+}
+
+// Condition maps condition operators, such as StringEquals, to condition keys and their values.
+type Condition map[string]map[string]ConditionValues
+
+// ConditionValues is a slice of condition values that supports unmarshalling from JSON strings, booleans, numbers,
+// or arrays of those. Non-string values are kept in their JSON text form.
+type ConditionValues []string
+
+// UnmarshalJSON implements json.Unmarshaler for ConditionValues.
+func (c *ConditionValues) UnmarshalJSON(data []byte) error {
+	var raw any
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse condition values: %w", err)
+	}
+
+	switch value := raw.(type) {
+	case nil:
+		*c = nil
+	case []any:
+		output := make([]string, 0, len(value))
+
+		for _, item := range value {
+			text, errText := conditionValueText(item)
+			if errText != nil {
+				return errText
+			}
+
+			output = append(output, text)
+		}
+
+		*c = output
+	default:
+		text, errText := conditionValueText(value)
+		if errText != nil {
+			return errText
+		}
+
+		*c = []string{text}
+	}
+
+	return nil
+}
+
+var _ json.Unmarshaler = (*ConditionValues)(nil)
+
+var errInvalidConditionValue = errors.New("condition value must be a string, boolean or number")
+
+func conditionValueText(value any) (string, error) {
+	switch typed := value.(type) {
+	case string:
+		return typed, nil
+	case bool, float64:
+		return fmt.Sprint(typed), nil
+	default:
+		return "", fmt.Errorf("%w: %v", errInvalidConditionValue, value)
+	}
+}
+
+// allowsAction reports whether the statement allows the given action, matching wildcards such as sts:* or sts:Tag*
+// case-insensitively.
+func (s *Statement) allowsAction(action string) bool {
+	if !strings.EqualFold(s.Effect, "Allow") {
+		return false
+	}
+
+	return slices.ContainsFunc(s.Action, func(item string) bool {
+		return globRegexp(strings.ToLower(item)).MatchString(strings.ToLower(action))
+	})
+}
+
+// hasConditionKey reports whether any condition operator of the statement tests the given key, compared
+// case-insensitively as IAM does.
+func (s *Statement) hasConditionKey(key string) bool {
+	for _, keys := range s.Condition {
+		for name := range keys {
+			if strings.EqualFold(name, key) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// stringConditionValues returns the values the statement constrains the given key to with a StringEquals or
+// StringLike condition, whatever its ForAnyValue or ForAllValues qualifier and IfExists suffix.
+func (s *Statement) stringConditionValues(key string) []string {
+	output := make([]string, 0)
+
+	for operator, keys := range s.Condition {
+		base := conditionOperatorBase(operator)
+		if base != "StringEquals" && base != "StringLike" {
+			continue
+		}
+
+		for name, values := range keys {
+			if strings.EqualFold(name, key) {
+				output = append(output, values...)
+			}
+		}
+	}
+
+	return uniqSlice(output)
+}
+
+// principalArnConstraint holds the ARN patterns a single condition operator constrains aws:PrincipalArn to. A
+// principal meets the constraint when it matches any of the patterns.
+type principalArnConstraint struct {
+	patterns   []string
+	wildcards  bool
+	ignoreCase bool
+}
+
+// matches reports whether the principal ARN meets the constraint.
+func (c principalArnConstraint) matches(principal string) bool {
+	return slices.ContainsFunc(c.patterns, func(pattern string) bool {
+		switch {
+		case c.wildcards:
+			return globRegexp(pattern).MatchString(principal)
+		case c.ignoreCase:
+			return strings.EqualFold(pattern, principal)
+		default:
+			return pattern == principal
+		}
+	})
+}
+
+// newPrincipalArnConstraint returns an empty constraint matching ARNs the way the given condition operator does, or
+// false when the operator does not list the allowed ARNs.
+func newPrincipalArnConstraint(operator string) (principalArnConstraint, bool) {
+	constraint := principalArnConstraint{patterns: nil, wildcards: false, ignoreCase: false}
+
+	switch conditionOperatorBase(operator) {
+	case "StringEquals":
+	case "StringEqualsIgnoreCase":
+		constraint.ignoreCase = true
+	case "StringLike", "ArnEquals", "ArnLike":
+		constraint.wildcards = true
+	default:
+		return constraint, false
+	}
+
+	return constraint, true
+}
+
+// principalArnConstraints returns the constraints the statement puts on aws:PrincipalArn with the String and ARN
+// operators listing the allowed ARNs. ArnEquals matches wildcards like ArnLike does. Negated operators exclude ARNs
+// instead of listing them, so they are not taken into account.
+func (s *Statement) principalArnConstraints() []principalArnConstraint {
+	output := make([]principalArnConstraint, 0)
+
+	for _, operator := range slices.Sorted(maps.Keys(s.Condition)) {
+		constraint, ok := newPrincipalArnConstraint(operator)
+		if !ok {
+			continue
+		}
+
+		for name, values := range s.Condition[operator] {
+			if strings.EqualFold(name, conditionPrincipalArn) {
+				constraint.patterns = append(constraint.patterns, values...)
+			}
+		}
+
+		if len(constraint.patterns) > 0 {
+			output = append(output, constraint)
+		}
+	}
+
+	return output
+}
+
+// meetsPrincipalArnConstraints reports whether the given principal, as named in a policy, meets every aws:PrincipalArn
+// constraint of the statement. Sessions are matched by the ARN of their role, which is what aws:PrincipalArn holds.
+func meetsPrincipalArnConstraints(constraints []principalArnConstraint, principal string) bool {
+	principal = normalisePrincipal(principal)
+	if role, ok := sessionRole(principal); ok {
+		principal = role
+	}
+
+	for _, constraint := range constraints {
+		if !constraint.matches(principal) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// derivedPrincipals returns the aws:PrincipalArn patterns that effectively replace an account root or wildcard
+// principal of the statement, or nil when the statement trusts no such principal.
+func (s *Statement) derivedPrincipals() []string {
+	for _, principal := range append(slices.Clone(s.Principal.AWS), s.Principal.Anonymous...) {
+		if isBroadPrincipal(principal) {
+			patterns := make([]string, 0)
+			for _, constraint := range s.principalArnConstraints() {
+				patterns = append(patterns, constraint.patterns...)
+			}
+
+			if len(patterns) == 0 {
+				return nil
+			}
+
+			return uniqSlice(patterns)
+		}
+	}
+
+	return nil
+}
+
+// organisationPrincipals returns the organisations a wildcard principal of the statement is scoped to with an
+// aws:PrincipalOrgID condition, rendered as org:o-xxxx. aws:PrincipalArn patterns take precedence, as they are more
+// specific.
+func (s *Statement) organisationPrincipals() []string {
+	if !slices.Contains(s.Principal.AWS, "*") && !slices.Contains(s.Principal.Anonymous, "*") {
+		return nil
+	}
+
+	if s.derivedPrincipals() != nil {
+		return nil
+	}
+
+	orgIDs := s.stringConditionValues(conditionPrincipalOrgID)
+	if len(orgIDs) == 0 {
+		return nil
+	}
+
+	output := make([]string, 0, len(orgIDs))
+	for _, orgID := range orgIDs {
+		output = append(output, orgPrincipalPrefix+orgID)
+	}
+
+	return output
+}
+
+// effectiveAWSPrincipals returns the AWS principals the statement trusts, with account roots and wildcards
+// replaced by the aws:PrincipalArn patterns constraining them, or wildcards replaced by the organisations an
+// aws:PrincipalOrgID condition scopes them to. aws:PrincipalArn constraints apply to the whole statement, so other
+// principals not meeting them are dropped.
+func (s *Statement) effectiveAWSPrincipals() []string {
+	principals := append(slices.Clone(s.Principal.AWS), s.Principal.Anonymous...)
+
+	if derived := s.derivedPrincipals(); derived != nil {
+		constraints := s.principalArnConstraints()
+
+		return replacePrincipals(principals, func(principal string) bool {
+			return isBroadPrincipal(principal) || !meetsPrincipalArnConstraints(constraints, principal)
+		}, derived)
+	}
+
+	if constraints := s.principalArnConstraints(); len(constraints) > 0 {
+		return replacePrincipals(principals, func(principal string) bool {
+			return !meetsPrincipalArnConstraints(constraints, principal)
+		}, nil)
+	}
+
+	if orgs := s.organisationPrincipals(); orgs != nil {
+		return replacePrincipals(principals, func(principal string) bool { return principal == "*" }, orgs)
+	}
+
+	return principals
+}
+
+// wildcardPrincipal returns the first AWS principal of the statement containing a wildcard, after conditions
+// constraining wildcards are taken into account, or an empty string if there is none.
+func (s *Statement) wildcardPrincipal() string {
+	for _, principal := range s.effectiveAWSPrincipals() {
+		if strings.ContainsAny(principal, "*?") {
+			return principal
+		}
+	}
+
+	return ""
+}
+
+// replacePrincipals drops the principals matching replace and adds the given replacements instead.
+func replacePrincipals(principals []string, replace func(string) bool, replacements []string) []string {
+	output := make([]string, 0, len(principals)+len(replacements))
+	for _, principal := range principals {
+		if !replace(principal) {
+			output = append(output, principal)
+		}
+	}
+
+	return uniqSlice(append(output, replacements...))
+}
+
+// isBroadPrincipal reports whether the principal is a wildcard or an account root, trusting every identity in it.
+func isBroadPrincipal(principal string) bool {
+	return principal == "*" || strings.HasSuffix(normalisePrincipal(principal), ":root")
+}
+
+// principalScope tells account roots, trusting every identity of the account, apart from specific principals
+// such as a role or user, by the resource portion of the principal ARN. Bare account IDs are account roots.
+func principalScope(principal string) string {
+	if principal == "*" {
+		return principalScopeWildcard
+	}
+
+	parsed, err := arn.Parse(normalisePrincipal(principal))
+	if err != nil || parsed.Resource == "" {
+		return principalScopeUnknown
+	}
+
+	if parsed.Resource == "root" {
+		return principalScopeAccountRoot
+	}
+
+	return principalScopeSpecific
 }
 
 // Principal represents an entity that can perform actions or access resources in an AWS policy statement.
 // It includes fields for various principal types: Service, AWS, Federated, CanonicalUser, and Anonymous.
 type Principal struct {
-	Service       Items `json:"Service"`
-	AWS           Items `json:"AWS"`
-	Federated     Items `json:"Federated"`
-	CanonicalUser Items `json:"CanonicalUser"`
-	Anonymous     Items `json:"*"`
+	Service       Items `json:"Service,omitempty"`
+	AWS           Items `json:"AWS,omitempty"`
+	Federated     Items `json:"Federated,omitempty"`
+	CanonicalUser Items `json:"CanonicalUser,omitempty"`
+	Anonymous     Items `json:"*,omitempty"`
 }
 
 // getAll returns a deduplicated list of principal identifiers across Service, AWS, Federated, CanonicalUser,
@@ -108,5 +535,7 @@ func (p *Principal) getAll() []string {
 	allItems = append(allItems, p.CanonicalUser...)
 	allItems = append(allItems, p.Anonymous...)
 
-	return uniqSlice(allItems)
+	slices.Sort(allItems)
+
+	return slices.Compact(allItems)
 }
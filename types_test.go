@@ -53,3 +53,337 @@ func TestItems_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestConditionValues_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected ConditionValues
+		wantErr  bool
+	}{
+		{
+			name:     "null",
+			input:    []byte("null"),
+			expected: nil,
+			wantErr:  false,
+		},
+		{
+			name:     "single string",
+			input:    []byte(`"https://signin.aws.amazon.com/saml"`),
+			expected: ConditionValues{"https://signin.aws.amazon.com/saml"},
+			wantErr:  false,
+		},
+		{
+			name:     "boolean",
+			input:    []byte("true"),
+			expected: ConditionValues{"true"},
+			wantErr:  false,
+		},
+		{
+			name:     "mixed array",
+			input:    []byte(`["repo:wakeful/veil:*", 42, false]`),
+			expected: ConditionValues{"repo:wakeful/veil:*", "42", "false"},
+			wantErr:  false,
+		},
+		{
+			name:     "nested object",
+			input:    []byte(`{"key": "value"}`),
+			expected: nil,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid JSON",
+			input:    []byte(`[`),
+			expected: nil,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var values ConditionValues
+
+			err := values.UnmarshalJSON(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(values, tt.expected) {
+				t.Errorf("UnmarshalJSON() got = %v, want %v", values, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrustPolicy_getDerivedPrincipals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			name:     "account root with StringEquals",
+			document: fixturePrincipalArnEquals,
+			want:     []string{"arn:aws:iam::012345678901:role/deployer (via-condition)"},
+		},
+		{
+			name:     "wildcard with StringLike",
+			document: fixturePrincipalArnLike,
+			want:     []string{"arn:aws:iam::*:role/ci-* (via-condition)"},
+		},
+		{
+			name:     "account ID with multiple values",
+			document: fixturePrincipalArnMultiValue,
+			want: []string{
+				"arn:aws:iam::123456789012:role/reader (via-condition)",
+				"arn:aws:iam::123456789012:role/writer (via-condition)",
+			},
+		},
+		{
+			name:     "no condition",
+			document: fixtureWildcardPrincipal,
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trust := mustDecodeTrust(t, "arn:aws:iam::0123456789:role/test", tt.document)
+			if got := trust.policy.getDerivedPrincipals(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getDerivedPrincipals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatement_effectiveAWSPrincipals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			name:     "account root replaced",
+			document: fixturePrincipalArnEquals,
+			want:     []string{"arn:aws:iam::012345678901:role/deployer"},
+		},
+		{
+			name:     "account ID replaced, role outside the condition dropped",
+			document: fixturePrincipalArnMultiValue,
+			want: []string{
+				"arn:aws:iam::123456789012:role/reader",
+				"arn:aws:iam::123456789012:role/writer",
+			},
+		},
+		{
+			name: "roles narrowed with ArnLike",
+			document: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":[` +
+				`"arn:aws:iam::123456789012:role/ci-build","arn:aws:iam::123456789012:role/admin"]},` +
+				`"Action":"sts:AssumeRole","Condition":{"ArnLike":{"aws:PrincipalArn":"arn:aws:iam::*:role/ci-*"}}}]}`,
+			want: []string{"arn:aws:iam::123456789012:role/ci-build"},
+		},
+		{
+			name:     "wildcard scoped to an organisation",
+			document: fixtureOrgWideTrust,
+			want:     []string{"org:o-a1b2c3d4e5"},
+		},
+		{
+			name:     "wildcard without condition kept",
+			document: fixtureWildcardPrincipal,
+			want:     []string{"*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trust := mustDecodeTrust(t, "arn:aws:iam::0123456789:role/test", tt.document)
+			if got := trust.policy.Statement[0].effectiveAWSPrincipals(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("effectiveAWSPrincipals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustPolicy_getOrgPrincipals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{
+			name:     "wildcard scoped to an organisation",
+			document: fixtureOrgWideTrust,
+			want:     []string{"org:o-a1b2c3d4e5"},
+		},
+		{
+			name:     "wildcard scoped by aws:PrincipalArn",
+			document: fixturePrincipalArnLike,
+			want:     []string{},
+		},
+		{
+			name:     "no condition",
+			document: fixtureWildcardPrincipal,
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trust := mustDecodeTrust(t, "arn:aws:iam::0123456789:role/test", tt.document)
+			if got := trust.policy.getOrgPrincipals(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getOrgPrincipals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_principalScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		principal string
+		want      string
+	}{
+		{name: "wildcard", principal: "*", want: principalScopeWildcard},
+		{name: "account root", principal: "arn:aws:iam::210987654321:root", want: principalScopeAccountRoot},
+		{name: "bare account ID", principal: "210987654321", want: principalScopeAccountRoot},
+		{name: "GovCloud account root", principal: "arn:aws-us-gov:iam::111111111111:root", want: principalScopeAccountRoot},
+		{name: "role", principal: "arn:aws:iam::210987654321:role/deployer", want: principalScopeSpecific},
+		{name: "user", principal: "arn:aws:iam::210987654321:user/alice", want: principalScopeSpecific},
+		{
+			name:      "assumed role session",
+			principal: "arn:aws:sts::210987654321:assumed-role/deployer/session",
+			want:      principalScopeSpecific,
+		},
+		{name: "role named root", principal: "arn:aws:iam::210987654321:role/root", want: principalScopeSpecific},
+		{name: "service", principal: "ecs.amazonaws.com", want: principalScopeUnknown},
+		{name: "malformed", principal: "arn:aws:iam:210987654321:root", want: principalScopeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := principalScope(tt.principal); got != tt.want {
+				t.Errorf("principalScope(%q) = %q, want %q", tt.principal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatement_Equal(t *testing.T) {
+	t.Parallel()
+
+	base := Statement{
+		Sid:       "Vendor",
+		Effect:    "Allow",
+		Principal: Principal{AWS: Items{"arn:aws:iam::210987654321:root", "123456789012"}},
+		Action:    Items{"sts:AssumeRole", "sts:TagSession"},
+		Condition: Condition{"StringEquals": {"sts:ExternalId": {"a", "b"}}},
+	}
+
+	tests := []struct {
+		name  string
+		other Statement
+		want  bool
+	}{
+		{
+			name: "reordered values and other Sid",
+			other: Statement{
+				Sid:       "",
+				Effect:    "allow",
+				Principal: Principal{AWS: Items{"123456789012", "arn:aws:iam::210987654321:root"}},
+				Action:    Items{"sts:TagSession", "sts:AssumeRole"},
+				Condition: Condition{"StringEquals": {"sts:ExternalId": {"b", "a"}}},
+			},
+			want: true,
+		},
+		{
+			name: "other effect",
+			other: Statement{
+				Effect:    "Deny",
+				Principal: base.Principal,
+				Action:    base.Action,
+				Condition: base.Condition,
+			},
+			want: false,
+		},
+		{
+			name: "principal moved to another type",
+			other: Statement{
+				Effect:    "Allow",
+				Principal: Principal{Federated: Items{"arn:aws:iam::210987654321:root", "123456789012"}},
+				Action:    base.Action,
+				Condition: base.Condition,
+			},
+			want: false,
+		},
+		{
+			name: "action removed",
+			other: Statement{
+				Effect:    "Allow",
+				Principal: base.Principal,
+				Action:    Items{"sts:AssumeRole"},
+				Condition: base.Condition,
+			},
+			want: false,
+		},
+		{
+			name: "condition value changed",
+			other: Statement{
+				Effect:    "Allow",
+				Principal: base.Principal,
+				Action:    base.Action,
+				Condition: Condition{"StringEquals": {"sts:ExternalId": {"a", "c"}}},
+			},
+			want: false,
+		},
+		{
+			name: "condition operator changed",
+			other: Statement{
+				Effect:    "Allow",
+				Principal: base.Principal,
+				Action:    base.Action,
+				Condition: Condition{"StringLike": {"sts:ExternalId": {"a", "b"}}},
+			},
+			want: false,
+		},
+		{
+			name: "condition removed",
+			other: Statement{
+				Effect:    "Allow",
+				Principal: base.Principal,
+				Action:    base.Action,
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := base.Equal(tt.other); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+
+			if got := tt.other.Equal(base); got != tt.want {
+				t.Errorf("Equal() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
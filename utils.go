@@ -4,50 +4,114 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/url"
-	"sort"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/iam/types"
 )
 
-// uniqSlice returns a sorted slice with duplicates removed from the input.
-// It uses a map to track unique elements and logs the input and output sizes for debugging.
+// uniqSlice returns a sorted copy of the input with duplicates removed. Principal lists are tiny, so sorting a copy
+// and compacting it is cheaper than tracking the elements seen in a map.
 func uniqSlice(input []string) []string {
-	hashMap := make(map[string]struct{}, len(input))
-
-	for _, item := range input {
-		hashMap[item] = struct{}{}
-	}
-
-	output := make([]string, 0, len(hashMap))
-	for item := range hashMap {
-		output = append(output, item)
+	output := slices.Clone(input)
+	if output == nil {
+		output = make([]string, 0)
 	}
 
-	slog.Debug("uniq output", slog.Int("input", len(input)), slog.Int("output", len(output)))
+	slices.Sort(output)
 
-	sort.Strings(output)
+	return slices.Compact(output)
+}
 
-	return output
+// documentBuffers holds the buffers trust policy documents are unescaped into. The JSON decoder copies every string it
+// keeps, so a buffer is reused as soon as its document is decoded.
+var documentBuffers = sync.Pool{ //nolint:gochecknoglobals
+	New: func() any { return new([]byte) },
 }
 
 // decodeRoleTrust decodes an IAM role's trust policy document into a TrustPolicy.
 // It unescapes the URL-encoded document, unmarshals the JSON, and returns the policy or an error.
-func decodeRoleTrust(role types.Role) (TrustPolicy, error) {
-	slog.Debug("decoding trust policy", slog.String("role", *role.Arn))
+// In strict mode unknown keys, such as a misspelled Statement or Principal, are rejected instead of ignored.
+func decodeRoleTrust(role types.Role, strict bool) (TrustPolicy, error) {
+	buffer, _ := documentBuffers.Get().(*[]byte)
+	defer documentBuffers.Put(buffer)
 
-	data, err := url.QueryUnescape(*role.AssumeRolePolicyDocument)
+	data, err := queryUnescape((*buffer)[:0], *role.AssumeRolePolicyDocument)
 	if err != nil {
 		return TrustPolicy{}, fmt.Errorf("failed to unescape URL: %w", err)
 	}
 
+	*buffer = data
+
+	return decodePolicy(data, strict)
+}
+
+// queryUnescape appends s to dst, unescaped as url.QueryUnescape does, without allocating an intermediate string.
+func queryUnescape(dst []byte, s string) ([]byte, error) {
+	for index := 0; index < len(s); index++ {
+		switch char := s[index]; char {
+		case '+':
+			dst = append(dst, ' ')
+		case '%':
+			high, okHigh := unhex(s, index+1)
+			low, okLow := unhex(s, index+2)
+
+			if !okHigh || !okLow {
+				return nil, url.EscapeError(s[index:min(index+3, len(s))]) //nolint:mnd
+			}
+
+			dst = append(dst, high<<4|low) //nolint:mnd
+			index += 2
+		default:
+			dst = append(dst, char)
+		}
+	}
+
+	return dst, nil
+}
+
+// unhex returns the value of the hexadecimal digit at index of s, if there is one.
+func unhex(s string, index int) (byte, bool) {
+	if index >= len(s) {
+		return 0, false
+	}
+
+	switch char := s[index]; {
+	case '0' <= char && char <= '9':
+		return char - '0', true
+	case 'a' <= char && char <= 'f':
+		return char - 'a' + 10, true //nolint:mnd
+	case 'A' <= char && char <= 'F':
+		return char - 'A' + 10, true //nolint:mnd
+	default:
+		return 0, false
+	}
+}
+
+// decodePolicy unmarshals a trust policy document, rejecting unknown keys and trailing data in strict mode.
+func decodePolicy(data []byte, strict bool) (TrustPolicy, error) {
+	if strict {
+		return decodeStrict(data)
+	}
+
 	var policy TrustPolicy
 
-	errUnmarshal := json.Unmarshal([]byte(data), &policy)
+	errUnmarshal := json.Unmarshal(data, &policy)
 	if errUnmarshal != nil {
 		return TrustPolicy{}, fmt.Errorf("failed to unmarshal JSON: %w", errUnmarshal)
 	}
@@ -55,6 +119,133 @@ func decodeRoleTrust(role types.Role) (TrustPolicy, error) {
 	return policy, nil
 }
 
+var (
+	errTrailingData     = errors.New("unexpected data after trust policy")
+	errUnknownPolicyKey = errors.New("unknown trust policy key")
+)
+
+var (
+	// policyKeys are the top-level elements of an IAM policy.
+	policyKeys = []string{"Version", "Id", "Statement"} //nolint:gochecknoglobals
+	// statementKeys are the elements of an IAM policy statement, including those veil does not evaluate.
+	statementKeys = []string{ //nolint:gochecknoglobals
+		"Sid", "Effect", "Principal", "NotPrincipal", "Action", "NotAction", "Resource", "NotResource", "Condition",
+	}
+	// principalKeys are the principal types of a Principal or NotPrincipal element.
+	principalKeys = []string{"AWS", "Service", "Federated", "CanonicalUser"} //nolint:gochecknoglobals
+)
+
+// decodeStrict unmarshals a trust policy, rejecting keys outside the IAM policy grammar and trailing data.
+func decodeStrict(data []byte) (TrustPolicy, error) {
+	var document map[string]json.RawMessage
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	err := decoder.Decode(&document)
+	if err != nil {
+		return TrustPolicy{}, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	_, err = decoder.Token()
+	if !errors.Is(err, io.EOF) {
+		return TrustPolicy{}, errTrailingData
+	}
+
+	err = checkPolicyKeys(document)
+	if err != nil {
+		return TrustPolicy{}, err
+	}
+
+	return decodePolicy(data, false)
+}
+
+// checkPolicyKeys checks the keys of a policy document, its statements and their principals.
+func checkPolicyKeys(document map[string]json.RawMessage) error {
+	err := checkKeys(document, policyKeys, "")
+	if err != nil {
+		return err
+	}
+
+	statements, err := rawObjects(document["Statement"])
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		err = checkKeys(statement, statementKeys, "Statement.")
+		if err != nil {
+			return err
+		}
+
+		for _, element := range []string{"Principal", "NotPrincipal"} {
+			principals, errPrincipal := rawObjects(statement[element])
+			if errPrincipal != nil {
+				return errPrincipal
+			}
+
+			for _, principal := range principals {
+				err = checkKeys(principal, principalKeys, "Statement."+element+".")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkKeys rejects the first key of object, in sorted order, that is not one of the allowed keys.
+func checkKeys(object map[string]json.RawMessage, allowed []string, prefix string) error {
+	for _, key := range slices.Sorted(maps.Keys(object)) {
+		if !slices.Contains(allowed, key) {
+			return fmt.Errorf("%w: %s%s", errUnknownPolicyKey, prefix, key)
+		}
+	}
+
+	return nil
+}
+
+// rawObjects decodes the objects of an element holding an object or an array of objects. Other values, such as the
+// "*" principal, hold no keys and are left to the policy decoding.
+func rawObjects(data json.RawMessage) ([]map[string]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var object map[string]json.RawMessage
+
+		err := json.Unmarshal(trimmed, &object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+
+		return []map[string]json.RawMessage{object}, nil
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var items []json.RawMessage
+
+		err := json.Unmarshal(trimmed, &items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+
+		output := make([]map[string]json.RawMessage, 0, len(items))
+
+		for _, item := range items {
+			objects, errItem := rawObjects(item)
+			if errItem != nil {
+				return nil, errItem
+			}
+
+			output = append(output, objects...)
+		}
+
+		return output, nil
+	default:
+		return nil, nil
+	}
+}
+
 // getLogger returns a slog.Logger configured with the given output and log level.
 // If verbose is true, the log level is set to debug; otherwise, it defaults to info.
 func getLogger(output io.Writer, verbose *bool) *slog.Logger {
@@ -87,9 +278,197 @@ func mapFlip(input map[string][]string) map[string][]string {
 
 	for _, roles := range output {
 		if len(roles) > 1 {
-			sort.Strings(roles)
+			slices.Sort(roles)
 		}
 	}
 
 	return output
 }
+
+// accountFromARN returns the AWS account ID of the given ARN, or an empty string if it has none.
+func accountFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6) //nolint:mnd
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+
+	return parts[4]
+}
+
+// defaultPartition is the partition of commercial AWS regions, assumed when no ARN tells otherwise.
+const defaultPartition = "aws"
+
+// partitionFromARN returns the partition of the given ARN, such as aws or aws-us-gov, or an empty string if it is
+// not an ARN.
+func partitionFromARN(input string) string {
+	parsed, err := arn.Parse(input)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Partition
+}
+
+// rolePartition returns the partition of the given role ARN, defaulting to the aws partition.
+func rolePartition(role string) string {
+	partition := partitionFromARN(role)
+	if partition == "" {
+		return defaultPartition
+	}
+
+	return partition
+}
+
+// regionPartitions maps the region prefixes outside the aws partition to their partition.
+var regionPartitions = []struct{ prefix, partition string }{ //nolint:gochecknoglobals
+	{prefix: "cn-", partition: "aws-cn"},
+	{prefix: "us-gov-", partition: "aws-us-gov"},
+	{prefix: "us-iso-", partition: "aws-iso"},
+	{prefix: "us-isob-", partition: "aws-iso-b"},
+	{prefix: "eu-isoe-", partition: "aws-iso-e"},
+	{prefix: "us-isof-", partition: "aws-iso-f"},
+	{prefix: "eusc-", partition: "aws-eusc"},
+}
+
+// partitionFromRegion returns the partition of the given region, e.g. aws-us-gov for us-gov-west-1, defaulting to the
+// aws partition.
+func partitionFromRegion(region string) string {
+	for _, entry := range regionPartitions {
+		if strings.HasPrefix(region, entry.prefix) {
+			return entry.partition
+		}
+	}
+
+	return defaultPartition
+}
+
+// splitList splits a comma-separated flag value, trimming spaces and dropping blank entries.
+func splitList(value string) []string {
+	output := make([]string, 0)
+
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			output = append(output, item)
+		}
+	}
+
+	return output
+}
+
+var errInvalidNewSince = errors.New("expected a duration such as 72h, a date such as 2025-01-02 or an RFC 3339 time")
+
+// parseNewSince parses a marker time given either as a duration before now, a date or an RFC 3339 time.
+func parseNewSince(value string, now time.Time) (time.Time, error) {
+	duration, err := time.ParseDuration(value)
+	if err == nil {
+		if duration < 0 {
+			return time.Time{}, fmt.Errorf("%w: %s", errInvalidNewSince, value)
+		}
+
+		return now.Add(-duration), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		marker, errParse := time.Parse(layout, value)
+		if errParse == nil {
+			return marker, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %s", errInvalidNewSince, value)
+}
+
+var errInvalidRoleAge = errors.New("expected a role age such as 36h or 7d, with -role-min-age below -role-max-age")
+
+// parseRoleAge parses a role age given as a duration, also accepting a number of days such as 7d.
+func parseRoleAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("%w: %s", errInvalidRoleAge, value)
+		}
+
+		return time.Duration(count) * 24 * time.Hour, nil //nolint:mnd
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%w: %s", errInvalidRoleAge, value)
+	}
+
+	return duration, nil
+}
+
+// RoleAge returns how long ago the role was created, or zero if its creation date is unknown.
+func RoleAge(role types.Role) time.Duration {
+	if role.CreateDate == nil {
+		return 0
+	}
+
+	return time.Since(*role.CreateDate)
+}
+
+// canonicalPolicy URL-decodes a policy document and re-marshals it with sorted keys and no insignificant whitespace,
+// so documents differing only in formatting produce identical output.
+func canonicalPolicy(document string) ([]byte, error) {
+	data, err := url.QueryUnescape(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unescape URL: %w", err)
+	}
+
+	var generic any
+
+	errUnmarshal := json.Unmarshal([]byte(data), &generic)
+	if errUnmarshal != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", errUnmarshal)
+	}
+
+	output, err := json.Marshal(generic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return output, nil
+}
+
+// sha256Hex returns the hex-encoded SHA-256 digest of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// writeOutput writes data to stdout, or to the file at path when one is given.
+// With tee set the data is written to both stdout and the file.
+func writeOutput(data []byte, stdout io.Writer, path string, tee bool) (err error) { //nolint:nonamedreturns
+	if path == "" {
+		_, err = stdout.Write(data)
+
+		return err //nolint:wrapcheck
+	}
+
+	file, err := os.Create(path) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	defer func() {
+		errClose := file.Close()
+		if errClose != nil && err == nil {
+			err = fmt.Errorf("failed to close output file: %w", errClose)
+		}
+	}()
+
+	var writer io.Writer = file
+	if tee {
+		writer = io.MultiWriter(stdout, file)
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -7,9 +7,13 @@ import (
 	"bytes"
 	_ "embed"
 	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam/types"
@@ -183,10 +187,102 @@ var (
 	fixtureAWSServiceRoleForECS string
 	//go:embed fixtures/AWSReservedSSOFullAdmin.json
 	fixtureAWSReservedSSOFullAdmin string
+	//go:embed fixtures/CrossAccountTagSession.json
+	fixtureCrossAccountTagSession string
+	//go:embed fixtures/SharedLambdaTrust.json
+	fixtureSharedLambdaTrust string
+	//go:embed fixtures/SharedLambdaTrustReordered.json
+	fixtureSharedLambdaTrustReordered string
+	//go:embed fixtures/WildcardPrincipal.json
+	fixtureWildcardPrincipal string
+	//go:embed fixtures/GitHubOIDCUnpinned.json
+	fixtureGitHubOIDCUnpinned string
+	//go:embed fixtures/MixedPartitions.json
+	fixtureMixedPartitions string
+	//go:embed fixtures/PrincipalArnEquals.json
+	fixturePrincipalArnEquals string
+	//go:embed fixtures/PrincipalArnLike.json
+	fixturePrincipalArnLike string
+	//go:embed fixtures/PrincipalArnMultiValue.json
+	fixturePrincipalArnMultiValue string
+	//go:embed fixtures/OrgWideTrust.json
+	fixtureOrgWideTrust string
+	//go:embed fixtures/MixedWildcardPrincipal.json
+	fixtureMixedWildcardPrincipal string
+	//go:embed fixtures/StarAction.json
+	fixtureStarAction string
+	//go:embed fixtures/WildcardAction.json
+	fixtureWildcardAction string
 	//go:embed fixtures/EmptyAction.json
 	fixtureEmptyAction string
+	//go:embed fixtures/MisspelledPrincipal.json
+	fixtureMisspelledPrincipal string
 	//go:embed fixtures/InvalidDataTypeNumber.json
 	fixtureInvalidDataTypeNumber string
+	//go:embed fixtures/AccountRootPrincipal.json
+	fixtureAccountRootPrincipal string
+	//go:embed fixtures/SpecificRolePrincipal.json
+	fixtureSpecificRolePrincipal string
+	//go:embed fixtures/MalformedPrincipal.json
+	fixtureMalformedPrincipal string
+	//go:embed fixtures/MisplacedPrincipal.json
+	fixtureMisplacedPrincipal string
+	//go:embed fixtures/GovCloudTrust.json
+	fixtureGovCloudTrust string
+	//go:embed fixtures/ChinaTrust.json
+	fixtureChinaTrust string
+	//go:embed fixtures/GitLabOIDCGroupWildcard.json
+	fixtureGitLabOIDCGroupWildcard string
+	//go:embed fixtures/GitLabOIDCSelfHosted.json
+	fixtureGitLabOIDCSelfHosted string
+	//go:embed fixtures/GitLabOIDCPinned.json
+	fixtureGitLabOIDCPinned string
+	//go:embed fixtures/GitLabOIDCPinnedQualified.json
+	fixtureGitLabOIDCPinnedQualified string
+	//go:embed fixtures/GitHubOIDCWildcardAudience.json
+	fixtureGitHubOIDCWildcardAudience string
+	//go:embed fixtures/GitHubOIDCNegatedSubject.json
+	fixtureGitHubOIDCNegatedSubject string
+	//go:embed fixtures/GitHubOIDCNoSubject.json
+	fixtureGitHubOIDCNoSubject string
+	//go:embed fixtures/GoogleWebIdentityNoAudience.json
+	fixtureGoogleWebIdentityNoAudience string
+	//go:embed fixtures/CognitoWebIdentityPinned.json
+	fixtureCognitoWebIdentityPinned string
+	//go:embed fixtures/BitbucketOIDCWorkspaceWildcard.json
+	fixtureBitbucketOIDCWorkspaceWildcard string
+	//go:embed fixtures/BitbucketOIDCPinned.json
+	fixtureBitbucketOIDCPinned string
+	//go:embed fixtures/UnscopedSAMLTrust.json
+	fixtureUnscopedSAMLTrust string
+	//go:embed fixtures/SAMLTrustNoAudience.json
+	fixtureSAMLTrustNoAudience string
+	//go:embed fixtures/UnscopedOIDCTrust.json
+	fixtureUnscopedOIDCTrust string
+	//go:embed fixtures/ScopedOIDCTrust.json
+	fixtureScopedOIDCTrust string
+	//go:embed fixtures/SAMLMetadata.xml
+	fixtureSAMLMetadata string
+	//go:embed fixtures/CompareProd.json
+	fixtureCompareProd string
+	//go:embed fixtures/CompareStaging.json
+	fixtureCompareStaging string
+	//go:embed fixtures/CompareEquivalence.yaml
+	fixtureCompareEquivalence string
+	//go:embed fixtures/ConfigSnapshot.json
+	fixtureConfigSnapshot string
+	//go:embed fixtures/WildcardTagCondition.json
+	fixtureWildcardTagCondition string
+	//go:embed fixtures/PolicyVariableTag.json
+	fixturePolicyVariableTag string
+	//go:embed fixtures/PolicyVariableUsername.json
+	fixturePolicyVariableUsername string
+	//go:embed fixtures/LegacyPolicyVersion.json
+	fixtureLegacyPolicyVersion string
+	//go:embed fixtures/AllowDenyAccount.json
+	fixtureAllowDenyAccount string
+	//go:embed fixtures/DenyWildcard.json
+	fixtureDenyWildcard string
 )
 
 func Test_decodeRoleTrust(t *testing.T) {
@@ -195,6 +291,7 @@ func Test_decodeRoleTrust(t *testing.T) {
 	tests := []struct {
 		name    string
 		role    types.Role
+		strict  bool
 		want    TrustPolicy
 		wantErr bool
 	}{
@@ -267,6 +364,11 @@ func Test_decodeRoleTrust(t *testing.T) {
 							},
 						},
 						Action: []string{"sts:AssumeRoleWithSAML", "sts:TagSession"},
+						Condition: Condition{
+							"StringEquals": {
+								"SAML:aud": ConditionValues{"https://signin.aws.amazon.com/saml"},
+							},
+						},
 					},
 				},
 			},
@@ -292,6 +394,102 @@ func Test_decodeRoleTrust(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "misspelled key is ignored by default",
+			role: types.Role{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/test-role"),
+				AssumeRolePolicyDocument: aws.String(fixtureMisspelledPrincipal),
+			},
+			strict: false,
+			want: TrustPolicy{
+				Version: "2012-10-17",
+				Statement: []Statement{
+					{
+						Effect: "Allow",
+						Action: Items{"sts:AssumeRole"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "misspelled key is rejected in strict mode",
+			role: types.Role{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/test-role"),
+				AssumeRolePolicyDocument: aws.String(fixtureMisspelledPrincipal),
+			},
+			strict:  true,
+			want:    TrustPolicy{},
+			wantErr: true,
+		},
+		{
+			name: "known keys are accepted in strict mode",
+			role: types.Role{
+				Arn: aws.String(
+					"arn:aws:iam::0123456789:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS",
+				),
+				AssumeRolePolicyDocument: aws.String(fixtureAWSServiceRoleForECS),
+			},
+			strict: true,
+			want: TrustPolicy{
+				Version: "2012-10-17",
+				Statement: []Statement{
+					{
+						Effect: "Allow",
+						Principal: Principal{
+							Service: Items{"ecs.amazonaws.com"},
+						},
+						Action: Items{"sts:AssumeRole"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "trailing data is rejected in strict mode",
+			role: types.Role{
+				Arn:                      aws.String("arn:aws:iam::0123456789:role/test-role"),
+				AssumeRolePolicyDocument: aws.String("{} {}"),
+			},
+			strict:  true,
+			want:    TrustPolicy{},
+			wantErr: true,
+		},
+		{
+			name: "valid IAM keys are accepted in strict mode",
+			role: types.Role{
+				Arn: aws.String("arn:aws:iam::0123456789:role/test-role"),
+				AssumeRolePolicyDocument: aws.String(`{"Version":"2012-10-17","Id":"trust","Statement":[{"Sid":"Deny",` +
+					`"Effect":"Deny","NotPrincipal":{"AWS":"arn:aws:iam::0123456789:root"},"NotAction":"sts:TagSession",` +
+					`"Resource":"*","Condition":{"Bool":{"aws:SecureTransport":"false"}}}]}`),
+			},
+			strict: true,
+			want: TrustPolicy{
+				Version: "2012-10-17",
+				ID:      "trust",
+				Statement: []Statement{
+					{
+						Sid:    "Deny",
+						Effect: "Deny",
+						Condition: Condition{
+							"Bool": {"aws:SecureTransport": ConditionValues{"false"}},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "misspelled principal type is rejected in strict mode",
+			role: types.Role{
+				Arn: aws.String("arn:aws:iam::0123456789:role/test-role"),
+				AssumeRolePolicyDocument: aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow",` +
+					`"Principal":{"Services":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`),
+			},
+			strict:  true,
+			want:    TrustPolicy{},
+			wantErr: true,
+		},
 		{
 			name: "invalid data type (number)",
 			role: types.Role{
@@ -306,7 +504,7 @@ func Test_decodeRoleTrust(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := decodeRoleTrust(tt.role)
+			got, err := decodeRoleTrust(tt.role, tt.strict)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("decodeRoleTrust() error = %v, wantErr %v", err, tt.wantErr)
 
@@ -319,3 +517,405 @@ func Test_decodeRoleTrust(t *testing.T) {
 		})
 	}
 }
+
+func Test_accountFromARN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		arn  string
+		want string
+	}{
+		{
+			name: "role",
+			arn:  "arn:aws:iam::0123456789:role/test",
+			want: "0123456789",
+		},
+		{
+			name: "account root",
+			arn:  "arn:aws:iam::0123456789:root",
+			want: "0123456789",
+		},
+		{
+			name: "service principal",
+			arn:  "ecs.amazonaws.com",
+			want: "",
+		},
+		{
+			name: "wildcard",
+			arn:  "*",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := accountFromARN(tt.arn); got != tt.want {
+				t.Errorf("accountFromARN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_canonicalPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		document string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "invalid escape",
+			document: "test%2x",
+			want:     "",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid JSON",
+			document: "test",
+			want:     "",
+			wantErr:  true,
+		},
+		{
+			name:     "sorted keys without whitespace",
+			document: "%7B%22Version%22%3A%20%222012-10-17%22%2C%20%22Statement%22%3A%20%5B%5D%7D",
+			want:     `{"Statement":[],"Version":"2012-10-17"}`,
+			wantErr:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := canonicalPolicy(tt.document)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("canonicalPolicy() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("canonicalPolicy() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeOutput(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{"ecs.amazonaws.com": ["arn:aws:iam::0123456789:role/ecs"]}`)
+
+	tests := []struct {
+		name       string
+		file       bool
+		tee        bool
+		wantStdout []byte
+		wantFile   []byte
+	}{
+		{
+			name:       "stdout only",
+			file:       false,
+			tee:        false,
+			wantStdout: data,
+			wantFile:   nil,
+		},
+		{
+			name:       "file only",
+			file:       true,
+			tee:        false,
+			wantStdout: nil,
+			wantFile:   data,
+		},
+		{
+			name:       "stdout and file",
+			file:       true,
+			tee:        true,
+			wantStdout: data,
+			wantFile:   data,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				stdout bytes.Buffer
+				path   string
+			)
+
+			if tt.file {
+				path = filepath.Join(t.TempDir(), "output.json")
+			}
+
+			err := writeOutput(data, &stdout, path, tt.tee)
+			if err != nil {
+				t.Fatalf("writeOutput() unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(stdout.Bytes(), tt.wantStdout) {
+				t.Errorf("writeOutput() stdout = %s, want %s", stdout.Bytes(), tt.wantStdout)
+			}
+
+			if !tt.file {
+				return
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read output file: %v", err)
+			}
+
+			if !bytes.Equal(got, tt.wantFile) {
+				t.Errorf("writeOutput() file = %s, want %s", got, tt.wantFile)
+			}
+		})
+	}
+}
+
+func Test_writeOutput_invalidPath(t *testing.T) {
+	t.Parallel()
+
+	var stdout bytes.Buffer
+
+	err := writeOutput([]byte("{}"), &stdout, filepath.Join(t.TempDir(), "missing", "output.json"), true)
+	if err == nil {
+		t.Errorf("writeOutput() expected error for missing directory")
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("writeOutput() wrote to stdout despite failing to create the file")
+	}
+}
+
+func Test_partitionFromARN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		arn  string
+		want string
+	}{
+		{
+			name: "commercial",
+			arn:  "arn:aws:iam::0123456789:role/test",
+			want: "aws",
+		},
+		{
+			name: "GovCloud",
+			arn:  "arn:aws-us-gov:iam::0123456789:root",
+			want: "aws-us-gov",
+		},
+		{
+			name: "China",
+			arn:  "arn:aws-cn:iam::0123456789:saml-provider/idp",
+			want: "aws-cn",
+		},
+		{
+			name: "service principal",
+			arn:  "ecs.amazonaws.com",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := partitionFromARN(tt.arn); got != tt.want {
+				t.Errorf("partitionFromARN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_partitionFromRegion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		region string
+		want   string
+	}{
+		{name: "commercial", region: "eu-west-1", want: "aws"},
+		{name: "GovCloud", region: "us-gov-west-1", want: "aws-us-gov"},
+		{name: "China", region: "cn-north-1", want: "aws-cn"},
+		{name: "ISOB", region: "us-isob-east-1", want: "aws-iso-b"},
+		{name: "empty", region: "", want: "aws"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := partitionFromRegion(tt.region); got != tt.want {
+				t.Errorf("partitionFromRegion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitList(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{
+			name:  "single",
+			value: "sts:AssumeRole",
+			want:  []string{"sts:AssumeRole"},
+		},
+		{
+			name:  "spaces and blanks",
+			value: " sts:AssumeRole, ,s3:GetObject,",
+			want:  []string{"sts:AssumeRole", "s3:GetObject"},
+		},
+		{
+			name:  "empty",
+			value: "",
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := splitList(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseNewSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "duration",
+			value: "72h",
+			want:  time.Date(2025, 6, 7, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "date",
+			value: "2025-01-02",
+			want:  time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "RFC 3339",
+			value: "2025-01-02T03:04:05Z",
+			want:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "negative duration",
+			value:   "-1h",
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			value:   "last week",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseNewSince(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNewSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("parseNewSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseRoleAge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name:  "days",
+			value: "7d",
+			want:  7 * 24 * time.Hour,
+		},
+		{
+			name:  "duration",
+			value: "36h",
+			want:  36 * time.Hour,
+		},
+		{
+			name:    "negative days",
+			value:   "-7d",
+			wantErr: true,
+		},
+		{
+			name:    "fractional days",
+			value:   "1.5d",
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			value:   "a week",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseRoleAge(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRoleAge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseRoleAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_queryUnescape(t *testing.T) {
+	t.Parallel()
+
+	for _, escaped := range []string{
+		"",
+		"%7B%22Version%22%3A%222012-10-17%22%7D",
+		"a+b%2Bc",
+		"%e2%9c%93",
+		"%",
+		"%4",
+		"%zz",
+		"ok%2",
+	} {
+		want, wantErr := url.QueryUnescape(escaped)
+
+		got, err := queryUnescape([]byte("stale"), escaped)
+		if !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("queryUnescape(%q) error = %v, want %v", escaped, err, wantErr)
+		}
+
+		if wantErr == nil && string(got) != "stale"+want {
+			t.Errorf("queryUnescape(%q) = %q, want %q", escaped, got, "stale"+want)
+		}
+	}
+}
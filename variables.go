@@ -0,0 +1,174 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	codePolicyVariableInTrust = "POLICY_VARIABLE_IN_TRUST"
+
+	// legacyPolicyVersion is the policy language version predating policy variables, under which they are compared
+	// literally instead of substituted.
+	legacyPolicyVersion = "2008-10-17"
+)
+
+// policyVariableRegex matches policy variables, e.g. ${aws:username} or ${aws:PrincipalTag/team, 'none'}, capturing
+// their name without the default value.
+var policyVariableRegex = regexp.MustCompile(`\$\{\s*([^},\s]+)[^}]*\}`) //nolint:gochecknoglobals
+
+// principalConditionKeys lists the condition keys, besides the aws:Principal ones, identifying who assumes a role.
+var principalConditionKeys = []string{ //nolint:gochecknoglobals
+	"aws:userid",
+	"aws:username",
+	"aws:FederatedProvider",
+	"aws:SourceAccount",
+	"aws:SourceArn",
+	"aws:SourceOwner",
+	conditionExternalID,
+	"sts:RoleSessionName",
+	"sts:SourceIdentity",
+}
+
+// policyVariables returns the sorted, distinct policy variables referenced by the values of the given conditions. The
+// ${*}, ${?} and ${$} escapes of special characters are not variables.
+func policyVariables(values ConditionValues) []string {
+	output := make([]string, 0)
+
+	for _, value := range values {
+		for _, match := range policyVariableRegex.FindAllStringSubmatch(value, -1) {
+			if match[1] == "*" || match[1] == "?" || match[1] == "$" {
+				continue
+			}
+
+			output = append(output, match[1])
+		}
+	}
+
+	slices.Sort(output)
+
+	return slices.Compact(output)
+}
+
+// isPrincipalConditionKey reports whether the condition key identifies who assumes the role: a key of the principal,
+// its source or session, or a claim of a SAML or OIDC provider.
+func isPrincipalConditionKey(key string) bool {
+	if len(key) >= len("aws:Principal") && strings.EqualFold(key[:len("aws:Principal")], "aws:Principal") {
+		return true
+	}
+
+	for _, principalKey := range principalConditionKeys {
+		if strings.EqualFold(key, principalKey) {
+			return true
+		}
+	}
+
+	host, _, found := strings.Cut(key, ":")
+
+	return found && (strings.EqualFold(host, "saml") || strings.Contains(host, "."))
+}
+
+// rolePolicyVariables returns the policy variables referenced by the conditions of each trust policy, for the roles
+// referencing any.
+func rolePolicyVariables(trusts map[string]roleTrust) map[string][]string {
+	output := make(map[string][]string)
+
+	for role, trust := range trusts {
+		variables := make([]string, 0)
+
+		for _, statement := range trust.policy.Statement {
+			for _, keys := range statement.Condition {
+				for _, values := range keys {
+					variables = append(variables, policyVariables(values)...)
+				}
+			}
+		}
+
+		if len(variables) > 0 {
+			slices.Sort(variables)
+			output[role] = slices.Compact(variables)
+		}
+	}
+
+	return output
+}
+
+// checkPolicyVariables flags the policy variables in conditions restricting who can assume the role, whose values
+// depend on the request rather than being fixed. Under the legacy policy version the variables are not substituted at
+// all, so the condition compares against the literal text, and the finding is raised to high severity.
+func checkPolicyVariables(role string, policy TrustPolicy) []Finding {
+	output := make([]Finding, 0)
+
+	for index, statement := range policy.Statement {
+		if !strings.EqualFold(statement.Effect, "Allow") {
+			continue
+		}
+
+		for _, operator := range slices.Sorted(maps.Keys(statement.Condition)) {
+			for _, key := range slices.Sorted(maps.Keys(statement.Condition[operator])) {
+				if !isPrincipalConditionKey(key) {
+					continue
+				}
+
+				for _, variable := range policyVariables(statement.Condition[operator][key]) {
+					finding := Finding{
+						Code:      codePolicyVariableInTrust,
+						Severity:  severityMedium,
+						Role:      role,
+						Principal: "",
+						Message: "the " + operator + " condition on " + key + " references the policy variable " +
+							variable + ", so who can assume the role depends on the request",
+						Remediation: &Remediation{
+							Summary: "Replace ${" + variable + "} with the fixed values allowed to assume the role, " +
+								"unless matching it per request is intended.",
+							Fragment:  nil,
+							Statement: index,
+						},
+					}
+
+					if policy.Version == legacyPolicyVersion {
+						finding.Severity = severityHigh
+						finding.Message = "the " + operator + " condition on " + key + " references the policy " +
+							"variable " + variable + ", which policy version " + legacyPolicyVersion +
+							" compares literally instead of substituting it"
+						finding.Remediation.Summary = "Set the policy Version to 2012-10-17 for ${" + variable +
+							"} to be substituted, or replace it with fixed values."
+					}
+
+					output = append(output, finding)
+				}
+			}
+		}
+	}
+
+	return output
+}
+
+func (a *App) runPolicyVariables(ctx context.Context) ([]byte, error) {
+	trusts, err := a.getRoleTrusts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IAM roles: %w", err)
+	}
+
+	output := rolePolicyVariables(trusts)
+	a.logger.Debug(
+		"found policy variables in IAM roles trust policies",
+		slog.Int("roles", len(trusts)),
+		slog.Int("flagged", len(output)),
+	)
+
+	marshal, err := a.marshalOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return marshal, nil
+}
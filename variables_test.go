@@ -0,0 +1,105 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_policyVariables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values ConditionValues
+		want   []string
+	}{
+		{
+			name:   "tag variable",
+			values: ConditionValues{"arn:aws:iam::123456789012:role/${aws:PrincipalTag/team}-*"},
+			want:   []string{"aws:PrincipalTag/team"},
+		},
+		{
+			name:   "default value",
+			values: ConditionValues{"${aws:username, 'unknown'}"},
+			want:   []string{"aws:username"},
+		},
+		{
+			name:   "repeated variables",
+			values: ConditionValues{"${aws:username}/${aws:userid}", "${aws:username}"},
+			want:   []string{"aws:userid", "aws:username"},
+		},
+		{
+			name:   "escaped characters",
+			values: ConditionValues{"${*}${?}${$}"},
+			want:   []string{},
+		},
+		{
+			name:   "static values",
+			values: ConditionValues{"arn:aws:iam::123456789012:role/deployer", "$team"},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := policyVariables(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policyVariables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rolePolicyVariables(t *testing.T) {
+	t.Parallel()
+
+	trusts := map[string]roleTrust{
+		"arn:aws:iam::123456789012:role/tag": mustDecodeTrust(
+			t, "arn:aws:iam::123456789012:role/tag", fixturePolicyVariableTag,
+		),
+		"arn:aws:iam::123456789012:role/username": mustDecodeTrust(
+			t, "arn:aws:iam::123456789012:role/username", fixturePolicyVariableUsername,
+		),
+		"arn:aws:iam::123456789012:role/static": mustDecodeTrust(
+			t, "arn:aws:iam::123456789012:role/static", fixturePrincipalArnLike,
+		),
+	}
+
+	want := map[string][]string{
+		"arn:aws:iam::123456789012:role/tag":      {"aws:PrincipalTag/team"},
+		"arn:aws:iam::123456789012:role/username": {"aws:username"},
+	}
+	if got := rolePolicyVariables(trusts); !reflect.DeepEqual(got, want) {
+		t.Errorf("rolePolicyVariables() = %v, want %v", got, want)
+	}
+}
+
+func Test_isPrincipalConditionKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "aws:PrincipalArn", want: true},
+		{key: "aws:principaltag/team", want: true},
+		{key: "aws:username", want: true},
+		{key: "sts:RoleSessionName", want: true},
+		{key: "SAML:sub", want: true},
+		{key: "token.actions.githubusercontent.com:sub", want: true},
+		{key: "aws:RequestTag/team", want: false},
+		{key: "aws:CurrentTime", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isPrincipalConditionKey(tt.key); got != tt.want {
+				t.Errorf("isPrincipalConditionKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package veilpb holds the Go bindings of veil.proto, the schema of the scan results written by -format proto.
+package veilpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative veil.proto
@@ -0,0 +1,423 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package veiltest provides test doubles of the IAM API scanned by veil: FakeIAM, an in-memory client with
+// programmable role pages and failures, which also serves the IAM query protocol over HTTP for end-to-end tests with
+// a custom endpoint URL.
+package veiltest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Operation names, as used by FakeIAM.Errors, FakeIAM.Throttle and FakeIAM.Calls.
+const (
+	OperationListRoles                = "ListRoles"
+	OperationGetRole                  = "GetRole"
+	OperationListRoleTags             = "ListRoleTags"
+	OperationUpdateAssumeRolePolicy   = "UpdateAssumeRolePolicy"
+	OperationSimulatePrincipalPolicy  = "SimulatePrincipalPolicy"
+	OperationGetSAMLProvider          = "GetSAMLProvider"
+	OperationGetOpenIDConnectProvider = "GetOpenIDConnectProvider"
+)
+
+// FakeIAM is an in-memory IAM client. Its fields configure the account it fakes and must not be changed while it is
+// in use; calls are safe for concurrent use and recorded, see Calls.
+type FakeIAM struct {
+	// Roles are the roles of the account, with their trust policy documents URL-encoded as returned by IAM. As with
+	// IAM, their permissions boundaries are returned by GetRole but not by ListRoles.
+	Roles []types.Role
+	// PageSize is the number of roles listed per page, unless a request asks for fewer. All roles are listed in a
+	// single page when zero.
+	PageSize int32
+	// Tags holds the tags of the roles, keyed by role name, for the roles whose Tags field is empty.
+	Tags map[string][]types.Tag
+	// SAMLProviders holds the metadata document of each SAML provider, keyed by ARN.
+	SAMLProviders map[string]string
+	// OIDCProviders holds the issuer URL of each OIDC provider, keyed by ARN.
+	OIDCProviders map[string]string
+	// Decisions holds the simulated decision of each action, which is implicitly denied otherwise.
+	Decisions map[string]types.PolicyEvaluationDecisionType
+	// Errors fails every call of an operation, keyed by operation name, with the given error.
+	Errors map[string]error
+	// RoleErrors fails the calls of any operation for a role, keyed by role name, with the given error.
+	RoleErrors map[string]error
+	// Throttle fails the given number of first calls of an operation, keyed by operation name, with ThrottlingError.
+	Throttle map[string]int
+
+	mutex sync.Mutex
+	calls []Call
+	// updated holds the trust policy documents set by UpdateAssumeRolePolicy, keyed by role name.
+	updated map[string]string
+}
+
+// Call is a recorded call of FakeIAM.
+type Call struct {
+	Operation string
+	Input     any
+}
+
+// apiError returns an error of the operation as the AWS SDK returns it for an error response.
+func apiError(operation string, status int, code string, message string) error {
+	return &smithy.OperationError{
+		ServiceID:     "IAM",
+		OperationName: operation,
+		Err: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}}, //nolint:exhaustruct
+			Err:      &smithy.GenericAPIError{Code: code, Message: message, Fault: smithy.FaultClient},
+		},
+	}
+}
+
+// ThrottlingError returns the error of an operation rejected for exceeding the request rate.
+func ThrottlingError(operation string) error {
+	return apiError(operation, http.StatusTooManyRequests, "Throttling", "Rate exceeded")
+}
+
+// AccessDeniedError returns the error of an operation the caller is not authorized to perform.
+func AccessDeniedError(operation string) error {
+	return apiError(
+		operation,
+		http.StatusForbidden,
+		"AccessDenied",
+		"User is not authorized to perform: iam:"+operation,
+	)
+}
+
+// noSuchEntity returns the error of a request for a missing entity.
+func noSuchEntity(kind string, name string) error {
+	return &types.NoSuchEntityException{ //nolint:exhaustruct
+		Message: aws.String("The " + kind + " with name " + name + " cannot be found."),
+	}
+}
+
+// record notes the call and returns the error it fails with, if any. It must be called with the mutex held.
+func (f *FakeIAM) record(operation string, input any, role string) error {
+	f.calls = append(f.calls, Call{Operation: operation, Input: input})
+
+	if f.Throttle[operation] > 0 && f.count(operation) <= f.Throttle[operation] {
+		return ThrottlingError(operation)
+	}
+
+	if err, ok := f.Errors[operation]; ok {
+		return err
+	}
+
+	if err, ok := f.RoleErrors[role]; ok && role != "" {
+		return err
+	}
+
+	return nil
+}
+
+// count returns the number of recorded calls of the operation. It must be called with the mutex held.
+func (f *FakeIAM) count(operation string) int {
+	output := 0
+
+	for _, call := range f.calls {
+		if call.Operation == operation {
+			output++
+		}
+	}
+
+	return output
+}
+
+// Calls returns the inputs of the calls of the operation, in order.
+func (f *FakeIAM) Calls(operation string) []any {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	output := make([]any, 0)
+
+	for _, call := range f.calls {
+		if call.Operation == operation {
+			output = append(output, call.Input)
+		}
+	}
+
+	return output
+}
+
+// roleName returns the name of the role, falling back to the last segment of its ARN.
+func roleName(role types.Role) string {
+	if role.RoleName != nil {
+		return *role.RoleName
+	}
+
+	arn := aws.ToString(role.Arn)
+
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// role returns the current state of the named role. It must be called with the mutex held.
+func (f *FakeIAM) role(name string) (types.Role, bool) {
+	for _, role := range f.Roles {
+		if roleName(role) != name {
+			continue
+		}
+
+		if document, ok := f.updated[name]; ok {
+			role.AssumeRolePolicyDocument = aws.String(document)
+		}
+
+		return role, true
+	}
+
+	return types.Role{}, false //nolint:exhaustruct
+}
+
+// page returns the items of the page starting at the marker, an offset, and the marker of the next page, if any.
+func page[T any](operation string, items []T, marker *string, maxItems *int32, pageSize int32) ([]T, *string, error) {
+	start := 0
+
+	if marker != nil {
+		offset, err := strconv.Atoi(*marker)
+		if err != nil || offset < 0 || offset > len(items) {
+			return nil, nil, &types.InvalidInputException{ //nolint:exhaustruct
+				Message: aws.String(fmt.Sprintf("%s: invalid marker %q", operation, *marker)),
+			}
+		}
+
+		start = offset
+	}
+
+	size := len(items) - start
+	if pageSize > 0 && int(pageSize) < size {
+		size = int(pageSize)
+	}
+
+	if maxItems != nil && *maxItems > 0 && int(*maxItems) < size {
+		size = int(*maxItems)
+	}
+
+	end := start + size
+	if end == len(items) {
+		return items[start:end], nil, nil
+	}
+
+	return items[start:end], aws.String(strconv.Itoa(end)), nil
+}
+
+// ListRoles lists the roles whose path starts with the path prefix, PageSize at a time.
+func (f *FakeIAM) ListRoles(
+	_ context.Context,
+	input *iam.ListRolesInput,
+	_ ...func(*iam.Options),
+) (*iam.ListRolesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := f.record(OperationListRoles, input, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the roles of the page are copied, sparing large listings a copy of every role per page.
+	matching := make([]*types.Role, 0, len(f.Roles))
+
+	for index := range f.Roles {
+		if strings.HasPrefix(aws.ToString(f.Roles[index].Path), aws.ToString(input.PathPrefix)) {
+			matching = append(matching, &f.Roles[index])
+		}
+	}
+
+	matching, marker, err := page(OperationListRoles, matching, input.Marker, input.MaxItems, f.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]types.Role, 0, len(matching))
+
+	for _, item := range matching {
+		role := *item
+		if document, ok := f.updated[roleName(role)]; ok {
+			role.AssumeRolePolicyDocument = aws.String(document)
+		}
+
+		role.PermissionsBoundary = nil
+		roles = append(roles, role)
+	}
+
+	return &iam.ListRolesOutput{Roles: roles, IsTruncated: marker != nil, Marker: marker}, nil //nolint:exhaustruct
+}
+
+// GetRole returns the named role.
+func (f *FakeIAM) GetRole(
+	_ context.Context,
+	input *iam.GetRoleInput,
+	_ ...func(*iam.Options),
+) (*iam.GetRoleOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	name := aws.ToString(input.RoleName)
+
+	err := f.record(OperationGetRole, input, name)
+	if err != nil {
+		return nil, err
+	}
+
+	role, ok := f.role(name)
+	if !ok {
+		return nil, noSuchEntity("role", name)
+	}
+
+	return &iam.GetRoleOutput{Role: &role}, nil //nolint:exhaustruct
+}
+
+// ListRoleTags lists the tags of the named role.
+func (f *FakeIAM) ListRoleTags(
+	_ context.Context,
+	input *iam.ListRoleTagsInput,
+	_ ...func(*iam.Options),
+) (*iam.ListRoleTagsOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	name := aws.ToString(input.RoleName)
+
+	err := f.record(OperationListRoleTags, input, name)
+	if err != nil {
+		return nil, err
+	}
+
+	role, ok := f.role(name)
+	if !ok {
+		return nil, noSuchEntity("role", name)
+	}
+
+	tags := role.Tags
+	if len(tags) == 0 {
+		tags = f.Tags[name]
+	}
+
+	tags, marker, err := page(OperationListRoleTags, tags, input.Marker, input.MaxItems, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iam.ListRoleTagsOutput{Tags: tags, IsTruncated: marker != nil, Marker: marker}, nil //nolint:exhaustruct
+}
+
+// UpdateAssumeRolePolicy replaces the trust policy of the named role, returned URL-encoded by later calls.
+func (f *FakeIAM) UpdateAssumeRolePolicy(
+	_ context.Context,
+	input *iam.UpdateAssumeRolePolicyInput,
+	_ ...func(*iam.Options),
+) (*iam.UpdateAssumeRolePolicyOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	name := aws.ToString(input.RoleName)
+
+	err := f.record(OperationUpdateAssumeRolePolicy, input, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := f.role(name); !ok {
+		return nil, noSuchEntity("role", name)
+	}
+
+	if f.updated == nil {
+		f.updated = make(map[string]string)
+	}
+
+	f.updated[name] = url.QueryEscape(aws.ToString(input.PolicyDocument))
+
+	return &iam.UpdateAssumeRolePolicyOutput{}, nil //nolint:exhaustruct
+}
+
+// SimulatePrincipalPolicy returns the configured decision of each action.
+func (f *FakeIAM) SimulatePrincipalPolicy(
+	_ context.Context,
+	input *iam.SimulatePrincipalPolicyInput,
+	_ ...func(*iam.Options),
+) (*iam.SimulatePrincipalPolicyOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	arn := aws.ToString(input.PolicySourceArn)
+
+	err := f.record(OperationSimulatePrincipalPolicy, input, arn[strings.LastIndex(arn, "/")+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	output := &iam.SimulatePrincipalPolicyOutput{} //nolint:exhaustruct
+
+	for _, action := range input.ActionNames {
+		decision, ok := f.Decisions[action]
+		if !ok {
+			decision = types.PolicyEvaluationDecisionTypeImplicitDeny
+		}
+
+		output.EvaluationResults = append(output.EvaluationResults, types.EvaluationResult{ //nolint:exhaustruct
+			EvalActionName: aws.String(action),
+			EvalDecision:   decision,
+		})
+	}
+
+	return output, nil
+}
+
+// GetSAMLProvider returns the metadata document of the SAML provider.
+func (f *FakeIAM) GetSAMLProvider(
+	_ context.Context,
+	input *iam.GetSAMLProviderInput,
+	_ ...func(*iam.Options),
+) (*iam.GetSAMLProviderOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	arn := aws.ToString(input.SAMLProviderArn)
+
+	err := f.record(OperationGetSAMLProvider, input, "")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, ok := f.SAMLProviders[arn]
+	if !ok {
+		return nil, noSuchEntity("SAML provider", arn)
+	}
+
+	return &iam.GetSAMLProviderOutput{SAMLMetadataDocument: aws.String(metadata)}, nil //nolint:exhaustruct
+}
+
+// GetOpenIDConnectProvider returns the issuer URL of the OIDC provider.
+func (f *FakeIAM) GetOpenIDConnectProvider(
+	_ context.Context,
+	input *iam.GetOpenIDConnectProviderInput,
+	_ ...func(*iam.Options),
+) (*iam.GetOpenIDConnectProviderOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	arn := aws.ToString(input.OpenIDConnectProviderArn)
+
+	err := f.record(OperationGetOpenIDConnectProvider, input, "")
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, ok := f.OIDCProviders[arn]
+	if !ok {
+		return nil, noSuchEntity("OpenID Connect provider", arn)
+	}
+
+	return &iam.GetOpenIDConnectProviderOutput{Url: aws.String(issuer)}, nil //nolint:exhaustruct
+}
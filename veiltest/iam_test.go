@@ -0,0 +1,251 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package veiltest
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+)
+
+const trustPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",` +
+	`"Principal":{"Service":"ecs.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+
+// testRoles returns the given number of roles named role-1 and so on, every other one under the /team/ path.
+func testRoles(count int) []types.Role {
+	output := make([]types.Role, 0, count)
+
+	for index := 1; index <= count; index++ {
+		path := "/"
+		if index%2 == 0 {
+			path = "/team/"
+		}
+
+		name := "role-" + strconv.Itoa(index)
+		output = append(output, types.Role{
+			Arn:                      aws.String("arn:aws:iam::123456789012:role" + path + name),
+			RoleName:                 aws.String(name),
+			Path:                     aws.String(path),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(trustPolicy)),
+		})
+	}
+
+	return output
+}
+
+func roleNames(roles []types.Role) []string {
+	output := make([]string, 0, len(roles))
+	for _, role := range roles {
+		output = append(output, aws.ToString(role.RoleName))
+	}
+
+	return output
+}
+
+func TestFakeIAM_ListRoles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		fake      *FakeIAM
+		input     *iam.ListRolesInput
+		wantPages [][]string
+		wantErr   bool
+	}{
+		{
+			name:      "single page",
+			fake:      &FakeIAM{Roles: testRoles(3)},
+			input:     &iam.ListRolesInput{},
+			wantPages: [][]string{{"role-1", "role-2", "role-3"}},
+			wantErr:   false,
+		},
+		{
+			name:      "page size",
+			fake:      &FakeIAM{Roles: testRoles(5), PageSize: 2},
+			input:     &iam.ListRolesInput{},
+			wantPages: [][]string{{"role-1", "role-2"}, {"role-3", "role-4"}, {"role-5"}},
+			wantErr:   false,
+		},
+		{
+			name:      "max items below the page size",
+			fake:      &FakeIAM{Roles: testRoles(3), PageSize: 2},
+			input:     &iam.ListRolesInput{MaxItems: aws.Int32(1)},
+			wantPages: [][]string{{"role-1"}, {"role-2"}, {"role-3"}},
+			wantErr:   false,
+		},
+		{
+			name:      "resumed from a page boundary",
+			fake:      &FakeIAM{Roles: testRoles(5), PageSize: 2},
+			input:     &iam.ListRolesInput{Marker: aws.String("2")},
+			wantPages: [][]string{{"role-3", "role-4"}, {"role-5"}},
+			wantErr:   false,
+		},
+		{
+			name:      "path prefix",
+			fake:      &FakeIAM{Roles: testRoles(5)},
+			input:     &iam.ListRolesInput{PathPrefix: aws.String("/team/")},
+			wantPages: [][]string{{"role-2", "role-4"}},
+			wantErr:   false,
+		},
+		{
+			name:      "throttled twice",
+			fake:      &FakeIAM{Roles: testRoles(1), Throttle: map[string]int{OperationListRoles: 2}},
+			input:     &iam.ListRolesInput{},
+			wantPages: nil,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := make([][]string, 0)
+
+			paginator := iam.NewListRolesPaginator(tt.fake, tt.input)
+			for paginator.HasMorePages() {
+				output, err := paginator.NextPage(t.Context())
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("NextPage() error = %v, wantErr %v", err, tt.wantErr)
+				}
+
+				if err != nil {
+					return
+				}
+
+				got = append(got, roleNames(output.Roles))
+			}
+
+			if !reflect.DeepEqual(got, tt.wantPages) {
+				t.Errorf("ListRoles() pages = %v, want %v", got, tt.wantPages)
+			}
+
+			if calls := len(tt.fake.Calls(OperationListRoles)); calls != len(tt.wantPages) {
+				t.Errorf("ListRoles() calls = %d, want %d", calls, len(tt.wantPages))
+			}
+		})
+	}
+}
+
+func TestFakeIAM_ListRoles_invalidMarker(t *testing.T) {
+	t.Parallel()
+
+	fake := &FakeIAM{Roles: testRoles(1)}
+
+	_, err := fake.ListRoles(t.Context(), &iam.ListRolesInput{Marker: aws.String("not-a-marker")})
+
+	var invalid *types.InvalidInputException
+	if !errors.As(err, &invalid) {
+		t.Errorf("ListRoles() error = %v, want InvalidInputException", err)
+	}
+}
+
+func TestFakeIAM_errors(t *testing.T) {
+	t.Parallel()
+
+	fake := &FakeIAM{
+		Roles:      testRoles(2),
+		Throttle:   map[string]int{OperationGetRole: 1},
+		Errors:     map[string]error{OperationListRoleTags: AccessDeniedError(OperationListRoleTags)},
+		RoleErrors: map[string]error{"role-2": AccessDeniedError(OperationGetRole)},
+	}
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode string
+	}{
+		{
+			name: "throttled first call",
+			call: func() error {
+				_, err := fake.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("role-1")})
+
+				return err
+			},
+			wantCode: "Throttling",
+		},
+		{
+			name: "operation error",
+			call: func() error {
+				_, err := fake.ListRoleTags(t.Context(), &iam.ListRoleTagsInput{RoleName: aws.String("role-1")})
+
+				return err
+			},
+			wantCode: "AccessDenied",
+		},
+		{
+			name: "role error",
+			call: func() error {
+				_, err := fake.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("role-2")})
+
+				return err
+			},
+			wantCode: "AccessDenied",
+		},
+		{
+			name: "missing role",
+			call: func() error {
+				_, err := fake.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("role-3")})
+
+				return err
+			},
+			wantCode: "NoSuchEntity",
+		},
+		{
+			name: "succeeds once throttling stops",
+			call: func() error {
+				_, err := fake.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("role-1")})
+
+				return err
+			},
+			wantCode: "",
+		},
+	}
+	// The cases run in order, as throttling depends on the calls made before.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+
+			var apiErr smithy.APIError
+			if tt.wantCode == "" && err != nil || tt.wantCode != "" && (!errors.As(err, &apiErr) ||
+				apiErr.ErrorCode() != tt.wantCode) {
+				t.Errorf("call error = %v, want code %q", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFakeIAM_UpdateAssumeRolePolicy(t *testing.T) {
+	t.Parallel()
+
+	fake := &FakeIAM{Roles: testRoles(1)}
+	document := `{"Version":"2012-10-17","Statement":[]}`
+
+	_, err := fake.UpdateAssumeRolePolicy(t.Context(), &iam.UpdateAssumeRolePolicyInput{
+		RoleName:       aws.String("role-1"),
+		PolicyDocument: aws.String(document),
+	})
+	if err != nil {
+		t.Fatalf("UpdateAssumeRolePolicy() unexpected error: %v", err)
+	}
+
+	output, err := fake.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("role-1")})
+	if err != nil {
+		t.Fatalf("GetRole() unexpected error: %v", err)
+	}
+
+	if got := aws.ToString(output.Role.AssumeRolePolicyDocument); got != url.QueryEscape(document) {
+		t.Errorf("GetRole() policy = %v, want the updated policy", got)
+	}
+
+	if calls := fake.Calls(OperationUpdateAssumeRolePolicy); len(calls) != 1 {
+		t.Errorf("Calls() = %v, want the update", calls)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package veiltest
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const (
+	iamNamespace = "https://iam.amazonaws.com/doc/2010-05-08/"
+	requestID    = "00000000-0000-0000-0000-000000000000"
+)
+
+// NewServer starts an IAM endpoint serving the fake, for clients configured with its URL as their base endpoint. The
+// caller closes it when done.
+func NewServer(fake *FakeIAM) *httptest.Server {
+	return httptest.NewServer(fake)
+}
+
+type responseXML struct {
+	XMLName   xml.Name
+	Namespace string     `xml:"xmlns,attr"`
+	Result    *resultXML `xml:",omitempty"`
+	RequestID string     `xml:"ResponseMetadata>RequestId"`
+}
+
+type resultXML struct {
+	XMLName              xml.Name
+	Role                 *roleXML        `xml:"Role,omitempty"`
+	Roles                []roleXML       `xml:"Roles>member,omitempty"`
+	Tags                 []tagXML        `xml:"Tags>member,omitempty"`
+	EvaluationResults    []evaluationXML `xml:"EvaluationResults>member,omitempty"`
+	SAMLMetadataDocument string          `xml:"SAMLMetadataDocument,omitempty"`
+	URL                  string          `xml:"Url,omitempty"`
+	IsTruncated          *bool           `xml:"IsTruncated,omitempty"`
+	Marker               string          `xml:"Marker,omitempty"`
+}
+
+type roleXML struct {
+	Path                     string       `xml:"Path"`
+	RoleName                 string       `xml:"RoleName"`
+	RoleID                   string       `xml:"RoleId"`
+	Arn                      string       `xml:"Arn"`
+	CreateDate               string       `xml:"CreateDate,omitempty"`
+	AssumeRolePolicyDocument string       `xml:"AssumeRolePolicyDocument,omitempty"`
+	Description              string       `xml:"Description,omitempty"`
+	MaxSessionDuration       int32        `xml:"MaxSessionDuration,omitempty"`
+	Tags                     []tagXML     `xml:"Tags>member,omitempty"`
+	PermissionsBoundary      *boundaryXML `xml:"PermissionsBoundary,omitempty"`
+}
+
+type boundaryXML struct {
+	PermissionsBoundaryType string `xml:"PermissionsBoundaryType"`
+	PermissionsBoundaryArn  string `xml:"PermissionsBoundaryArn"`
+}
+
+type tagXML struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type evaluationXML struct {
+	EvalActionName string `xml:"EvalActionName"`
+	EvalDecision   string `xml:"EvalDecision"`
+}
+
+type errorResponseXML struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Namespace string   `xml:"xmlns,attr"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestID string   `xml:"RequestId"`
+}
+
+func newRoleXML(role types.Role) roleXML {
+	output := roleXML{
+		Path:                     aws.ToString(role.Path),
+		RoleName:                 roleName(role),
+		RoleID:                   aws.ToString(role.RoleId),
+		Arn:                      aws.ToString(role.Arn),
+		CreateDate:               "",
+		AssumeRolePolicyDocument: aws.ToString(role.AssumeRolePolicyDocument),
+		Description:              aws.ToString(role.Description),
+		MaxSessionDuration:       aws.ToInt32(role.MaxSessionDuration),
+		Tags:                     newTagsXML(role.Tags),
+		PermissionsBoundary:      nil,
+	}
+
+	if role.CreateDate != nil {
+		output.CreateDate = role.CreateDate.UTC().Format(time.RFC3339)
+	}
+
+	if role.PermissionsBoundary != nil {
+		output.PermissionsBoundary = &boundaryXML{
+			PermissionsBoundaryType: string(role.PermissionsBoundary.PermissionsBoundaryType),
+			PermissionsBoundaryArn:  aws.ToString(role.PermissionsBoundary.PermissionsBoundaryArn),
+		}
+	}
+
+	return output
+}
+
+func newTagsXML(tags []types.Tag) []tagXML {
+	output := make([]tagXML, 0, len(tags))
+	for _, tag := range tags {
+		output = append(output, tagXML{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)})
+	}
+
+	return output
+}
+
+// optionalString returns the form value, or nil if it is not set.
+func optionalString(r *http.Request, key string) *string {
+	if !r.Form.Has(key) {
+		return nil
+	}
+
+	return aws.String(r.Form.Get(key))
+}
+
+// optionalInt32 returns the form value, or nil if it is not set or not a number.
+func optionalInt32(r *http.Request, key string) *int32 {
+	value, err := strconv.ParseInt(r.Form.Get(key), 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	return aws.Int32(int32(value))
+}
+
+// members returns the values of a list parameter of the query protocol, e.g. ActionNames.member.1.
+func members(r *http.Request, key string) []string {
+	output := make([]string, 0)
+
+	for index := 1; r.Form.Has(key + ".member." + strconv.Itoa(index)); index++ {
+		output = append(output, r.Form.Get(key+".member."+strconv.Itoa(index)))
+	}
+
+	return output
+}
+
+// ServeHTTP serves the calls of the fake over the IAM query protocol: form encoded actions answered with XML
+// documents. Errors are answered with the status and code the SDK maps back to the same error.
+func (f *FakeIAM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeError(w, &smithy.GenericAPIError{Code: "MalformedQueryString", Message: err.Error()}) //nolint:exhaustruct
+
+		return
+	}
+
+	action := r.Form.Get("Action")
+	result := &resultXML{XMLName: xml.Name{Space: "", Local: action + "Result"}} //nolint:exhaustruct
+
+	switch action {
+	case OperationListRoles:
+		output, errList := f.ListRoles(r.Context(), &iam.ListRolesInput{
+			Marker:     optionalString(r, "Marker"),
+			MaxItems:   optionalInt32(r, "MaxItems"),
+			PathPrefix: optionalString(r, "PathPrefix"),
+		})
+		err = errList
+
+		if err == nil {
+			for _, role := range output.Roles {
+				result.Roles = append(result.Roles, newRoleXML(role))
+			}
+
+			result.IsTruncated, result.Marker = aws.Bool(output.IsTruncated), aws.ToString(output.Marker)
+		}
+	case OperationGetRole:
+		output, errGet := f.GetRole(r.Context(), &iam.GetRoleInput{RoleName: optionalString(r, "RoleName")})
+		err = errGet
+
+		if err == nil {
+			role := newRoleXML(*output.Role)
+			result.Role = &role
+		}
+	case OperationListRoleTags:
+		output, errList := f.ListRoleTags(r.Context(), &iam.ListRoleTagsInput{
+			RoleName: optionalString(r, "RoleName"),
+			Marker:   optionalString(r, "Marker"),
+			MaxItems: optionalInt32(r, "MaxItems"),
+		})
+		err = errList
+
+		if err == nil {
+			result.Tags = newTagsXML(output.Tags)
+			result.IsTruncated, result.Marker = aws.Bool(output.IsTruncated), aws.ToString(output.Marker)
+		}
+	case OperationUpdateAssumeRolePolicy:
+		_, err = f.UpdateAssumeRolePolicy(r.Context(), &iam.UpdateAssumeRolePolicyInput{
+			RoleName:       optionalString(r, "RoleName"),
+			PolicyDocument: optionalString(r, "PolicyDocument"),
+		})
+		result = nil
+	case OperationSimulatePrincipalPolicy:
+		output, errSimulate := f.SimulatePrincipalPolicy(r.Context(), &iam.SimulatePrincipalPolicyInput{ //nolint:exhaustruct
+			PolicySourceArn: optionalString(r, "PolicySourceArn"),
+			ActionNames:     members(r, "ActionNames"),
+		})
+		err = errSimulate
+
+		if err == nil {
+			for _, evaluation := range output.EvaluationResults {
+				result.EvaluationResults = append(result.EvaluationResults, evaluationXML{
+					EvalActionName: aws.ToString(evaluation.EvalActionName),
+					EvalDecision:   string(evaluation.EvalDecision),
+				})
+			}
+
+			result.IsTruncated = aws.Bool(false)
+		}
+	case OperationGetSAMLProvider:
+		output, errGet := f.GetSAMLProvider(r.Context(), &iam.GetSAMLProviderInput{
+			SAMLProviderArn: optionalString(r, "SAMLProviderArn"),
+		})
+		err = errGet
+
+		if err == nil {
+			result.SAMLMetadataDocument = aws.ToString(output.SAMLMetadataDocument)
+		}
+	case OperationGetOpenIDConnectProvider:
+		output, errGet := f.GetOpenIDConnectProvider(r.Context(), &iam.GetOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: optionalString(r, "OpenIDConnectProviderArn"),
+		})
+		err = errGet
+
+		if err == nil {
+			result.URL = aws.ToString(output.Url)
+		}
+	default:
+		err = &smithy.GenericAPIError{Code: "InvalidAction", Message: "unsupported action " + action} //nolint:exhaustruct
+	}
+
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	writeXML(w, http.StatusOK, responseXML{
+		XMLName:   xml.Name{Space: "", Local: action + "Response"},
+		Namespace: iamNamespace,
+		Result:    result,
+		RequestID: requestID,
+	})
+}
+
+// writeError answers with the error, with the status of the response it wraps, if any, or the status IAM uses for
+// its code.
+func writeError(w http.ResponseWriter, err error) {
+	code, message := "ServiceFailure", err.Error()
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code, message = apiErr.ErrorCode(), apiErr.ErrorMessage()
+	}
+
+	status := http.StatusBadRequest
+
+	var responseErr *smithyhttp.ResponseError
+
+	switch {
+	case errors.As(err, &responseErr):
+		status = responseErr.HTTPStatusCode()
+	case code == "NoSuchEntity":
+		status = http.StatusNotFound
+	case code == "ServiceFailure":
+		status = http.StatusInternalServerError
+	}
+
+	faultType := "Sender"
+	if status >= http.StatusInternalServerError {
+		faultType = "Receiver"
+	}
+
+	writeXML(w, status, errorResponseXML{
+		XMLName:   xml.Name{Space: "", Local: "ErrorResponse"},
+		Namespace: iamNamespace,
+		Type:      faultType,
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
+
+func writeXML(w http.ResponseWriter, status int, document any) {
+	data, err := xml.Marshal(document)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write(append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 variHQ OÜ
+// SPDX-License-Identifier: BSD-3-Clause
+
+package veiltest
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+)
+
+// newClient returns an SDK IAM client calling the fake over HTTP, without retries.
+func newClient(t *testing.T, fake *FakeIAM) *iam.Client {
+	t.Helper()
+
+	server := NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return iam.NewFromConfig(aws.Config{ //nolint:exhaustruct
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKIA0123456789", "secret", ""),
+		BaseEndpoint:     aws.String(server.URL),
+		RetryMaxAttempts: 1,
+	})
+}
+
+func TestNewServer_ListRoles(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, time.January, 2, 3, 4, 5, 0, time.UTC)
+	roles := testRoles(5)
+	roles[0].CreateDate = &created
+	roles[0].Tags = []types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}}
+
+	fake := &FakeIAM{Roles: roles, PageSize: 2}
+	client := newClient(t, fake)
+
+	got := make([][]string, 0)
+
+	paginator := iam.NewListRolesPaginator(client, &iam.ListRolesInput{})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(t.Context())
+		if err != nil {
+			t.Fatalf("NextPage() unexpected error: %v", err)
+		}
+
+		if len(got) == 0 {
+			first := output.Roles[0]
+			if !aws.ToTime(first.CreateDate).Equal(created) || len(first.Tags) != 1 ||
+				aws.ToString(first.AssumeRolePolicyDocument) != url.QueryEscape(trustPolicy) {
+				t.Errorf("ListRoles() first role = %+v, want it as configured", first)
+			}
+		}
+
+		got = append(got, roleNames(output.Roles))
+	}
+
+	want := [][]string{{"role-1", "role-2"}, {"role-3", "role-4"}, {"role-5"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListRoles() pages = %v, want %v", got, want)
+	}
+}
+
+func TestNewServer_errors(t *testing.T) {
+	t.Parallel()
+
+	fake := &FakeIAM{
+		Roles:    testRoles(1),
+		Throttle: map[string]int{OperationListRoleTags: 1},
+		Errors:   map[string]error{OperationSimulatePrincipalPolicy: AccessDeniedError(OperationSimulatePrincipalPolicy)},
+	}
+	client := newClient(t, fake)
+
+	_, err := client.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("missing")})
+
+	var notFound *types.NoSuchEntityException
+	if !errors.As(err, &notFound) {
+		t.Errorf("GetRole() error = %v, want NoSuchEntityException", err)
+	}
+
+	_, err = client.ListRoleTags(t.Context(), &iam.ListRoleTagsInput{RoleName: aws.String("role-1")})
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "Throttling" {
+		t.Errorf("ListRoleTags() error = %v, want Throttling", err)
+	}
+
+	_, err = client.SimulatePrincipalPolicy(t.Context(), &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String("arn:aws:iam::123456789012:role/role-1"),
+		ActionNames:     []string{"s3:GetObject"},
+	})
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+		t.Errorf("SimulatePrincipalPolicy() error = %v, want AccessDenied", err)
+	}
+}
+
+func TestNewServer_operations(t *testing.T) {
+	t.Parallel()
+
+	samlARN := "arn:aws:iam::123456789012:saml-provider/okta"
+	oidcARN := "arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"
+	fake := &FakeIAM{
+		Roles:         testRoles(1),
+		Tags:          map[string][]types.Tag{"role-1": {{Key: aws.String("team"), Value: aws.String("platform")}}},
+		SAMLProviders: map[string]string{samlARN: "<EntityDescriptor/>"},
+		OIDCProviders: map[string]string{oidcARN: "token.actions.githubusercontent.com"},
+		Decisions: map[string]types.PolicyEvaluationDecisionType{
+			"s3:GetObject": types.PolicyEvaluationDecisionTypeAllowed,
+		},
+	}
+	client := newClient(t, fake)
+
+	tags, err := client.ListRoleTags(t.Context(), &iam.ListRoleTagsInput{RoleName: aws.String("role-1")})
+	if err != nil || len(tags.Tags) != 1 || aws.ToString(tags.Tags[0].Value) != "platform" {
+		t.Errorf("ListRoleTags() = %+v, %v, want the configured tag", tags, err)
+	}
+
+	document := `{"Version":"2012-10-17","Statement":[]}`
+
+	_, err = client.UpdateAssumeRolePolicy(t.Context(), &iam.UpdateAssumeRolePolicyInput{
+		RoleName:       aws.String("role-1"),
+		PolicyDocument: aws.String(document),
+	})
+	if err != nil {
+		t.Fatalf("UpdateAssumeRolePolicy() unexpected error: %v", err)
+	}
+
+	role, err := client.GetRole(t.Context(), &iam.GetRoleInput{RoleName: aws.String("role-1")})
+	if err != nil || aws.ToString(role.Role.AssumeRolePolicyDocument) != url.QueryEscape(document) {
+		t.Errorf("GetRole() = %+v, %v, want the updated policy", role, err)
+	}
+
+	simulation, err := client.SimulatePrincipalPolicy(t.Context(), &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String("arn:aws:iam::123456789012:role/role-1"),
+		ActionNames:     []string{"s3:GetObject", "s3:PutObject"},
+	})
+	if err != nil || len(simulation.EvaluationResults) != 2 ||
+		simulation.EvaluationResults[0].EvalDecision != types.PolicyEvaluationDecisionTypeAllowed ||
+		simulation.EvaluationResults[1].EvalDecision != types.PolicyEvaluationDecisionTypeImplicitDeny {
+		t.Errorf("SimulatePrincipalPolicy() = %+v, %v, want the configured decisions", simulation, err)
+	}
+
+	saml, err := client.GetSAMLProvider(t.Context(), &iam.GetSAMLProviderInput{SAMLProviderArn: aws.String(samlARN)})
+	if err != nil || aws.ToString(saml.SAMLMetadataDocument) != "<EntityDescriptor/>" {
+		t.Errorf("GetSAMLProvider() = %+v, %v, want the metadata", saml, err)
+	}
+
+	oidc, err := client.GetOpenIDConnectProvider(t.Context(), &iam.GetOpenIDConnectProviderInput{
+		OpenIDConnectProviderArn: aws.String(oidcARN),
+	})
+	if err != nil || aws.ToString(oidc.Url) != "token.actions.githubusercontent.com" {
+		t.Errorf("GetOpenIDConnectProvider() = %+v, %v, want the issuer", oidc, err)
+	}
+
+	if calls := fake.Calls(OperationUpdateAssumeRolePolicy); len(calls) != 1 {
+		t.Errorf("Calls() = %v, want the update", calls)
+	}
+}